@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/soydoradesu/product_discovery/internal/config"
+	"github.com/soydoradesu/product_discovery/internal/db"
+	"github.com/soydoradesu/product_discovery/internal/domain"
+	"github.com/soydoradesu/product_discovery/internal/repository/postgres"
+	"github.com/soydoradesu/product_discovery/internal/service"
+)
+
+const usage = `usage: admin <command> [flags]
+
+commands:
+  grant-role   -email <email> [-role admin]   grant a role to a user
+  revoke-role  -email <email> [-role admin]   revoke a role from a user
+  export       [-format csv|ndjson|json] [-o file] [-q text] [-category 1,2]
+               [-min-price n] [-max-price n] [-in-stock true|false]
+                                              write matching products to stdout or a file
+  load-rates   [-format csv|json] <file|->    replace the exchange rate table
+`
+
+var errUsage = errors.New("usage")
+
+func main() {
+	log.SetFlags(log.LstdFlags | log.LUTC)
+
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	if err := run(os.Args[1], os.Args[2:]); err != nil {
+		if errors.Is(err, errUsage) {
+			fmt.Fprint(os.Stderr, usage)
+			os.Exit(2)
+		}
+		log.Fatalf("%s: %v", os.Args[1], err)
+	}
+}
+
+func run(cmd string, args []string) error {
+	switch cmd {
+	case "grant-role", "revoke-role":
+		return runRole(cmd, args)
+	case "export":
+		return runExport(args)
+	case "load-rates":
+		return runLoadRates(args)
+	default:
+		return errUsage
+	}
+}
+
+func runRole(cmd string, args []string) error {
+	fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+	email := fs.String("email", "", "user email")
+	role := fs.String("role", domain.RoleAdmin, "role name")
+	_ = fs.Parse(args)
+
+	if *email == "" {
+		return errUsage
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	pool, err := connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	access := service.NewAccessService(postgres.NewRoleRepo(pool), postgres.NewUserRepo(pool))
+	if cmd == "grant-role" {
+		err = access.GrantRole(ctx, *email, *role)
+	} else {
+		err = access.RevokeRole(ctx, *email, *role)
+	}
+	if err != nil {
+		return err
+	}
+
+	log.Printf("%s: %s %s", cmd, *email, *role)
+	return nil
+}
+
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	format := fs.String("format", service.CatalogFormatNDJSON, "csv, ndjson or json")
+	out := fs.String("o", "", "output file (default stdout)")
+	q := fs.String("q", "", "full-text query")
+	categories := fs.String("category", "", "comma-separated category ids")
+	minPrice := fs.String("min-price", "", "minimum price in the catalog currency")
+	maxPrice := fs.String("max-price", "", "maximum price in the catalog currency")
+	inStock := fs.String("in-stock", "", "true or false")
+	timeout := fs.Duration("timeout", 30*time.Minute, "give up after this long")
+	_ = fs.Parse(args)
+
+	if !service.IsExportFormat(*format) {
+		return errUsage
+	}
+	params := domain.SearchParams{Q: *q}
+	for _, s := range strings.Split(*categories, ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || id <= 0 {
+			return fmt.Errorf("invalid category id %q", s)
+		}
+		params.CategoryID = append(params.CategoryID, id)
+	}
+	currency := config.Load().Currency
+	if *minPrice != "" {
+		m, err := domain.ParseMoney(*minPrice, currency)
+		if err != nil || m.Amount < 0 {
+			return fmt.Errorf("invalid -min-price %q", *minPrice)
+		}
+		params.MinPrice = &m
+	}
+	if *maxPrice != "" {
+		m, err := domain.ParseMoney(*maxPrice, currency)
+		if err != nil || m.Amount < 0 {
+			return fmt.Errorf("invalid -max-price %q", *maxPrice)
+		}
+		params.MaxPrice = &m
+	}
+	if *inStock != "" {
+		b, err := strconv.ParseBool(*inStock)
+		if err != nil {
+			return fmt.Errorf("invalid -in-stock %q", *inStock)
+		}
+		params.InStock = &b
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	pool, err := connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	products := service.NewProductService(postgres.NewProductRepo(pool))
+	products.Currency = currency
+	if *out == "" {
+		return products.Export(ctx, os.Stdout, *format, params)
+	}
+
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	if err := products.Export(ctx, f, *format, params); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	log.Printf("export: wrote %s", *out)
+	return nil
+}
+
+func runLoadRates(args []string) error {
+	fs := flag.NewFlagSet("load-rates", flag.ExitOnError)
+	format := fs.String("format", "", "csv or json (default from the file extension, else csv)")
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 {
+		return errUsage
+	}
+
+	name := fs.Arg(0)
+	in := os.Stdin
+	if name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	if *format == "" {
+		*format = service.RatesFormatCSV
+		if strings.EqualFold(filepath.Ext(name), ".json") {
+			*format = service.RatesFormatJSON
+		}
+	}
+
+	rates, err := service.ParseExchangeRates(in, *format)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	pool, err := connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	loaded, err := service.NewExchangeRateService(postgres.NewExchangeRateRepo(pool)).Replace(ctx, rates)
+	if err != nil {
+		return err
+	}
+	log.Printf("load-rates: %d rates loaded", len(loaded))
+	return nil
+}
+
+func connect(ctx context.Context) (*pgxpool.Pool, error) {
+	cfg := config.Load()
+
+	pool, err := db.Connect(ctx, cfg.PostgresDSN())
+	if err != nil {
+		return nil, fmt.Errorf("db connect: %w", err)
+	}
+
+	if err := db.ApplyMigrations(ctx, pool, "./migrations"); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("migrate: %w", err)
+	}
+	return pool, nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/soydoradesu/product_discovery/internal/config"
+	"github.com/soydoradesu/product_discovery/internal/db"
+	"github.com/soydoradesu/product_discovery/internal/repository/postgres"
+	"github.com/soydoradesu/product_discovery/internal/service"
+)
+
+const usage = `usage: import [-format csv|ndjson] [-dry-run] <file|->
+
+Upserts products from a CSV or NDJSON catalog on their externalKey. The
+format defaults to the file extension; use -format when reading stdin.
+Exits with status 1 when any row was rejected.
+`
+
+func main() {
+	log.SetFlags(log.LstdFlags | log.LUTC)
+
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	fs.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	format := fs.String("format", "", "csv or ndjson")
+	dryRun := fs.Bool("dry-run", false, "validate and report without writing")
+	timeout := fs.Duration("timeout", 10*time.Minute, "give up after this long")
+	_ = fs.Parse(os.Args[1:])
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	path := fs.Arg(0)
+
+	var in io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			log.Fatalf("open: %v", err)
+		}
+		defer f.Close()
+		in = f
+		if *format == "" {
+			*format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+			if *format == "jsonl" {
+				*format = service.CatalogFormatNDJSON
+			}
+		}
+	}
+
+	cfg := config.Load()
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	pool, err := db.Connect(ctx, cfg.PostgresDSN())
+	if err != nil {
+		log.Fatalf("db connect: %v", err)
+	}
+	defer pool.Close()
+
+	if err := db.ApplyMigrations(ctx, pool, "./migrations"); err != nil {
+		log.Fatalf("migrate: %v", err)
+	}
+
+	imports := service.NewCatalogImportService(postgres.NewCatalogImportRepo(pool))
+	imports.MaxRows = 0
+	imports.Currency = cfg.Currency
+	summary, err := imports.Import(ctx, in, *format, *dryRun)
+	if err != nil {
+		log.Fatalf("import: %v", err)
+	}
+
+	for _, e := range summary.Errors {
+		if e.Field != "" {
+			fmt.Fprintf(os.Stderr, "line %d: %s %s\n", e.Line, e.Field, e.Message)
+		} else {
+			fmt.Fprintf(os.Stderr, "line %d: %s\n", e.Line, e.Message)
+		}
+	}
+
+	mode := "import"
+	if summary.DryRun {
+		mode = "dry run"
+	}
+	log.Printf("%s done (rows=%d, created=%d, updated=%d, skipped=%d, errors=%d)",
+		mode, summary.Rows, summary.Created, summary.Updated, summary.Skipped, len(summary.Errors))
+
+	if len(summary.Errors) > 0 {
+		pool.Close()
+		os.Exit(1)
+	}
+}
@@ -37,6 +37,7 @@ func main() {
 		Users:      users,
 		Products:   products,
 		RandomSeed: randomSeed,
+		Currency:   cfg.Currency,
 	}
 
 	ctxSeed, cancel := context.WithTimeout(ctx, 2*time.Minute)
@@ -6,9 +6,11 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/soydoradesu/product_discovery/internal/auth"
 	"github.com/soydoradesu/product_discovery/internal/config"
 	"github.com/soydoradesu/product_discovery/internal/db"
 	httpapi "github.com/soydoradesu/product_discovery/internal/http"
+	"github.com/soydoradesu/product_discovery/internal/mail"
 
 )
 
@@ -17,6 +19,15 @@ func main() {
 
 	cfg := config.Load()
 
+	keys, err := auth.LoadKeyring(cfg)
+	if err != nil {
+		log.Fatalf("jwt keys: %v", err)
+	}
+	mailer, err := mail.New(cfg)
+	if err != nil {
+		log.Fatalf("mailer: %v", err)
+	}
+
 	ctx := context.Background()
 	pool, err := db.Connect(ctx, cfg.PostgresDSN())
 	if err != nil {
@@ -28,7 +39,7 @@ func main() {
 		log.Fatalf("migrate: %v", err)
 	}
 
-	r := httpapi.NewRouter(cfg, pool)
+	r := httpapi.NewRouter(cfg, pool, keys, mailer)
 
 	srv := &http.Server{
 		Addr: cfg.BackendAddr,
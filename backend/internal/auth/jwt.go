@@ -7,34 +7,74 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// PurposeTwoFactor marks a token that only proves the password step of a
+// two-factor login; it is never accepted as a session.
+const PurposeTwoFactor = "2fa"
+
+// PurposeMagicLink marks an emailed login link. The email is the subject and
+// the jti names the server-side record that makes the link single-use.
+const PurposeMagicLink = "magic-link"
+
+// PurposePasskeyRegister and PurposePasskeyLogin carry the challenge of a
+// WebAuthn ceremony between its begin and finish requests, as the jti.
+const (
+	PurposePasskeyRegister = "passkey-register"
+	PurposePasskeyLogin    = "passkey-login"
+)
+
 type Claims struct {
-	UserID int64 `json:"userId"`
+	UserID  int64  `json:"userId"`
+	Purpose string `json:"purpose,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func SignJWT(secret string, userID int64, ttl time.Duration) (string, error) {
-	now := time.Now()
-	claims := Claims{
-		UserID: userID,
-		RegisteredClaims: jwt.RegisteredClaims{
-			IssuedAt:  jwt.NewNumericDate(now),
-			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
-		},
+func SignJWT(keys *Keyring, userID int64, ttl time.Duration) (string, error) {
+	return signClaims(keys, Claims{UserID: userID}, ttl)
+}
+
+// SignSession issues a session token bound to a server-side session, whose id
+// is carried as the token's jti so the session can be revoked.
+func SignSession(keys *Keyring, userID int64, sessionID string, ttl time.Duration) (string, error) {
+	return signClaims(keys, Claims{UserID: userID, RegisteredClaims: jwt.RegisteredClaims{ID: sessionID}}, ttl)
+}
+
+func VerifyJWT(keys *Keyring, tokenStr string) (*Claims, error) {
+	claims, err := keys.parse(tokenStr)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Purpose != "" {
+		return nil, errors.New("invalid token")
 	}
-	t := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return t.SignedString([]byte(secret))
+	return claims, nil
+}
+
+func SignChallenge(keys *Keyring, userID int64, purpose string, ttl time.Duration) (string, error) {
+	return signClaims(keys, Claims{UserID: userID, Purpose: purpose}, ttl)
 }
 
-func VerifyJWT(secret, tokenStr string) (*Claims, error) {
-	t, err := jwt.ParseWithClaims(tokenStr, &Claims{}, func(token *jwt.Token) (any, error) {
-		return []byte(secret), nil
-	})
+func VerifyChallenge(keys *Keyring, tokenStr, purpose string) (*Claims, error) {
+	claims, err := keys.parse(tokenStr)
 	if err != nil {
 		return nil, err
 	}
-	claims, ok := t.Claims.(*Claims)
-	if !ok || !t.Valid {
+	if purpose == "" || claims.Purpose != purpose {
 		return nil, errors.New("invalid token")
 	}
 	return claims, nil
-}
\ No newline at end of file
+}
+
+func SignCeremony(keys *Keyring, userID int64, purpose, challenge string, ttl time.Duration) (string, error) {
+	return signClaims(keys, Claims{UserID: userID, Purpose: purpose, RegisteredClaims: jwt.RegisteredClaims{ID: challenge}}, ttl)
+}
+
+func SignMagicLink(keys *Keyring, email, linkID string, ttl time.Duration) (string, error) {
+	return signClaims(keys, Claims{Purpose: PurposeMagicLink, RegisteredClaims: jwt.RegisteredClaims{Subject: email, ID: linkID}}, ttl)
+}
+
+func signClaims(keys *Keyring, claims Claims, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims.IssuedAt = jwt.NewNumericDate(now)
+	claims.ExpiresAt = jwt.NewNumericDate(now.Add(ttl))
+	return keys.sign(claims)
+}
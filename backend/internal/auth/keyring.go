@@ -0,0 +1,231 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/soydoradesu/product_discovery/internal/config"
+)
+
+// Signing algorithms a keyring key may use.
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+// HMACKeyID is the kid of the single key made from JWT_SECRET when no
+// JWT_KEYS are configured.
+const HMACKeyID = "default"
+
+// Key is one signing key. Tokens carry its ID in the kid header.
+type Key struct {
+	ID  string
+	Alg string
+
+	secret  []byte
+	private crypto.Signer
+}
+
+func NewHMACKey(id string, secret []byte) (Key, error) {
+	if id == "" || len(secret) == 0 {
+		return Key{}, errors.New("keyring: hmac key needs an id and a secret")
+	}
+	return Key{ID: id, Alg: AlgHS256, secret: secret}, nil
+}
+
+// NewPrivateKey parses a PEM encoded RSA (PKCS#1 or PKCS#8) or Ed25519
+// (PKCS#8) private key for alg.
+func NewPrivateKey(id, alg string, pemBytes []byte) (Key, error) {
+	if id == "" {
+		return Key{}, errors.New("keyring: key needs an id")
+	}
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return Key{}, fmt.Errorf("keyring: key %q: no PEM block", id)
+	}
+
+	var parsed any
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return Key{}, fmt.Errorf("keyring: key %q: unsupported PEM type %q", id, block.Type)
+	}
+	if err != nil {
+		return Key{}, fmt.Errorf("keyring: key %q: %w", id, err)
+	}
+
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		if alg != AlgRS256 {
+			break
+		}
+		if k.N.BitLen() < 2048 {
+			return Key{}, fmt.Errorf("keyring: key %q: rsa keys must be at least 2048 bits", id)
+		}
+		return Key{ID: id, Alg: alg, private: k}, nil
+	case ed25519.PrivateKey:
+		if alg != AlgEdDSA {
+			break
+		}
+		return Key{ID: id, Alg: alg, private: k}, nil
+	}
+	return Key{}, fmt.Errorf("keyring: key %q: key type does not match alg %q", id, alg)
+}
+
+// Keyring signs tokens with its newest key and verifies them against any of
+// its keys, so keys can be rotated without logging everybody out: add the new
+// key first, and drop the old one once tokens it signed have expired.
+type Keyring struct {
+	keys []Key
+}
+
+// NewKeyring takes the keys newest first.
+func NewKeyring(keys ...Key) (*Keyring, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("keyring: no keys")
+	}
+	seen := map[string]bool{}
+	for _, k := range keys {
+		if seen[k.ID] {
+			return nil, fmt.Errorf("keyring: duplicate key id %q", k.ID)
+		}
+		seen[k.ID] = true
+	}
+	return &Keyring{keys: keys}, nil
+}
+
+// NewHMACKeyring is a single HS256 key ring, as used by tests and by
+// deployments that only set JWT_SECRET.
+func NewHMACKeyring(secret string) *Keyring {
+	return &Keyring{keys: []Key{{ID: HMACKeyID, Alg: AlgHS256, secret: []byte(secret)}}}
+}
+
+// LoadKeyring builds the keyring from JWT_KEYS, or from JWT_SECRET when no
+// keys are listed. With secure cookies on (i.e. in production) it refuses the
+// default secret.
+func LoadKeyring(cfg config.Config) (*Keyring, error) {
+	if len(cfg.JWTKeys) == 0 {
+		if cfg.JWTSecret == "" || (cfg.CookieSecure && cfg.JWTSecret == config.DefaultJWTSecret) {
+			return nil, errors.New("keyring: set JWT_SECRET or JWT_KEYS; the default secret is not allowed with COOKIE_SECURE=true")
+		}
+		return NewHMACKeyring(cfg.JWTSecret), nil
+	}
+
+	keys := make([]Key, 0, len(cfg.JWTKeys))
+	for _, c := range cfg.JWTKeys {
+		var k Key
+		var err error
+		switch c.Alg {
+		case AlgHS256:
+			if cfg.CookieSecure && c.Secret == config.DefaultJWTSecret {
+				return nil, fmt.Errorf("keyring: key %q uses the default secret", c.ID)
+			}
+			k, err = NewHMACKey(c.ID, []byte(c.Secret))
+		case AlgRS256, AlgEdDSA:
+			var pemBytes []byte
+			pemBytes, err = os.ReadFile(c.KeyFile)
+			if err != nil {
+				return nil, fmt.Errorf("keyring: key %q: %w", c.ID, err)
+			}
+			k, err = NewPrivateKey(c.ID, c.Alg, pemBytes)
+		default:
+			err = fmt.Errorf("keyring: key %q: unsupported alg %q", c.ID, c.Alg)
+		}
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return NewKeyring(keys...)
+}
+
+// HasPublicKeys reports whether any key is asymmetric, i.e. whether
+// publishing a JWKS makes sense.
+func (r *Keyring) HasPublicKeys() bool {
+	for _, k := range r.keys {
+		if k.private != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// JWKS returns the public half of every asymmetric key.
+func (r *Keyring) JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	for _, k := range r.keys {
+		if k.private == nil {
+			continue
+		}
+		jwk, err := PublicJWK(k.ID, k.Alg, k.private.Public())
+		if err != nil {
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+func (r *Keyring) sign(claims Claims) (string, error) {
+	k := r.keys[0]
+	var t *jwt.Token
+	var signingKey any
+	switch k.Alg {
+	case AlgHS256:
+		t, signingKey = jwt.NewWithClaims(jwt.SigningMethodHS256, claims), k.secret
+	case AlgRS256:
+		t, signingKey = jwt.NewWithClaims(jwt.SigningMethodRS256, claims), k.private
+	case AlgEdDSA:
+		t, signingKey = jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims), k.private
+	default:
+		return "", fmt.Errorf("keyring: unsupported alg %q", k.Alg)
+	}
+	t.Header["kid"] = k.ID
+	return t.SignedString(signingKey)
+}
+
+func (r *Keyring) parse(tokenStr string) (*Claims, error) {
+	t, err := jwt.ParseWithClaims(tokenStr, &Claims{}, r.keyFunc,
+		jwt.WithValidMethods([]string{AlgHS256, AlgRS256, AlgEdDSA}))
+	if err != nil {
+		return nil, err
+	}
+	claims, ok := t.Claims.(*Claims)
+	if !ok || !t.Valid {
+		return nil, errors.New("invalid token")
+	}
+	return claims, nil
+}
+
+// keyFunc picks the key named by the token's kid and only if it was made for
+// the token's algorithm, so e.g. an RSA public key is never tried as an HMAC
+// secret.
+func (r *Keyring) keyFunc(t *jwt.Token) (any, error) {
+	kid, _ := t.Header["kid"].(string)
+	for _, k := range r.keys {
+		if k.ID != kid {
+			continue
+		}
+		if k.Alg != t.Method.Alg() {
+			return nil, errors.New("token algorithm does not match key")
+		}
+		if k.private != nil {
+			return k.private.Public(), nil
+		}
+		return k.secret, nil
+	}
+	return nil, errors.New("unknown signing key")
+}
@@ -3,8 +3,14 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
+
+	"github.com/soydoradesu/product_discovery/internal/domain"
 )
 
+// DefaultJWTSecret is only fit for local development.
+const DefaultJWTSecret = "change-me"
+
 type Config struct {
 	BackendAddr string
 
@@ -17,14 +23,87 @@ type Config struct {
 	JWTSecret    string
 	CookieSecure bool
 	FrontendURL  string
+	TOTPIssuer   string
+
+	// JWTKeys, newest first, replace JWTSecret when set. The first key signs
+	// new tokens; the others only verify tokens issued before a rotation.
+	JWTKeys []JWTKey
+
+	// Rules for passwords users choose; existing passwords are not rechecked.
+	PasswordMinLength     int
+	PasswordRequireUpper  bool
+	PasswordRequireLower  bool
+	PasswordRequireDigit  bool
+	PasswordRequireSymbol bool
+
+	// PasswordHash picks the algorithm for new hashes (argon2id or bcrypt);
+	// stored hashes with other settings are upgraded on the next login.
+	PasswordHash      string
+	BcryptCost        int
+	Argon2MemoryKiB   int
+	Argon2Iterations  int
+	Argon2Parallelism int
+
+	// Magic-link login. BackendPublicURL is where emailed links point.
+	BackendPublicURL string
+	MagicLinkTTL     int // minutes
+	MagicLinkSignup  bool
+
+	Mailer       string // log, file or smtp
+	MailFrom     string
+	MailDir      string
+	SMTPAddr     string
+	SMTPUser     string
+	SMTPPassword string
 
 	GoogleClientID     string
 	GoogleClientSecret string
 	GoogleRedirectURL  string
+
+	// Passkeys are scoped to WebAuthnRPID, a domain the frontend is served
+	// from, and only work on pages at WebAuthnOrigins.
+	WebAuthnRPID    string
+	WebAuthnRPName  string
+	WebAuthnOrigins []string
+
+	// ProductDetailVisibility is who may open a product's detail page: full
+	// (everyone), preview (anonymous visitors get a reduced view) or login.
+	ProductDetailVisibility string
+
+	// Currency is the ISO 4217 code of catalog prices that do not name
+	// their own.
+	Currency string
+
+	// OIDCProviders includes Google when GOOGLE_CLIENT_ID is set, plus every
+	// provider listed in OIDC_PROVIDERS.
+	OIDCProviders []OIDCProvider
+}
+
+// Values for ProductDetailVisibility.
+const (
+	ProductVisibilityFull    = "full"
+	ProductVisibilityPreview = "preview"
+	ProductVisibilityLogin   = "login"
+)
+
+type JWTKey struct {
+	ID      string
+	Alg     string // HS256, RS256 or EdDSA
+	Secret  string // HS256 only
+	KeyFile string // PEM private key for RS256 and EdDSA
+}
+
+type OIDCProvider struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
 }
 
 func Load() Config {
-	return Config{
+	c := Config{
 		BackendAddr: getenv("BACKEND_ADDR", ":8080"),
 
 		PostgresHost: getenv("POSTGRES_HOST", "localhost"),
@@ -33,14 +112,130 @@ func Load() Config {
 		PostgresPass: getenv("POSTGRES_PASSWORD", "app"),
 		PostgresDB:   getenv("POSTGRES_DB", "productdb"),
 
-		JWTSecret:    getenv("JWT_SECRET", "change-me"),
+		JWTSecret:    getenv("JWT_SECRET", DefaultJWTSecret),
 		CookieSecure: getenvBool("COOKIE_SECURE", false),
 		FrontendURL:  getenv("FRONTEND_URL", "http://localhost:5173"),
+		TOTPIssuer:   getenv("TOTP_ISSUER", "Product Discovery"),
+
+		PasswordMinLength:     getenvInt("PASSWORD_MIN_LENGTH", 8),
+		PasswordRequireUpper:  getenvBool("PASSWORD_REQUIRE_UPPER", false),
+		PasswordRequireLower:  getenvBool("PASSWORD_REQUIRE_LOWER", false),
+		PasswordRequireDigit:  getenvBool("PASSWORD_REQUIRE_DIGIT", false),
+		PasswordRequireSymbol: getenvBool("PASSWORD_REQUIRE_SYMBOL", false),
+
+		PasswordHash:      getenv("PASSWORD_HASH", "argon2id"),
+		BcryptCost:        getenvPositiveInt("BCRYPT_COST", 10),
+		Argon2MemoryKiB:   getenvPositiveInt("ARGON2_MEMORY_KIB", 19*1024),
+		Argon2Iterations:  getenvPositiveInt("ARGON2_ITERATIONS", 2),
+		Argon2Parallelism: getenvPositiveInt("ARGON2_PARALLELISM", 1),
+
+		BackendPublicURL: getenv("BACKEND_PUBLIC_URL", "http://localhost:8080"),
+		MagicLinkTTL:     getenvPositiveInt("MAGIC_LINK_TTL_MINUTES", 15),
+		MagicLinkSignup:  getenvBool("MAGIC_LINK_SIGNUP", false),
+
+		Mailer:       getenv("MAILER", "log"),
+		MailFrom:     getenv("MAIL_FROM", "Product Discovery <no-reply@localhost>"),
+		MailDir:      getenv("MAIL_DIR", "./mail-outbox"),
+		SMTPAddr:     getenv("SMTP_ADDR", ""),
+		SMTPUser:     getenv("SMTP_USER", ""),
+		SMTPPassword: getenv("SMTP_PASSWORD", ""),
+
+		WebAuthnRPID:   getenv("WEBAUTHN_RP_ID", "localhost"),
+		WebAuthnRPName: getenv("WEBAUTHN_RP_NAME", "Product Discovery"),
+
+		ProductDetailVisibility: productDetailVisibility(getenv("PRODUCT_DETAIL_VISIBILITY", ProductVisibilityLogin)),
+		Currency:                strings.ToUpper(strings.TrimSpace(getenv("CATALOG_CURRENCY", "IDR"))),
 
 		GoogleClientID:     getenv("GOOGLE_CLIENT_ID", ""),
 		GoogleClientSecret: getenv("GOOGLE_CLIENT_SECRET", ""),
 		GoogleRedirectURL:  getenv("GOOGLE_REDIRECT_URL", "http://localhost:8080/api/auth/google/callback"),
 	}
+	if c.PasswordHash != "argon2id" && c.PasswordHash != "bcrypt" {
+		c.PasswordHash = "argon2id"
+	}
+	if !domain.IsCurrencyCode(c.Currency) {
+		c.Currency = "IDR"
+	}
+	c.JWTKeys = loadJWTKeys()
+	c.OIDCProviders = loadOIDCProviders(c)
+	c.WebAuthnOrigins = splitList(getenv("WEBAUTHN_ORIGINS", c.FrontendURL))
+	return c
+}
+
+// loadJWTKeys reads JWT_KEYS=id1,id2 (newest first) and, for each id,
+// JWT_KEY_<ID>_ALG (default RS256), _SECRET and _FILE.
+func loadJWTKeys() []JWTKey {
+	var out []JWTKey
+	for _, id := range splitList(os.Getenv("JWT_KEYS")) {
+		prefix := "JWT_KEY_" + envName(id) + "_"
+		out = append(out, JWTKey{
+			ID:      id,
+			Alg:     getenv(prefix+"ALG", "RS256"),
+			Secret:  getenv(prefix+"SECRET", ""),
+			KeyFile: getenv(prefix+"FILE", ""),
+		})
+	}
+	return out
+}
+
+// envName upper-cases an id and replaces characters that can't appear in an
+// environment variable name, so key id "2024-10" reads JWT_KEY_2024_10_*.
+func envName(id string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, id)
+}
+
+// loadOIDCProviders reads OIDC_PROVIDERS=name1,name2 and, for each name,
+// OIDC_<NAME>_ISSUER, _CLIENT_ID, _CLIENT_SECRET, _REDIRECT_URL and _SCOPES.
+func loadOIDCProviders(c Config) []OIDCProvider {
+	var out []OIDCProvider
+	if strings.TrimSpace(c.GoogleClientID) != "" && strings.TrimSpace(c.GoogleClientSecret) != "" {
+		out = append(out, OIDCProvider{
+			Name:         "google",
+			Issuer:       "https://accounts.google.com",
+			ClientID:     c.GoogleClientID,
+			ClientSecret: c.GoogleClientSecret,
+			RedirectURL:  c.GoogleRedirectURL,
+		})
+	}
+
+	for _, name := range splitList(os.Getenv("OIDC_PROVIDERS")) {
+		name = strings.ToLower(name)
+		if name == "google" && len(out) > 0 && out[0].Name == "google" {
+			continue
+		}
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		p := OIDCProvider{
+			Name:         name,
+			Issuer:       getenv(prefix+"ISSUER", ""),
+			ClientID:     getenv(prefix+"CLIENT_ID", ""),
+			ClientSecret: getenv(prefix+"CLIENT_SECRET", ""),
+			RedirectURL:  getenv(prefix+"REDIRECT_URL", "http://localhost:8080/api/auth/oidc/"+name+"/callback"),
+			Scopes:       splitList(os.Getenv(prefix + "SCOPES")),
+		}
+		if p.Issuer == "" || p.ClientID == "" {
+			continue
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+func splitList(v string) []string {
+	var out []string
+	for _, s := range strings.FieldsFunc(v, func(r rune) bool { return r == ',' || r == ' ' }) {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
 }
 
 func (c Config) PostgresDSN() string {
@@ -67,6 +262,13 @@ func getenvInt(k string, def int) int {
 	return n
 }
 
+func getenvPositiveInt(k string, def int) int {
+	if n := getenvInt(k, def); n > 0 {
+		return n
+	}
+	return def
+}
+
 func getenvBool(k string, def bool) bool {
 	v := os.Getenv(k)
 	if v == "" {
@@ -77,4 +279,14 @@ func getenvBool(k string, def bool) bool {
 		return def
 	}
 	return b
+}
+
+// productDetailVisibility falls back to requiring a login for unknown values,
+// so a typo never publishes more than intended.
+func productDetailVisibility(v string) string {
+	switch v = strings.ToLower(strings.TrimSpace(v)); v {
+	case ProductVisibilityFull, ProductVisibilityPreview:
+		return v
+	}
+	return ProductVisibilityLogin
 }
\ No newline at end of file
@@ -6,13 +6,244 @@ type User struct {
 	ID int64 `json:"id"`
 	Email string `json:"email"`
 	PasswordHash *string `json:"-"`
-	GoogleID *string `json:"-"`
 	CreatedAt time.Time `json:"createdAt"`
+	// DisabledAt is set while an administrator has disabled the account.
+	DisabledAt *time.Time `json:"disabledAt,omitempty"`
 }
 
+// User list filters for administrators.
+const (
+	UserStatusActive = "active"
+	UserStatusDisabled = "disabled"
+)
+
+type UserListParams struct {
+	// Q matches part of the email address.
+	Q string
+	Status string
+	Page int
+	PageSize int
+}
+
+// UserDetails is what administrators see about one user.
+type UserDetails struct {
+	User
+	HasPassword bool `json:"hasPassword"`
+	Roles []string `json:"roles"`
+	Identities []UserIdentity `json:"identities"`
+	ActiveSessions int `json:"activeSessions"`
+}
+
+// UserIdentity links a user to an account at an external login provider.
+type UserIdentity struct {
+	ID int64 `json:"id"`
+	UserID int64 `json:"-"`
+	Provider string `json:"provider"`
+	Subject string `json:"-"`
+	Email *string `json:"email,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type UserTOTP struct {
+	UserID int64
+	Secret string
+	EnabledAt *time.Time
+	LastUsedStep int64
+	// FailedAttempts counts wrong codes since the last accepted one;
+	// LockedUntil is set once there were too many.
+	FailedAttempts int
+	LockedUntil *time.Time
+}
+
+const (
+	RoleAdmin = "admin"
+
+	PermCatalogWrite = "catalog:write"
+	PermUsersManage = "users:manage"
+	PermAuditRead = "audit:read"
+)
+
+// KnownPermissions lists every permission a role or an API key scope can name.
+var KnownPermissions = []string{PermCatalogWrite, PermUsersManage, PermAuditRead}
+
+// Access is the set of roles a user holds and the permissions they grant.
+type Access struct {
+	Roles []string `json:"roles"`
+	Permissions []string `json:"permissions"`
+}
+
+func (a Access) HasRole(role string) bool {
+	for _, r := range a.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+func (a Access) HasPermission(perm string) bool {
+	for _, p := range a.Permissions {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+type APIKey struct {
+	ID int64 `json:"id"`
+	UserID int64 `json:"-"`
+	Name string `json:"name"`
+	Prefix string `json:"prefix"`
+	Scopes []string `json:"scopes"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	RevokedAt *time.Time `json:"revokedAt,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type Session struct {
+	ID string `json:"id"`
+	UserID int64 `json:"-"`
+	UserAgent string `json:"userAgent"`
+	IP string `json:"ip"`
+	CreatedAt time.Time `json:"createdAt"`
+	LastSeenAt time.Time `json:"lastSeenAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	RevokedAt *time.Time `json:"revokedAt,omitempty"`
+}
+
+// Passkey is a WebAuthn credential registered for passwordless login.
+type Passkey struct {
+	ID int64 `json:"id"`
+	UserID int64 `json:"-"`
+	CredentialID []byte `json:"-"`
+	PublicKey []byte `json:"-"`
+	SignCount uint32 `json:"-"`
+	AAGUID []byte `json:"-"`
+	Transports []string `json:"transports"`
+	Name string `json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+}
+
+// UserExport is everything stored about a user, as returned by the personal
+// data export.
+type UserExport struct {
+	ExportedAt time.Time `json:"exportedAt"`
+	Profile User `json:"profile"`
+	HasPassword bool `json:"hasPassword"`
+	TwoFactorEnabled bool `json:"twoFactorEnabled"`
+	Roles []string `json:"roles"`
+	Identities []IdentityExport `json:"identities"`
+	Sessions []Session `json:"sessions"`
+	APIKeys []APIKey `json:"apiKeys"`
+	Passkeys []Passkey `json:"passkeys"`
+	AuthEvents []AuthEvent `json:"authEvents"`
+}
+
+// IdentityExport includes the provider subject that UserIdentity hides.
+type IdentityExport struct {
+	Provider string `json:"provider"`
+	Subject string `json:"subject"`
+	Email *string `json:"email,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Auth event types recorded in the audit log.
+const (
+	AuthEventLoginSucceeded = "login_succeeded"
+	AuthEventLoginFailed = "login_failed"
+	AuthEventLogout = "logout"
+	AuthEventReauthenticated = "reauthenticated"
+	AuthEventReauthFailed = "reauth_failed"
+	AuthEventIdentityLinked = "identity_linked"
+	AuthEventIdentityLinkFailed = "identity_link_failed"
+	AuthEventIdentityUnlinked = "identity_unlinked"
+	AuthEventPasswordChanged = "password_changed"
+	AuthEventPasswordChangeFailed = "password_change_failed"
+	AuthEventSessionRevoked = "session_revoked"
+	AuthEventTwoFactorEnabled = "two_factor_enabled"
+	AuthEventTwoFactorDisabled = "two_factor_disabled"
+	AuthEventAPIKeyCreated = "api_key_created"
+	AuthEventAPIKeyRevoked = "api_key_revoked"
+	AuthEventPasskeyAdded = "passkey_added"
+	AuthEventPasskeyRemoved = "passkey_removed"
+	AuthEventMagicLinkRequested = "magic_link_requested"
+	AuthEventAccountDisabled = "account_disabled"
+	AuthEventAccountEnabled = "account_enabled"
+	AuthEventPasswordResetForced = "password_reset_forced"
+)
+
+type AuthEvent struct {
+	ID int64 `json:"id"`
+	UserID *int64 `json:"userId,omitempty"`
+	// Email is the address a login was attempted with, recorded even when it
+	// matched no account.
+	Email *string `json:"email,omitempty"`
+	Type string `json:"type"`
+	Reason string `json:"reason,omitempty"`
+	Provider string `json:"provider,omitempty"`
+	IP string `json:"ip"`
+	UserAgent string `json:"userAgent"`
+	RequestID string `json:"requestId,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// AuthEventFilter selects events newest first. BeforeID continues from the
+// last event of a previous page.
+type AuthEventFilter struct {
+	UserID *int64
+	Email string
+	Type string
+	IP string
+	Since *time.Time
+	Until *time.Time
+	BeforeID int64
+	Limit int
+}
+
+// Category is embedded in products with only ID and Name; the category
+// endpoints fill in the rest.
 type Category struct {
 	ID int64 `json:"id"`
 	Name string `json:"name"`
+	ParentID *int64 `json:"parentId,omitempty"`
+	Slug string `json:"slug,omitempty"`
+	SortOrder int32 `json:"sortOrder,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// CategoryNode is a category in the tree. ProductCount counts distinct
+// products in the category or any of its descendants, matching search.
+type CategoryNode struct {
+	Category
+	ProductCount int64 `json:"productCount"`
+	Children []CategoryNode `json:"children"`
+}
+
+// CategoryInput is an admin write. An empty Slug is derived from the name on
+// create and leaves the current slug alone on update.
+type CategoryInput struct {
+	Name string
+	Slug string
+	ParentID *int64
+	SortOrder int32
+	Description string
+}
+
+// Brand is embedded in products with ID, Name and Slug.
+type Brand struct {
+	ID int64 `json:"id"`
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+// BrandInput is an admin write. An empty Slug is derived from the name on
+// create and leaves the current slug alone on update.
+type BrandInput struct {
+	Name string
+	Slug string
 }
 
 type ProductImage struct {
@@ -22,36 +253,309 @@ type ProductImage struct {
 
 type Product struct {
 	ID int64 `json:"id"`
+	// ExternalKey is set on products that came from a catalog import.
+	ExternalKey *string `json:"externalKey,omitempty"`
 	Name string `json:"name"`
-	Price float64 `json:"price"`
+	Price Money `json:"price"`
 	Description string `json:"description"`
 	Rating float64 `json:"rating"`
 	InStock bool `json:"inStock"`
 	CreatedAt time.Time `json:"createdAt"`
+	Brand *Brand `json:"brand,omitempty"`
 
 	Images []ProductImage `json:"images"`
 	Categories []Category `json:"categories"`
+	// Prices is the product's price list in other currencies; Price is
+	// converted through it or the exchange rates when asked for one.
+	Prices []Money `json:"prices,omitempty"`
+	// Variants, when there are any, decide Price (the lowest variant price)
+	// and InStock (whether any variant is).
+	Variants []Variant `json:"variants,omitempty"`
+	// Attributes are the values defined for the product's categories, in
+	// their order; AttributeValues is every stored value by code.
+	Attributes []ProductAttribute `json:"attributes,omitempty"`
+	AttributeValues map[string]any `json:"-"`
+}
+
+// Variant is one sellable version of a product, told apart by its option
+// values such as {"color": "black", "size": "M"}.
+type Variant struct {
+	ID int64 `json:"id"`
+	SKU string `json:"sku"`
+	Options map[string]string `json:"options"`
+	Price Money `json:"price"`
+	InStock bool `json:"inStock"`
+}
+
+// VariantInput is one variant of an admin write. Price.Currency may be
+// empty; otherwise it must be the product's currency.
+type VariantInput struct {
+	SKU string
+	Options map[string]string
+	Price MoneyInput
+	InStock bool
 }
 
 type ProductSummary struct {
 	ID int64 `json:"id"`
 	Name string `json:"name"`
-	Price float64 `json:"price"`
+	Price Money `json:"price"`
+	// PriceMax is the highest variant price; it equals Price for products
+	// without variants.
+	PriceMax Money `json:"priceMax"`
 	Rating float64 `json:"rating"`
 	InStock bool `json:"inStock"`
 	CreatedAt time.Time `json:"createdAt"`
 	Thumbnail *string `json:"thumbnail,omitempty"`
+	Brand *Brand `json:"brand,omitempty"`
 	Categories []Category `json:"categories"`
 }
 
 type SearchParams struct {
 	Q string
 	CategoryID []int64
-	MinPrice *float64
-	MaxPrice *float64
+	// BrandID keeps products of any of the brands.
+	BrandID []int64
+	// Currency prices are shown, filtered and sorted in; products without a
+	// price in it are left out. Empty means each product's own price.
+	Currency string
+	// MinPrice and MaxPrice bound the price, inclusive; a product with
+	// variants matches when its price range overlaps them.
+	MinPrice *Money
+	MaxPrice *Money
 	InStock *bool
+	// Options keeps products with a variant matching every option, taking
+	// any of the values listed for it, e.g. {"color": {"black", "white"}};
+	// values match without regard to case.
+	Options map[string][]string
+	// Attributes keeps products matching every filter.
+	Attributes []AttributeFilter
 	Sort string
 	Method string
 	Page int
 	PageSize int
-}
\ No newline at end of file
+}
+// ProductInput is an admin write. Nil fields are left unchanged by a partial
+// update; create and full update require the scalar fields to be set.
+type ProductInput struct {
+	Name *string
+	Price *MoneyInput
+	Description *string
+	Rating *float64
+	InStock *bool
+	ImageURLs *[]string
+	CategoryIDs *[]int64
+	// BrandID sets the brand; 0 removes it.
+	BrandID *int64
+	// Attributes replaces the attribute values; they must be defined for
+	// the product's categories.
+	Attributes *map[string]any
+}
+
+// ImportRow is one valid product from a catalog import file. Product carries
+// the scalar fields and images; categories are given by name and created if
+// missing.
+type ImportRow struct {
+	Line int
+	ExternalKey string
+	Product Product
+	Categories []string
+}
+
+type ImportRowError struct {
+	Line int `json:"line"`
+	Field string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// ImportSummary counts what an import did, or would do in a dry run.
+// Skipped rows are invalid ones plus rows identical to the stored product.
+type ImportSummary struct {
+	DryRun bool `json:"dryRun"`
+	Rows int `json:"rows"`
+	Created int `json:"created"`
+	Updated int `json:"updated"`
+	Skipped int `json:"skipped"`
+	Errors []ImportRowError `json:"errors"`
+}
+
+// MoneyInput is a price as written by an admin or an import file: a decimal
+// in major units. An empty Currency means the catalog currency.
+type MoneyInput struct {
+	Amount string
+	Currency string
+}
+
+// ExchangeRate says one unit of Base buys Rate units of Quote. Rate is a
+// positive decimal string so it is never rounded through a float.
+type ExchangeRate struct {
+	Base string `json:"base"`
+	Quote string `json:"quote"`
+	Rate string `json:"rate"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// DefaultWarehouse is where stock goes when no warehouse is named.
+const DefaultWarehouse = "main"
+
+type Warehouse struct {
+	ID int64 `json:"id"`
+	Code string `json:"code"`
+	Name string `json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// StockLevel is the stock of a product, or of one of its variants, in one
+// warehouse. Available is OnHand less what is Reserved.
+type StockLevel struct {
+	VariantID *int64 `json:"variantId,omitempty"`
+	Warehouse string `json:"warehouse"`
+	OnHand int `json:"onHand"`
+	Reserved int `json:"reserved"`
+	Available int `json:"available"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// ProductStock is a product's inventory as administrators see it. A product
+// with no levels is untracked and keeps the InStock it was given.
+type ProductStock struct {
+	ProductID int64 `json:"productId"`
+	InStock bool `json:"inStock"`
+	LowStockThreshold int `json:"lowStockThreshold"`
+	Levels []StockLevel `json:"levels"`
+}
+
+// StockAdjustment is one administrator change of an on-hand quantity. SKU
+// names the variant even after it is deleted.
+type StockAdjustment struct {
+	ID int64 `json:"id"`
+	ProductID int64 `json:"productId"`
+	VariantID *int64 `json:"variantId,omitempty"`
+	SKU string `json:"sku,omitempty"`
+	Warehouse string `json:"warehouse"`
+	Delta int `json:"delta"`
+	OnHandAfter int `json:"onHandAfter"`
+	Reason string `json:"reason"`
+	ActorUserID *int64 `json:"actorUserId,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// StockAdjustmentInput changes on-hand stock: OnHand, when set, is a counted
+// quantity that replaces it; otherwise Delta is added to it.
+type StockAdjustmentInput struct {
+	ProductID int64
+	VariantID *int64
+	Warehouse string
+	Delta int
+	OnHand *int
+	Reason string
+	ActorUserID *int64
+}
+
+// Reservation statuses.
+const (
+	ReservationActive = "active"
+	ReservationReleased = "released"
+)
+
+// Reservation holds Quantity of an item back from sale in one warehouse.
+type Reservation struct {
+	ID int64 `json:"id"`
+	ProductID int64 `json:"productId"`
+	VariantID *int64 `json:"variantId,omitempty"`
+	Warehouse string `json:"warehouse"`
+	Quantity int `json:"quantity"`
+	Reference string `json:"reference"`
+	Status string `json:"status"`
+	CreatedAt time.Time `json:"createdAt"`
+	ReleasedAt *time.Time `json:"releasedAt,omitempty"`
+}
+
+// ReservationInput asks for Quantity of an item. An empty Warehouse takes it
+// from whichever warehouse has the most available.
+type ReservationInput struct {
+	ProductID int64
+	VariantID *int64
+	Warehouse string
+	Quantity int
+	Reference string
+}
+
+// LowStockItem is a tracked product or variant whose available quantity,
+// summed over warehouses, is at or below the product's threshold.
+type LowStockItem struct {
+	ProductID int64 `json:"productId"`
+	Name string `json:"name"`
+	VariantID *int64 `json:"variantId,omitempty"`
+	SKU *string `json:"sku,omitempty"`
+	OnHand int `json:"onHand"`
+	Reserved int `json:"reserved"`
+	Available int `json:"available"`
+	LowStockThreshold int `json:"lowStockThreshold"`
+}
+
+// Attribute types.
+const (
+	AttributeString = "string"
+	AttributeNumber = "number"
+	AttributeBoolean = "boolean"
+	AttributeEnum = "enum"
+)
+
+// AttributeDefinition is a typed attribute the products of a category, and
+// of its subcategories, can have. Values lists the choices of an enum.
+type AttributeDefinition struct {
+	ID int64 `json:"id"`
+	CategoryID int64 `json:"categoryId"`
+	Code string `json:"code"`
+	Label string `json:"label"`
+	Type string `json:"type"`
+	Unit string `json:"unit,omitempty"`
+	Values []string `json:"values,omitempty"`
+}
+
+// ProductAttribute is one attribute value of a product with its definition.
+type ProductAttribute struct {
+	Code string `json:"code"`
+	Label string `json:"label"`
+	Type string `json:"type"`
+	Unit string `json:"unit,omitempty"`
+	Value any `json:"value"`
+}
+
+// Attribute filter operators.
+const (
+	AttributeOpEq = "="
+	AttributeOpGt = ">"
+	AttributeOpGte = ">="
+	AttributeOpLt = "<"
+	AttributeOpLte = "<="
+)
+
+// AttributeFilter keeps products whose attribute Code equals one of Values,
+// compared case-insensitively, or for the other operators is a number
+// compared to Number.
+type AttributeFilter struct {
+	Code string
+	Op string
+	Values []string
+	Number float64
+}
+
+// AttributeFacet counts the products of a search by their value of one
+// attribute. Number attributes have the range of values instead.
+type AttributeFacet struct {
+	Code string `json:"code"`
+	Label string `json:"label"`
+	Type string `json:"type"`
+	Unit string `json:"unit,omitempty"`
+	Count int64 `json:"count"`
+	Values []FacetValue `json:"values,omitempty"`
+	Min *float64 `json:"min,omitempty"`
+	Max *float64 `json:"max,omitempty"`
+}
+
+type FacetValue struct {
+	Value string `json:"value"`
+	Count int64 `json:"count"`
+}
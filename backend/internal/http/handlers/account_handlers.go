@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/soydoradesu/product_discovery/internal/http/middleware"
+	"github.com/soydoradesu/product_discovery/internal/http/respond"
+	"github.com/soydoradesu/product_discovery/internal/service"
+)
+
+// GET /api/me/export downloads everything stored about the signed-in user.
+func (h *AuthHandlers) ExportAccount(w http.ResponseWriter, r *http.Request) {
+	uid, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		respond.Fail(w, http.StatusUnauthorized, "UNAUTHORIZED", "missing session")
+		return
+	}
+
+	out, err := h.Auth.ExportAccount(r.Context(), uid)
+	if err != nil {
+		if errors.Is(err, service.ErrUserNotFound) {
+			respond.Fail(w, http.StatusUnauthorized, "UNAUTHORIZED", "invalid session")
+			return
+		}
+		respond.Fail(w, http.StatusInternalServerError, "INTERNAL", "something went wrong")
+		return
+	}
+
+	w.Header().Set("Content-Disposition", `attachment; filename="account-export.json"`)
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(out)
+}
+
+// DELETE /api/me permanently deletes the signed-in user. The route requires a
+// recent re-authentication.
+func (h *AuthHandlers) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	uid, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		respond.Fail(w, http.StatusUnauthorized, "UNAUTHORIZED", "missing session")
+		return
+	}
+
+	if err := h.Auth.DeleteAccount(r.Context(), uid); err != nil {
+		if errors.Is(err, service.ErrUserNotFound) {
+			respond.Fail(w, http.StatusUnauthorized, "UNAUTHORIZED", "invalid session")
+			return
+		}
+		respond.Fail(w, http.StatusInternalServerError, "INTERNAL", "something went wrong")
+		return
+	}
+
+	h.clearSessionCookie(w)
+	respond.JSON(w, http.StatusOK, okResp{OK: true})
+}
@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/soydoradesu/product_discovery/internal/domain"
+	"github.com/soydoradesu/product_discovery/internal/http/respond"
+	"github.com/soydoradesu/product_discovery/internal/service"
+)
+
+// productReq is the body of the admin product writes. Omitted fields decode
+// to nil so PATCH can tell them apart from zero values.
+type productReq struct {
+	Name        *string   `json:"name"`
+	Price       *moneyReq `json:"price"`
+	Description *string   `json:"description"`
+	Rating      *float64  `json:"rating"`
+	InStock     *bool     `json:"inStock"`
+	Images      *[]string `json:"images"`
+	CategoryIDs *[]int64  `json:"categoryIds"`
+	// BrandID sets the brand; 0 removes it.
+	BrandID *int64 `json:"brandId"`
+	// Attributes replaces the attribute values, e.g. {"ram_gb":16}.
+	Attributes *map[string]any `json:"attributes"`
+}
+
+// moneyReq is a price in the shape responses use, {"amount":"12.50",
+// "currency":"USD"}, or a bare number or decimal string in the catalog
+// currency.
+type moneyReq struct {
+	Amount   json.Number `json:"amount"`
+	Currency string      `json:"currency"`
+}
+
+func (m *moneyReq) UnmarshalJSON(b []byte) error {
+	var bare json.Number
+	if err := json.Unmarshal(b, &bare); err == nil {
+		*m = moneyReq{Amount: bare}
+		return nil
+	}
+	type plain moneyReq
+	return json.Unmarshal(b, (*plain)(m))
+}
+
+func (req productReq) input() domain.ProductInput {
+	var price *domain.MoneyInput
+	if req.Price != nil {
+		price = &domain.MoneyInput{Amount: req.Price.Amount.String(), Currency: req.Price.Currency}
+	}
+	return domain.ProductInput{
+		Name:        req.Name,
+		Price:       price,
+		Description: req.Description,
+		Rating:      req.Rating,
+		InStock:     req.InStock,
+		ImageURLs:   req.Images,
+		CategoryIDs: req.CategoryIDs,
+		BrandID:     req.BrandID,
+		Attributes:  req.Attributes,
+	}
+}
+
+// POST /api/admin/products
+func (h *ProductHandlers) Create(w http.ResponseWriter, r *http.Request) {
+	var req productReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Fail(w, http.StatusBadRequest, "BAD_REQUEST", "invalid json")
+		return
+	}
+
+	p, err := h.Products.Create(r.Context(), req.input())
+	if err != nil {
+		writeProductWriteError(w, err)
+		return
+	}
+	respond.JSON(w, http.StatusCreated, p)
+}
+
+// PUT /api/admin/products/{id}
+func (h *ProductHandlers) Replace(w http.ResponseWriter, r *http.Request) {
+	h.update(w, r, h.Products.Replace)
+}
+
+// PATCH /api/admin/products/{id}
+func (h *ProductHandlers) Patch(w http.ResponseWriter, r *http.Request) {
+	h.update(w, r, h.Products.Patch)
+}
+
+func (h *ProductHandlers) update(w http.ResponseWriter, r *http.Request, apply func(context.Context, int64, domain.ProductInput) (domain.Product, error)) {
+	id, ok := productIDParam(w, r)
+	if !ok {
+		return
+	}
+	var req productReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Fail(w, http.StatusBadRequest, "BAD_REQUEST", "invalid json")
+		return
+	}
+
+	p, err := apply(r.Context(), id, req.input())
+	if err != nil {
+		writeProductWriteError(w, err)
+		return
+	}
+	respond.JSON(w, http.StatusOK, p)
+}
+
+// DELETE /api/admin/products/{id}
+func (h *ProductHandlers) Delete(w http.ResponseWriter, r *http.Request) {
+	id, ok := productIDParam(w, r)
+	if !ok {
+		return
+	}
+	if err := h.Products.Delete(r.Context(), id); err != nil {
+		writeProductWriteError(w, err)
+		return
+	}
+	respond.JSON(w, http.StatusOK, okResp{OK: true})
+}
+
+type setPriceReq struct {
+	Amount json.Number `json:"amount"`
+}
+
+// PUT /api/admin/products/{id}/prices/{currency}
+func (h *ProductHandlers) SetPrice(w http.ResponseWriter, r *http.Request) {
+	id, ok := productIDParam(w, r)
+	if !ok {
+		return
+	}
+	var req setPriceReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Fail(w, http.StatusBadRequest, "BAD_REQUEST", "invalid json")
+		return
+	}
+
+	p, err := h.Products.SetPrice(r.Context(), id, domain.MoneyInput{Amount: req.Amount.String(), Currency: chi.URLParam(r, "currency")})
+	if err != nil {
+		writeProductWriteError(w, err)
+		return
+	}
+	respond.JSON(w, http.StatusOK, p)
+}
+
+// DELETE /api/admin/products/{id}/prices/{currency}
+func (h *ProductHandlers) DeletePrice(w http.ResponseWriter, r *http.Request) {
+	id, ok := productIDParam(w, r)
+	if !ok {
+		return
+	}
+	if err := h.Products.DeletePrice(r.Context(), id, chi.URLParam(r, "currency")); err != nil {
+		if errors.Is(err, service.ErrPriceNotFound) {
+			respond.Fail(w, http.StatusNotFound, "NOT_FOUND", err.Error())
+			return
+		}
+		writeProductWriteError(w, err)
+		return
+	}
+	respond.JSON(w, http.StatusOK, okResp{OK: true})
+}
+
+type variantReq struct {
+	SKU     string            `json:"sku"`
+	Options map[string]string `json:"options"`
+	Price   moneyReq          `json:"price"`
+	InStock bool              `json:"inStock"`
+}
+
+type replaceVariantsReq struct {
+	Variants []variantReq `json:"variants"`
+}
+
+// PUT /api/admin/products/{id}/variants
+func (h *ProductHandlers) ReplaceVariants(w http.ResponseWriter, r *http.Request) {
+	id, ok := productIDParam(w, r)
+	if !ok {
+		return
+	}
+	var req replaceVariantsReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Fail(w, http.StatusBadRequest, "BAD_REQUEST", "invalid json")
+		return
+	}
+
+	in := make([]domain.VariantInput, len(req.Variants))
+	for i, v := range req.Variants {
+		in[i] = domain.VariantInput{
+			SKU:     v.SKU,
+			Options: v.Options,
+			Price:   domain.MoneyInput{Amount: v.Price.Amount.String(), Currency: v.Price.Currency},
+			InStock: v.InStock,
+		}
+	}
+	p, err := h.Products.ReplaceVariants(r.Context(), id, in)
+	if err != nil {
+		writeProductWriteError(w, err)
+		return
+	}
+	respond.JSON(w, http.StatusOK, p)
+}
+
+func productIDParam(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		respond.Fail(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid product id")
+		return 0, false
+	}
+	return id, true
+}
+
+func writeProductWriteError(w http.ResponseWriter, err error) {
+	var invalid *service.ValidationError
+	switch {
+	case errors.As(err, &invalid):
+		respond.FailFields(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid product", invalid.Fields)
+	case errors.Is(err, service.ErrProductNotFound):
+		respond.Fail(w, http.StatusNotFound, "NOT_FOUND", "product not found")
+	case errors.Is(err, service.ErrProductExists), errors.Is(err, service.ErrVariantExists),
+		errors.Is(err, service.ErrVariantHasStock), errors.Is(err, service.ErrProductHasStock):
+		respond.Fail(w, http.StatusConflict, "CONFLICT", err.Error())
+	default:
+		respond.Fail(w, http.StatusInternalServerError, "INTERNAL", "something went wrong")
+	}
+}
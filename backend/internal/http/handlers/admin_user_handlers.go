@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/soydoradesu/product_discovery/internal/domain"
+	"github.com/soydoradesu/product_discovery/internal/http/middleware"
+	"github.com/soydoradesu/product_discovery/internal/http/respond"
+	"github.com/soydoradesu/product_discovery/internal/service"
+)
+
+type AdminUserHandlers struct {
+	Users *service.UserAdminService
+	// MagicLinks, when set, emails a login link to users whose password was
+	// reset so they can get back in and choose a new one.
+	MagicLinks *service.MagicLinkService
+	Audit      *service.AuditService
+}
+
+type listUsersResp struct {
+	Items      []domain.User `json:"items"`
+	Page       int           `json:"page"`
+	PageSize   int           `json:"pageSize"`
+	Total      int64         `json:"total"`
+	TotalPages int           `json:"totalPages"`
+}
+
+type revokeSessionsResp struct {
+	OK              bool  `json:"ok"`
+	RevokedSessions int64 `json:"revokedSessions"`
+}
+
+// GET /api/admin/users?q=&status=active|disabled&page=&pageSize=
+func (h *AdminUserHandlers) List(w http.ResponseWriter, r *http.Request) {
+	qp := r.URL.Query()
+
+	params := domain.UserListParams{
+		Q:      strings.TrimSpace(qp.Get("q")),
+		Status: qp.Get("status"),
+	}
+	if n, err := strconv.Atoi(qp.Get("page")); err == nil {
+		params.Page = n
+	}
+	if n, err := strconv.Atoi(qp.Get("pageSize")); err == nil {
+		params.PageSize = n
+	}
+
+	items, total, normalized, err := h.Users.List(r.Context(), params)
+	if err != nil {
+		respond.Fail(w, http.StatusInternalServerError, "INTERNAL", "something went wrong")
+		return
+	}
+
+	respond.JSON(w, http.StatusOK, listUsersResp{
+		Items:      items,
+		Page:       normalized.Page,
+		PageSize:   normalized.PageSize,
+		Total:      total,
+		TotalPages: int((total + int64(normalized.PageSize) - 1) / int64(normalized.PageSize)),
+	})
+}
+
+// GET /api/admin/users/{id}
+func (h *AdminUserHandlers) Get(w http.ResponseWriter, r *http.Request) {
+	id, ok := userIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	details, err := h.Users.Details(r.Context(), id)
+	if err != nil {
+		writeAdminUserError(w, err)
+		return
+	}
+	respond.JSON(w, http.StatusOK, details)
+}
+
+// POST /api/admin/users/{id}/disable
+func (h *AdminUserHandlers) Disable(w http.ResponseWriter, r *http.Request) {
+	h.setDisabled(w, r, true)
+}
+
+// POST /api/admin/users/{id}/enable
+func (h *AdminUserHandlers) Enable(w http.ResponseWriter, r *http.Request) {
+	h.setDisabled(w, r, false)
+}
+
+func (h *AdminUserHandlers) setDisabled(w http.ResponseWriter, r *http.Request, disabled bool) {
+	id, ok := userIDParam(w, r)
+	if !ok {
+		return
+	}
+	if uid, _ := middleware.UserIDFromContext(r.Context()); disabled && uid == id {
+		respond.Fail(w, http.StatusConflict, "CONFLICT", "you cannot disable your own account")
+		return
+	}
+
+	if err := h.Users.SetDisabled(r.Context(), id, disabled); err != nil {
+		writeAdminUserError(w, err)
+		return
+	}
+
+	event := domain.AuthEventAccountEnabled
+	if disabled {
+		event = domain.AuthEventAccountDisabled
+	}
+	recordEvent(h.Audit, r, domain.AuthEvent{UserID: &id, Type: event, Reason: reasonByAdmin})
+	respond.JSON(w, http.StatusOK, okResp{OK: true})
+}
+
+// POST /api/admin/users/{id}/password-reset
+func (h *AdminUserHandlers) ForcePasswordReset(w http.ResponseWriter, r *http.Request) {
+	id, ok := userIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	u, err := h.Users.ForcePasswordReset(r.Context(), id)
+	if err != nil {
+		writeAdminUserError(w, err)
+		return
+	}
+	recordEvent(h.Audit, r, domain.AuthEvent{UserID: &id, Type: domain.AuthEventPasswordResetForced, Reason: reasonByAdmin})
+
+	if h.MagicLinks != nil && u.DisabledAt == nil {
+		if err := h.MagicLinks.Request(r.Context(), u.Email); err != nil {
+			log.Printf("password reset link for user %d: %v", id, err)
+		}
+	}
+	respond.JSON(w, http.StatusOK, okResp{OK: true})
+}
+
+// DELETE /api/admin/users/{id}/sessions
+func (h *AdminUserHandlers) RevokeSessions(w http.ResponseWriter, r *http.Request) {
+	id, ok := userIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	revoked, err := h.Users.RevokeSessions(r.Context(), id)
+	if err != nil {
+		writeAdminUserError(w, err)
+		return
+	}
+	recordEvent(h.Audit, r, domain.AuthEvent{UserID: &id, Type: domain.AuthEventSessionRevoked, Reason: reasonByAdmin})
+	respond.JSON(w, http.StatusOK, revokeSessionsResp{OK: true, RevokedSessions: revoked})
+}
+
+func userIDParam(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		respond.Fail(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid user id")
+		return 0, false
+	}
+	return id, true
+}
+
+func writeAdminUserError(w http.ResponseWriter, err error) {
+	if errors.Is(err, service.ErrUserNotFound) {
+		respond.Fail(w, http.StatusNotFound, "NOT_FOUND", "user not found")
+		return
+	}
+	respond.Fail(w, http.StatusInternalServerError, "INTERNAL", "something went wrong")
+}
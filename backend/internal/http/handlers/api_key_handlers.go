@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/soydoradesu/product_discovery/internal/domain"
+	"github.com/soydoradesu/product_discovery/internal/http/middleware"
+	"github.com/soydoradesu/product_discovery/internal/http/respond"
+	"github.com/soydoradesu/product_discovery/internal/service"
+)
+
+type APIKeyHandlers struct {
+	Keys  *service.APIKeyService
+	Audit *service.AuditService
+}
+
+type createAPIKeyReq struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+type createAPIKeyResp struct {
+	APIKey domain.APIKey `json:"apiKey"`
+	// Key is the plaintext secret; it is only ever returned here.
+	Key string `json:"key"`
+}
+
+type listAPIKeysResp struct {
+	Items []domain.APIKey `json:"items"`
+}
+
+// GET /api/me/api-keys
+func (h *APIKeyHandlers) List(w http.ResponseWriter, r *http.Request) {
+	uid, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		respond.Fail(w, http.StatusUnauthorized, "UNAUTHORIZED", "missing session")
+		return
+	}
+
+	items, err := h.Keys.List(r.Context(), uid)
+	if err != nil {
+		respond.Fail(w, http.StatusInternalServerError, "INTERNAL", "something went wrong")
+		return
+	}
+	respond.JSON(w, http.StatusOK, listAPIKeysResp{Items: items})
+}
+
+// POST /api/me/api-keys
+func (h *APIKeyHandlers) Create(w http.ResponseWriter, r *http.Request) {
+	uid, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		respond.Fail(w, http.StatusUnauthorized, "UNAUTHORIZED", "missing session")
+		return
+	}
+
+	var req createAPIKeyReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Fail(w, http.StatusBadRequest, "BAD_REQUEST", "invalid json")
+		return
+	}
+
+	key, raw, err := h.Keys.Create(r.Context(), uid, service.CreateAPIKeyInput{
+		Name:      req.Name,
+		Scopes:    req.Scopes,
+		ExpiresAt: req.ExpiresAt,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidAPIKeyInput) {
+			respond.Fail(w, http.StatusBadRequest, "VALIDATION_ERROR", "name is required, scopes must be known permissions and expiresAt must be in the future")
+			return
+		}
+		respond.Fail(w, http.StatusInternalServerError, "INTERNAL", "something went wrong")
+		return
+	}
+
+	recordEvent(h.Audit, r, domain.AuthEvent{UserID: &uid, Type: domain.AuthEventAPIKeyCreated})
+	respond.JSON(w, http.StatusCreated, createAPIKeyResp{APIKey: key, Key: raw})
+}
+
+// DELETE /api/me/api-keys/{id}
+func (h *APIKeyHandlers) Revoke(w http.ResponseWriter, r *http.Request) {
+	uid, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		respond.Fail(w, http.StatusUnauthorized, "UNAUTHORIZED", "missing session")
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		respond.Fail(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid api key id")
+		return
+	}
+
+	if err := h.Keys.Revoke(r.Context(), uid, id); err != nil {
+		if errors.Is(err, service.ErrAPIKeyNotFound) {
+			respond.Fail(w, http.StatusNotFound, "NOT_FOUND", "api key not found")
+			return
+		}
+		respond.Fail(w, http.StatusInternalServerError, "INTERNAL", "something went wrong")
+		return
+	}
+	recordEvent(h.Audit, r, domain.AuthEvent{UserID: &uid, Type: domain.AuthEventAPIKeyRevoked})
+	respond.JSON(w, http.StatusOK, okResp{OK: true})
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/soydoradesu/product_discovery/internal/domain"
+	"github.com/soydoradesu/product_discovery/internal/http/respond"
+	"github.com/soydoradesu/product_discovery/internal/service"
+)
+
+type AttributeHandlers struct {
+	Attributes *service.AttributeService
+}
+
+type listAttributesResp struct {
+	Items []domain.AttributeDefinition `json:"items"`
+}
+
+// GET /api/categories/{id}/attributes
+//
+// Lists the attributes of the category's products, including those of its
+// ancestors.
+func (h *AttributeHandlers) ForCategory(w http.ResponseWriter, r *http.Request) {
+	id, ok := categoryIDParam(w, r)
+	if !ok {
+		return
+	}
+	items, err := h.Attributes.ForCategory(r.Context(), id)
+	if err != nil {
+		writeAttributeError(w, err)
+		return
+	}
+	respond.JSON(w, http.StatusOK, listAttributesResp{Items: items})
+}
+
+type attributeReq struct {
+	Code   string   `json:"code"`
+	Label  string   `json:"label"`
+	Type   string   `json:"type"`
+	Unit   string   `json:"unit"`
+	Values []string `json:"values"`
+}
+
+type replaceAttributesReq struct {
+	Attributes []attributeReq `json:"attributes"`
+}
+
+// PUT /api/admin/categories/{id}/attributes
+func (h *AttributeHandlers) Replace(w http.ResponseWriter, r *http.Request) {
+	id, ok := categoryIDParam(w, r)
+	if !ok {
+		return
+	}
+	var req replaceAttributesReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Fail(w, http.StatusBadRequest, "BAD_REQUEST", "invalid json")
+		return
+	}
+
+	in := make([]domain.AttributeDefinition, len(req.Attributes))
+	for i, a := range req.Attributes {
+		in[i] = domain.AttributeDefinition{Code: a.Code, Label: a.Label, Type: a.Type, Unit: a.Unit, Values: a.Values}
+	}
+	items, err := h.Attributes.Replace(r.Context(), id, in)
+	if err != nil {
+		writeAttributeError(w, err)
+		return
+	}
+	respond.JSON(w, http.StatusOK, listAttributesResp{Items: items})
+}
+
+func writeAttributeError(w http.ResponseWriter, err error) {
+	var invalid *service.ValidationError
+	switch {
+	case errors.As(err, &invalid):
+		respond.FailFields(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid attributes", invalid.Fields)
+	case errors.Is(err, service.ErrCategoryNotFound):
+		respond.Fail(w, http.StatusNotFound, "NOT_FOUND", "category not found")
+	default:
+		respond.Fail(w, http.StatusInternalServerError, "INTERNAL", "something went wrong")
+	}
+}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	chimw "github.com/go-chi/chi/v5/middleware"
+
+	"github.com/soydoradesu/product_discovery/internal/domain"
+	"github.com/soydoradesu/product_discovery/internal/http/middleware"
+	"github.com/soydoradesu/product_discovery/internal/http/respond"
+	"github.com/soydoradesu/product_discovery/internal/service"
+)
+
+// Reasons recorded with failed events.
+const (
+	reasonUnknownEmail     = "unknown_email"
+	reasonBadPassword      = "bad_password"
+	reasonBadTwoFactorCode = "bad_2fa_code"
+	reasonTwoFactorLocked  = "2fa_locked"
+	reasonProviderDenied   = "provider_denied"
+	reasonInvalidState     = "invalid_state"
+	reasonExchangeFailed   = "exchange_failed"
+	reasonEmailUnverified  = "email_unverified"
+	reasonAccountConflict  = "account_conflict"
+	reasonNotLinked        = "identity_not_linked"
+	reasonWeakPassword     = "weak_password"
+	reasonRevokedByUser    = "revoked_by_user"
+	reasonPasswordChanged  = "password_changed"
+	reasonInvalidLink      = "invalid_link"
+	reasonAccountDisabled  = "account_disabled"
+	reasonByAdmin          = "by_admin"
+	reasonInvalidPasskey   = "invalid_passkey"
+)
+
+// Login methods recorded in AuthEvent.Provider alongside provider names.
+const (
+	methodPassword  = "password"
+	methodTwoFactor = "totp"
+	methodMagicLink = "magic_link"
+)
+
+type AuditHandlers struct {
+	Audit *service.AuditService
+}
+
+type listAuthEventsResp struct {
+	Items []domain.AuthEvent `json:"items"`
+	// NextBefore is passed back as ?before= for the next page; 0 when there
+	// are no more events.
+	NextBefore int64 `json:"nextBefore"`
+}
+
+// recordEvent fills in where the request came from and stores e. A nil
+// service records nothing.
+func recordEvent(audit *service.AuditService, r *http.Request, e domain.AuthEvent) {
+	if audit == nil {
+		return
+	}
+	e.IP = clientIP(r)
+	e.UserAgent = r.UserAgent()
+	e.RequestID = chimw.GetReqID(r.Context())
+	audit.Record(r.Context(), e)
+}
+
+func (h *AuthHandlers) audit(r *http.Request, e domain.AuthEvent) {
+	recordEvent(h.Audit, r, e)
+}
+
+// GET /api/admin/auth-events?userId=&email=&type=&ip=&since=&until=&before=&limit=
+func (h *AuditHandlers) List(w http.ResponseWriter, r *http.Request) {
+	qp := r.URL.Query()
+
+	var f domain.AuthEventFilter
+	f.Email = strings.TrimSpace(qp.Get("email"))
+	f.Type = strings.TrimSpace(qp.Get("type"))
+	f.IP = strings.TrimSpace(qp.Get("ip"))
+
+	if v := strings.TrimSpace(qp.Get("userId")); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			respond.Fail(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid userId")
+			return
+		}
+		f.UserID = &id
+	}
+	for name, dst := range map[string]**time.Time{"since": &f.Since, "until": &f.Until} {
+		if v := strings.TrimSpace(qp.Get(name)); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				respond.Fail(w, http.StatusBadRequest, "VALIDATION_ERROR", name+" must be an RFC 3339 time")
+				return
+			}
+			*dst = &t
+		}
+	}
+	f.BeforeID, f.Limit = pageParams(r)
+
+	h.writeEvents(w, r, f)
+}
+
+// GET /api/me/activity
+func (h *AuditHandlers) MyActivity(w http.ResponseWriter, r *http.Request) {
+	uid, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		respond.Fail(w, http.StatusUnauthorized, "UNAUTHORIZED", "missing session")
+		return
+	}
+
+	f := domain.AuthEventFilter{UserID: &uid}
+	f.BeforeID, f.Limit = pageParams(r)
+	h.writeEvents(w, r, f)
+}
+
+func (h *AuditHandlers) writeEvents(w http.ResponseWriter, r *http.Request, f domain.AuthEventFilter) {
+	items, err := h.Audit.List(r.Context(), f)
+	if err != nil {
+		respond.Fail(w, http.StatusInternalServerError, "INTERNAL", "something went wrong")
+		return
+	}
+
+	resp := listAuthEventsResp{Items: items}
+	if f.Limit > 0 && len(items) == f.Limit {
+		resp.NextBefore = items[len(items)-1].ID
+	}
+	respond.JSON(w, http.StatusOK, resp)
+}
+
+// pageParams reads ?before= and ?limit=, ignoring malformed values.
+func pageParams(r *http.Request) (before int64, limit int) {
+	qp := r.URL.Query()
+	if v, err := strconv.ParseInt(qp.Get("before"), 10, 64); err == nil && v > 0 {
+		before = v
+	}
+	if v, err := strconv.Atoi(qp.Get("limit")); err == nil && v > 0 {
+		limit = v
+	}
+	return before, limit
+}
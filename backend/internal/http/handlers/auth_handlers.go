@@ -5,23 +5,30 @@ import (
 	"net/http"
 	"strings"
 	"time"
-	"errors"
-	"crypto/rand"
-	"encoding/base64"
 
 	"github.com/soydoradesu/product_discovery/internal/auth"
 	"github.com/soydoradesu/product_discovery/internal/config"
+	"github.com/soydoradesu/product_discovery/internal/domain"
 	"github.com/soydoradesu/product_discovery/internal/http/middleware"
 	"github.com/soydoradesu/product_discovery/internal/http/respond"
+	"github.com/soydoradesu/product_discovery/internal/oidc"
 	"github.com/soydoradesu/product_discovery/internal/service"
-
-	"golang.org/x/oauth2"
-	"golang.org/x/oauth2/google"
 )
 
 type AuthHandlers struct {
-	Cfg  config.Config
-	Auth *service.AuthService
+	Cfg        config.Config
+	Keys       *auth.Keyring
+	Auth       *service.AuthService
+	TwoFactor  *service.TwoFactorService
+	Access     *service.AccessService
+	OIDC       *oidc.Registry
+	Sessions   *service.SessionService
+	MagicLinks *service.MagicLinkService
+	Passkeys   *service.PasskeyService
+	Audit      *service.AuditService
+	// RecentAuth is how long after logging in a user without a password may
+	// set one without re-authenticating.
+	RecentAuth time.Duration
 }
 
 type loginReq struct {
@@ -36,25 +43,18 @@ type okResp struct {
 type meResp struct {
 	UserID int64 `json:"userId"`
 	Email  string `json:"email"`
+	Roles  []string `json:"roles"`
 }
 
-const oauthStateCookie = "oauth_state"
+// sessionTTL is how long a browser session lasts without logging in again.
+const sessionTTL = 7 * 24 * time.Hour
 
-func (h *AuthHandlers) oauthConfig() (*oauth2.Config, error) {
-	if strings.TrimSpace(h.Cfg.GoogleClientID) == "" || strings.TrimSpace(h.Cfg.GoogleClientSecret) == "" {
-		return nil, errors.New("google oauth not configured")
+func (h *AuthHandlers) setSessionCookie(w http.ResponseWriter, r *http.Request, userID int64) error {
+	sess, err := h.Sessions.Create(r.Context(), userID, r.UserAgent(), clientIP(r), sessionTTL)
+	if err != nil {
+		return err
 	}
-	return &oauth2.Config{
-		ClientID: h.Cfg.GoogleClientID,
-		ClientSecret: h.Cfg.GoogleClientSecret,
-		RedirectURL: h.Cfg.GoogleRedirectURL,
-		Endpoint: google.Endpoint,
-		Scopes: []string{"email", "profile"},
-	}, nil
-}
-
-func (h *AuthHandlers) setSessionCookie(w http.ResponseWriter, userID int64) error {
-	token, err := auth.SignJWT(h.Cfg.JWTSecret, userID, 7*24*time.Hour)
+	token, err := auth.SignSession(h.Keys, userID, sess.ID, sessionTTL)
 	if err != nil {
 		return err
 	}
@@ -65,7 +65,7 @@ func (h *AuthHandlers) setSessionCookie(w http.ResponseWriter, userID int64) err
 		HttpOnly: true,
 		Secure: h.Cfg.CookieSecure,
 		SameSite: http.SameSiteLaxMode,
-		MaxAge: int((7 * 24 * time.Hour).Seconds()),
+		MaxAge: int(sessionTTL.Seconds()),
 	})
 	return nil
 }
@@ -89,34 +89,57 @@ func (h *AuthHandlers) Login(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		switch err {
 		case service.ErrInvalidCredentials, service.ErrUserNotFound:
+			reason := reasonBadPassword
+			if err == service.ErrUserNotFound {
+				reason = reasonUnknownEmail
+			}
+			h.audit(r, domain.AuthEvent{Email: &req.Email, Type: domain.AuthEventLoginFailed, Reason: reason, Provider: methodPassword})
 			respond.Fail(w, http.StatusUnauthorized, "INVALID_CREDENTIALS", "email or password is incorrect")
 			return
+		case service.ErrAccountDisabled:
+			h.audit(r, domain.AuthEvent{Email: &req.Email, Type: domain.AuthEventLoginFailed, Reason: reasonAccountDisabled, Provider: methodPassword})
+			respond.Fail(w, http.StatusForbidden, "ACCOUNT_DISABLED", "this account is disabled")
+			return
 		default:
 			respond.Fail(w, http.StatusInternalServerError, "INTERNAL", "something went wrong")
 			return
 		}
 	}
 
-	token, err := auth.SignJWT(h.Cfg.JWTSecret, userID, 7*24*time.Hour)
+	enabled, err := h.TwoFactor.Enabled(r.Context(), userID)
 	if err != nil {
-		respond.Fail(w, http.StatusInternalServerError, "INTERNAL", "failed to create session")
+		respond.Fail(w, http.StatusInternalServerError, "INTERNAL", "something went wrong")
+		return
+	}
+	if enabled {
+		challenge, err := auth.SignChallenge(h.Keys, userID, auth.PurposeTwoFactor, twoFactorChallengeTTL)
+		if err != nil {
+			respond.Fail(w, http.StatusInternalServerError, "INTERNAL", "failed to start two-factor login")
+			return
+		}
+		respond.JSON(w, http.StatusOK, twoFactorChallengeResp{TwoFactorRequired: true, ChallengeToken: challenge})
 		return
 	}
 
-	http.SetCookie(w, &http.Cookie{
-		Name: "session",
-		Value: token,
-		Path: "/",
-		HttpOnly: true,
-		Secure: h.Cfg.CookieSecure,
-		SameSite: http.SameSiteLaxMode,
-		MaxAge: int((7 * 24 * time.Hour).Seconds()),
-	})
+	if err := h.setSessionCookie(w, r, userID); err != nil {
+		respond.Fail(w, http.StatusInternalServerError, "INTERNAL", "failed to create session")
+		return
+	}
+	h.audit(r, domain.AuthEvent{UserID: &userID, Email: &req.Email, Type: domain.AuthEventLoginSucceeded, Provider: methodPassword})
 
 	respond.JSON(w, http.StatusOK, okResp{OK: true})
 }
 
 func (h *AuthHandlers) Logout(w http.ResponseWriter, r *http.Request) {
+	if uid, sid, ok := h.currentSession(r); ok {
+		_ = h.Sessions.Revoke(r.Context(), uid, sid)
+		h.audit(r, domain.AuthEvent{UserID: &uid, Type: domain.AuthEventLogout})
+	}
+	h.clearSessionCookie(w)
+	respond.JSON(w, http.StatusOK, okResp{OK: true})
+}
+
+func (h *AuthHandlers) clearSessionCookie(w http.ResponseWriter) {
 	http.SetCookie(w, &http.Cookie{
 		Name: "session",
 		Value: "",
@@ -126,7 +149,6 @@ func (h *AuthHandlers) Logout(w http.ResponseWriter, r *http.Request) {
 		SameSite: http.SameSiteLaxMode,
 		MaxAge: -1,
 	})
-	respond.JSON(w, http.StatusOK, okResp{OK: true})
 }
 
 func (h *AuthHandlers) Me(w http.ResponseWriter, r *http.Request) {
@@ -142,123 +164,11 @@ func (h *AuthHandlers) Me(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respond.JSON(w, http.StatusOK, meResp{UserID: uid, Email: u.Email})
-}
-
-func (h *AuthHandlers) GoogleStart(w http.ResponseWriter, r *http.Request) {
-	ocfg, err := h.oauthConfig()
+	access, err := h.Access.Access(r.Context(), uid)
 	if err != nil {
-		respond.Fail(w, http.StatusInternalServerError, "CONFIG_ERROR", "google oauth is not configured")
-		return
-	}
-
-	state := make([]byte, 32)
-	if _, err := rand.Read(state); err != nil {
-		respond.Fail(w, http.StatusInternalServerError, "INTERNAL", "failed to start oauth")
-		return
-	}
-	stateStr := base64.RawURLEncoding.EncodeToString(state)
-
-	http.SetCookie(w, &http.Cookie{
-		Name: oauthStateCookie,
-		Value: stateStr,
-		Path: "/api/auth/google",
-		HttpOnly: true,
-		Secure: h.Cfg.CookieSecure,
-		SameSite: http.SameSiteLaxMode,
-		MaxAge: int((10 * time.Minute).Seconds()),
-	})
-
-	url := ocfg.AuthCodeURL(stateStr, oauth2.AccessTypeOnline)
-	http.Redirect(w, r, url, http.StatusFound)
-}
-
-type googleUserInfo struct {
-	ID string `json:"id"`
-	Email string `json:"email"`
-	VerifiedEmail bool `json:"verified_email"`
-}
-
-// GET /api/auth/google/callback
-func (h *AuthHandlers) GoogleCallback(w http.ResponseWriter, r *http.Request) {
-	ocfg, err := h.oauthConfig()
-	if err != nil {
-		respond.Fail(w, http.StatusInternalServerError, "CONFIG_ERROR", "google oauth is not configured")
-		return
-	}
-
-	code := r.URL.Query().Get("code")
-	state := r.URL.Query().Get("state")
-	if code == "" || state == "" {
-		respond.Fail(w, http.StatusBadRequest, "BAD_REQUEST", "missing code/state")
-		return
-	}
-
-	c, err := r.Cookie(oauthStateCookie)
-	if err != nil || c.Value == "" || c.Value != state {
-		respond.Fail(w, http.StatusUnauthorized, "UNAUTHORIZED", "invalid oauth state")
-		return
-	}
-
-	http.SetCookie(w, &http.Cookie{
-		Name: oauthStateCookie,
-		Value: "",
-		Path: "/api/auth/google",
-		HttpOnly: true,
-		Secure: h.Cfg.CookieSecure,
-		SameSite: http.SameSiteLaxMode,
-		MaxAge: -1,
-	})
-
-	tok, err := ocfg.Exchange(r.Context(), code)
-	if err != nil {
-		respond.Fail(w, http.StatusUnauthorized, "UNAUTHORIZED", "oauth exchange failed")
-		return
-	}
-
-	client := ocfg.Client(r.Context(), tok)
-	resp, err := client.Get("https://www.googleapis.com/oauth2/v2/userinfo")
-	if err != nil {
-		respond.Fail(w, http.StatusUnauthorized, "UNAUTHORIZED", "failed to fetch userinfo")
-		return
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		respond.Fail(w, http.StatusUnauthorized, "UNAUTHORIZED", "userinfo request failed")
-		return
-	}
-
-	var ui googleUserInfo
-	if err := json.NewDecoder(resp.Body).Decode(&ui); err != nil {
-		respond.Fail(w, http.StatusInternalServerError, "INTERNAL", "failed to parse userinfo")
-		return
-	}
-
-	if ui.Email == "" || ui.ID == "" {
-		respond.Fail(w, http.StatusUnauthorized, "UNAUTHORIZED", "invalid userinfo")
-		return
-	}
-	if !ui.VerifiedEmail {
-		respond.Fail(w, http.StatusUnauthorized, "UNAUTHORIZED", "email not verified")
-		return
-	}
-
-	userID, err := h.Auth.OAuthLogin(r.Context(), ui.Email, ui.ID)
-	if err != nil {
-		if err == service.ErrOAuthAccountConflict {
-			respond.Fail(w, http.StatusConflict, "CONFLICT", "account already linked to different google id")
-			return
-		}
 		respond.Fail(w, http.StatusInternalServerError, "INTERNAL", "something went wrong")
 		return
 	}
 
-	if err := h.setSessionCookie(w, userID); err != nil {
-		respond.Fail(w, http.StatusInternalServerError, "INTERNAL", "failed to create session")
-		return
-	}
-
-	// redirect to frontend
-	http.Redirect(w, r, h.Cfg.FrontendURL+"/?oauth=success", http.StatusFound)
-}
\ No newline at end of file
+	respond.JSON(w, http.StatusOK, meResp{UserID: uid, Email: u.Email, Roles: access.Roles})
+}
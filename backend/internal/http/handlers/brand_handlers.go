@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/soydoradesu/product_discovery/internal/domain"
+	"github.com/soydoradesu/product_discovery/internal/http/respond"
+	"github.com/soydoradesu/product_discovery/internal/service"
+)
+
+type BrandHandlers struct {
+	Brands *service.BrandService
+}
+
+type listBrandsResp struct {
+	Items []domain.Brand `json:"items"`
+}
+
+type brandReq struct {
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+func (req brandReq) input() domain.BrandInput {
+	return domain.BrandInput{Name: req.Name, Slug: req.Slug}
+}
+
+// GET /api/brands
+func (h *BrandHandlers) List(w http.ResponseWriter, r *http.Request) {
+	items, err := h.Brands.List(r.Context())
+	if err != nil {
+		respond.Fail(w, http.StatusInternalServerError, "INTERNAL", "something went wrong")
+		return
+	}
+	respond.JSON(w, http.StatusOK, listBrandsResp{Items: items})
+}
+
+// GET /api/brands/{id}
+func (h *BrandHandlers) Get(w http.ResponseWriter, r *http.Request) {
+	id, ok := brandIDParam(w, r)
+	if !ok {
+		return
+	}
+	b, err := h.Brands.GetByID(r.Context(), id)
+	if err != nil {
+		writeBrandError(w, err)
+		return
+	}
+	respond.JSON(w, http.StatusOK, b)
+}
+
+// POST /api/admin/brands
+func (h *BrandHandlers) Create(w http.ResponseWriter, r *http.Request) {
+	var req brandReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Fail(w, http.StatusBadRequest, "BAD_REQUEST", "invalid json")
+		return
+	}
+	b, err := h.Brands.Create(r.Context(), req.input())
+	if err != nil {
+		writeBrandError(w, err)
+		return
+	}
+	respond.JSON(w, http.StatusCreated, b)
+}
+
+// PUT /api/admin/brands/{id}
+func (h *BrandHandlers) Update(w http.ResponseWriter, r *http.Request) {
+	id, ok := brandIDParam(w, r)
+	if !ok {
+		return
+	}
+	var req brandReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Fail(w, http.StatusBadRequest, "BAD_REQUEST", "invalid json")
+		return
+	}
+	b, err := h.Brands.Update(r.Context(), id, req.input())
+	if err != nil {
+		writeBrandError(w, err)
+		return
+	}
+	respond.JSON(w, http.StatusOK, b)
+}
+
+// DELETE /api/admin/brands/{id}
+func (h *BrandHandlers) Delete(w http.ResponseWriter, r *http.Request) {
+	id, ok := brandIDParam(w, r)
+	if !ok {
+		return
+	}
+	if err := h.Brands.Delete(r.Context(), id); err != nil {
+		writeBrandError(w, err)
+		return
+	}
+	respond.JSON(w, http.StatusOK, okResp{OK: true})
+}
+
+func brandIDParam(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		respond.Fail(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid brand id")
+		return 0, false
+	}
+	return id, true
+}
+
+func writeBrandError(w http.ResponseWriter, err error) {
+	var invalid *service.ValidationError
+	switch {
+	case errors.As(err, &invalid):
+		respond.FailFields(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid brand", invalid.Fields)
+	case errors.Is(err, service.ErrBrandNotFound):
+		respond.Fail(w, http.StatusNotFound, "NOT_FOUND", "brand not found")
+	case errors.Is(err, service.ErrBrandExists), errors.Is(err, service.ErrBrandInUse):
+		respond.Fail(w, http.StatusConflict, "CONFLICT", err.Error())
+	default:
+		respond.Fail(w, http.StatusInternalServerError, "INTERNAL", "something went wrong")
+	}
+}
@@ -1,7 +1,12 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
 
 	"github.com/soydoradesu/product_discovery/internal/domain"
 	"github.com/soydoradesu/product_discovery/internal/http/respond"
@@ -23,4 +28,120 @@ func (h *CategoryHandlers) List(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	respond.JSON(w, http.StatusOK, listCategoriesResp{Items: items})
-}
\ No newline at end of file
+}
+
+type categoryTreeResp struct {
+	Items []domain.CategoryNode `json:"items"`
+}
+
+type categoryReq struct {
+	Name        string `json:"name"`
+	Slug        string `json:"slug"`
+	ParentID    *int64 `json:"parentId"`
+	SortOrder   int32  `json:"sortOrder"`
+	Description string `json:"description"`
+}
+
+func (req categoryReq) input() domain.CategoryInput {
+	return domain.CategoryInput{
+		Name:        req.Name,
+		Slug:        req.Slug,
+		ParentID:    req.ParentID,
+		SortOrder:   req.SortOrder,
+		Description: req.Description,
+	}
+}
+
+// GET /api/categories/tree
+func (h *CategoryHandlers) Tree(w http.ResponseWriter, r *http.Request) {
+	items, err := h.Categories.Tree(r.Context())
+	if err != nil {
+		respond.Fail(w, http.StatusInternalServerError, "INTERNAL", "something went wrong")
+		return
+	}
+	respond.JSON(w, http.StatusOK, categoryTreeResp{Items: items})
+}
+
+// GET /api/categories/{id}
+func (h *CategoryHandlers) Get(w http.ResponseWriter, r *http.Request) {
+	id, ok := categoryIDParam(w, r)
+	if !ok {
+		return
+	}
+	c, err := h.Categories.GetByID(r.Context(), id)
+	if err != nil {
+		writeCategoryError(w, err)
+		return
+	}
+	respond.JSON(w, http.StatusOK, c)
+}
+
+// POST /api/admin/categories
+func (h *CategoryHandlers) Create(w http.ResponseWriter, r *http.Request) {
+	var req categoryReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Fail(w, http.StatusBadRequest, "BAD_REQUEST", "invalid json")
+		return
+	}
+	c, err := h.Categories.Create(r.Context(), req.input())
+	if err != nil {
+		writeCategoryError(w, err)
+		return
+	}
+	respond.JSON(w, http.StatusCreated, c)
+}
+
+// PUT /api/admin/categories/{id}
+func (h *CategoryHandlers) Update(w http.ResponseWriter, r *http.Request) {
+	id, ok := categoryIDParam(w, r)
+	if !ok {
+		return
+	}
+	var req categoryReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Fail(w, http.StatusBadRequest, "BAD_REQUEST", "invalid json")
+		return
+	}
+	c, err := h.Categories.Update(r.Context(), id, req.input())
+	if err != nil {
+		writeCategoryError(w, err)
+		return
+	}
+	respond.JSON(w, http.StatusOK, c)
+}
+
+// DELETE /api/admin/categories/{id}
+func (h *CategoryHandlers) Delete(w http.ResponseWriter, r *http.Request) {
+	id, ok := categoryIDParam(w, r)
+	if !ok {
+		return
+	}
+	if err := h.Categories.Delete(r.Context(), id); err != nil {
+		writeCategoryError(w, err)
+		return
+	}
+	respond.JSON(w, http.StatusOK, okResp{OK: true})
+}
+
+func categoryIDParam(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		respond.Fail(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid category id")
+		return 0, false
+	}
+	return id, true
+}
+
+func writeCategoryError(w http.ResponseWriter, err error) {
+	var invalid *service.ValidationError
+	switch {
+	case errors.As(err, &invalid):
+		respond.FailFields(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid category", invalid.Fields)
+	case errors.Is(err, service.ErrCategoryNotFound):
+		respond.Fail(w, http.StatusNotFound, "NOT_FOUND", "category not found")
+	case errors.Is(err, service.ErrCategoryExists), errors.Is(err, service.ErrCategoryHasChildren):
+		respond.Fail(w, http.StatusConflict, "CONFLICT", err.Error())
+	default:
+		respond.Fail(w, http.StatusInternalServerError, "INTERNAL", "something went wrong")
+	}
+}
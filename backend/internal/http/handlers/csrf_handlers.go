@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+
+	"github.com/soydoradesu/product_discovery/internal/http/middleware"
+	"github.com/soydoradesu/product_discovery/internal/http/respond"
+)
+
+type csrfResp struct {
+	Token string `json:"token"`
+}
+
+// GET /api/auth/csrf returns the token to send in the X-CSRF-Token header,
+// reusing the one already in the cookie so open tabs keep working.
+func (h *AuthHandlers) CSRFToken(w http.ResponseWriter, r *http.Request) {
+	if c, err := r.Cookie(middleware.CSRFCookie); err == nil && len(c.Value) >= 32 {
+		respond.JSON(w, http.StatusOK, csrfResp{Token: c.Value})
+		return
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		respond.Fail(w, http.StatusInternalServerError, "INTERNAL", "failed to create csrf token")
+		return
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     middleware.CSRFCookie,
+		Value:    token,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   h.Cfg.CookieSecure,
+		SameSite: http.SameSiteLaxMode,
+	})
+	w.Header().Set("Cache-Control", "no-store")
+	respond.JSON(w, http.StatusOK, csrfResp{Token: token})
+}
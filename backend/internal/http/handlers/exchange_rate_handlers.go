@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/soydoradesu/product_discovery/internal/domain"
+	"github.com/soydoradesu/product_discovery/internal/http/respond"
+	"github.com/soydoradesu/product_discovery/internal/service"
+)
+
+// maxRatesBytes bounds an uploaded exchange rate table.
+const maxRatesBytes = 1 << 20
+
+type ExchangeRateHandlers struct {
+	Rates *service.ExchangeRateService
+}
+
+type listExchangeRatesResp struct {
+	Items []domain.ExchangeRate `json:"items"`
+}
+
+// GET /api/admin/exchange-rates
+func (h *ExchangeRateHandlers) List(w http.ResponseWriter, r *http.Request) {
+	items, err := h.Rates.List(r.Context())
+	if err != nil {
+		respond.Fail(w, http.StatusInternalServerError, "INTERNAL", "something went wrong")
+		return
+	}
+	respond.JSON(w, http.StatusOK, listExchangeRatesResp{Items: items})
+}
+
+// PUT /api/admin/exchange-rates
+//
+// Replaces the whole table with the body: a JSON array of rates or, with a
+// text/csv content type, a base,quote,rate CSV file.
+func (h *ExchangeRateHandlers) Replace(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRatesBytes)
+
+	format := service.RatesFormatJSON
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "text/csv" {
+		format = service.RatesFormatCSV
+	}
+	rates, err := service.ParseExchangeRates(r.Body, format)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			respond.Fail(w, http.StatusRequestEntityTooLarge, "TOO_LARGE", "exchange rate table is too large")
+			return
+		}
+		respond.Fail(w, http.StatusBadRequest, "BAD_REQUEST", err.Error())
+		return
+	}
+
+	items, err := h.Rates.Replace(r.Context(), rates)
+	if err != nil {
+		writeExchangeRateError(w, err)
+		return
+	}
+	respond.JSON(w, http.StatusOK, listExchangeRatesResp{Items: items})
+}
+
+type exchangeRateReq struct {
+	Rate json.Number `json:"rate"`
+}
+
+// PUT /api/admin/exchange-rates/{base}/{quote}
+func (h *ExchangeRateHandlers) Set(w http.ResponseWriter, r *http.Request) {
+	var req exchangeRateReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Fail(w, http.StatusBadRequest, "BAD_REQUEST", "invalid json")
+		return
+	}
+
+	rate, err := h.Rates.Set(r.Context(), domain.ExchangeRate{
+		Base:  chi.URLParam(r, "base"),
+		Quote: chi.URLParam(r, "quote"),
+		Rate:  req.Rate.String(),
+	})
+	if err != nil {
+		writeExchangeRateError(w, err)
+		return
+	}
+	respond.JSON(w, http.StatusOK, rate)
+}
+
+// DELETE /api/admin/exchange-rates/{base}/{quote}
+func (h *ExchangeRateHandlers) Delete(w http.ResponseWriter, r *http.Request) {
+	if err := h.Rates.Delete(r.Context(), chi.URLParam(r, "base"), chi.URLParam(r, "quote")); err != nil {
+		writeExchangeRateError(w, err)
+		return
+	}
+	respond.JSON(w, http.StatusOK, okResp{OK: true})
+}
+
+func writeExchangeRateError(w http.ResponseWriter, err error) {
+	var invalid *service.ValidationError
+	switch {
+	case errors.As(err, &invalid):
+		respond.FailFields(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid exchange rate", invalid.Fields)
+	case errors.Is(err, service.ErrExchangeRateNotFound):
+		respond.Fail(w, http.StatusNotFound, "NOT_FOUND", err.Error())
+	default:
+		respond.Fail(w, http.StatusInternalServerError, "INTERNAL", "something went wrong")
+	}
+}
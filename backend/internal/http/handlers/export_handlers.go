@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/soydoradesu/product_discovery/internal/http/respond"
+	"github.com/soydoradesu/product_discovery/internal/service"
+)
+
+// defaultExportTimeout bounds a catalog export, which outlives the router's
+// per-request timeout on large catalogs.
+const defaultExportTimeout = 10 * time.Minute
+
+var exportContentTypes = map[string]string{
+	service.CatalogFormatCSV:    "text/csv; charset=utf-8",
+	service.CatalogFormatNDJSON: "application/x-ndjson",
+	service.CatalogFormatJSON:   "application/json; charset=utf-8",
+}
+
+// GET /api/admin/products/export?format=csv|ndjson|json&q=&category=&minPrice=&maxPrice=&inStock=
+//
+// Streams every matching product. Paging and sort parameters are ignored.
+func (h *ProductHandlers) Export(w http.ResponseWriter, r *http.Request) {
+	format := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("format")))
+	if format == "" {
+		format = service.CatalogFormatNDJSON
+	}
+	if !service.IsExportFormat(format) {
+		respond.Fail(w, http.StatusBadRequest, "VALIDATION_ERROR", "format must be csv, ndjson or json")
+		return
+	}
+	params := searchParamsFromQuery(r.URL.Query(), h.Products.Currency)
+
+	timeout := h.ExportTimeout
+	if timeout <= 0 {
+		timeout = defaultExportTimeout
+	}
+	// outlast the server write timeout and the router's request timeout; a
+	// client that goes away still stops the export at the next failed write
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(timeout))
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), timeout)
+	defer cancel()
+
+	w.Header().Set("Content-Type", exportContentTypes[format])
+	w.Header().Set("Content-Disposition", `attachment; filename="products.`+format+`"`)
+	w.WriteHeader(http.StatusOK)
+
+	if err := h.Products.Export(ctx, w, format, params); err != nil {
+		// the status is already sent; the truncated body is all we can do
+		log.Printf("product export: %v", err)
+	}
+}
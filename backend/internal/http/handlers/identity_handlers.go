@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/soydoradesu/product_discovery/internal/domain"
+	"github.com/soydoradesu/product_discovery/internal/http/middleware"
+	"github.com/soydoradesu/product_discovery/internal/http/respond"
+	"github.com/soydoradesu/product_discovery/internal/service"
+)
+
+type identitiesResp struct {
+	Items       []domain.UserIdentity `json:"items"`
+	HasPassword bool                  `json:"hasPassword"`
+}
+
+type reauthReq struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+// GET /api/me/identities
+func (h *AuthHandlers) ListIdentities(w http.ResponseWriter, r *http.Request) {
+	uid, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		respond.Fail(w, http.StatusUnauthorized, "UNAUTHORIZED", "missing session")
+		return
+	}
+
+	u, err := h.Auth.Users.GetByID(r.Context(), uid)
+	if err != nil {
+		respond.Fail(w, http.StatusUnauthorized, "UNAUTHORIZED", "invalid session")
+		return
+	}
+
+	items, err := h.Auth.ListIdentities(r.Context(), uid)
+	if err != nil {
+		respond.Fail(w, http.StatusInternalServerError, "INTERNAL", "something went wrong")
+		return
+	}
+	respond.JSON(w, http.StatusOK, identitiesResp{Items: items, HasPassword: u.PasswordHash != nil})
+}
+
+// POST /api/me/identities/{provider}/link returns the provider URL to send the
+// browser to; the provider callback finishes the link.
+func (h *AuthHandlers) LinkIdentityStart(w http.ResponseWriter, r *http.Request) {
+	uid, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		respond.Fail(w, http.StatusUnauthorized, "UNAUTHORIZED", "missing session")
+		return
+	}
+
+	url, ok := h.beginOIDC(w, r, chi.URLParam(r, "provider"), oauthState{Mode: oauthModeLink, UserID: uid})
+	if !ok {
+		return
+	}
+	respond.JSON(w, http.StatusOK, redirectResp{URL: url})
+}
+
+// DELETE /api/me/identities/{provider}
+func (h *AuthHandlers) UnlinkIdentity(w http.ResponseWriter, r *http.Request) {
+	uid, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		respond.Fail(w, http.StatusUnauthorized, "UNAUTHORIZED", "missing session")
+		return
+	}
+
+	provider := chi.URLParam(r, "provider")
+	err := h.Auth.UnlinkIdentity(r.Context(), uid, provider)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrIdentityNotFound):
+			respond.Fail(w, http.StatusNotFound, "NOT_FOUND", "provider is not linked")
+		case errors.Is(err, service.ErrLastLoginMethod):
+			respond.Fail(w, http.StatusConflict, "LAST_LOGIN_METHOD", "set a password, link another provider or add a passkey first")
+		default:
+			respond.Fail(w, http.StatusInternalServerError, "INTERNAL", "something went wrong")
+		}
+		return
+	}
+	h.audit(r, domain.AuthEvent{UserID: &uid, Type: domain.AuthEventIdentityUnlinked, Provider: provider})
+	respond.JSON(w, http.StatusOK, okResp{OK: true})
+}
+
+// POST /api/auth/reauth confirms the signed-in user with their password and,
+// when enabled, a two-factor code, then refreshes the session.
+func (h *AuthHandlers) Reauthenticate(w http.ResponseWriter, r *http.Request) {
+	uid, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		respond.Fail(w, http.StatusUnauthorized, "UNAUTHORIZED", "missing session")
+		return
+	}
+
+	var req reauthReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Fail(w, http.StatusBadRequest, "BAD_REQUEST", "invalid json")
+		return
+	}
+
+	u, err := h.Auth.Users.GetByID(r.Context(), uid)
+	if err != nil {
+		respond.Fail(w, http.StatusUnauthorized, "UNAUTHORIZED", "invalid session")
+		return
+	}
+	twoFactor, err := h.TwoFactor.Enabled(r.Context(), uid)
+	if err != nil {
+		respond.Fail(w, http.StatusInternalServerError, "INTERNAL", "something went wrong")
+		return
+	}
+
+	if u.PasswordHash == nil && !twoFactor {
+		respond.Fail(w, http.StatusConflict, "USE_PROVIDER", "confirm your identity with your login provider")
+		return
+	}
+
+	if u.PasswordHash != nil {
+		if err := h.Auth.VerifyPassword(r.Context(), uid, req.Password); err != nil {
+			if errors.Is(err, service.ErrInvalidCredentials) {
+				h.audit(r, domain.AuthEvent{UserID: &uid, Type: domain.AuthEventReauthFailed, Reason: reasonBadPassword, Provider: methodPassword})
+				respond.Fail(w, http.StatusUnauthorized, "INVALID_CREDENTIALS", "password is incorrect")
+				return
+			}
+			respond.Fail(w, http.StatusInternalServerError, "INTERNAL", "something went wrong")
+			return
+		}
+	}
+	if twoFactor {
+		if err := h.TwoFactor.Verify(r.Context(), uid, req.Code); err != nil {
+			switch {
+			case errors.Is(err, service.ErrInvalidTwoFactorCode):
+				h.audit(r, domain.AuthEvent{UserID: &uid, Type: domain.AuthEventReauthFailed, Reason: reasonBadTwoFactorCode, Provider: methodTwoFactor})
+			case errors.Is(err, service.ErrTwoFactorLocked):
+				h.audit(r, domain.AuthEvent{UserID: &uid, Type: domain.AuthEventReauthFailed, Reason: reasonTwoFactorLocked, Provider: methodTwoFactor})
+			}
+			writeTwoFactorError(w, err)
+			return
+		}
+	}
+
+	if err := h.setSessionCookie(w, r, uid); err != nil {
+		respond.Fail(w, http.StatusInternalServerError, "INTERNAL", "failed to create session")
+		return
+	}
+	// the fresh session replaces the one used to call this endpoint
+	if sid, ok := middleware.SessionIDFromContext(r.Context()); ok {
+		_ = h.Sessions.Revoke(r.Context(), uid, sid)
+	}
+	h.audit(r, domain.AuthEvent{UserID: &uid, Type: domain.AuthEventReauthenticated, Provider: methodPassword})
+	respond.JSON(w, http.StatusOK, okResp{OK: true})
+}
+
+// POST /api/auth/reauth/oidc/{provider} is the re-authentication path for
+// accounts that only log in through a provider.
+func (h *AuthHandlers) ReauthenticateOIDCStart(w http.ResponseWriter, r *http.Request) {
+	uid, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		respond.Fail(w, http.StatusUnauthorized, "UNAUTHORIZED", "missing session")
+		return
+	}
+
+	url, ok := h.beginOIDC(w, r, chi.URLParam(r, "provider"), oauthState{Mode: oauthModeReauth, UserID: uid})
+	if !ok {
+		return
+	}
+	respond.JSON(w, http.StatusOK, redirectResp{URL: url})
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/soydoradesu/product_discovery/internal/http/respond"
+	"github.com/soydoradesu/product_discovery/internal/service"
+)
+
+// defaultMaxImportBytes bounds an uploaded catalog file.
+const defaultMaxImportBytes = 32 << 20
+
+// defaultImportTimeout bounds reading and importing a catalog file, which
+// outlives the server read timeout and the router's per-request timeout on
+// large files.
+const defaultImportTimeout = 10 * time.Minute
+
+type ImportHandlers struct {
+	Imports *service.CatalogImportService
+	// MaxBytes overrides defaultMaxImportBytes when set.
+	MaxBytes int64
+	// Timeout overrides defaultImportTimeout when set.
+	Timeout time.Duration
+}
+
+// POST /api/admin/products/import?format=csv|ndjson&dryRun=true
+//
+// The file is either the raw request body or the "file" part of a multipart
+// form. Without a format parameter it is inferred from the content type or
+// the uploaded file name.
+func (h *ImportHandlers) Import(w http.ResponseWriter, r *http.Request) {
+	limit := h.MaxBytes
+	if limit <= 0 {
+		limit = defaultMaxImportBytes
+	}
+	timeout := h.Timeout
+	if timeout <= 0 {
+		timeout = defaultImportTimeout
+	}
+	// the upload and the import transaction must not be cut off by the
+	// server deadlines or cancelled with the router's request context
+	rc := http.NewResponseController(w)
+	_ = rc.SetReadDeadline(time.Now().Add(timeout))
+	_ = rc.SetWriteDeadline(time.Now().Add(timeout))
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), timeout)
+	defer cancel()
+
+	r.Body = http.MaxBytesReader(w, r.Body, limit)
+
+	qp := r.URL.Query()
+	dryRun, _ := strconv.ParseBool(qp.Get("dryRun"))
+	format := strings.ToLower(strings.TrimSpace(qp.Get("format")))
+
+	var body io.Reader = r.Body
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "multipart/form-data" {
+		file, header, err := r.FormFile("file")
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			respond.Fail(w, http.StatusRequestEntityTooLarge, "TOO_LARGE", "import file is too large")
+			return
+		}
+		if err != nil {
+			respond.Fail(w, http.StatusBadRequest, "BAD_REQUEST", "missing file")
+			return
+		}
+		defer file.Close()
+		body = file
+		if format == "" {
+			format = importFormatFromName(header.Filename)
+		}
+	} else if format == "" {
+		format = importFormatFromMediaType(mediaType)
+	}
+	if format == "" {
+		respond.Fail(w, http.StatusBadRequest, "VALIDATION_ERROR", "format must be csv or ndjson")
+		return
+	}
+
+	summary, err := h.Imports.Import(ctx, body, format, dryRun)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.As(err, &tooLarge):
+			respond.Fail(w, http.StatusRequestEntityTooLarge, "TOO_LARGE", "import file is too large")
+		case errors.Is(err, service.ErrInvalidImportFile):
+			respond.Fail(w, http.StatusBadRequest, "VALIDATION_ERROR", err.Error())
+		default:
+			respond.Fail(w, http.StatusInternalServerError, "INTERNAL", "something went wrong")
+		}
+		return
+	}
+	respond.JSON(w, http.StatusOK, summary)
+}
+
+func importFormatFromMediaType(mediaType string) string {
+	switch mediaType {
+	case "text/csv":
+		return service.CatalogFormatCSV
+	case "application/x-ndjson", "application/ndjson", "application/jsonl":
+		return service.CatalogFormatNDJSON
+	}
+	return ""
+}
+
+func importFormatFromName(name string) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv":
+		return service.CatalogFormatCSV
+	case ".ndjson", ".jsonl":
+		return service.CatalogFormatNDJSON
+	}
+	return ""
+}
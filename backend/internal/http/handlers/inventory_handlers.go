@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/soydoradesu/product_discovery/internal/domain"
+	"github.com/soydoradesu/product_discovery/internal/http/middleware"
+	"github.com/soydoradesu/product_discovery/internal/http/respond"
+	"github.com/soydoradesu/product_discovery/internal/service"
+)
+
+type InventoryHandlers struct {
+	Inventory *service.InventoryService
+}
+
+type listWarehousesResp struct {
+	Items []domain.Warehouse `json:"items"`
+}
+
+// GET /api/admin/inventory/warehouses
+func (h *InventoryHandlers) ListWarehouses(w http.ResponseWriter, r *http.Request) {
+	items, err := h.Inventory.ListWarehouses(r.Context())
+	if err != nil {
+		respond.Fail(w, http.StatusInternalServerError, "INTERNAL", "something went wrong")
+		return
+	}
+	respond.JSON(w, http.StatusOK, listWarehousesResp{Items: items})
+}
+
+type createWarehouseReq struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+// POST /api/admin/inventory/warehouses
+func (h *InventoryHandlers) CreateWarehouse(w http.ResponseWriter, r *http.Request) {
+	var req createWarehouseReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Fail(w, http.StatusBadRequest, "BAD_REQUEST", "invalid json")
+		return
+	}
+	wh, err := h.Inventory.CreateWarehouse(r.Context(), req.Code, req.Name)
+	if err != nil {
+		writeInventoryError(w, err)
+		return
+	}
+	respond.JSON(w, http.StatusCreated, wh)
+}
+
+// GET /api/admin/products/{id}/inventory
+func (h *InventoryHandlers) Get(w http.ResponseWriter, r *http.Request) {
+	id, ok := productIDParam(w, r)
+	if !ok {
+		return
+	}
+	stock, err := h.Inventory.ProductStock(r.Context(), id)
+	if err != nil {
+		writeInventoryError(w, err)
+		return
+	}
+	respond.JSON(w, http.StatusOK, stock)
+}
+
+type thresholdReq struct {
+	LowStockThreshold *int `json:"lowStockThreshold"`
+}
+
+// PUT /api/admin/products/{id}/inventory/threshold
+func (h *InventoryHandlers) SetThreshold(w http.ResponseWriter, r *http.Request) {
+	id, ok := productIDParam(w, r)
+	if !ok {
+		return
+	}
+	var req thresholdReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.LowStockThreshold == nil {
+		respond.Fail(w, http.StatusBadRequest, "BAD_REQUEST", "lowStockThreshold is required")
+		return
+	}
+	stock, err := h.Inventory.SetLowStockThreshold(r.Context(), id, *req.LowStockThreshold)
+	if err != nil {
+		writeInventoryError(w, err)
+		return
+	}
+	respond.JSON(w, http.StatusOK, stock)
+}
+
+// adjustStockReq either adds delta to on-hand stock or, with onHand, sets it
+// to a counted quantity.
+type adjustStockReq struct {
+	VariantID *int64 `json:"variantId"`
+	Warehouse string `json:"warehouse"`
+	Delta     int    `json:"delta"`
+	OnHand    *int   `json:"onHand"`
+	Reason    string `json:"reason"`
+}
+
+// POST /api/admin/products/{id}/inventory/adjustments
+func (h *InventoryHandlers) Adjust(w http.ResponseWriter, r *http.Request) {
+	id, ok := productIDParam(w, r)
+	if !ok {
+		return
+	}
+	var req adjustStockReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Fail(w, http.StatusBadRequest, "BAD_REQUEST", "invalid json")
+		return
+	}
+
+	in := domain.StockAdjustmentInput{
+		ProductID: id,
+		VariantID: req.VariantID,
+		Warehouse: req.Warehouse,
+		Delta:     req.Delta,
+		OnHand:    req.OnHand,
+		Reason:    req.Reason,
+	}
+	if uid, ok := middleware.UserIDFromContext(r.Context()); ok {
+		in.ActorUserID = &uid
+	}
+	adj, err := h.Inventory.Adjust(r.Context(), in)
+	if err != nil {
+		writeInventoryError(w, err)
+		return
+	}
+	respond.JSON(w, http.StatusCreated, adj)
+}
+
+type listAdjustmentsResp struct {
+	Items []domain.StockAdjustment `json:"items"`
+	// NextBefore is passed back as ?before= for the next page; 0 when there
+	// are no more adjustments.
+	NextBefore int64 `json:"nextBefore"`
+}
+
+// GET /api/admin/products/{id}/inventory/adjustments?before=&limit=
+func (h *InventoryHandlers) Adjustments(w http.ResponseWriter, r *http.Request) {
+	id, ok := productIDParam(w, r)
+	if !ok {
+		return
+	}
+	before, limit := pageParams(r)
+	items, err := h.Inventory.Adjustments(r.Context(), id, before, limit)
+	if err != nil {
+		respond.Fail(w, http.StatusInternalServerError, "INTERNAL", "something went wrong")
+		return
+	}
+
+	resp := listAdjustmentsResp{Items: items}
+	if limit > 0 && len(items) == limit {
+		resp.NextBefore = items[len(items)-1].ID
+	}
+	respond.JSON(w, http.StatusOK, resp)
+}
+
+type reserveStockReq struct {
+	VariantID *int64 `json:"variantId"`
+	Warehouse string `json:"warehouse"`
+	Quantity  int    `json:"quantity"`
+	Reference string `json:"reference"`
+}
+
+// POST /api/admin/products/{id}/inventory/reservations
+func (h *InventoryHandlers) Reserve(w http.ResponseWriter, r *http.Request) {
+	id, ok := productIDParam(w, r)
+	if !ok {
+		return
+	}
+	var req reserveStockReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Fail(w, http.StatusBadRequest, "BAD_REQUEST", "invalid json")
+		return
+	}
+
+	res, err := h.Inventory.Reserve(r.Context(), domain.ReservationInput{
+		ProductID: id,
+		VariantID: req.VariantID,
+		Warehouse: req.Warehouse,
+		Quantity:  req.Quantity,
+		Reference: req.Reference,
+	})
+	if err != nil {
+		writeInventoryError(w, err)
+		return
+	}
+	respond.JSON(w, http.StatusCreated, res)
+}
+
+// POST /api/admin/inventory/reservations/{id}/release
+func (h *InventoryHandlers) Release(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		respond.Fail(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid reservation id")
+		return
+	}
+	res, err := h.Inventory.Release(r.Context(), id)
+	if err != nil {
+		writeInventoryError(w, err)
+		return
+	}
+	respond.JSON(w, http.StatusOK, res)
+}
+
+type listLowStockResp struct {
+	Items []domain.LowStockItem `json:"items"`
+}
+
+// GET /api/admin/inventory/low-stock?limit=
+func (h *InventoryHandlers) LowStock(w http.ResponseWriter, r *http.Request) {
+	_, limit := pageParams(r)
+	items, err := h.Inventory.LowStock(r.Context(), limit)
+	if err != nil {
+		respond.Fail(w, http.StatusInternalServerError, "INTERNAL", "something went wrong")
+		return
+	}
+	respond.JSON(w, http.StatusOK, listLowStockResp{Items: items})
+}
+
+func writeInventoryError(w http.ResponseWriter, err error) {
+	var invalid *service.ValidationError
+	switch {
+	case errors.As(err, &invalid):
+		respond.FailFields(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid inventory change", invalid.Fields)
+	case errors.Is(err, service.ErrProductNotFound), errors.Is(err, service.ErrReservationNotFound):
+		respond.Fail(w, http.StatusNotFound, "NOT_FOUND", err.Error())
+	case errors.Is(err, service.ErrInsufficientStock):
+		respond.Fail(w, http.StatusConflict, "INSUFFICIENT_STOCK", err.Error())
+	case errors.Is(err, service.ErrReservationReleased), errors.Is(err, service.ErrWarehouseExists):
+		respond.Fail(w, http.StatusConflict, "CONFLICT", err.Error())
+	default:
+		respond.Fail(w, http.StatusInternalServerError, "INTERNAL", "something went wrong")
+	}
+}
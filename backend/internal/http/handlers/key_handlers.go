@@ -0,0 +1,19 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/soydoradesu/product_discovery/internal/auth"
+	"github.com/soydoradesu/product_discovery/internal/http/respond"
+)
+
+type KeyHandlers struct {
+	Keys *auth.Keyring
+}
+
+// GET /.well-known/jwks.json publishes the public session signing keys so
+// other services can verify our tokens.
+func (h *KeyHandlers) JWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	respond.JSON(w, http.StatusOK, h.Keys.JWKS())
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+
+	"github.com/soydoradesu/product_discovery/internal/auth"
+	"github.com/soydoradesu/product_discovery/internal/domain"
+	"github.com/soydoradesu/product_discovery/internal/http/respond"
+	"github.com/soydoradesu/product_discovery/internal/service"
+)
+
+type magicLinkReq struct {
+	Email string `json:"email"`
+}
+
+// POST /api/auth/magic-link always answers 202 for a well-formed address so
+// it doesn't reveal which addresses have accounts.
+func (h *AuthHandlers) RequestMagicLink(w http.ResponseWriter, r *http.Request) {
+	var req magicLinkReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Fail(w, http.StatusBadRequest, "BAD_REQUEST", "invalid json")
+		return
+	}
+
+	if err := h.MagicLinks.Request(r.Context(), req.Email); err != nil {
+		if errors.Is(err, service.ErrInvalidEmail) {
+			respond.Fail(w, http.StatusBadRequest, "VALIDATION_ERROR", "a valid email is required")
+			return
+		}
+		log.Printf("magic link: %v", err)
+		respond.Fail(w, http.StatusInternalServerError, "INTERNAL", "something went wrong")
+		return
+	}
+	h.audit(r, domain.AuthEvent{Email: &req.Email, Type: domain.AuthEventMagicLinkRequested, Provider: methodMagicLink})
+	respond.JSON(w, http.StatusAccepted, okResp{OK: true})
+}
+
+// GET /api/auth/magic-link/verify is opened from the email, so it answers
+// with redirects to the frontend rather than JSON.
+func (h *AuthHandlers) VerifyMagicLink(w http.ResponseWriter, r *http.Request) {
+	// keep the token out of the Referer sent to the frontend
+	w.Header().Set("Referrer-Policy", "no-referrer")
+
+	userID, err := h.MagicLinks.Consume(r.Context(), r.URL.Query().Get("token"))
+	if errors.Is(err, service.ErrAccountDisabled) {
+		h.audit(r, domain.AuthEvent{Type: domain.AuthEventLoginFailed, Reason: reasonAccountDisabled, Provider: methodMagicLink})
+		http.Redirect(w, r, h.Cfg.FrontendURL+"/login?magic=disabled", http.StatusFound)
+		return
+	}
+	if err != nil {
+		if !errors.Is(err, service.ErrInvalidMagicLink) {
+			log.Printf("magic link: %v", err)
+		} else {
+			h.audit(r, domain.AuthEvent{Type: domain.AuthEventLoginFailed, Reason: reasonInvalidLink, Provider: methodMagicLink})
+		}
+		http.Redirect(w, r, h.Cfg.FrontendURL+"/login?magic=invalid", http.StatusFound)
+		return
+	}
+
+	// the link only proves access to the mailbox; a second factor still applies
+	enabled, err := h.TwoFactor.Enabled(r.Context(), userID)
+	if err != nil {
+		http.Redirect(w, r, h.Cfg.FrontendURL+"/login?magic=error", http.StatusFound)
+		return
+	}
+	if enabled {
+		challenge, err := auth.SignChallenge(h.Keys, userID, auth.PurposeTwoFactor, twoFactorChallengeTTL)
+		if err != nil {
+			http.Redirect(w, r, h.Cfg.FrontendURL+"/login?magic=error", http.StatusFound)
+			return
+		}
+		// in the fragment so it never reaches server logs
+		http.Redirect(w, r, h.Cfg.FrontendURL+"/login#twoFactor="+url.QueryEscape(challenge), http.StatusFound)
+		return
+	}
+
+	if err := h.setSessionCookie(w, r, userID); err != nil {
+		http.Redirect(w, r, h.Cfg.FrontendURL+"/login?magic=error", http.StatusFound)
+		return
+	}
+	h.audit(r, domain.AuthEvent{UserID: &userID, Type: domain.AuthEventLoginSucceeded, Provider: methodMagicLink})
+	http.Redirect(w, r, h.Cfg.FrontendURL+"/?magic=success", http.StatusFound)
+}
@@ -0,0 +1,321 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/soydoradesu/product_discovery/internal/auth"
+	"github.com/soydoradesu/product_discovery/internal/domain"
+	"github.com/soydoradesu/product_discovery/internal/http/respond"
+	"github.com/soydoradesu/product_discovery/internal/oidc"
+	"github.com/soydoradesu/product_discovery/internal/service"
+)
+
+const oauthStateCookie = "oauth_state"
+
+// A provider round trip either logs in, links a new identity to the signed-in
+// user, or re-authenticates the signed-in user.
+const (
+	oauthModeLogin  = ""
+	oauthModeLink   = "link"
+	oauthModeReauth = "reauth"
+)
+
+// oauthState is kept in a short-lived cookie between the start and callback
+// requests.
+type oauthState struct {
+	Provider string `json:"p"`
+	Mode     string `json:"m,omitempty"`
+	UserID   int64  `json:"u,omitempty"`
+	oidc.AuthRequest
+}
+
+type redirectResp struct {
+	URL string `json:"url"`
+}
+
+type providersResp struct {
+	Items []string `json:"items"`
+}
+
+// GET /api/auth/providers
+func (h *AuthHandlers) Providers(w http.ResponseWriter, r *http.Request) {
+	respond.JSON(w, http.StatusOK, providersResp{Items: h.OIDC.Names()})
+}
+
+// GET /api/auth/oidc/{provider}/start
+func (h *AuthHandlers) OIDCStart(w http.ResponseWriter, r *http.Request) {
+	h.oidcStart(w, r, chi.URLParam(r, "provider"))
+}
+
+// GET /api/auth/oidc/{provider}/callback
+func (h *AuthHandlers) OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	h.oidcCallback(w, r, chi.URLParam(r, "provider"))
+}
+
+// GET /api/auth/google/start is kept for existing Google redirect URLs.
+func (h *AuthHandlers) GoogleStart(w http.ResponseWriter, r *http.Request) {
+	h.oidcStart(w, r, "google")
+}
+
+// GET /api/auth/google/callback
+func (h *AuthHandlers) GoogleCallback(w http.ResponseWriter, r *http.Request) {
+	h.oidcCallback(w, r, "google")
+}
+
+func (h *AuthHandlers) oidcStart(w http.ResponseWriter, r *http.Request, name string) {
+	url, ok := h.beginOIDC(w, r, name, oauthState{Mode: oauthModeLogin})
+	if !ok {
+		return
+	}
+	http.Redirect(w, r, url, http.StatusFound)
+}
+
+// beginOIDC stores the round-trip state and returns the provider URL to send
+// the browser to. It writes the error response itself when it fails.
+func (h *AuthHandlers) beginOIDC(w http.ResponseWriter, r *http.Request, name string, st oauthState) (string, bool) {
+	p, err := h.OIDC.Get(name)
+	if err != nil {
+		respond.Fail(w, http.StatusNotFound, "NOT_FOUND", "login provider is not configured")
+		return "", false
+	}
+
+	areq, err := oidc.NewAuthRequest()
+	if err != nil {
+		respond.Fail(w, http.StatusInternalServerError, "INTERNAL", "failed to start oauth")
+		return "", false
+	}
+
+	url, err := p.AuthCodeURL(r.Context(), areq)
+	if err != nil {
+		log.Printf("oidc %s: %v", name, err)
+		respond.Fail(w, http.StatusBadGateway, "PROVIDER_ERROR", "login provider is unavailable")
+		return "", false
+	}
+
+	st.Provider = name
+	st.AuthRequest = areq
+	if err := h.setOAuthState(w, st); err != nil {
+		respond.Fail(w, http.StatusInternalServerError, "INTERNAL", "failed to start oauth")
+		return "", false
+	}
+	return url, true
+}
+
+func (h *AuthHandlers) oidcCallback(w http.ResponseWriter, r *http.Request, name string) {
+	p, err := h.OIDC.Get(name)
+	if err != nil {
+		respond.Fail(w, http.StatusNotFound, "NOT_FOUND", "login provider is not configured")
+		return
+	}
+
+	if e := r.URL.Query().Get("error"); e != "" {
+		h.audit(r, domain.AuthEvent{Type: domain.AuthEventLoginFailed, Reason: reasonProviderDenied, Provider: name})
+		respond.Fail(w, http.StatusUnauthorized, "UNAUTHORIZED", "login was cancelled or denied")
+		return
+	}
+
+	code := r.URL.Query().Get("code")
+	state := r.URL.Query().Get("state")
+	if code == "" || state == "" {
+		respond.Fail(w, http.StatusBadRequest, "BAD_REQUEST", "missing code/state")
+		return
+	}
+
+	st, ok := h.oauthStateFromCookie(r)
+	h.clearOAuthState(w)
+	if !ok || st.Provider != name || st.State != state {
+		h.audit(r, domain.AuthEvent{Type: domain.AuthEventLoginFailed, Reason: reasonInvalidState, Provider: name})
+		respond.Fail(w, http.StatusUnauthorized, "UNAUTHORIZED", "invalid oauth state")
+		return
+	}
+
+	identity, err := p.Exchange(r.Context(), code, st.AuthRequest)
+	if err != nil {
+		log.Printf("oidc %s: %v", name, err)
+		h.audit(r, domain.AuthEvent{Type: domain.AuthEventLoginFailed, Reason: reasonExchangeFailed, Provider: name})
+		respond.Fail(w, http.StatusUnauthorized, "UNAUTHORIZED", "oauth exchange failed")
+		return
+	}
+
+	switch st.Mode {
+	case oauthModeLink:
+		h.finishLink(w, r, st, identity)
+		return
+	case oauthModeReauth:
+		h.finishReauth(w, r, st, identity)
+		return
+	}
+
+	if identity.Email == "" {
+		respond.Fail(w, http.StatusUnauthorized, "UNAUTHORIZED", "provider did not return an email")
+		return
+	}
+	if !identity.EmailVerified {
+		h.audit(r, domain.AuthEvent{Email: &identity.Email, Type: domain.AuthEventLoginFailed, Reason: reasonEmailUnverified, Provider: name})
+		respond.Fail(w, http.StatusUnauthorized, "UNAUTHORIZED", "email not verified")
+		return
+	}
+
+	userID, linked, err := h.Auth.OAuthLogin(r.Context(), identity.Provider, identity.Subject, identity.Email)
+	if err != nil {
+		if errors.Is(err, service.ErrOAuthAccountConflict) {
+			h.audit(r, domain.AuthEvent{Email: &identity.Email, Type: domain.AuthEventLoginFailed, Reason: reasonAccountConflict, Provider: name})
+			respond.Fail(w, http.StatusConflict, "CONFLICT", "account already linked to a different "+name+" identity")
+			return
+		}
+		if errors.Is(err, service.ErrAccountDisabled) {
+			h.audit(r, domain.AuthEvent{Email: &identity.Email, Type: domain.AuthEventLoginFailed, Reason: reasonAccountDisabled, Provider: name})
+			respond.Fail(w, http.StatusForbidden, "ACCOUNT_DISABLED", "this account is disabled")
+			return
+		}
+		respond.Fail(w, http.StatusInternalServerError, "INTERNAL", "something went wrong")
+		return
+	}
+	// linking by email lets the provider into an account it was never
+	// attached to, so it is recorded whatever happens next
+	if linked {
+		h.audit(r, domain.AuthEvent{UserID: &userID, Email: &identity.Email, Type: domain.AuthEventIdentityLinked, Provider: name})
+	}
+
+	// the provider stands in for the password only; a second factor still
+	// applies
+	enabled, err := h.TwoFactor.Enabled(r.Context(), userID)
+	if err != nil {
+		respond.Fail(w, http.StatusInternalServerError, "INTERNAL", "something went wrong")
+		return
+	}
+	if enabled {
+		challenge, err := auth.SignChallenge(h.Keys, userID, auth.PurposeTwoFactor, twoFactorChallengeTTL)
+		if err != nil {
+			respond.Fail(w, http.StatusInternalServerError, "INTERNAL", "something went wrong")
+			return
+		}
+		// in the fragment so it never reaches server logs
+		http.Redirect(w, r, h.Cfg.FrontendURL+"/login#twoFactor="+url.QueryEscape(challenge), http.StatusFound)
+		return
+	}
+
+	if err := h.setSessionCookie(w, r, userID); err != nil {
+		respond.Fail(w, http.StatusInternalServerError, "INTERNAL", "failed to create session")
+		return
+	}
+	h.audit(r, domain.AuthEvent{UserID: &userID, Email: &identity.Email, Type: domain.AuthEventLoginSucceeded, Provider: name})
+
+	// redirect to frontend
+	http.Redirect(w, r, h.Cfg.FrontendURL+"/?oauth=success", http.StatusFound)
+}
+
+func (h *AuthHandlers) finishLink(w http.ResponseWriter, r *http.Request, st oauthState, identity oidc.Identity) {
+	if uid, _, ok := h.currentSession(r); !ok || uid != st.UserID {
+		respond.Fail(w, http.StatusUnauthorized, "UNAUTHORIZED", "session changed while linking")
+		return
+	}
+
+	if err := h.Auth.LinkIdentity(r.Context(), st.UserID, identity.Provider, identity.Subject, identity.Email); err != nil {
+		if errors.Is(err, service.ErrOAuthAccountConflict) {
+			h.audit(r, domain.AuthEvent{UserID: &st.UserID, Type: domain.AuthEventIdentityLinkFailed, Reason: reasonAccountConflict, Provider: st.Provider})
+			respond.Fail(w, http.StatusConflict, "CONFLICT", "this "+st.Provider+" account or provider is already linked")
+			return
+		}
+		respond.Fail(w, http.StatusInternalServerError, "INTERNAL", "something went wrong")
+		return
+	}
+
+	h.audit(r, domain.AuthEvent{UserID: &st.UserID, Email: &identity.Email, Type: domain.AuthEventIdentityLinked, Provider: st.Provider})
+	http.Redirect(w, r, h.Cfg.FrontendURL+"/?link=success", http.StatusFound)
+}
+
+func (h *AuthHandlers) finishReauth(w http.ResponseWriter, r *http.Request, st oauthState, identity oidc.Identity) {
+	uid, oldSession, ok := h.currentSession(r)
+	if !ok || uid != st.UserID {
+		respond.Fail(w, http.StatusUnauthorized, "UNAUTHORIZED", "session changed while re-authenticating")
+		return
+	}
+
+	u, err := h.Auth.Users.GetByIdentity(r.Context(), identity.Provider, identity.Subject)
+	if err != nil || u.ID != st.UserID {
+		h.audit(r, domain.AuthEvent{UserID: &st.UserID, Type: domain.AuthEventReauthFailed, Reason: reasonNotLinked, Provider: st.Provider})
+		respond.Fail(w, http.StatusUnauthorized, "UNAUTHORIZED", "this "+st.Provider+" account is not linked to you")
+		return
+	}
+
+	if err := h.setSessionCookie(w, r, u.ID); err != nil {
+		respond.Fail(w, http.StatusInternalServerError, "INTERNAL", "failed to create session")
+		return
+	}
+	// the fresh session replaces the one that started the round trip
+	_ = h.Sessions.Revoke(r.Context(), u.ID, oldSession)
+	h.audit(r, domain.AuthEvent{UserID: &u.ID, Type: domain.AuthEventReauthenticated, Provider: st.Provider})
+	http.Redirect(w, r, h.Cfg.FrontendURL+"/?reauth=success", http.StatusFound)
+}
+
+// currentSession reads the session cookie on routes that aren't behind
+// RequireAuth, such as provider callbacks and logout, and returns the user and
+// session it belongs to if the session is still active.
+func (h *AuthHandlers) currentSession(r *http.Request) (int64, string, bool) {
+	c, err := r.Cookie("session")
+	if err != nil || c.Value == "" {
+		return 0, "", false
+	}
+	claims, err := auth.VerifyJWT(h.Keys, c.Value)
+	if err != nil {
+		return 0, "", false
+	}
+	if err := h.Sessions.ValidateSession(r.Context(), claims.UserID, claims.ID); err != nil {
+		return 0, "", false
+	}
+	return claims.UserID, claims.ID, true
+}
+
+func (h *AuthHandlers) setOAuthState(w http.ResponseWriter, st oauthState) error {
+	b, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthStateCookie,
+		Value:    base64.RawURLEncoding.EncodeToString(b),
+		Path:     "/api/auth",
+		HttpOnly: true,
+		Secure:   h.Cfg.CookieSecure,
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   int((10 * time.Minute).Seconds()),
+	})
+	return nil
+}
+
+func (h *AuthHandlers) oauthStateFromCookie(r *http.Request) (oauthState, bool) {
+	c, err := r.Cookie(oauthStateCookie)
+	if err != nil || c.Value == "" {
+		return oauthState{}, false
+	}
+	b, err := base64.RawURLEncoding.DecodeString(c.Value)
+	if err != nil {
+		return oauthState{}, false
+	}
+	var st oauthState
+	if err := json.Unmarshal(b, &st); err != nil || st.State == "" {
+		return oauthState{}, false
+	}
+	return st, true
+}
+
+func (h *AuthHandlers) clearOAuthState(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthStateCookie,
+		Value:    "",
+		Path:     "/api/auth",
+		HttpOnly: true,
+		Secure:   h.Cfg.CookieSecure,
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   -1,
+	})
+}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/soydoradesu/product_discovery/internal/domain"
+	"github.com/soydoradesu/product_discovery/internal/http/middleware"
+	"github.com/soydoradesu/product_discovery/internal/http/respond"
+	"github.com/soydoradesu/product_discovery/internal/service"
+	"github.com/soydoradesu/product_discovery/internal/webauthn"
+)
+
+// methodPasskey is recorded in AuthEvent.Provider for passkey logins.
+const methodPasskey = "passkey"
+
+type passkeyCreationResp struct {
+	ChallengeToken string                   `json:"challengeToken"`
+	PublicKey      webauthn.CreationOptions `json:"publicKey"`
+}
+
+type passkeyRequestResp struct {
+	ChallengeToken string                  `json:"challengeToken"`
+	PublicKey      webauthn.RequestOptions `json:"publicKey"`
+}
+
+type passkeyRegisterReq struct {
+	ChallengeToken string                       `json:"challengeToken"`
+	Name           string                       `json:"name"`
+	Credential     webauthn.AttestationResponse `json:"credential"`
+}
+
+type passkeyLoginReq struct {
+	ChallengeToken string                     `json:"challengeToken"`
+	Credential     webauthn.AssertionResponse `json:"credential"`
+}
+
+type listPasskeysResp struct {
+	Items []domain.Passkey `json:"items"`
+}
+
+// POST /api/auth/passkey/login/begin
+func (h *AuthHandlers) PasskeyLoginBegin(w http.ResponseWriter, r *http.Request) {
+	opts, token, err := h.Passkeys.BeginLogin(r.Context())
+	if err != nil {
+		respond.Fail(w, http.StatusInternalServerError, "INTERNAL", "failed to start passkey login")
+		return
+	}
+	respond.JSON(w, http.StatusOK, passkeyRequestResp{ChallengeToken: token, PublicKey: opts})
+}
+
+// POST /api/auth/passkey/login/finish logs in like a password login would.
+// A passkey already proves possession and user verification, so there is no
+// separate two-factor step.
+func (h *AuthHandlers) PasskeyLoginFinish(w http.ResponseWriter, r *http.Request) {
+	var req passkeyLoginReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Fail(w, http.StatusBadRequest, "BAD_REQUEST", "invalid json")
+		return
+	}
+
+	userID, err := h.Passkeys.FinishLogin(r.Context(), req.ChallengeToken, req.Credential)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidPasskey):
+			h.audit(r, domain.AuthEvent{Type: domain.AuthEventLoginFailed, Reason: reasonInvalidPasskey, Provider: methodPasskey})
+			respond.Fail(w, http.StatusUnauthorized, "INVALID_PASSKEY", "passkey could not be verified")
+		case errors.Is(err, service.ErrAccountDisabled):
+			respond.Fail(w, http.StatusForbidden, "ACCOUNT_DISABLED", "this account is disabled")
+		default:
+			respond.Fail(w, http.StatusInternalServerError, "INTERNAL", "something went wrong")
+		}
+		return
+	}
+
+	if err := h.setSessionCookie(w, r, userID); err != nil {
+		respond.Fail(w, http.StatusInternalServerError, "INTERNAL", "failed to create session")
+		return
+	}
+	h.audit(r, domain.AuthEvent{UserID: &userID, Type: domain.AuthEventLoginSucceeded, Provider: methodPasskey})
+	respond.JSON(w, http.StatusOK, okResp{OK: true})
+}
+
+// GET /api/me/passkeys
+func (h *AuthHandlers) ListPasskeys(w http.ResponseWriter, r *http.Request) {
+	uid, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		respond.Fail(w, http.StatusUnauthorized, "UNAUTHORIZED", "missing session")
+		return
+	}
+
+	items, err := h.Passkeys.List(r.Context(), uid)
+	if err != nil {
+		respond.Fail(w, http.StatusInternalServerError, "INTERNAL", "something went wrong")
+		return
+	}
+	respond.JSON(w, http.StatusOK, listPasskeysResp{Items: items})
+}
+
+// POST /api/me/passkeys/register/begin
+func (h *AuthHandlers) PasskeyRegisterBegin(w http.ResponseWriter, r *http.Request) {
+	uid, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		respond.Fail(w, http.StatusUnauthorized, "UNAUTHORIZED", "missing session")
+		return
+	}
+
+	opts, token, err := h.Passkeys.BeginRegistration(r.Context(), uid)
+	if err != nil {
+		respond.Fail(w, http.StatusInternalServerError, "INTERNAL", "failed to start passkey registration")
+		return
+	}
+	respond.JSON(w, http.StatusOK, passkeyCreationResp{ChallengeToken: token, PublicKey: opts})
+}
+
+// POST /api/me/passkeys/register/finish
+func (h *AuthHandlers) PasskeyRegisterFinish(w http.ResponseWriter, r *http.Request) {
+	uid, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		respond.Fail(w, http.StatusUnauthorized, "UNAUTHORIZED", "missing session")
+		return
+	}
+
+	var req passkeyRegisterReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Fail(w, http.StatusBadRequest, "BAD_REQUEST", "invalid json")
+		return
+	}
+
+	p, err := h.Passkeys.FinishRegistration(r.Context(), uid, req.ChallengeToken, req.Name, req.Credential)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidPasskey):
+			respond.Fail(w, http.StatusBadRequest, "INVALID_PASSKEY", "passkey could not be verified")
+		case errors.Is(err, service.ErrPasskeyExists):
+			respond.Fail(w, http.StatusConflict, "CONFLICT", "this passkey is already registered")
+		default:
+			respond.Fail(w, http.StatusInternalServerError, "INTERNAL", "something went wrong")
+		}
+		return
+	}
+	h.audit(r, domain.AuthEvent{UserID: &uid, Type: domain.AuthEventPasskeyAdded, Provider: methodPasskey})
+	respond.JSON(w, http.StatusCreated, p)
+}
+
+// DELETE /api/me/passkeys/{id}
+func (h *AuthHandlers) DeletePasskey(w http.ResponseWriter, r *http.Request) {
+	uid, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		respond.Fail(w, http.StatusUnauthorized, "UNAUTHORIZED", "missing session")
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		respond.Fail(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid passkey id")
+		return
+	}
+
+	if err := h.Passkeys.Delete(r.Context(), uid, id); err != nil {
+		switch {
+		case errors.Is(err, service.ErrPasskeyNotFound):
+			respond.Fail(w, http.StatusNotFound, "NOT_FOUND", "passkey not found")
+		case errors.Is(err, service.ErrLastLoginMethod):
+			respond.Fail(w, http.StatusConflict, "LAST_LOGIN_METHOD", "set a password, link a provider or add another passkey first")
+		default:
+			respond.Fail(w, http.StatusInternalServerError, "INTERNAL", "something went wrong")
+		}
+		return
+	}
+	h.audit(r, domain.AuthEvent{UserID: &uid, Type: domain.AuthEventPasskeyRemoved, Provider: methodPasskey})
+	respond.JSON(w, http.StatusOK, okResp{OK: true})
+}
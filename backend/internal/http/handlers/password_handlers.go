@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/soydoradesu/product_discovery/internal/domain"
+	"github.com/soydoradesu/product_discovery/internal/http/middleware"
+	"github.com/soydoradesu/product_discovery/internal/http/respond"
+	"github.com/soydoradesu/product_discovery/internal/service"
+)
+
+type changePasswordReq struct {
+	CurrentPassword string `json:"currentPassword"`
+	NewPassword     string `json:"newPassword"`
+}
+
+type changePasswordResp struct {
+	OK              bool  `json:"ok"`
+	RevokedSessions int64 `json:"revokedSessions"`
+}
+
+// POST /api/me/password changes the password, or sets the first one for an
+// account that so far only logs in through a provider. Every other session of
+// the user is signed out.
+func (h *AuthHandlers) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	uid, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		respond.Fail(w, http.StatusUnauthorized, "UNAUTHORIZED", "missing session")
+		return
+	}
+
+	var req changePasswordReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Fail(w, http.StatusBadRequest, "BAD_REQUEST", "invalid json")
+		return
+	}
+	if req.NewPassword == "" {
+		respond.Fail(w, http.StatusBadRequest, "VALIDATION_ERROR", "newPassword is required")
+		return
+	}
+
+	u, err := h.Auth.Users.GetByID(r.Context(), uid)
+	if err != nil {
+		respond.Fail(w, http.StatusUnauthorized, "UNAUTHORIZED", "invalid session")
+		return
+	}
+	// without a current password to check, a stolen session must not be
+	// enough to add one
+	if u.PasswordHash == nil {
+		at, ok := middleware.AuthTimeFromContext(r.Context())
+		if !ok || time.Since(at) > h.RecentAuth {
+			respond.Fail(w, http.StatusForbidden, "REAUTH_REQUIRED", "please confirm your identity again")
+			return
+		}
+	}
+
+	if err := h.Auth.ChangePassword(r.Context(), uid, req.CurrentPassword, req.NewPassword); err != nil {
+		var policyErr *service.PasswordPolicyError
+		switch {
+		case errors.As(err, &policyErr):
+			h.audit(r, domain.AuthEvent{UserID: &uid, Type: domain.AuthEventPasswordChangeFailed, Reason: reasonWeakPassword})
+			respond.Fail(w, http.StatusBadRequest, "WEAK_PASSWORD", "password "+strings.Join(policyErr.Problems, "; "))
+		case errors.Is(err, service.ErrInvalidCredentials):
+			h.audit(r, domain.AuthEvent{UserID: &uid, Type: domain.AuthEventPasswordChangeFailed, Reason: reasonBadPassword})
+			respond.Fail(w, http.StatusUnauthorized, "INVALID_CREDENTIALS", "current password is incorrect")
+		default:
+			respond.Fail(w, http.StatusInternalServerError, "INTERNAL", "something went wrong")
+		}
+		return
+	}
+
+	h.audit(r, domain.AuthEvent{UserID: &uid, Type: domain.AuthEventPasswordChanged})
+
+	current, _ := middleware.SessionIDFromContext(r.Context())
+	revoked, err := h.Sessions.RevokeOthers(r.Context(), uid, current)
+	if err != nil {
+		respond.Fail(w, http.StatusInternalServerError, "INTERNAL", "password changed but other sessions could not be signed out")
+		return
+	}
+	if revoked > 0 {
+		h.audit(r, domain.AuthEvent{UserID: &uid, Type: domain.AuthEventSessionRevoked, Reason: reasonPasswordChanged})
+	}
+	respond.JSON(w, http.StatusOK, changePasswordResp{OK: true, RevokedSessions: revoked})
+}
@@ -1,23 +1,77 @@
 package handlers
 
 import (
+	"math"
 	"net/http"
+	"net/url"
+	"sort"
 	"strconv"
 	"strings"
-	"math"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 
+	"github.com/soydoradesu/product_discovery/internal/config"
 	"github.com/soydoradesu/product_discovery/internal/domain"
+	"github.com/soydoradesu/product_discovery/internal/http/middleware"
 	"github.com/soydoradesu/product_discovery/internal/http/respond"
 	"github.com/soydoradesu/product_discovery/internal/service"
 )
 
+// maxOptionFilters bounds how many variant options one search filters on,
+// and maxAttributeFilters how many attribute conditions.
+const (
+	maxOptionFilters    = 10
+	maxAttributeFilters = 10
+)
+
 type ProductHandlers struct {
 	Products *service.ProductService
+	// Visibility is one of the config.ProductVisibility values; it decides
+	// what anonymous visitors get from GetByID.
+	Visibility string
+	// ExportTimeout overrides defaultExportTimeout when set.
+	ExportTimeout time.Duration
+}
+
+// productPreview is what anonymous visitors see in preview mode: no price or
+// stock, and only the first image.
+type productPreview struct {
+	ID int64 `json:"id"`
+	Name string `json:"name"`
+	Description string `json:"description"`
+	Rating float64 `json:"rating"`
+	CreatedAt time.Time `json:"createdAt"`
+	Images []domain.ProductImage `json:"images"`
+	Categories []domain.Category `json:"categories"`
+	Preview bool `json:"preview"`
+}
+
+func newProductPreview(p domain.Product) productPreview {
+	images := p.Images
+	if len(images) > 1 {
+		images = images[:1]
+	}
+	return productPreview{
+		ID: p.ID,
+		Name: p.Name,
+		Description: p.Description,
+		Rating: p.Rating,
+		CreatedAt: p.CreatedAt,
+		Images: images,
+		Categories: p.Categories,
+		Preview: true,
+	}
 }
 
+// GetByID runs behind OptionalAuth.
 func (h *ProductHandlers) GetByID(w http.ResponseWriter, r *http.Request) {
+	_, signedIn := middleware.UserIDFromContext(r.Context())
+	if !signedIn && h.Visibility != config.ProductVisibilityFull && h.Visibility != config.ProductVisibilityPreview {
+		respond.Fail(w, http.StatusUnauthorized, "UNAUTHORIZED", "missing session")
+		return
+	}
+
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil || id <= 0 {
@@ -25,16 +79,34 @@ func (h *ProductHandlers) GetByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	p, err := h.Products.GetByID(r.Context(), id)
+	currency := strings.ToUpper(strings.TrimSpace(r.URL.Query().Get("currency")))
+	if currency != "" && !domain.IsCurrencyCode(currency) {
+		respond.Fail(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid currency")
+		return
+	}
+	// like search, so a product shows here only when it can show there
+	if currency == "" {
+		currency = h.Products.Currency
+	}
+
+	p, err := h.Products.GetByIDInCurrency(r.Context(), id, currency)
 	if err != nil {
 		if err == service.ErrProductNotFound {
 			respond.Fail(w, http.StatusNotFound, "NOT_FOUND", "product not found")
 			return
 		}
+		if err == service.ErrNoPriceInCurrency {
+			respond.Fail(w, http.StatusNotFound, "NO_PRICE", "product has no price in "+currency)
+			return
+		}
 		respond.Fail(w, http.StatusInternalServerError, "INTERNAL", "something went wrong")
 		return
 	}
 
+	if !signedIn && h.Visibility == config.ProductVisibilityPreview {
+		respond.JSON(w, http.StatusOK, newProductPreview(p))
+		return
+	}
 	respond.JSON(w, http.StatusOK, p)
 }
 
@@ -44,14 +116,52 @@ type searchResp struct {
 	PageSize int `json:"pageSize"`
 	Total int64 `json:"total"`
 	TotalPages int `json:"totalPages"`
+	Currency string `json:"currency"`
+	// Facets are only computed when asked for with facets=true.
+	Facets []domain.AttributeFacet `json:"facets,omitempty"`
 }
 
 func (h *ProductHandlers) Search(w http.ResponseWriter, r *http.Request) {
-	qp := r.URL.Query()
+	params := searchParamsFromQuery(r.URL.Query(), h.Products.Currency)
+
+	items, total, normalized, err := h.Products.Search(r.Context(), params)
+	if err != nil {
+		respond.Fail(w, http.StatusInternalServerError, "INTERNAL", "something went wrong")
+		return
+	}
+
+	totalPages := int((total + int64(normalized.PageSize) - 1) / int64(normalized.PageSize))
+	resp := searchResp{
+		Items: items,
+		Page: normalized.Page,
+		PageSize: normalized.PageSize,
+		Total: total,
+		TotalPages: totalPages,
+		Currency: normalized.Currency,
+	}
+	if withFacets, _ := strconv.ParseBool(r.URL.Query().Get("facets")); withFacets {
+		resp.Facets, err = h.Products.AttributeFacets(r.Context(), normalized)
+		if err != nil {
+			respond.Fail(w, http.StatusInternalServerError, "INTERNAL", "something went wrong")
+			return
+		}
+	}
+	respond.JSON(w, http.StatusOK, resp)
+}
 
+// searchParamsFromQuery reads the search filters, sorting and paging from the
+// query string, dropping values that do not parse. Price bounds are in the
+// requested currency, or in defaultCurrency when none is given.
+func searchParamsFromQuery(qp url.Values, defaultCurrency string) domain.SearchParams {
 	var params domain.SearchParams
 	params.Q = strings.TrimSpace(qp.Get("q"))
 
+	currency := defaultCurrency
+	if v := strings.ToUpper(strings.TrimSpace(qp.Get("currency"))); domain.IsCurrencyCode(v) {
+		params.Currency = v
+		currency = v
+	}
+
 	// category multi-value: category=1&category=2
 	for _, s := range qp["category"] {
 		id, err := strconv.ParseInt(s, 10, 64)
@@ -59,17 +169,24 @@ func (h *ProductHandlers) Search(w http.ResponseWriter, r *http.Request) {
 			params.CategoryID = append(params.CategoryID, id)
 		}
 	}
+	// brand multi-value: brand=1&brand=2
+	for _, s := range qp["brand"] {
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err == nil && id > 0 {
+			params.BrandID = append(params.BrandID, id)
+		}
+	}
 
+	// price bounds are decimals; anything that does not parse exactly (bad
+	// syntax, too many decimals) is ignored
 	if v := strings.TrimSpace(qp.Get("minPrice")); v != "" {
-		if f, err := strconv.ParseFloat(v, 64); err == nil && f >= 0 {
-			f = math.Round(f*100) / 100
-			params.MinPrice = &f
+		if m, err := domain.ParseMoney(v, currency); err == nil && m.Amount >= 0 {
+			params.MinPrice = &m
 		}
 	}
 	if v := strings.TrimSpace(qp.Get("maxPrice")); v != "" {
-		if f, err := strconv.ParseFloat(v, 64); err == nil && f >= 0 {
-			f = math.Round(f*100) / 100
-			params.MaxPrice = &f
+		if m, err := domain.ParseMoney(v, currency); err == nil && m.Amount >= 0 {
+			params.MaxPrice = &m
 		}
 	}
 
@@ -79,6 +196,25 @@ func (h *ProductHandlers) Search(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// variant options: option.color=black&option.color=white&option.size=M
+	for key, values := range qp {
+		name, ok := strings.CutPrefix(key, "option.")
+		name = strings.ToLower(name)
+		if !ok || !service.OptionNameRe.MatchString(name) || len(params.Options) >= maxOptionFilters {
+			continue
+		}
+		for _, v := range values {
+			if v = strings.TrimSpace(v); v != "" {
+				if params.Options == nil {
+					params.Options = map[string][]string{}
+				}
+				params.Options[name] = append(params.Options[name], v)
+			}
+		}
+	}
+
+	params.Attributes = attributeFiltersFromQuery(qp)
+
 	params.Sort = qp.Get("sort")     
 	params.Method = qp.Get("method")
 
@@ -92,20 +228,64 @@ func (h *ProductHandlers) Search(w http.ResponseWriter, r *http.Request) {
 			params.PageSize = n
 		}
 	}
+	return params
+}
 
-	items, total, normalized, err := h.Products.Search(r.Context(), params)
-	if err != nil {
-		respond.Fail(w, http.StatusInternalServerError, "INTERNAL", "something went wrong")
-		return
+// attributeFiltersFromQuery reads attr.<code>=<value> (repeat it to allow
+// several values) and the number comparisons attr.<code>>=<n>,
+// attr.<code><=<n>, attr.<code>><n> and attr.<code><<n>. A query string
+// splits those at the first "=", so attr.ram_gb>=16 arrives as the key
+// attr.ram_gb> and attr.ram_gb>16 as a key without a value.
+func attributeFiltersFromQuery(qp url.Values) []domain.AttributeFilter {
+	keys := make([]string, 0, len(qp))
+	for key := range qp {
+		if strings.HasPrefix(key, "attr.") {
+			keys = append(keys, key)
+		}
 	}
+	sort.Strings(keys)
 
-	totalPages := int((total + int64(normalized.PageSize) - 1) / int64(normalized.PageSize))
-	resp := searchResp{
-		Items: items,
-		Page: normalized.Page,
-		PageSize: normalized.PageSize,
-		Total: total,
-		TotalPages: totalPages,
+	var filters []domain.AttributeFilter
+	equals := map[string]int{}
+	for _, key := range keys {
+		if len(filters) >= maxAttributeFilters {
+			break
+		}
+		code, cmp := strings.TrimPrefix(key, "attr."), ""
+		if i := strings.IndexAny(code, "<>"); i >= 0 {
+			code, cmp = code[:i], code[i:]
+		}
+		code = strings.ToLower(strings.TrimSpace(code))
+		if !service.AttributeCodeRe.MatchString(code) {
+			continue
+		}
+
+		if cmp == "" {
+			// attr.Color and attr.color are the same filter
+			var values []string
+			for _, v := range qp[key] {
+				if v = strings.ToLower(strings.TrimSpace(v)); v != "" {
+					values = append(values, v)
+				}
+			}
+			if i, ok := equals[code]; ok {
+				filters[i].Values = append(filters[i].Values, values...)
+			} else if len(values) > 0 {
+				equals[code] = len(filters)
+				filters = append(filters, domain.AttributeFilter{Code: code, Op: domain.AttributeOpEq, Values: values})
+			}
+			continue
+		}
+
+		op, value := cmp+"=", qp.Get(key)
+		if len(cmp) > 1 {
+			op, value = cmp[:1], cmp[1:]
+		}
+		n, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || math.IsNaN(n) || math.IsInf(n, 0) {
+			continue
+		}
+		filters = append(filters, domain.AttributeFilter{Code: code, Op: op, Number: n})
 	}
-	respond.JSON(w, http.StatusOK, resp)
+	return filters
 }
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"errors"
+	"net"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/soydoradesu/product_discovery/internal/domain"
+	"github.com/soydoradesu/product_discovery/internal/http/middleware"
+	"github.com/soydoradesu/product_discovery/internal/http/respond"
+	"github.com/soydoradesu/product_discovery/internal/service"
+)
+
+type sessionItem struct {
+	domain.Session
+	Current bool `json:"current"`
+}
+
+type listSessionsResp struct {
+	Items []sessionItem `json:"items"`
+}
+
+// GET /api/me/sessions
+func (h *AuthHandlers) ListSessions(w http.ResponseWriter, r *http.Request) {
+	uid, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		respond.Fail(w, http.StatusUnauthorized, "UNAUTHORIZED", "missing session")
+		return
+	}
+	current, _ := middleware.SessionIDFromContext(r.Context())
+
+	sessions, err := h.Sessions.List(r.Context(), uid)
+	if err != nil {
+		respond.Fail(w, http.StatusInternalServerError, "INTERNAL", "something went wrong")
+		return
+	}
+
+	items := make([]sessionItem, 0, len(sessions))
+	for _, s := range sessions {
+		items = append(items, sessionItem{Session: s, Current: s.ID == current})
+	}
+	respond.JSON(w, http.StatusOK, listSessionsResp{Items: items})
+}
+
+// DELETE /api/me/sessions/{id}
+func (h *AuthHandlers) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	uid, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		respond.Fail(w, http.StatusUnauthorized, "UNAUTHORIZED", "missing session")
+		return
+	}
+
+	if err := h.Sessions.Revoke(r.Context(), uid, chi.URLParam(r, "id")); err != nil {
+		if errors.Is(err, service.ErrSessionNotFound) {
+			respond.Fail(w, http.StatusNotFound, "NOT_FOUND", "session not found")
+			return
+		}
+		respond.Fail(w, http.StatusInternalServerError, "INTERNAL", "something went wrong")
+		return
+	}
+	h.audit(r, domain.AuthEvent{UserID: &uid, Type: domain.AuthEventSessionRevoked, Reason: reasonRevokedByUser})
+	respond.JSON(w, http.StatusOK, okResp{OK: true})
+}
+
+// clientIP expects chimw.RealIP to have already replaced RemoteAddr with the
+// forwarded address where there is one.
+func clientIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
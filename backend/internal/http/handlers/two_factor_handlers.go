@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/soydoradesu/product_discovery/internal/auth"
+	"github.com/soydoradesu/product_discovery/internal/domain"
+	"github.com/soydoradesu/product_discovery/internal/http/middleware"
+	"github.com/soydoradesu/product_discovery/internal/http/respond"
+	"github.com/soydoradesu/product_discovery/internal/service"
+)
+
+const twoFactorChallengeTTL = 5 * time.Minute
+
+type twoFactorChallengeResp struct {
+	TwoFactorRequired bool   `json:"twoFactorRequired"`
+	ChallengeToken    string `json:"challengeToken"`
+}
+
+type twoFactorLoginReq struct {
+	ChallengeToken string `json:"challengeToken"`
+	Code           string `json:"code"`
+}
+
+type twoFactorCodeReq struct {
+	Code string `json:"code"`
+}
+
+type twoFactorStatusResp struct {
+	Enabled bool `json:"enabled"`
+}
+
+type recoveryCodesResp struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+// POST /api/auth/login/2fa
+func (h *AuthHandlers) LoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var req twoFactorLoginReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Fail(w, http.StatusBadRequest, "BAD_REQUEST", "invalid json")
+		return
+	}
+	req.Code = strings.TrimSpace(req.Code)
+	if req.ChallengeToken == "" || req.Code == "" {
+		respond.Fail(w, http.StatusBadRequest, "VALIDATION_ERROR", "challengeToken and code are required")
+		return
+	}
+
+	claims, err := auth.VerifyChallenge(h.Keys, req.ChallengeToken, auth.PurposeTwoFactor)
+	if err != nil {
+		respond.Fail(w, http.StatusUnauthorized, "UNAUTHORIZED", "invalid or expired challenge")
+		return
+	}
+
+	if err := h.TwoFactor.Verify(r.Context(), claims.UserID, req.Code); err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidTwoFactorCode):
+			h.audit(r, domain.AuthEvent{UserID: &claims.UserID, Type: domain.AuthEventLoginFailed, Reason: reasonBadTwoFactorCode, Provider: methodTwoFactor})
+		case errors.Is(err, service.ErrTwoFactorLocked):
+			h.audit(r, domain.AuthEvent{UserID: &claims.UserID, Type: domain.AuthEventLoginFailed, Reason: reasonTwoFactorLocked, Provider: methodTwoFactor})
+		}
+		writeTwoFactorError(w, err)
+		return
+	}
+
+	if err := h.setSessionCookie(w, r, claims.UserID); err != nil {
+		respond.Fail(w, http.StatusInternalServerError, "INTERNAL", "failed to create session")
+		return
+	}
+	h.audit(r, domain.AuthEvent{UserID: &claims.UserID, Type: domain.AuthEventLoginSucceeded, Provider: methodTwoFactor})
+
+	respond.JSON(w, http.StatusOK, okResp{OK: true})
+}
+
+// GET /api/me/2fa
+func (h *AuthHandlers) TwoFactorStatus(w http.ResponseWriter, r *http.Request) {
+	uid, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		respond.Fail(w, http.StatusUnauthorized, "UNAUTHORIZED", "missing session")
+		return
+	}
+
+	enabled, err := h.TwoFactor.Enabled(r.Context(), uid)
+	if err != nil {
+		respond.Fail(w, http.StatusInternalServerError, "INTERNAL", "something went wrong")
+		return
+	}
+	respond.JSON(w, http.StatusOK, twoFactorStatusResp{Enabled: enabled})
+}
+
+// POST /api/me/2fa/enroll
+func (h *AuthHandlers) TwoFactorEnroll(w http.ResponseWriter, r *http.Request) {
+	uid, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		respond.Fail(w, http.StatusUnauthorized, "UNAUTHORIZED", "missing session")
+		return
+	}
+
+	u, err := h.Auth.Users.GetByID(r.Context(), uid)
+	if err != nil {
+		respond.Fail(w, http.StatusUnauthorized, "UNAUTHORIZED", "invalid session")
+		return
+	}
+
+	enrollment, err := h.TwoFactor.Enroll(r.Context(), uid, u.Email)
+	if err != nil {
+		writeTwoFactorError(w, err)
+		return
+	}
+	respond.JSON(w, http.StatusOK, enrollment)
+}
+
+// POST /api/me/2fa/confirm
+func (h *AuthHandlers) TwoFactorConfirm(w http.ResponseWriter, r *http.Request) {
+	uid, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		respond.Fail(w, http.StatusUnauthorized, "UNAUTHORIZED", "missing session")
+		return
+	}
+
+	var req twoFactorCodeReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Fail(w, http.StatusBadRequest, "BAD_REQUEST", "invalid json")
+		return
+	}
+
+	codes, err := h.TwoFactor.Confirm(r.Context(), uid, req.Code)
+	if err != nil {
+		writeTwoFactorError(w, err)
+		return
+	}
+	h.audit(r, domain.AuthEvent{UserID: &uid, Type: domain.AuthEventTwoFactorEnabled})
+	respond.JSON(w, http.StatusOK, recoveryCodesResp{RecoveryCodes: codes})
+}
+
+// POST /api/me/2fa/disable
+func (h *AuthHandlers) TwoFactorDisable(w http.ResponseWriter, r *http.Request) {
+	uid, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		respond.Fail(w, http.StatusUnauthorized, "UNAUTHORIZED", "missing session")
+		return
+	}
+
+	var req twoFactorCodeReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Fail(w, http.StatusBadRequest, "BAD_REQUEST", "invalid json")
+		return
+	}
+
+	if err := h.TwoFactor.Disable(r.Context(), uid, req.Code); err != nil {
+		writeTwoFactorError(w, err)
+		return
+	}
+	h.audit(r, domain.AuthEvent{UserID: &uid, Type: domain.AuthEventTwoFactorDisabled})
+	respond.JSON(w, http.StatusOK, okResp{OK: true})
+}
+
+func writeTwoFactorError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidTwoFactorCode):
+		respond.Fail(w, http.StatusUnauthorized, "INVALID_2FA_CODE", "two-factor code is incorrect")
+	case errors.Is(err, service.ErrTwoFactorLocked):
+		respond.Fail(w, http.StatusTooManyRequests, "2FA_LOCKED", "too many incorrect two-factor codes, try again later")
+	case errors.Is(err, service.ErrTwoFactorAlreadyEnabled):
+		respond.Fail(w, http.StatusConflict, "CONFLICT", "two-factor authentication is already enabled")
+	case errors.Is(err, service.ErrTwoFactorNotEnrolled):
+		respond.Fail(w, http.StatusConflict, "CONFLICT", "start two-factor enrollment first")
+	case errors.Is(err, service.ErrTwoFactorNotEnabled):
+		respond.Fail(w, http.StatusConflict, "CONFLICT", "two-factor authentication is not enabled")
+	default:
+		respond.Fail(w, http.StatusInternalServerError, "INTERNAL", "something went wrong")
+	}
+}
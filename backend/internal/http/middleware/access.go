@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/soydoradesu/product_discovery/internal/domain"
+	"github.com/soydoradesu/product_discovery/internal/http/respond"
+)
+
+const accessKey ctxKey = "access"
+
+type AccessResolver interface {
+	Access(ctx context.Context, userID int64) (domain.Access, error)
+}
+
+func AccessFromContext(ctx context.Context) (domain.Access, bool) {
+	v, ok := ctx.Value(accessKey).(domain.Access)
+	return v, ok
+}
+
+// RequireRole must run after RequireAuth.
+func RequireRole(resolver AccessResolver, role string) func(http.Handler) http.Handler {
+	return requireAccess(resolver, func(a domain.Access) bool { return a.HasRole(role) })
+}
+
+// RequirePermission must run after RequireAuth.
+func RequirePermission(resolver AccessResolver, perm string) func(http.Handler) http.Handler {
+	return requireAccess(resolver, func(a domain.Access) bool { return a.HasPermission(perm) })
+}
+
+func requireAccess(resolver AccessResolver, allowed func(domain.Access) bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			uid, ok := UserIDFromContext(r.Context())
+			if !ok {
+				respond.Fail(w, http.StatusUnauthorized, "UNAUTHORIZED", "missing session")
+				return
+			}
+
+			access, ok := AccessFromContext(r.Context())
+			if !ok {
+				var err error
+				access, err = resolver.Access(r.Context(), uid)
+				if err != nil {
+					respond.Fail(w, http.StatusInternalServerError, "INTERNAL", "something went wrong")
+					return
+				}
+				if key, ok := APIKeyFromContext(r.Context()); ok && len(key.Scopes) > 0 {
+					// a scoped key grants only its scopes; the owner's roles
+					// would let RequireRole look past them
+					access.Permissions = intersect(access.Permissions, key.Scopes)
+					access.Roles = nil
+				}
+			}
+
+			if !allowed(access) {
+				respond.Fail(w, http.StatusForbidden, "FORBIDDEN", "insufficient permissions")
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), accessKey, access)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func intersect(have, allowed []string) []string {
+	out := []string{}
+	for _, h := range have {
+		for _, a := range allowed {
+			if h == a {
+				out = append(out, h)
+				break
+			}
+		}
+	}
+	return out
+}
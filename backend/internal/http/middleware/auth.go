@@ -3,15 +3,44 @@ package middleware
 import (
 	"context"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/soydoradesu/product_discovery/internal/auth"
-	"github.com/soydoradesu/product_discovery/internal/config"
+	"github.com/soydoradesu/product_discovery/internal/domain"
 	"github.com/soydoradesu/product_discovery/internal/http/respond"
 )
 
 type ctxKey string
 
-const userIDKey ctxKey = "userID"
+const (
+	userIDKey   ctxKey = "userID"
+	apiKeyKey   ctxKey = "apiKey"
+	authTimeKey ctxKey = "authTime"
+	sessionKey  ctxKey = "sessionID"
+)
+
+type APIKeyAuthenticator interface {
+	AuthenticateAPIKey(ctx context.Context, rawKey string) (domain.APIKey, error)
+}
+
+type SessionValidator interface {
+	ValidateSession(ctx context.Context, userID int64, sessionID string) error
+}
+
+type UserStatusChecker interface {
+	CheckUserActive(ctx context.Context, userID int64) error
+}
+
+// Authenticators are the server-side lookups RequireAuth can use. A nil
+// APIKeys disables API keys; a nil Sessions trusts any validly signed session
+// token without checking it against the session store; a nil Users skips
+// checking that the account hasn't been disabled.
+type Authenticators struct {
+	APIKeys  APIKeyAuthenticator
+	Sessions SessionValidator
+	Users    UserStatusChecker
+}
 
 func UserIDFromContext(ctx context.Context) (int64, bool) {
 	v := ctx.Value(userIDKey)
@@ -19,22 +48,149 @@ func UserIDFromContext(ctx context.Context) (int64, bool) {
 	return id, ok
 }
 
-func RequireAuth(cfg config.Config) func(http.Handler) http.Handler {
+// APIKeyFromContext reports the key the request authenticated with, if it
+// used one instead of the session cookie.
+func APIKeyFromContext(ctx context.Context) (domain.APIKey, bool) {
+	k, ok := ctx.Value(apiKeyKey).(domain.APIKey)
+	return k, ok
+}
+
+// SessionIDFromContext reports the server-side session the request's cookie
+// belongs to.
+func SessionIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(sessionKey).(string)
+	return id, ok && id != ""
+}
+
+// AuthTimeFromContext reports when the session's user last proved who they
+// are; it is unset for API keys.
+func AuthTimeFromContext(ctx context.Context) (time.Time, bool) {
+	at, ok := ctx.Value(authTimeKey).(time.Time)
+	return at, ok
+}
+
+func RequireAuth(keys *auth.Keyring, authn Authenticators) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			c, err := r.Cookie("session")
-			if err != nil || c.Value == "" {
-				respond.Fail(w, http.StatusUnauthorized, "UNAUTHORIZED", "missing session")
+			ctx, fail := authn.authenticate(keys, r)
+			if fail != nil {
+				respond.Fail(w, http.StatusUnauthorized, fail.code, fail.message)
 				return
 			}
-			
-			claims, err := auth.VerifyJWT(cfg.JWTSecret, c.Value)
-			if err != nil {
-				respond.Fail(w, http.StatusUnauthorized, "UNAUTHORIZED", "invalid session")
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// OptionalAuth attaches the user the same way RequireAuth does when the
+// request carries valid credentials, and otherwise lets it through
+// anonymously. Handlers tell the two apart with UserIDFromContext.
+func OptionalAuth(keys *auth.Keyring, authn Authenticators) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if ctx, fail := authn.authenticate(keys, r); fail == nil {
+				r = r.WithContext(ctx)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+type authFailure struct {
+	code    string
+	message string
+}
+
+// authenticate resolves the request's API key or session cookie to a user
+// and returns the context carrying it.
+func (a Authenticators) authenticate(keys *auth.Keyring, r *http.Request) (context.Context, *authFailure) {
+	if raw, ok := bearerToken(r); ok {
+		if a.APIKeys == nil {
+			return nil, &authFailure{"UNAUTHORIZED", "invalid api key"}
+		}
+		key, err := a.APIKeys.AuthenticateAPIKey(r.Context(), raw)
+		if err != nil {
+			return nil, &authFailure{"UNAUTHORIZED", "invalid api key"}
+		}
+		if fail := a.checkUser(r.Context(), key.UserID); fail != nil {
+			return nil, fail
+		}
+		ctx := context.WithValue(r.Context(), userIDKey, key.UserID)
+		return context.WithValue(ctx, apiKeyKey, key), nil
+	}
+
+	c, err := r.Cookie("session")
+	if err != nil || c.Value == "" {
+		return nil, &authFailure{"UNAUTHORIZED", "missing session"}
+	}
+
+	claims, err := auth.VerifyJWT(keys, c.Value)
+	if err != nil {
+		return nil, &authFailure{"UNAUTHORIZED", "invalid session"}
+	}
+	if a.Sessions != nil {
+		if err := a.Sessions.ValidateSession(r.Context(), claims.UserID, claims.ID); err != nil {
+			return nil, &authFailure{"UNAUTHORIZED", "invalid session"}
+		}
+	}
+	if fail := a.checkUser(r.Context(), claims.UserID); fail != nil {
+		return nil, fail
+	}
+	ctx := context.WithValue(r.Context(), userIDKey, claims.UserID)
+	ctx = context.WithValue(ctx, sessionKey, claims.ID)
+	if claims.IssuedAt != nil {
+		ctx = context.WithValue(ctx, authTimeKey, claims.IssuedAt.Time)
+	}
+	return ctx, nil
+}
+
+func (a Authenticators) checkUser(ctx context.Context, userID int64) *authFailure {
+	if a.Users == nil {
+		return nil
+	}
+	if err := a.Users.CheckUserActive(ctx, userID); err != nil {
+		return &authFailure{"ACCOUNT_DISABLED", "this account is disabled or no longer exists"}
+	}
+	return nil
+}
+
+// RequireRecentAuth must run after RequireAuth. Session tokens are only issued
+// right after the user proves who they are, so the token's issue time is the
+// authentication time; API keys never count as a recent authentication.
+func RequireRecentAuth(maxAge time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			at, ok := AuthTimeFromContext(r.Context())
+			if !ok || time.Since(at) > maxAge {
+				respond.Fail(w, http.StatusForbidden, "REAUTH_REQUIRED", "please confirm your identity again")
 				return
 			}
-			ctx := context.WithValue(r.Context(), userIDKey, claims.UserID)
-			next.ServeHTTP(w, r.WithContext(ctx))
+			next.ServeHTTP(w, r)
 		})
 	}
-}
\ No newline at end of file
+}
+
+// RequireSession rejects requests made with an API key. It guards endpoints
+// that manage credentials, so a leaked key can't mint or revoke others.
+func RequireSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := APIKeyFromContext(r.Context()); ok {
+			respond.Fail(w, http.StatusForbidden, "FORBIDDEN", "this endpoint requires a browser session")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	h := r.Header.Get("Authorization")
+	if h == "" {
+		return "", false
+	}
+	scheme, token, ok := strings.Cut(h, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/soydoradesu/product_discovery/internal/http/respond"
+)
+
+// CSRF uses the double-submit pattern: GET /api/auth/csrf sets CSRFCookie and
+// returns the same token, and every state-changing request must echo it in
+// CSRFHeader. A cross-site page can make the browser send the cookie but can
+// neither read it nor set the header.
+const (
+	CSRFCookie = "csrf_token"
+	CSRFHeader = "X-CSRF-Token"
+)
+
+// RequireCSRF checks every request that isn't GET, HEAD or OPTIONS. Requests
+// carrying a bearer token are exempt: they don't rely on cookies, and a
+// browser can't attach that header cross-site without passing CORS.
+func RequireCSRF(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		}
+		if _, ok := bearerToken(r); ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		c, err := r.Cookie(CSRFCookie)
+		header := r.Header.Get(CSRFHeader)
+		if err != nil || c.Value == "" || header == "" ||
+			subtle.ConstantTimeCompare([]byte(c.Value), []byte(header)) != 1 {
+			respond.Fail(w, http.StatusForbidden, "CSRF_FAILED", "missing or invalid csrf token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -8,6 +8,9 @@ import (
 type APIError struct {
 	Code string `json:"code"`
 	Message string `json:"message"`
+	// Fields maps a request field to what is wrong with it, for validation
+	// failures that concern more than one input.
+	Fields map[string]string `json:"fields,omitempty"`
 }
 
 type ErrorEnvelope struct {
@@ -22,4 +25,7 @@ func JSON(w http.ResponseWriter, status int, v any) {
 
 func Fail(w http.ResponseWriter, status int, code, message string) {
 	JSON(w, status, ErrorEnvelope{Error: APIError{Code: code, Message: message}})
-}
\ No newline at end of file
+}
+func FailFields(w http.ResponseWriter, status int, code, message string, fields map[string]string) {
+	JSON(w, status, ErrorEnvelope{Error: APIError{Code: code, Message: message, Fields: fields}})
+}
@@ -8,25 +8,108 @@ import (
 	"github.com/go-chi/chi/v5"
 	chimw "github.com/go-chi/chi/v5/middleware"
 
+	"github.com/soydoradesu/product_discovery/internal/auth"
 	"github.com/soydoradesu/product_discovery/internal/config"
+	"github.com/soydoradesu/product_discovery/internal/domain"
 	"github.com/soydoradesu/product_discovery/internal/http/handlers"
 	"github.com/soydoradesu/product_discovery/internal/http/middleware"
+	"github.com/soydoradesu/product_discovery/internal/mail"
+	"github.com/soydoradesu/product_discovery/internal/oidc"
 	"github.com/soydoradesu/product_discovery/internal/repository/postgres"
 	"github.com/soydoradesu/product_discovery/internal/service"
+	"github.com/soydoradesu/product_discovery/internal/webauthn"
 )
 
-func NewRouter(cfg config.Config, pool *pgxpool.Pool) http.Handler {
+// recentAuthWindow is how long after logging in (or re-authenticating) a user
+// may change sensitive account settings such as linked providers.
+const recentAuthWindow = 10 * time.Minute
+
+func NewRouter(cfg config.Config, pool *pgxpool.Pool, keys *auth.Keyring, mailer mail.Mailer) http.Handler {
 	userRepo := postgres.NewUserRepo(pool)
 	productRepo := postgres.NewProductRepo(pool)
 	categoryRepo := postgres.NewCategoryRepo(pool)
+	twoFactorRepo := postgres.NewTwoFactorRepo(pool)
+	roleRepo := postgres.NewRoleRepo(pool)
+	apiKeyRepo := postgres.NewAPIKeyRepo(pool)
+	sessionRepo := postgres.NewSessionRepo(pool)
+	magicLinkRepo := postgres.NewMagicLinkRepo(pool)
+	authEventRepo := postgres.NewAuthEventRepo(pool)
+	passkeyRepo := postgres.NewPasskeyRepo(pool)
+	catalogImportRepo := postgres.NewCatalogImportRepo(pool)
+	exchangeRateRepo := postgres.NewExchangeRateRepo(pool)
+	inventoryRepo := postgres.NewInventoryRepo(pool)
+	attributeRepo := postgres.NewAttributeRepo(pool)
+	brandRepo := postgres.NewBrandRepo(pool)
 
 	authSvc := service.NewAuthService(userRepo)
 	productSvc := service.NewProductService(productRepo)
+	catalogImportSvc := service.NewCatalogImportService(catalogImportRepo)
+	productSvc.Currency = cfg.Currency
+	productSvc.Attributes = attributeRepo
+	productSvc.Brands = brandRepo
+	catalogImportSvc.Currency = cfg.Currency
 	categorySvc := service.NewCategoryService(categoryRepo)
+	attributeSvc := service.NewAttributeService(attributeRepo, categoryRepo)
+	brandSvc := service.NewBrandService(brandRepo)
+	exchangeRateSvc := service.NewExchangeRateService(exchangeRateRepo)
+	inventorySvc := service.NewInventoryService(inventoryRepo, productRepo)
+	twoFactorSvc := service.NewTwoFactorService(twoFactorRepo, cfg.TOTPIssuer)
+	accessSvc := service.NewAccessService(roleRepo, userRepo)
+	apiKeySvc := service.NewAPIKeyService(apiKeyRepo)
+	sessionSvc := service.NewSessionService(sessionRepo)
+	magicLinkSvc := service.NewMagicLinkService(magicLinkRepo, userRepo, mailer, keys)
+	auditSvc := service.NewAuditService(authEventRepo)
+	passkeySvc := service.NewPasskeyService(passkeyRepo, userRepo, webauthn.RelyingParty{
+		ID:      cfg.WebAuthnRPID,
+		Name:    cfg.WebAuthnRPName,
+		Origins: cfg.WebAuthnOrigins,
+	}, keys)
+	userAdminSvc := service.NewUserAdminService(userRepo, roleRepo, sessionRepo)
+	magicLinkSvc.TTL = time.Duration(cfg.MagicLinkTTL) * time.Minute
+	magicLinkSvc.VerifyURL = cfg.BackendPublicURL + "/api/auth/magic-link/verify"
+	magicLinkSvc.AllowSignup = cfg.MagicLinkSignup
+	authSvc.Policy = service.PasswordPolicy{
+		MinLength:     cfg.PasswordMinLength,
+		RequireUpper:  cfg.PasswordRequireUpper,
+		RequireLower:  cfg.PasswordRequireLower,
+		RequireDigit:  cfg.PasswordRequireDigit,
+		RequireSymbol: cfg.PasswordRequireSymbol,
+	}
+	authSvc.Hasher = service.PasswordHasher{
+		Algorithm:  cfg.PasswordHash,
+		BcryptCost: cfg.BcryptCost,
+		Argon2: service.Argon2Params{
+			MemoryKiB:   uint32(cfg.Argon2MemoryKiB),
+			Iterations:  uint32(cfg.Argon2Iterations),
+			Parallelism: uint8(cfg.Argon2Parallelism),
+			SaltLen:     16,
+			KeyLen:      32,
+		},
+	}
+
+	oidcProviders := make([]oidc.Config, 0, len(cfg.OIDCProviders))
+	for _, p := range cfg.OIDCProviders {
+		oidcProviders = append(oidcProviders, oidc.Config(p))
+	}
+	oidcRegistry := oidc.NewRegistry(oidcProviders, nil)
 
-	authH := &handlers.AuthHandlers{Cfg: cfg, Auth: authSvc}
-	productH := &handlers.ProductHandlers{Products: productSvc}
+	authH := &handlers.AuthHandlers{Cfg: cfg, Keys: keys, Auth: authSvc, TwoFactor: twoFactorSvc, Access: accessSvc, OIDC: oidcRegistry, Sessions: sessionSvc, MagicLinks: magicLinkSvc, Passkeys: passkeySvc, Audit: auditSvc, RecentAuth: recentAuthWindow}
+	productH := &handlers.ProductHandlers{Products: productSvc, Visibility: cfg.ProductDetailVisibility}
 	categoryH := &handlers.CategoryHandlers{Categories: categorySvc}
+	attributeH := &handlers.AttributeHandlers{Attributes: attributeSvc}
+	brandH := &handlers.BrandHandlers{Brands: brandSvc}
+	importH := &handlers.ImportHandlers{Imports: catalogImportSvc}
+	exchangeRateH := &handlers.ExchangeRateHandlers{Rates: exchangeRateSvc}
+	inventoryH := &handlers.InventoryHandlers{Inventory: inventorySvc}
+	apiKeyH := &handlers.APIKeyHandlers{Keys: apiKeySvc, Audit: auditSvc}
+	auditH := &handlers.AuditHandlers{Audit: auditSvc}
+	adminUserH := &handlers.AdminUserHandlers{Users: userAdminSvc, MagicLinks: magicLinkSvc, Audit: auditSvc}
+	keyH := &handlers.KeyHandlers{Keys: keys}
+
+	authenticators := middleware.Authenticators{APIKeys: apiKeySvc, Sessions: sessionSvc, Users: authSvc}
+	requireAuth := middleware.RequireAuth(keys, authenticators)
+	optionalAuth := middleware.OptionalAuth(keys, authenticators)
+	requireRecentAuth := middleware.RequireRecentAuth(recentAuthWindow)
 
 	r := chi.NewRouter()
 	r.Use(chimw.RequestID)
@@ -39,21 +122,136 @@ func NewRouter(cfg config.Config, pool *pgxpool.Pool) http.Handler {
 	r.Get("/healthz", func(w http.ResponseWriter, r *http.Request) { 
 		w.WriteHeader(http.StatusOK) 
 	})
+	if keys.HasPublicKeys() {
+		r.Get("/.well-known/jwks.json", keyH.JWKS)
+	}
 
 	r.Route("/api", func(api chi.Router) {
+		api.Use(middleware.RequireCSRF)
+
 		api.Route("/auth", func(ar chi.Router) {
+			ar.Get("/csrf", authH.CSRFToken)
 			ar.Post("/login", authH.Login)
+			ar.Post("/login/2fa", authH.LoginTwoFactor)
 			ar.Post("/logout", authH.Logout)
+			ar.Post("/magic-link", authH.RequestMagicLink)
+			ar.Get("/magic-link/verify", authH.VerifyMagicLink)
+			ar.Post("/passkey/login/begin", authH.PasskeyLoginBegin)
+			ar.Post("/passkey/login/finish", authH.PasskeyLoginFinish)
 
+			ar.Get("/providers", authH.Providers)
+			ar.Get("/oidc/{provider}/start", authH.OIDCStart)
+			ar.Get("/oidc/{provider}/callback", authH.OIDCCallback)
 			ar.Get("/google/start", authH.GoogleStart)
 			ar.Get("/google/callback", authH.GoogleCallback)
+
+			ar.With(requireAuth, middleware.RequireSession).Post("/reauth", authH.Reauthenticate)
+			ar.With(requireAuth, middleware.RequireSession).Post("/reauth/oidc/{provider}", authH.ReauthenticateOIDCStart)
 		})
 
 		api.Get("/products/search", productH.Search)
 		api.Get("/categories", categoryH.List)
+		api.Get("/categories/tree", categoryH.Tree)
+		api.Get("/categories/{id}", categoryH.Get)
+		api.Get("/categories/{id}/attributes", attributeH.ForCategory)
+		api.Get("/brands", brandH.List)
+		api.Get("/brands/{id}", brandH.Get)
+
+		api.With(requireAuth).Get("/me", authH.Me)
+		api.With(requireAuth, middleware.RequireSession).Get("/me/export", authH.ExportAccount)
+		api.With(requireAuth, middleware.RequireSession, requireRecentAuth).Delete("/me", authH.DeleteAccount)
+		api.With(requireAuth, middleware.RequireSession).Post("/me/password", authH.ChangePassword)
+		api.With(requireAuth, middleware.RequireSession).Get("/me/activity", auditH.MyActivity)
+		api.Route("/me/sessions", func(sr chi.Router) {
+			sr.Use(requireAuth, middleware.RequireSession)
+			sr.Get("/", authH.ListSessions)
+			sr.Delete("/{id}", authH.RevokeSession)
+		})
+		api.Route("/me/2fa", func(tr chi.Router) {
+			tr.Use(requireAuth, middleware.RequireSession)
+			tr.Get("/", authH.TwoFactorStatus)
+			tr.Post("/enroll", authH.TwoFactorEnroll)
+			tr.Post("/confirm", authH.TwoFactorConfirm)
+			tr.Post("/disable", authH.TwoFactorDisable)
+		})
+		api.Route("/me/identities", func(ir chi.Router) {
+			ir.Use(requireAuth, middleware.RequireSession)
+			ir.Get("/", authH.ListIdentities)
+			ir.With(requireRecentAuth).Post("/{provider}/link", authH.LinkIdentityStart)
+			ir.With(requireRecentAuth).Delete("/{provider}", authH.UnlinkIdentity)
+		})
+		api.Route("/me/passkeys", func(pr chi.Router) {
+			pr.Use(requireAuth, middleware.RequireSession)
+			pr.Get("/", authH.ListPasskeys)
+			pr.With(requireRecentAuth).Post("/register/begin", authH.PasskeyRegisterBegin)
+			pr.With(requireRecentAuth).Post("/register/finish", authH.PasskeyRegisterFinish)
+			pr.With(requireRecentAuth).Delete("/{id}", authH.DeletePasskey)
+		})
+		api.Route("/me/api-keys", func(kr chi.Router) {
+			kr.Use(requireAuth, middleware.RequireSession)
+			kr.Get("/", apiKeyH.List)
+			kr.Post("/", apiKeyH.Create)
+			kr.Delete("/{id}", apiKeyH.Revoke)
+		})
+		api.With(optionalAuth).Get("/products/{id}", productH.GetByID)
 
-		api.With(middleware.RequireAuth(cfg)).Get("/me", authH.Me)
-		api.With(middleware.RequireAuth(cfg)).Get("/products/{id}", productH.GetByID)
+		api.Route("/admin", func(adm chi.Router) {
+			adm.Use(requireAuth)
+			adm.With(middleware.RequirePermission(accessSvc, domain.PermAuditRead)).Get("/auth-events", auditH.List)
+			adm.Route("/products", func(pr chi.Router) {
+				pr.Use(middleware.RequirePermission(accessSvc, domain.PermCatalogWrite))
+				pr.Post("/", productH.Create)
+				pr.Post("/import", importH.Import)
+				pr.Get("/export", productH.Export)
+				pr.Put("/{id}", productH.Replace)
+				pr.Patch("/{id}", productH.Patch)
+				pr.Delete("/{id}", productH.Delete)
+				pr.Put("/{id}/variants", productH.ReplaceVariants)
+				pr.Put("/{id}/prices/{currency}", productH.SetPrice)
+				pr.Delete("/{id}/prices/{currency}", productH.DeletePrice)
+				pr.Get("/{id}/inventory", inventoryH.Get)
+				pr.Put("/{id}/inventory/threshold", inventoryH.SetThreshold)
+				pr.Get("/{id}/inventory/adjustments", inventoryH.Adjustments)
+				pr.Post("/{id}/inventory/adjustments", inventoryH.Adjust)
+				pr.Post("/{id}/inventory/reservations", inventoryH.Reserve)
+			})
+			adm.Route("/inventory", func(ir chi.Router) {
+				ir.Use(middleware.RequirePermission(accessSvc, domain.PermCatalogWrite))
+				ir.Get("/low-stock", inventoryH.LowStock)
+				ir.Get("/warehouses", inventoryH.ListWarehouses)
+				ir.Post("/warehouses", inventoryH.CreateWarehouse)
+				ir.Post("/reservations/{id}/release", inventoryH.Release)
+			})
+			adm.Route("/categories", func(cr chi.Router) {
+				cr.Use(middleware.RequirePermission(accessSvc, domain.PermCatalogWrite))
+				cr.Post("/", categoryH.Create)
+				cr.Put("/{id}", categoryH.Update)
+				cr.Delete("/{id}", categoryH.Delete)
+				cr.Put("/{id}/attributes", attributeH.Replace)
+			})
+			adm.Route("/brands", func(br chi.Router) {
+				br.Use(middleware.RequirePermission(accessSvc, domain.PermCatalogWrite))
+				br.Post("/", brandH.Create)
+				br.Put("/{id}", brandH.Update)
+				br.Delete("/{id}", brandH.Delete)
+			})
+			adm.Route("/exchange-rates", func(er chi.Router) {
+				er.Use(middleware.RequirePermission(accessSvc, domain.PermCatalogWrite))
+				er.Get("/", exchangeRateH.List)
+				er.Put("/", exchangeRateH.Replace)
+				er.Put("/{base}/{quote}", exchangeRateH.Set)
+				er.Delete("/{base}/{quote}", exchangeRateH.Delete)
+			})
+			adm.Route("/users", func(ur chi.Router) {
+				ur.Use(middleware.RequirePermission(accessSvc, domain.PermUsersManage))
+				ur.Get("/", adminUserH.List)
+				ur.Get("/{id}", adminUserH.Get)
+				ur.Post("/{id}/disable", adminUserH.Disable)
+				ur.Post("/{id}/enable", adminUserH.Enable)
+				ur.Post("/{id}/password-reset", adminUserH.ForcePasswordReset)
+				ur.Delete("/{id}/sessions", adminUserH.RevokeSessions)
+			})
+		})
 	})
 	return r
 }
\ No newline at end of file
@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/soydoradesu/product_discovery/internal/domain"
+)
+
+type APIKeyRepository interface {
+	Create(ctx context.Context, key domain.APIKey, keyHash string) (domain.APIKey, error)
+	ListByUser(ctx context.Context, userID int64) ([]domain.APIKey, error)
+	GetByHash(ctx context.Context, keyHash string) (domain.APIKey, error)
+	// Revoke returns ErrNotFound unless the key belongs to userID and is still active.
+	Revoke(ctx context.Context, userID, id int64) error
+	TouchLastUsed(ctx context.Context, id int64, at time.Time) error
+}
@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/soydoradesu/product_discovery/internal/domain"
+)
+
+type AttributeRepository interface {
+	// List returns every definition, by category and then position.
+	List(ctx context.Context) ([]domain.AttributeDefinition, error)
+	// ForCategories returns the definitions of the categories and their
+	// ancestors, the nearest category's first.
+	ForCategories(ctx context.Context, categoryIDs []int64) ([]domain.AttributeDefinition, error)
+	// Replace sets a category's own definitions, keeping the ids of codes
+	// that stay. It returns ErrNotFound for an unknown category.
+	Replace(ctx context.Context, categoryID int64, defs []domain.AttributeDefinition) error
+}
@@ -0,0 +1,13 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/soydoradesu/product_discovery/internal/domain"
+)
+
+// AuthEventRepository is append-only: events are never updated or deleted.
+type AuthEventRepository interface {
+	Insert(ctx context.Context, e domain.AuthEvent) error
+	List(ctx context.Context, f domain.AuthEventFilter) ([]domain.AuthEvent, error)
+}
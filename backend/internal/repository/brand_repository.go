@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/soydoradesu/product_discovery/internal/domain"
+)
+
+type BrandRepository interface {
+	List(ctx context.Context) ([]domain.Brand, error)
+	GetByID(ctx context.Context, id int64) (domain.Brand, error)
+
+	// Create and Update return ErrConflict when the name or slug is taken.
+	Create(ctx context.Context, b domain.Brand) (domain.Brand, error)
+	Update(ctx context.Context, b domain.Brand) (domain.Brand, error)
+	// Delete returns ErrNotFound, or ErrConflict while products have the
+	// brand.
+	Delete(ctx context.Context, id int64) error
+}
@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/soydoradesu/product_discovery/internal/domain"
+)
+
+type CatalogImportRepository interface {
+	// Import upserts rows on their external key in one transaction and
+	// rolls it back when dryRun is set. Rows that would clash with another
+	// product's name and description are reported as errors; rows matching
+	// the stored product exactly count as skipped.
+	Import(ctx context.Context, rows []domain.ImportRow, dryRun bool) (domain.ImportSummary, error)
+}
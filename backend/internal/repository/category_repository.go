@@ -8,4 +8,17 @@ import (
 
 type CategoryRepository interface {
 	List(ctx context.Context) ([]domain.Category, error)
-}
\ No newline at end of file
+	GetByID(ctx context.Context, id int64) (domain.Category, error)
+	// ProductCounts maps each category id to the number of distinct products
+	// in it or any of its descendants.
+	ProductCounts(ctx context.Context) (map[int64]int64, error)
+
+	// Create and Update return ErrConflict when the name or slug is taken and
+	// ErrInvalidReference when the parent does not exist or, for Update, is
+	// the category itself or one of its descendants.
+	Create(ctx context.Context, c domain.Category) (domain.Category, error)
+	Update(ctx context.Context, c domain.Category) (domain.Category, error)
+	// Delete returns ErrNotFound, or ErrConflict while the category still has
+	// children. Product links to it are removed.
+	Delete(ctx context.Context, id int64) error
+}
@@ -1,7 +1,16 @@
 package repository
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var (
 	ErrNotFound = errors.New("not found")
+	ErrConflict = errors.New("conflict")
+	// ErrInvalidReference means a write pointed at a row that does not exist.
+	ErrInvalidReference = errors.New("invalid reference")
+	// ErrStockRemaining wraps ErrConflict when a write would drop an item that
+	// still has stock on hand or active reservations.
+	ErrStockRemaining = fmt.Errorf("stock remaining: %w", ErrConflict)
 )
\ No newline at end of file
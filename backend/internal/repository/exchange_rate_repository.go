@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/soydoradesu/product_discovery/internal/domain"
+)
+
+type ExchangeRateRepository interface {
+	List(ctx context.Context) ([]domain.ExchangeRate, error)
+	// Replace swaps the whole table for rates in one transaction.
+	Replace(ctx context.Context, rates []domain.ExchangeRate) error
+	Upsert(ctx context.Context, rate domain.ExchangeRate) (domain.ExchangeRate, error)
+	// Delete returns ErrNotFound when the pair is not listed.
+	Delete(ctx context.Context, base, quote string) error
+}
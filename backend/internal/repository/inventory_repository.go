@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/soydoradesu/product_discovery/internal/domain"
+)
+
+// InventoryRepository keeps stock levels. Every write re-derives the in_stock
+// of the product and its variants from what is available.
+type InventoryRepository interface {
+	ListWarehouses(ctx context.Context) ([]domain.Warehouse, error)
+	// CreateWarehouse returns ErrConflict when the code is taken.
+	CreateWarehouse(ctx context.Context, w domain.Warehouse) (domain.Warehouse, error)
+
+	// ProductStock returns ErrNotFound for an unknown product.
+	ProductStock(ctx context.Context, productID int64) (domain.ProductStock, error)
+	// SetLowStockThreshold returns ErrNotFound for an unknown product.
+	SetLowStockThreshold(ctx context.Context, productID int64, threshold int) error
+	LowStock(ctx context.Context, limit int) ([]domain.LowStockItem, error)
+
+	// Adjust changes on-hand stock and records the change, creating the
+	// level if needed. It returns ErrInvalidReference for an unknown
+	// warehouse, product or variant and ErrConflict when on-hand would drop
+	// below zero or below what is reserved.
+	Adjust(ctx context.Context, in domain.StockAdjustmentInput) (domain.StockAdjustment, error)
+	// Adjustments lists a product's adjustments newest first; beforeID
+	// continues from the last one of a previous page.
+	Adjustments(ctx context.Context, productID int64, beforeID int64, limit int) ([]domain.StockAdjustment, error)
+
+	// Reserve locks the item's levels while it picks one, so concurrent
+	// reservations never hold more than is on hand. It returns ErrConflict
+	// when no single warehouse has enough available and ErrInvalidReference
+	// for an unknown warehouse.
+	Reserve(ctx context.Context, in domain.ReservationInput) (domain.Reservation, error)
+	// Release gives a reservation's quantity back. It returns ErrNotFound for
+	// an unknown reservation and ErrConflict when it was already released.
+	Release(ctx context.Context, id int64) (domain.Reservation, error)
+}
@@ -0,0 +1,14 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+type MagicLinkRepository interface {
+	Create(ctx context.Context, id, email string, expiresAt time.Time) error
+	// Consume marks the link used and returns its email. It returns
+	// ErrNotFound if the link doesn't exist, was already used or has expired.
+	Consume(ctx context.Context, id string) (string, error)
+	CountSince(ctx context.Context, email string, since time.Time) (int, error)
+}
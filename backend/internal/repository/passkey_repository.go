@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/soydoradesu/product_discovery/internal/domain"
+)
+
+type PasskeyRepository interface {
+	// Create returns ErrConflict if the credential is already registered.
+	Create(ctx context.Context, p domain.Passkey) (domain.Passkey, error)
+	ListByUser(ctx context.Context, userID int64) ([]domain.Passkey, error)
+	GetByCredentialID(ctx context.Context, credentialID []byte) (domain.Passkey, error)
+	RecordUse(ctx context.Context, id int64, signCount uint32, at time.Time) error
+	// Delete returns ErrNotFound if the user has no such passkey and
+	// ErrConflict if it is the user's last way to log in.
+	Delete(ctx context.Context, userID, id int64) error
+}
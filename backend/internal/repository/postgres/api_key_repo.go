@@ -0,0 +1,107 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/soydoradesu/product_discovery/internal/domain"
+	"github.com/soydoradesu/product_discovery/internal/repository"
+)
+
+type APIKeyRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewAPIKeyRepo(pool *pgxpool.Pool) repository.APIKeyRepository {
+	return &APIKeyRepo{pool: pool}
+}
+
+const apiKeyColumns = `id, user_id, name, prefix, scopes, expires_at, last_used_at, revoked_at, created_at`
+
+func scanAPIKey(row pgx.Row) (domain.APIKey, error) {
+	var k domain.APIKey
+	err := row.Scan(&k.ID, &k.UserID, &k.Name, &k.Prefix, &k.Scopes, &k.ExpiresAt, &k.LastUsedAt, &k.RevokedAt, &k.CreatedAt)
+	return k, err
+}
+
+func (r *APIKeyRepo) Create(ctx context.Context, key domain.APIKey, keyHash string) (domain.APIKey, error) {
+	if key.Scopes == nil {
+		key.Scopes = []string{}
+	}
+	return scanAPIKey(r.pool.QueryRow(ctx, `
+		INSERT INTO api_keys(user_id, name, prefix, key_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING `+apiKeyColumns,
+		key.UserID, key.Name, key.Prefix, keyHash, key.Scopes, key.ExpiresAt))
+}
+
+func (r *APIKeyRepo) ListByUser(ctx context.Context, userID int64) ([]domain.APIKey, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+apiKeyColumns+`
+		FROM api_keys
+		WHERE user_id = $1
+		ORDER BY created_at DESC, id DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []domain.APIKey{}
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, k)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	return out, nil
+}
+
+func (r *APIKeyRepo) GetByHash(ctx context.Context, keyHash string) (domain.APIKey, error) {
+	k, err := scanAPIKey(r.pool.QueryRow(ctx, `
+		SELECT `+apiKeyColumns+`
+		FROM api_keys
+		WHERE key_hash = $1
+	`, keyHash))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.APIKey{}, repository.ErrNotFound
+	}
+	if err != nil {
+		return domain.APIKey{}, err
+	}
+	return k, nil
+}
+
+func (r *APIKeyRepo) Revoke(ctx context.Context, userID, id int64) error {
+	ct, err := r.pool.Exec(ctx, `
+		UPDATE api_keys
+		SET revoked_at = now()
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+	`, id, userID)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+// TouchLastUsed only writes when the stored value is more than a minute old,
+// so a busy script doesn't turn every request into an UPDATE.
+func (r *APIKeyRepo) TouchLastUsed(ctx context.Context, id int64, at time.Time) error {
+	_, err := r.pool.Exec(ctx, `
+		UPDATE api_keys
+		SET last_used_at = $2
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < $2::timestamptz - interval '1 minute')
+	`, id, at)
+	return err
+}
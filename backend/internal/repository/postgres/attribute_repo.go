@@ -0,0 +1,105 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/soydoradesu/product_discovery/internal/domain"
+	"github.com/soydoradesu/product_discovery/internal/repository"
+)
+
+type AttributeRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewAttributeRepo(pool *pgxpool.Pool) repository.AttributeRepository {
+	return &AttributeRepo{pool: pool}
+}
+
+const attributeColumns = `a.id, a.category_id, a.code, a.label, a.type, a.unit, a.enum_values`
+
+func scanAttribute(row pgx.Row) (domain.AttributeDefinition, error) {
+	var d domain.AttributeDefinition
+	err := row.Scan(&d.ID, &d.CategoryID, &d.Code, &d.Label, &d.Type, &d.Unit, &d.Values)
+	if len(d.Values) == 0 {
+		d.Values = nil
+	}
+	return d, err
+}
+
+func (r *AttributeRepo) List(ctx context.Context) ([]domain.AttributeDefinition, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+attributeColumns+`
+		FROM category_attributes a
+		ORDER BY a.category_id ASC, a.position ASC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.AttributeDefinition, error) { return scanAttribute(row) })
+}
+
+func (r *AttributeRepo) ForCategories(ctx context.Context, categoryIDs []int64) ([]domain.AttributeDefinition, error) {
+	rows, err := r.pool.Query(ctx, `
+		WITH RECURSIVE up AS (
+			SELECT id, parent_id, 0 AS depth FROM categories WHERE id = ANY($1::bigint[])
+			UNION ALL
+			SELECT c.id, c.parent_id, up.depth + 1 FROM categories c JOIN up ON c.id = up.parent_id
+		)
+		SELECT `+attributeColumns+`
+		FROM category_attributes a
+		JOIN (SELECT id, MIN(depth) AS depth FROM up GROUP BY id) u ON u.id = a.category_id
+		ORDER BY u.depth ASC, a.category_id ASC, a.position ASC
+	`, categoryIDs)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.AttributeDefinition, error) { return scanAttribute(row) })
+}
+
+func (r *AttributeRepo) Replace(ctx context.Context, categoryID int64, defs []domain.AttributeDefinition) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var id int64
+	err = tx.QueryRow(ctx, `SELECT id FROM categories WHERE id = $1 FOR UPDATE`, categoryID).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return repository.ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	codes := make([]string, len(defs))
+	for i, d := range defs {
+		codes[i] = d.Code
+	}
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM category_attributes
+		WHERE category_id = $1 AND NOT (code = ANY($2::text[]))
+	`, categoryID, codes); err != nil {
+		return err
+	}
+	for i, d := range defs {
+		values := d.Values
+		if values == nil {
+			values = []string{}
+		}
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO category_attributes(category_id, code, label, type, unit, enum_values, position)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			ON CONFLICT ON CONSTRAINT category_attributes_code_key DO UPDATE SET
+				label = EXCLUDED.label, type = EXCLUDED.type, unit = EXCLUDED.unit,
+				enum_values = EXCLUDED.enum_values, position = EXCLUDED.position
+		`, categoryID, d.Code, d.Label, d.Type, d.Unit, values, i); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,78 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/soydoradesu/product_discovery/internal/domain"
+	"github.com/soydoradesu/product_discovery/internal/repository"
+)
+
+type AuthEventRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewAuthEventRepo(pool *pgxpool.Pool) repository.AuthEventRepository {
+	return &AuthEventRepo{pool: pool}
+}
+
+const authEventColumns = `id, user_id, email, type, reason, provider, ip, user_agent, request_id, created_at`
+
+func scanAuthEvent(row pgx.Row) (domain.AuthEvent, error) {
+	var e domain.AuthEvent
+	err := row.Scan(&e.ID, &e.UserID, &e.Email, &e.Type, &e.Reason, &e.Provider, &e.IP, &e.UserAgent, &e.RequestID, &e.CreatedAt)
+	return e, err
+}
+
+func (r *AuthEventRepo) Insert(ctx context.Context, e domain.AuthEvent) error {
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO auth_events(user_id, email, type, reason, provider, ip, user_agent, request_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`, e.UserID, e.Email, e.Type, e.Reason, e.Provider, e.IP, e.UserAgent, e.RequestID)
+	return err
+}
+
+func (r *AuthEventRepo) List(ctx context.Context, f domain.AuthEventFilter) ([]domain.AuthEvent, error) {
+	args := []any{}
+	where := "WHERE TRUE"
+	add := func(cond string, v any) {
+		args = append(args, v)
+		where += fmt.Sprintf(" AND "+cond, len(args))
+	}
+	if f.UserID != nil {
+		add("user_id = $%d", *f.UserID)
+	}
+	if f.Email != "" {
+		add("email = $%d", f.Email)
+	}
+	if f.Type != "" {
+		add("type = $%d", f.Type)
+	}
+	if f.IP != "" {
+		add("ip = $%d", f.IP)
+	}
+	if f.Since != nil {
+		add("created_at >= $%d", *f.Since)
+	}
+	if f.Until != nil {
+		add("created_at < $%d", *f.Until)
+	}
+	if f.BeforeID > 0 {
+		add("id < $%d", f.BeforeID)
+	}
+	args = append(args, f.Limit)
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+authEventColumns+`
+		FROM auth_events
+		`+where+`
+		ORDER BY id DESC
+		LIMIT $`+fmt.Sprint(len(args)), args...)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.AuthEvent, error) { return scanAuthEvent(row) })
+}
@@ -0,0 +1,98 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/soydoradesu/product_discovery/internal/domain"
+	"github.com/soydoradesu/product_discovery/internal/repository"
+)
+
+type BrandRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewBrandRepo(pool *pgxpool.Pool) repository.BrandRepository {
+	return &BrandRepo{pool: pool}
+}
+
+const brandColumns = `id, name, slug`
+
+func scanBrand(row pgx.Row) (domain.Brand, error) {
+	var b domain.Brand
+	err := row.Scan(&b.ID, &b.Name, &b.Slug)
+	return b, err
+}
+
+func (r *BrandRepo) List(ctx context.Context) ([]domain.Brand, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+brandColumns+`
+		FROM brands
+		ORDER BY lower(name) ASC, id ASC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.Brand, error) { return scanBrand(row) })
+}
+
+func (r *BrandRepo) GetByID(ctx context.Context, id int64) (domain.Brand, error) {
+	b, err := scanBrand(r.pool.QueryRow(ctx, `
+		SELECT `+brandColumns+`
+		FROM brands
+		WHERE id = $1
+	`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.Brand{}, repository.ErrNotFound
+	}
+	return b, err
+}
+
+func (r *BrandRepo) Create(ctx context.Context, b domain.Brand) (domain.Brand, error) {
+	// an empty slug is filled in by the brand_default_slug trigger
+	created, err := scanBrand(r.pool.QueryRow(ctx, `
+		INSERT INTO brands(name, slug)
+		VALUES ($1, NULLIF($2, ''))
+		RETURNING `+brandColumns,
+		b.Name, b.Slug))
+	if isUniqueViolation(err) {
+		return domain.Brand{}, repository.ErrConflict
+	}
+	return created, err
+}
+
+func (r *BrandRepo) Update(ctx context.Context, b domain.Brand) (domain.Brand, error) {
+	// an empty slug keeps the current one; products pick up a new name
+	// through the brand_rename_products trigger
+	updated, err := scanBrand(r.pool.QueryRow(ctx, `
+		UPDATE brands
+		SET name = $2, slug = COALESCE(NULLIF($3, ''), slug)
+		WHERE id = $1
+		RETURNING `+brandColumns,
+		b.ID, b.Name, b.Slug))
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return domain.Brand{}, repository.ErrNotFound
+	case isUniqueViolation(err):
+		return domain.Brand{}, repository.ErrConflict
+	}
+	return updated, err
+}
+
+func (r *BrandRepo) Delete(ctx context.Context, id int64) error {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM brands WHERE id = $1`, id)
+	if isForeignKeyViolation(err) {
+		// products reference it through brand_id (ON DELETE RESTRICT)
+		return repository.ErrConflict
+	}
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
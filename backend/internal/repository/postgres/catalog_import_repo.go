@@ -0,0 +1,244 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/soydoradesu/product_discovery/internal/domain"
+	"github.com/soydoradesu/product_discovery/internal/repository"
+)
+
+type CatalogImportRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewCatalogImportRepo(pool *pgxpool.Pool) repository.CatalogImportRepository {
+	return &CatalogImportRepo{pool: pool}
+}
+
+// Import copies the rows into temporary staging tables and merges them into
+// the catalog with set-based statements, so large files cost a handful of
+// round trips rather than several per row.
+func (r *CatalogImportRepo) Import(ctx context.Context, rows []domain.ImportRow, dryRun bool) (domain.ImportSummary, error) {
+	summary := domain.ImportSummary{DryRun: dryRun}
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return summary, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `
+		CREATE TEMP TABLE import_products (
+			line INT NOT NULL,
+			external_key TEXT PRIMARY KEY,
+			name TEXT NOT NULL,
+			price_minor BIGINT NOT NULL,
+			currency TEXT NOT NULL,
+			description TEXT NOT NULL,
+			rating DOUBLE PRECISION NOT NULL,
+			in_stock BOOLEAN NOT NULL
+		) ON COMMIT DROP;
+		CREATE TEMP TABLE import_images (
+			external_key TEXT NOT NULL,
+			url TEXT NOT NULL,
+			position INT NOT NULL
+		) ON COMMIT DROP;
+		CREATE TEMP TABLE import_categories (
+			external_key TEXT NOT NULL,
+			name TEXT NOT NULL
+		) ON COMMIT DROP;
+	`); err != nil {
+		return summary, err
+	}
+
+	var products, images, categories [][]any
+	for _, row := range rows {
+		p := row.Product
+		products = append(products, []any{row.Line, row.ExternalKey, p.Name, p.Price.Amount, p.Price.Currency, p.Description, p.Rating, p.InStock})
+		for _, img := range p.Images {
+			images = append(images, []any{row.ExternalKey, img.URL, img.Position})
+		}
+		for _, name := range row.Categories {
+			categories = append(categories, []any{row.ExternalKey, name})
+		}
+	}
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"import_products"},
+		[]string{"line", "external_key", "name", "price_minor", "currency", "description", "rating", "in_stock"},
+		pgx.CopyFromRows(products)); err != nil {
+		return summary, err
+	}
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"import_images"},
+		[]string{"external_key", "url", "position"}, pgx.CopyFromRows(images)); err != nil {
+		return summary, err
+	}
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"import_categories"},
+		[]string{"external_key", "name"}, pgx.CopyFromRows(categories)); err != nil {
+		return summary, err
+	}
+
+	// another product already holds this name and description
+	conflicts, err := tx.Query(ctx, `
+		DELETE FROM import_products s
+		USING products p
+		WHERE p.name = s.name
+		AND p.description = s.description
+		AND p.external_key IS DISTINCT FROM s.external_key
+		RETURNING s.line
+	`)
+	if err != nil {
+		return summary, err
+	}
+	lines, err := pgx.CollectRows(conflicts, pgx.RowTo[int32])
+	if err != nil {
+		return summary, err
+	}
+	for _, line := range lines {
+		summary.Errors = append(summary.Errors, domain.ImportRowError{
+			Line:    int(line),
+			Field:   "name",
+			Message: "another product has the same name and description",
+		})
+	}
+
+	// variant prices are in the product's currency, so it stays fixed while
+	// there are variants
+	currencyChanges, err := tx.Query(ctx, `
+		DELETE FROM import_products s
+		USING products p
+		WHERE p.external_key = s.external_key
+		AND p.currency <> s.currency
+		AND EXISTS (SELECT 1 FROM product_variants v WHERE v.product_id = p.id)
+		RETURNING s.line
+	`)
+	if err != nil {
+		return summary, err
+	}
+	lines, err = pgx.CollectRows(currencyChanges, pgx.RowTo[int32])
+	if err != nil {
+		return summary, err
+	}
+	for _, line := range lines {
+		summary.Errors = append(summary.Errors, domain.ImportRowError{
+			Line:    int(line),
+			Field:   "currency",
+			Message: "cannot change while the product has variants",
+		})
+	}
+
+	// the file's price is not used for products with variants, nor its
+	// inStock for products with variants or tracked stock, so neither makes
+	// such a row differ
+	tag, err := tx.Exec(ctx, `
+		DELETE FROM import_products s
+		USING products p
+		WHERE p.external_key = s.external_key
+		AND p.name = s.name
+		AND (p.price_minor = s.price_minor
+			OR EXISTS (SELECT 1 FROM product_variants v WHERE v.product_id = p.id))
+		AND p.currency = s.currency
+		AND p.description = s.description
+		AND p.rating = s.rating
+		AND (p.in_stock = s.in_stock
+			OR EXISTS (SELECT 1 FROM product_variants v WHERE v.product_id = p.id)
+			OR EXISTS (SELECT 1 FROM inventory_levels l WHERE l.product_id = p.id))
+		AND ARRAY(SELECT url FROM product_images WHERE product_id = p.id ORDER BY position)
+			= ARRAY(SELECT url FROM import_images i WHERE i.external_key = s.external_key ORDER BY position)
+		AND ARRAY(
+			SELECT c.name
+			FROM product_categories pc
+			JOIN categories c ON c.id = pc.category_id
+			WHERE pc.product_id = p.id
+			ORDER BY c.name
+		) = ARRAY(SELECT name FROM import_categories ic WHERE ic.external_key = s.external_key ORDER BY name)
+	`)
+	if err != nil {
+		return summary, err
+	}
+	summary.Skipped = int(tag.RowsAffected())
+
+	upserted, err := tx.Query(ctx, `
+		INSERT INTO products(external_key, name, price_minor, currency, description, rating, in_stock)
+		SELECT external_key, name, price_minor, currency, description, rating, in_stock
+		FROM import_products
+		ON CONFLICT (external_key) DO UPDATE SET
+			name = EXCLUDED.name,
+			price_minor = EXCLUDED.price_minor,
+			currency = EXCLUDED.currency,
+			description = EXCLUDED.description,
+			rating = EXCLUDED.rating,
+			in_stock = EXCLUDED.in_stock
+		RETURNING id, xmax = 0
+	`)
+	if err != nil {
+		return summary, err
+	}
+	var ids []int64
+	for upserted.Next() {
+		var (
+			id      int64
+			created bool
+		)
+		if err := upserted.Scan(&id, &created); err != nil {
+			upserted.Close()
+			return summary, err
+		}
+		ids = append(ids, id)
+		if created {
+			summary.Created++
+		} else {
+			summary.Updated++
+		}
+	}
+	if err := upserted.Err(); err != nil {
+		return summary, productWriteError(err)
+	}
+
+	// the file is the source of truth for images and categories
+	for _, stmt := range []string{
+		`DELETE FROM product_images pi
+		USING products p, import_products s
+		WHERE pi.product_id = p.id AND p.external_key = s.external_key`,
+		`DELETE FROM product_categories pc
+		USING products p, import_products s
+		WHERE pc.product_id = p.id AND p.external_key = s.external_key`,
+		`INSERT INTO product_images(product_id, url, position)
+		SELECT p.id, i.url, i.position
+		FROM import_images i
+		JOIN import_products s ON s.external_key = i.external_key
+		JOIN products p ON p.external_key = i.external_key`,
+		`INSERT INTO categories(name)
+		SELECT DISTINCT ic.name
+		FROM import_categories ic
+		JOIN import_products s ON s.external_key = ic.external_key
+		ON CONFLICT (name) DO NOTHING`,
+		`INSERT INTO product_categories(product_id, category_id)
+		SELECT p.id, c.id
+		FROM import_categories ic
+		JOIN import_products s ON s.external_key = ic.external_key
+		JOIN products p ON p.external_key = ic.external_key
+		JOIN categories c ON c.name = ic.name
+		ON CONFLICT DO NOTHING`,
+		// a price list entry in the product's own currency would never be
+		// used
+		`DELETE FROM product_prices pp
+		USING products p, import_products s
+		WHERE pp.product_id = p.id AND p.external_key = s.external_key AND pp.currency = p.currency`,
+	} {
+		if _, err := tx.Exec(ctx, stmt); err != nil {
+			return summary, err
+		}
+	}
+	// the file's price and stock only stand for products without variants
+	// or tracked stock
+	if err := refreshProductRollups(ctx, tx, ids); err != nil {
+		return summary, err
+	}
+
+	if dryRun {
+		return summary, nil
+	}
+	return summary, tx.Commit(ctx)
+}
@@ -2,7 +2,9 @@ package postgres
 
 import (
 	"context"
+	"errors"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/soydoradesu/product_discovery/internal/domain"
 	"github.com/soydoradesu/product_discovery/internal/repository"
@@ -16,28 +18,147 @@ func NewCategoryRepo(pool *pgxpool.Pool) repository.CategoryRepository {
 	return &CategoryRepo{pool: pool}
 }
 
+const categoryColumns = `id, name, parent_id, slug, sort_order, description`
+
+func scanCategory(row pgx.Row) (domain.Category, error) {
+	var c domain.Category
+	err := row.Scan(&c.ID, &c.Name, &c.ParentID, &c.Slug, &c.SortOrder, &c.Description)
+	return c, err
+}
+
 func (r *CategoryRepo) List(ctx context.Context) ([]domain.Category, error) {
 	rows, err := r.pool.Query(ctx, `
-		SELECT id, name
+		SELECT `+categoryColumns+`
 		FROM categories
 		ORDER BY name ASC, id ASC
 	`)
 	if err != nil {
 		return nil, err
 	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.Category, error) { return scanCategory(row) })
+}
+
+func (r *CategoryRepo) GetByID(ctx context.Context, id int64) (domain.Category, error) {
+	c, err := scanCategory(r.pool.QueryRow(ctx, `
+		SELECT `+categoryColumns+`
+		FROM categories
+		WHERE id = $1
+	`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.Category{}, repository.ErrNotFound
+	}
+	return c, err
+}
+
+func (r *CategoryRepo) ProductCounts(ctx context.Context) (map[int64]int64, error) {
+	rows, err := r.pool.Query(ctx, `
+		WITH RECURSIVE subtree AS (
+			SELECT id AS root, id FROM categories
+			UNION
+			SELECT s.root, c.id
+			FROM subtree s
+			JOIN categories c ON c.parent_id = s.id
+		)
+		SELECT s.root, COUNT(DISTINCT pc.product_id)
+		FROM subtree s
+		LEFT JOIN product_categories pc ON pc.category_id = s.id
+		GROUP BY s.root
+	`)
+	if err != nil {
+		return nil, err
+	}
 	defer rows.Close()
 
-	var out []domain.Category
+	counts := map[int64]int64{}
 	for rows.Next() {
-		var c domain.Category
-		if err := rows.Scan(&c.ID, &c.Name); err != nil {
+		var id, n int64
+		if err := rows.Scan(&id, &n); err != nil {
 			return nil, err
 		}
-		out = append(out, c)
+		counts[id] = n
 	}
-	if rows.Err() != nil {
-		return nil, rows.Err()
+	return counts, rows.Err()
+}
+
+func (r *CategoryRepo) Create(ctx context.Context, c domain.Category) (domain.Category, error) {
+	// an empty slug is filled in by the category_default_slug trigger
+	created, err := scanCategory(r.pool.QueryRow(ctx, `
+		INSERT INTO categories(name, slug, parent_id, sort_order, description)
+		VALUES ($1, NULLIF($2, ''), $3, $4, $5)
+		RETURNING `+categoryColumns,
+		c.Name, c.Slug, c.ParentID, c.SortOrder, c.Description))
+	if err != nil {
+		return domain.Category{}, categoryWriteError(err)
 	}
+	return created, nil
+}
 
-	return out, nil
-}
\ No newline at end of file
+func (r *CategoryRepo) Update(ctx context.Context, c domain.Category) (domain.Category, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return domain.Category{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	// serialize tree edits so two moves cannot form a cycle between them
+	if _, err := tx.Exec(ctx, `LOCK TABLE categories IN SHARE ROW EXCLUSIVE MODE`); err != nil {
+		return domain.Category{}, err
+	}
+	if c.ParentID != nil {
+		var cycle bool
+		err := tx.QueryRow(ctx, `
+			WITH RECURSIVE subtree AS (
+				SELECT id FROM categories WHERE id = $1
+				UNION
+				SELECT c.id FROM categories c JOIN subtree s ON c.parent_id = s.id
+			)
+			SELECT EXISTS (SELECT 1 FROM subtree WHERE id = $2)
+		`, c.ID, *c.ParentID).Scan(&cycle)
+		if err != nil {
+			return domain.Category{}, err
+		}
+		if cycle {
+			return domain.Category{}, repository.ErrInvalidReference
+		}
+	}
+
+	// an empty slug keeps the current one
+	updated, err := scanCategory(tx.QueryRow(ctx, `
+		UPDATE categories
+		SET name = $2, slug = COALESCE(NULLIF($3, ''), slug), parent_id = $4, sort_order = $5, description = $6
+		WHERE id = $1
+		RETURNING `+categoryColumns,
+		c.ID, c.Name, c.Slug, c.ParentID, c.SortOrder, c.Description))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.Category{}, repository.ErrNotFound
+	}
+	if err != nil {
+		return domain.Category{}, categoryWriteError(err)
+	}
+	return updated, tx.Commit(ctx)
+}
+
+func (r *CategoryRepo) Delete(ctx context.Context, id int64) error {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM categories WHERE id = $1`, id)
+	if isForeignKeyViolation(err) {
+		// children reference it through parent_id (ON DELETE RESTRICT)
+		return repository.ErrConflict
+	}
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+func categoryWriteError(err error) error {
+	switch {
+	case isUniqueViolation(err):
+		return repository.ErrConflict
+	case isForeignKeyViolation(err):
+		return repository.ErrInvalidReference
+	}
+	return err
+}
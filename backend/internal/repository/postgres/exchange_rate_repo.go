@@ -0,0 +1,83 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/soydoradesu/product_discovery/internal/domain"
+	"github.com/soydoradesu/product_discovery/internal/repository"
+)
+
+type ExchangeRateRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewExchangeRateRepo(pool *pgxpool.Pool) repository.ExchangeRateRepository {
+	return &ExchangeRateRepo{pool: pool}
+}
+
+// rates are read as text so they keep every decimal
+const exchangeRateColumns = `base, quote, rate::text, updated_at`
+
+func scanExchangeRate(row pgx.Row) (domain.ExchangeRate, error) {
+	var rate domain.ExchangeRate
+	err := row.Scan(&rate.Base, &rate.Quote, &rate.Rate, &rate.UpdatedAt)
+	return rate, err
+}
+
+func (r *ExchangeRateRepo) List(ctx context.Context) ([]domain.ExchangeRate, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+exchangeRateColumns+`
+		FROM exchange_rates
+		ORDER BY base ASC, quote ASC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.ExchangeRate, error) { return scanExchangeRate(row) })
+}
+
+func (r *ExchangeRateRepo) Replace(ctx context.Context, rates []domain.ExchangeRate) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM exchange_rates`); err != nil {
+		return err
+	}
+	for _, rate := range rates {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO exchange_rates(base, quote, rate)
+			VALUES ($1, $2, $3::numeric)
+		`, rate.Base, rate.Quote, rate.Rate); err != nil {
+			if isUniqueViolation(err) {
+				return repository.ErrConflict
+			}
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *ExchangeRateRepo) Upsert(ctx context.Context, rate domain.ExchangeRate) (domain.ExchangeRate, error) {
+	return scanExchangeRate(r.pool.QueryRow(ctx, `
+		INSERT INTO exchange_rates(base, quote, rate)
+		VALUES ($1, $2, $3::numeric)
+		ON CONFLICT (base, quote) DO UPDATE SET rate = EXCLUDED.rate, updated_at = now()
+		RETURNING `+exchangeRateColumns,
+		rate.Base, rate.Quote, rate.Rate))
+}
+
+func (r *ExchangeRateRepo) Delete(ctx context.Context, base, quote string) error {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM exchange_rates WHERE base = $1 AND quote = $2`, base, quote)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,370 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/soydoradesu/product_discovery/internal/domain"
+	"github.com/soydoradesu/product_discovery/internal/repository"
+)
+
+type InventoryRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewInventoryRepo(pool *pgxpool.Pool) repository.InventoryRepository {
+	return &InventoryRepo{pool: pool}
+}
+
+func (r *InventoryRepo) ListWarehouses(ctx context.Context) ([]domain.Warehouse, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT id, code, name, created_at
+		FROM warehouses
+		ORDER BY code ASC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.Warehouse, error) {
+		var w domain.Warehouse
+		err := row.Scan(&w.ID, &w.Code, &w.Name, &w.CreatedAt)
+		return w, err
+	})
+}
+
+func (r *InventoryRepo) CreateWarehouse(ctx context.Context, w domain.Warehouse) (domain.Warehouse, error) {
+	err := r.pool.QueryRow(ctx, `
+		INSERT INTO warehouses(code, name)
+		VALUES ($1, $2)
+		RETURNING id, created_at
+	`, w.Code, w.Name).Scan(&w.ID, &w.CreatedAt)
+	if isUniqueViolation(err) {
+		return domain.Warehouse{}, repository.ErrConflict
+	}
+	return w, err
+}
+
+func (r *InventoryRepo) ProductStock(ctx context.Context, productID int64) (domain.ProductStock, error) {
+	stock := domain.ProductStock{ProductID: productID}
+	err := r.pool.QueryRow(ctx, `
+		SELECT in_stock, low_stock_threshold
+		FROM products
+		WHERE id = $1
+	`, productID).Scan(&stock.InStock, &stock.LowStockThreshold)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.ProductStock{}, repository.ErrNotFound
+	}
+	if err != nil {
+		return domain.ProductStock{}, err
+	}
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT l.variant_id, w.code, l.on_hand, l.reserved, l.updated_at
+		FROM inventory_levels l
+		JOIN warehouses w ON w.id = l.warehouse_id
+		LEFT JOIN product_variants v ON v.id = l.variant_id
+		WHERE l.product_id = $1
+		ORDER BY v.position ASC NULLS FIRST, l.variant_id ASC NULLS FIRST, w.code ASC
+	`, productID)
+	if err != nil {
+		return domain.ProductStock{}, err
+	}
+	stock.Levels, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.StockLevel, error) {
+		var l domain.StockLevel
+		err := row.Scan(&l.VariantID, &l.Warehouse, &l.OnHand, &l.Reserved, &l.UpdatedAt)
+		l.Available = l.OnHand - l.Reserved
+		return l, err
+	})
+	return stock, err
+}
+
+func (r *InventoryRepo) SetLowStockThreshold(ctx context.Context, productID int64, threshold int) error {
+	tag, err := r.pool.Exec(ctx, `UPDATE products SET low_stock_threshold = $2 WHERE id = $1`, productID, threshold)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+func (r *InventoryRepo) LowStock(ctx context.Context, limit int) ([]domain.LowStockItem, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT l.product_id, p.name, l.variant_id, v.sku,
+			SUM(l.on_hand)::int, SUM(l.reserved)::int, p.low_stock_threshold
+		FROM inventory_levels l
+		JOIN products p ON p.id = l.product_id
+		LEFT JOIN product_variants v ON v.id = l.variant_id
+		GROUP BY l.product_id, p.name, l.variant_id, v.sku, p.low_stock_threshold
+		HAVING SUM(l.on_hand - l.reserved) <= p.low_stock_threshold
+		ORDER BY SUM(l.on_hand - l.reserved) ASC, l.product_id ASC, l.variant_id ASC NULLS FIRST
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.LowStockItem, error) {
+		var it domain.LowStockItem
+		err := row.Scan(&it.ProductID, &it.Name, &it.VariantID, &it.SKU, &it.OnHand, &it.Reserved, &it.LowStockThreshold)
+		it.Available = it.OnHand - it.Reserved
+		return it, err
+	})
+}
+
+func (r *InventoryRepo) Adjust(ctx context.Context, in domain.StockAdjustmentInput) (domain.StockAdjustment, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return domain.StockAdjustment{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockProduct(ctx, tx, in.ProductID); err != nil {
+		return domain.StockAdjustment{}, err
+	}
+	var warehouseID int64
+	err = tx.QueryRow(ctx, `SELECT id FROM warehouses WHERE code = $1`, in.Warehouse).Scan(&warehouseID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.StockAdjustment{}, repository.ErrInvalidReference
+	}
+	if err != nil {
+		return domain.StockAdjustment{}, err
+	}
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO inventory_levels(product_id, variant_id, warehouse_id)
+		VALUES ($1, $2, $3)
+		ON CONFLICT ON CONSTRAINT inventory_levels_item_key DO NOTHING
+	`, in.ProductID, in.VariantID, warehouseID); err != nil {
+		if isForeignKeyViolation(err) {
+			return domain.StockAdjustment{}, repository.ErrInvalidReference
+		}
+		return domain.StockAdjustment{}, err
+	}
+
+	var levelID int64
+	var onHand, reserved int
+	if err := tx.QueryRow(ctx, `
+		SELECT id, on_hand, reserved
+		FROM inventory_levels
+		WHERE product_id = $1 AND variant_id IS NOT DISTINCT FROM $2 AND warehouse_id = $3
+		FOR UPDATE
+	`, in.ProductID, in.VariantID, warehouseID).Scan(&levelID, &onHand, &reserved); err != nil {
+		return domain.StockAdjustment{}, err
+	}
+
+	after := onHand + in.Delta
+	if in.OnHand != nil {
+		after = *in.OnHand
+	}
+	if after < 0 || after < reserved {
+		return domain.StockAdjustment{}, repository.ErrConflict
+	}
+	if _, err := tx.Exec(ctx, `
+		UPDATE inventory_levels SET on_hand = $2, updated_at = now() WHERE id = $1
+	`, levelID, after); err != nil {
+		return domain.StockAdjustment{}, err
+	}
+
+	adj := domain.StockAdjustment{
+		ProductID:   in.ProductID,
+		VariantID:   in.VariantID,
+		Warehouse:   in.Warehouse,
+		Delta:       after - onHand,
+		OnHandAfter: after,
+		Reason:      in.Reason,
+		ActorUserID: in.ActorUserID,
+	}
+	if err := tx.QueryRow(ctx, `
+		INSERT INTO inventory_adjustments(product_id, variant_id, sku, warehouse_id, delta, on_hand_after, reason, actor_user_id)
+		VALUES ($1, $2, COALESCE((SELECT sku FROM product_variants WHERE id = $2), ''), $3, $4, $5, $6, $7)
+		RETURNING id, sku, created_at
+	`, adj.ProductID, adj.VariantID, warehouseID, adj.Delta, adj.OnHandAfter, adj.Reason, adj.ActorUserID).Scan(&adj.ID, &adj.SKU, &adj.CreatedAt); err != nil {
+		return domain.StockAdjustment{}, err
+	}
+
+	if err := refreshProductRollup(ctx, tx, in.ProductID); err != nil {
+		return domain.StockAdjustment{}, err
+	}
+	return adj, tx.Commit(ctx)
+}
+
+func (r *InventoryRepo) Adjustments(ctx context.Context, productID int64, beforeID int64, limit int) ([]domain.StockAdjustment, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT a.id, a.product_id, a.variant_id, a.sku, w.code, a.delta, a.on_hand_after, a.reason, a.actor_user_id, a.created_at
+		FROM inventory_adjustments a
+		JOIN warehouses w ON w.id = a.warehouse_id
+		WHERE a.product_id = $1 AND ($2 = 0 OR a.id < $2)
+		ORDER BY a.id DESC
+		LIMIT $3
+	`, productID, beforeID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.StockAdjustment, error) {
+		var a domain.StockAdjustment
+		err := row.Scan(&a.ID, &a.ProductID, &a.VariantID, &a.SKU, &a.Warehouse, &a.Delta, &a.OnHandAfter, &a.Reason, &a.ActorUserID, &a.CreatedAt)
+		return a, err
+	})
+}
+
+func (r *InventoryRepo) Reserve(ctx context.Context, in domain.ReservationInput) (domain.Reservation, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return domain.Reservation{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockProduct(ctx, tx, in.ProductID); err != nil {
+		return domain.Reservation{}, err
+	}
+	// Every candidate level is locked, in id order so concurrent reservations
+	// cannot deadlock, before one is picked; a competing reservation waits
+	// here and then sees what this one left.
+	rows, err := tx.Query(ctx, `
+		SELECT l.id, w.code, l.on_hand - l.reserved
+		FROM inventory_levels l
+		JOIN warehouses w ON w.id = l.warehouse_id
+		WHERE l.product_id = $1 AND l.variant_id IS NOT DISTINCT FROM $2 AND ($3 = '' OR w.code = $3)
+		ORDER BY l.id ASC
+		FOR UPDATE OF l
+	`, in.ProductID, in.VariantID, in.Warehouse)
+	if err != nil {
+		return domain.Reservation{}, err
+	}
+	type candidate struct {
+		levelID   int64
+		warehouse string
+		available int
+	}
+	candidates, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (candidate, error) {
+		var c candidate
+		err := row.Scan(&c.levelID, &c.warehouse, &c.available)
+		return c, err
+	})
+	if err != nil {
+		return domain.Reservation{}, err
+	}
+
+	var pick *candidate
+	for i, c := range candidates {
+		if c.available >= in.Quantity && (pick == nil || c.available > pick.available) {
+			pick = &candidates[i]
+		}
+	}
+	if pick == nil {
+		if in.Warehouse != "" && len(candidates) == 0 {
+			var known bool
+			if err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM warehouses WHERE code = $1)`, in.Warehouse).Scan(&known); err != nil {
+				return domain.Reservation{}, err
+			}
+			if !known {
+				return domain.Reservation{}, repository.ErrInvalidReference
+			}
+		}
+		return domain.Reservation{}, repository.ErrConflict
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE inventory_levels SET reserved = reserved + $2, updated_at = now() WHERE id = $1
+	`, pick.levelID, in.Quantity); err != nil {
+		return domain.Reservation{}, err
+	}
+	res := domain.Reservation{
+		ProductID: in.ProductID,
+		VariantID: in.VariantID,
+		Warehouse: pick.warehouse,
+		Quantity:  in.Quantity,
+		Reference: in.Reference,
+		Status:    domain.ReservationActive,
+	}
+	if err := tx.QueryRow(ctx, `
+		INSERT INTO inventory_reservations(level_id, quantity, reference)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at
+	`, pick.levelID, in.Quantity, in.Reference).Scan(&res.ID, &res.CreatedAt); err != nil {
+		return domain.Reservation{}, err
+	}
+
+	if err := refreshProductRollup(ctx, tx, in.ProductID); err != nil {
+		return domain.Reservation{}, err
+	}
+	return res, tx.Commit(ctx)
+}
+
+func (r *InventoryRepo) Release(ctx context.Context, id int64) (domain.Reservation, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return domain.Reservation{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	// a reservation's level never moves to another product, so the product
+	// can be looked up before it is locked
+	var productID int64
+	err = tx.QueryRow(ctx, `
+		SELECT l.product_id
+		FROM inventory_reservations rv
+		JOIN inventory_levels l ON l.id = rv.level_id
+		WHERE rv.id = $1
+	`, id).Scan(&productID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.Reservation{}, repository.ErrNotFound
+	}
+	if err != nil {
+		return domain.Reservation{}, err
+	}
+	if err := lockProduct(ctx, tx, productID); err != nil {
+		return domain.Reservation{}, err
+	}
+
+	// the reservation row is locked next so two releases cannot both give
+	// the quantity back
+	var res domain.Reservation
+	var levelID int64
+	err = tx.QueryRow(ctx, `
+		SELECT rv.id, rv.level_id, l.product_id, l.variant_id, w.code, rv.quantity, rv.reference, rv.status, rv.created_at, rv.released_at
+		FROM inventory_reservations rv
+		JOIN inventory_levels l ON l.id = rv.level_id
+		JOIN warehouses w ON w.id = l.warehouse_id
+		WHERE rv.id = $1
+		FOR UPDATE OF rv
+	`, id).Scan(&res.ID, &levelID, &res.ProductID, &res.VariantID, &res.Warehouse, &res.Quantity, &res.Reference, &res.Status, &res.CreatedAt, &res.ReleasedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.Reservation{}, repository.ErrNotFound
+	}
+	if err != nil {
+		return domain.Reservation{}, err
+	}
+	if res.Status != domain.ReservationActive {
+		return domain.Reservation{}, repository.ErrConflict
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE inventory_levels SET reserved = reserved - $2, updated_at = now() WHERE id = $1
+	`, levelID, res.Quantity); err != nil {
+		return domain.Reservation{}, err
+	}
+	if err := tx.QueryRow(ctx, `
+		UPDATE inventory_reservations SET status = $2, released_at = now() WHERE id = $1
+		RETURNING status, released_at
+	`, id, domain.ReservationReleased).Scan(&res.Status, &res.ReleasedAt); err != nil {
+		return domain.Reservation{}, err
+	}
+
+	if err := refreshProductRollup(ctx, tx, res.ProductID); err != nil {
+		return domain.Reservation{}, err
+	}
+	return res, tx.Commit(ctx)
+}
+
+// lockProduct takes the product row lock that ProductRepo.Update and
+// ReplaceVariants take first, before any inventory row is locked, so stock
+// changes and product writes lock in the same order and cannot deadlock.
+func lockProduct(ctx context.Context, tx pgx.Tx, productID int64) error {
+	_, err := tx.Exec(ctx, `SELECT 1 FROM products WHERE id = $1 FOR UPDATE`, productID)
+	return err
+}
@@ -0,0 +1,55 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/soydoradesu/product_discovery/internal/repository"
+)
+
+type MagicLinkRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewMagicLinkRepo(pool *pgxpool.Pool) repository.MagicLinkRepository {
+	return &MagicLinkRepo{pool: pool}
+}
+
+func (r *MagicLinkRepo) Create(ctx context.Context, id, email string, expiresAt time.Time) error {
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO magic_links(id, email, expires_at)
+		VALUES ($1, $2, $3)
+	`, id, email, expiresAt)
+	return err
+}
+
+func (r *MagicLinkRepo) Consume(ctx context.Context, id string) (string, error) {
+	var email string
+	err := r.pool.QueryRow(ctx, `
+		UPDATE magic_links
+		SET used_at = now()
+		WHERE id = $1 AND used_at IS NULL AND expires_at > now()
+		RETURNING email
+	`, id).Scan(&email)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", repository.ErrNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	return email, nil
+}
+
+func (r *MagicLinkRepo) CountSince(ctx context.Context, email string, since time.Time) (int, error) {
+	var n int
+	err := r.pool.QueryRow(ctx, `
+		SELECT COUNT(*)
+		FROM magic_links
+		WHERE email = $1 AND created_at >= $2
+	`, email, since).Scan(&n)
+	return n, err
+}
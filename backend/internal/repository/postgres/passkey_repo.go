@@ -0,0 +1,126 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/soydoradesu/product_discovery/internal/domain"
+	"github.com/soydoradesu/product_discovery/internal/repository"
+)
+
+type PasskeyRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewPasskeyRepo(pool *pgxpool.Pool) repository.PasskeyRepository {
+	return &PasskeyRepo{pool: pool}
+}
+
+const passkeyColumns = `id, user_id, credential_id, public_key, sign_count, aaguid, transports, name, created_at, last_used_at`
+
+func scanPasskey(row pgx.Row) (domain.Passkey, error) {
+	var p domain.Passkey
+	var signCount int64
+	err := row.Scan(&p.ID, &p.UserID, &p.CredentialID, &p.PublicKey, &signCount, &p.AAGUID, &p.Transports, &p.Name, &p.CreatedAt, &p.LastUsedAt)
+	p.SignCount = uint32(signCount)
+	return p, err
+}
+
+func (r *PasskeyRepo) Create(ctx context.Context, p domain.Passkey) (domain.Passkey, error) {
+	if p.Transports == nil {
+		p.Transports = []string{}
+	}
+	created, err := scanPasskey(r.pool.QueryRow(ctx, `
+		INSERT INTO passkeys(user_id, credential_id, public_key, sign_count, aaguid, transports, name)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING `+passkeyColumns,
+		p.UserID, p.CredentialID, p.PublicKey, int64(p.SignCount), p.AAGUID, p.Transports, p.Name))
+	if isUniqueViolation(err) {
+		return domain.Passkey{}, repository.ErrConflict
+	}
+	return created, err
+}
+
+func (r *PasskeyRepo) ListByUser(ctx context.Context, userID int64) ([]domain.Passkey, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+passkeyColumns+`
+		FROM passkeys
+		WHERE user_id = $1
+		ORDER BY created_at ASC, id ASC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.Passkey, error) { return scanPasskey(row) })
+}
+
+func (r *PasskeyRepo) GetByCredentialID(ctx context.Context, credentialID []byte) (domain.Passkey, error) {
+	p, err := scanPasskey(r.pool.QueryRow(ctx, `
+		SELECT `+passkeyColumns+`
+		FROM passkeys
+		WHERE credential_id = $1
+	`, credentialID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.Passkey{}, repository.ErrNotFound
+	}
+	return p, err
+}
+
+func (r *PasskeyRepo) RecordUse(ctx context.Context, id int64, signCount uint32, at time.Time) error {
+	_, err := r.pool.Exec(ctx, `
+		UPDATE passkeys
+		SET sign_count = $2, last_used_at = $3
+		WHERE id = $1
+	`, id, int64(signCount), at)
+	return err
+}
+
+func (r *PasskeyRepo) Delete(ctx context.Context, userID, id int64) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	// same lock as UnlinkIdentity, so the two can't together remove the
+	// last login method
+	var hasPassword bool
+	err = tx.QueryRow(ctx, `
+		SELECT password_hash IS NOT NULL
+		FROM users
+		WHERE id = $1
+		FOR UPDATE
+	`, userID).Scan(&hasPassword)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return repository.ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	var found, otherPasskeys, identities int
+	err = tx.QueryRow(ctx, `
+		SELECT
+			(SELECT COUNT(*) FROM passkeys WHERE user_id = $1 AND id = $2),
+			(SELECT COUNT(*) FROM passkeys WHERE user_id = $1 AND id <> $2),
+			(SELECT COUNT(*) FROM user_identities WHERE user_id = $1)
+	`, userID, id).Scan(&found, &otherPasskeys, &identities)
+	if err != nil {
+		return err
+	}
+	if found == 0 {
+		return repository.ErrNotFound
+	}
+	if !hasPassword && otherPasskeys == 0 && identities == 0 {
+		return repository.ErrConflict
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM passkeys WHERE user_id = $1 AND id = $2`, userID, id); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
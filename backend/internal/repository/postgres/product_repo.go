@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"strings"
 	"regexp"
+	"sort"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -24,14 +25,19 @@ func NewProductRepo(pool *pgxpool.Pool) repository.ProductRepository {
 }
 
 func (r *ProductRepo) GetByID(ctx context.Context, id int64) (domain.Product, error) {
-	var p domain.Product
-	var price float64
+	var (
+		p domain.Product
+		brand nullableBrand
+	)
 
 	err := r.pool.QueryRow(ctx, `
-		SELECT id, name, price, description, rating, in_stock, created_at
-		FROM products
-		WHERE id = $1
-	`, id).Scan(&p.ID, &p.Name, &price, &p.Description, &p.Rating, &p.InStock, &p.CreatedAt)
+		SELECT p.id, p.external_key, p.name, p.price_minor, p.currency, p.description, p.rating, p.in_stock, p.created_at, p.attributes,
+			b.id, b.name, b.slug
+		FROM products p
+		LEFT JOIN brands b ON b.id = p.brand_id
+		WHERE p.id = $1
+	`, id).Scan(&p.ID, &p.ExternalKey, &p.Name, &p.Price.Amount, &p.Price.Currency, &p.Description, &p.Rating, &p.InStock, &p.CreatedAt, &p.AttributeValues,
+		&brand.id, &brand.name, &brand.slug)
 
 	if errors.Is(err, pgx.ErrNoRows) {
 		return domain.Product{}, repository.ErrNotFound
@@ -39,7 +45,7 @@ func (r *ProductRepo) GetByID(ctx context.Context, id int64) (domain.Product, er
 	if err != nil {
 		return domain.Product{}, err
 	}
-	p.Price = price
+	p.Brand = brand.brand()
 
 	// images
 	rows, err := r.pool.Query(ctx, `
@@ -92,45 +98,240 @@ func (r *ProductRepo) GetByID(ctx context.Context, id int64) (domain.Product, er
 	}
 	p.Categories = cats
 
+	prows, err := r.pool.Query(ctx, `
+		SELECT amount_minor, currency
+		FROM product_prices
+		WHERE product_id = $1
+		ORDER BY currency ASC
+	`, id)
+	if err != nil {
+		return domain.Product{}, err
+	}
+	p.Prices, err = pgx.CollectRows(prows, func(row pgx.CollectableRow) (domain.Money, error) {
+		var m domain.Money
+		err := row.Scan(&m.Amount, &m.Currency)
+		return m, err
+	})
+	if err != nil {
+		return domain.Product{}, err
+	}
+	if len(p.Prices) == 0 {
+		p.Prices = nil
+	}
+
+	vrows, err := r.pool.Query(ctx, `
+		SELECT id, sku, options, price_minor, in_stock
+		FROM product_variants
+		WHERE product_id = $1
+		ORDER BY position ASC, id ASC
+	`, id)
+	if err != nil {
+		return domain.Product{}, err
+	}
+	p.Variants, err = pgx.CollectRows(vrows, func(row pgx.CollectableRow) (domain.Variant, error) {
+		v := domain.Variant{Price: domain.Money{Currency: p.Price.Currency}}
+		err := row.Scan(&v.ID, &v.SKU, &v.Options, &v.Price.Amount, &v.InStock)
+		return v, err
+	})
+	if err != nil {
+		return domain.Product{}, err
+	}
+	if len(p.Variants) == 0 {
+		p.Variants = nil
+	}
+
 	return p, nil
 }
 
-func (r *ProductRepo) Search(ctx context.Context, params domain.SearchParams) ([]domain.ProductSummary, int64, error) {
-	q := strings.TrimSpace(params.Q)
-	tsq := buildPrefixTSQuery(q)
+func (r *ProductRepo) VariantPrices(ctx context.Context, id int64, currency string) (map[int64]domain.Money, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT v.id, product_price_scaled(p.id, p.price_minor, v.price_minor, p.currency, $2)
+		FROM product_variants v
+		JOIN products p ON p.id = v.product_id
+		WHERE v.product_id = $1
+	`, id, currency)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
-	// $1 = q, $2 = category array
-	args := []any{tsq, params.CategoryID}
-	where := "WHERE ($1 = '' OR p.search_vector @@ to_tsquery('simple', $1))"
+	prices := map[int64]domain.Money{}
+	for rows.Next() {
+		var (
+			variantID int64
+			amount    *int64
+		)
+		if err := rows.Scan(&variantID, &amount); err != nil {
+			return nil, err
+		}
+		if amount != nil {
+			prices[variantID] = domain.Money{Amount: *amount, Currency: currency}
+		}
+	}
+	return prices, rows.Err()
+}
 
-	// category multi-value: match ANY selected category
-	where += `
-	AND (
-		COALESCE(array_length($2::bigint[], 1), 0) = 0
-		OR EXISTS (
+func (r *ProductRepo) ReplaceVariants(ctx context.Context, id int64, variants []domain.Variant) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// lock the product so concurrent replaces of its variants queue up
+	var exists bool
+	if err := tx.QueryRow(ctx, `SELECT true FROM products WHERE id = $1 FOR UPDATE`, id).Scan(&exists); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return repository.ErrNotFound
+		}
+		return err
+	}
+
+	skus := make([]string, len(variants))
+	for i, v := range variants {
+		skus[i] = v.SKU
+	}
+	// dropping a variant would drop its stock along with it
+	var stocked bool
+	if err := tx.QueryRow(ctx, `
+		SELECT EXISTS (
 			SELECT 1
-			FROM product_categories pc2
-			WHERE pc2.product_id = p.id
-			AND pc2.category_id = ANY($2::bigint[])
+			FROM product_variants v
+			JOIN inventory_levels l ON l.variant_id = v.id
+			WHERE v.product_id = $1 AND v.sku <> ALL($2) AND `+levelHasStock+`
 		)
-	)`
+	`, id, skus).Scan(&stocked); err != nil {
+		return err
+	}
+	if stocked {
+		return repository.ErrStockRemaining
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM product_variants WHERE product_id = $1 AND sku <> ALL($2)`, id, skus); err != nil {
+		return err
+	}
 
-	idx := 3
-	if params.MinPrice != nil {
-		where += fmt.Sprintf(" AND p.price >= $%d", idx)
-		args = append(args, *params.MinPrice)
-		idx++
+	// upserting by SKU keeps the ids of variants that stay
+	for i, v := range variants {
+		tag, err := tx.Exec(ctx, `
+			INSERT INTO product_variants(product_id, sku, options, price_minor, in_stock, position)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (sku) DO UPDATE
+			SET options = EXCLUDED.options, price_minor = EXCLUDED.price_minor,
+				in_stock = EXCLUDED.in_stock, position = EXCLUDED.position
+			WHERE product_variants.product_id = EXCLUDED.product_id
+		`, id, v.SKU, v.Options, v.Price.Amount, v.InStock, i)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			// the SKU belongs to another product
+			return repository.ErrConflict
+		}
 	}
-	if params.MaxPrice != nil {
-		where += fmt.Sprintf(" AND p.price <= $%d", idx)
-		args = append(args, *params.MaxPrice)
-		idx++
+
+	if err := refreshProductRollup(ctx, tx, id); err != nil {
+		return err
 	}
-	if params.InStock != nil {
-		where += fmt.Sprintf(" AND p.in_stock = $%d", idx)
-		args = append(args, *params.InStock)
-		idx++
+	if err := tx.Commit(ctx); err != nil {
+		if isUniqueViolation(err) {
+			return repository.ErrConflict
+		}
+		return err
+	}
+	return nil
+}
+
+// refreshProductRollup derives the in_stock of tracked variants and products
+// from their available inventory, then copies the price range and stock of
+// the product's variants onto its row, so search can filter and sort on the
+// product alone. Untracked items and products without variants keep their own
+// price and stock.
+func refreshProductRollup(ctx context.Context, tx pgx.Tx, id int64) error {
+	return refreshProductRollups(ctx, tx, []int64{id})
+}
+
+// refreshProductRollups is refreshProductRollup for many products at once.
+func refreshProductRollups(ctx context.Context, tx pgx.Tx, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if _, err := tx.Exec(ctx, `
+		UPDATE product_variants v
+		SET in_stock = s.available > 0
+		FROM (
+			SELECT variant_id, SUM(on_hand - reserved) AS available
+			FROM inventory_levels
+			WHERE product_id = ANY($1::bigint[]) AND variant_id IS NOT NULL
+			GROUP BY variant_id
+		) s
+		WHERE v.id = s.variant_id
+	`, ids); err != nil {
+		return err
+	}
+	_, err := tx.Exec(ctx, `
+		UPDATE products p
+		SET price_minor = COALESCE(v.min_price, p.price_minor),
+			price_max_minor = v.max_price,
+			in_stock = COALESCE(v.any_in_stock, s.available > 0, p.in_stock)
+		FROM (SELECT DISTINCT unnest($1::bigint[]) AS id) ids
+		LEFT JOIN (
+			SELECT product_id, MIN(price_minor) AS min_price, MAX(price_minor) AS max_price, BOOL_OR(in_stock) AS any_in_stock
+			FROM product_variants
+			WHERE product_id = ANY($1::bigint[])
+			GROUP BY product_id
+		) v ON v.product_id = ids.id
+		LEFT JOIN (
+			SELECT product_id, SUM(on_hand - reserved) AS available
+			FROM inventory_levels
+			WHERE product_id = ANY($1::bigint[]) AND variant_id IS NULL
+			GROUP BY product_id
+		) s ON s.product_id = ids.id
+		WHERE p.id = ids.id
+	`, ids)
+	return err
+}
+
+func (r *ProductRepo) GetPrice(ctx context.Context, id int64, currency string) (domain.Money, error) {
+	var amount *int64
+	err := r.pool.QueryRow(ctx, `
+		SELECT product_price(id, price_minor, currency, $2)
+		FROM products
+		WHERE id = $1
+	`, id, currency).Scan(&amount)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && amount == nil) {
+		return domain.Money{}, repository.ErrNotFound
+	}
+	if err != nil {
+		return domain.Money{}, err
+	}
+	return domain.Money{Amount: *amount, Currency: currency}, nil
+}
+
+func (r *ProductRepo) SetPrice(ctx context.Context, id int64, price domain.Money) error {
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO product_prices(product_id, currency, amount_minor)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (product_id, currency) DO UPDATE SET amount_minor = EXCLUDED.amount_minor, updated_at = now()
+	`, id, price.Currency, price.Amount)
+	if isForeignKeyViolation(err) {
+		return repository.ErrNotFound
+	}
+	return err
+}
+
+func (r *ProductRepo) DeletePrice(ctx context.Context, id int64, currency string) error {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM product_prices WHERE product_id = $1 AND currency = $2`, id, currency)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return repository.ErrNotFound
 	}
+	return nil
+}
+
+func (r *ProductRepo) Search(ctx context.Context, params domain.SearchParams) ([]domain.ProductSummary, int64, error) {
+	where, args, price := searchFilter(params)
 
 	// count for distinct products
 	countSQL := `
@@ -154,7 +355,7 @@ func (r *ProductRepo) Search(ctx context.Context, params domain.SearchParams) ([
 	case "relevance":
 		orderBy = fmt.Sprintf("ORDER BY rank %s, p.id ASC", method)
 	case "price":
-		orderBy = fmt.Sprintf("ORDER BY p.price %s, p.id ASC", method)
+		orderBy = fmt.Sprintf("ORDER BY %s %s, p.id ASC", price.amount, method)
 	case "created_at":
 		orderBy = fmt.Sprintf("ORDER BY p.created_at %s, p.id ASC", method)
 	case "rating":
@@ -168,7 +369,9 @@ func (r *ProductRepo) Search(ctx context.Context, params domain.SearchParams) ([
 	SELECT
 		p.id,
 		p.name,
-		p.price,
+		` + price.amount + `,
+		` + price.max + `,
+		` + price.currency + `,
 		p.rating,
 		p.in_stock,
 		p.created_at,
@@ -178,6 +381,9 @@ func (r *ProductRepo) Search(ctx context.Context, params domain.SearchParams) ([
 			FILTER (WHERE c.id IS NOT NULL),
 			'[]'::jsonb
 		) AS categories_json,
+		b.id,
+		b.name,
+		b.slug,
 		CASE
 			WHEN $1 <> '' THEN ts_rank_cd(p.search_vector, to_tsquery('simple', $1))
 			ELSE 0
@@ -185,8 +391,9 @@ func (r *ProductRepo) Search(ctx context.Context, params domain.SearchParams) ([
 	FROM products p
 	LEFT JOIN product_categories pc ON pc.product_id = p.id
 	LEFT JOIN categories c ON c.id = pc.category_id
+	LEFT JOIN brands b ON b.id = p.brand_id
 	` + where + `
-	GROUP BY p.id
+	GROUP BY p.id, b.id
 	` + orderBy + `
 	LIMIT ` + fmt.Sprintf("%d", limit) + ` OFFSET ` + fmt.Sprintf("%d", offset)
 
@@ -200,28 +407,34 @@ func (r *ProductRepo) Search(ctx context.Context, params domain.SearchParams) ([
 	for rows.Next() {
 		var (
 			ps domain.ProductSummary
-			price float64
 			thumb *string
 			catsJSON []byte
+			brand nullableBrand
 			rank float64
 		)
 
 		if err := rows.Scan(
 			&ps.ID,
 			&ps.Name,
-			&price,
+			&ps.Price.Amount,
+			&ps.PriceMax.Amount,
+			&ps.Price.Currency,
 			&ps.Rating,
 			&ps.InStock,
 			&ps.CreatedAt,
 			&thumb,
 			&catsJSON,
+			&brand.id,
+			&brand.name,
+			&brand.slug,
 			&rank,
 		); err != nil {
 			return nil, 0, err
 		}
 
-		ps.Price = price
+		ps.PriceMax.Currency = ps.Price.Currency
 		ps.Thumbnail = thumb
+		ps.Brand = brand.brand()
 
 		var cats []domain.Category
 		if err := json.Unmarshal(catsJSON, &cats); err != nil {
@@ -238,6 +451,191 @@ func (r *ProductRepo) Search(ctx context.Context, params domain.SearchParams) ([
 	return out, total, nil
 }
 
+func (r *ProductRepo) AttributeFacets(ctx context.Context, params domain.SearchParams) ([]domain.AttributeFacet, error) {
+	where, args, _ := searchFilter(params)
+
+	// numbers are grouped by attribute only and give their range; the other
+	// types are grouped by value
+	rows, err := r.pool.Query(ctx, `
+		SELECT a.key, jsonb_typeof(a.value) AS kind,
+			CASE WHEN jsonb_typeof(a.value) = 'number' THEN NULL ELSE a.value #>> '{}' END AS value,
+			COUNT(*),
+			MIN(CASE WHEN jsonb_typeof(a.value) = 'number' THEN (a.value #>> '{}')::float8 END),
+			MAX(CASE WHEN jsonb_typeof(a.value) = 'number' THEN (a.value #>> '{}')::float8 END)
+		FROM products p
+		CROSS JOIN LATERAL jsonb_each(p.attributes) a
+		`+where+`
+		AND jsonb_typeof(a.value) IN ('string', 'number', 'boolean')
+		GROUP BY 1, 2, 3
+		ORDER BY 1 ASC, 4 DESC, 3 ASC
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []domain.AttributeFacet
+	for rows.Next() {
+		var (
+			code, kind string
+			value      *string
+			count      int64
+			min, max   *float64
+		)
+		if err := rows.Scan(&code, &kind, &value, &count, &min, &max); err != nil {
+			return nil, err
+		}
+		if len(out) == 0 || out[len(out)-1].Code != code {
+			out = append(out, domain.AttributeFacet{Code: code, Type: kind})
+		}
+		f := &out[len(out)-1]
+		f.Count += count
+		if value != nil {
+			f.Values = append(f.Values, domain.FacetValue{Value: *value, Count: count})
+		} else {
+			f.Min, f.Max = min, max
+		}
+	}
+	return out, rows.Err()
+}
+
+// attributeValues is what goes into products.attributes, never NULL.
+func attributeValues(p domain.Product) map[string]any {
+	if p.AttributeValues == nil {
+		return map[string]any{}
+	}
+	return p.AttributeValues
+}
+
+// brandID is what goes into products.brand_id.
+func brandID(p domain.Product) *int64 {
+	if p.Brand == nil {
+		return nil
+	}
+	return &p.Brand.ID
+}
+
+// nullableBrand scans the brand columns of a LEFT JOIN.
+type nullableBrand struct {
+	id   *int64
+	name *string
+	slug *string
+}
+
+func (b nullableBrand) brand() *domain.Brand {
+	if b.id == nil {
+		return nil
+	}
+	return &domain.Brand{ID: *b.id, Name: *b.name, Slug: *b.slug}
+}
+
+// priceColumns are the SQL expressions for a product's price range in the
+// currency a search asked for.
+type priceColumns struct {
+	amount   string
+	max      string
+	currency string
+}
+
+// searchFilter builds the WHERE clause shared by Search and Export. $1 is
+// always the prefix tsquery (Search ranks on it) and $2 the category ids.
+// The search vector covers the name, description and brand name.
+// With a currency, prices are compared in it and products without a price
+// in it are left out.
+func searchFilter(params domain.SearchParams) (string, []any, priceColumns) {
+	q := strings.TrimSpace(params.Q)
+	tsq := buildPrefixTSQuery(q)
+
+	// $1 = q, $2 = category array
+	args := []any{tsq, params.CategoryID}
+	where := "WHERE ($1 = '' OR p.search_vector @@ to_tsquery('simple', $1))"
+
+	// category multi-value: match ANY selected category or its descendants
+	where += `
+	AND (
+		COALESCE(array_length($2::bigint[], 1), 0) = 0
+		OR EXISTS (
+			SELECT 1
+			FROM product_categories pc2
+			WHERE pc2.product_id = p.id
+			AND pc2.category_id IN (
+				WITH RECURSIVE selected AS (
+					SELECT id FROM categories WHERE id = ANY($2::bigint[])
+					UNION
+					SELECT c2.id FROM categories c2 JOIN selected s ON c2.parent_id = s.id
+				)
+				SELECT id FROM selected
+			)
+		)
+	)`
+
+	idx := 3
+	price := priceColumns{amount: "p.price_minor", max: "COALESCE(p.price_max_minor, p.price_minor)", currency: "p.currency"}
+	if params.Currency != "" {
+		price = priceColumns{
+			amount:   fmt.Sprintf("product_price(p.id, p.price_minor, p.currency, $%d)", idx),
+			max:      fmt.Sprintf("product_price_scaled(p.id, p.price_minor, COALESCE(p.price_max_minor, p.price_minor), p.currency, $%d)", idx),
+			currency: fmt.Sprintf("$%d::char(3)", idx),
+		}
+		where += " AND " + price.amount + " IS NOT NULL"
+		args = append(args, params.Currency)
+		idx++
+	}
+	// a price range matches when it overlaps the bounds
+	if params.MinPrice != nil {
+		where += fmt.Sprintf(" AND %s >= $%d", price.max, idx)
+		args = append(args, params.MinPrice.Amount)
+		idx++
+	}
+	if params.MaxPrice != nil {
+		where += fmt.Sprintf(" AND %s <= $%d", price.amount, idx)
+		args = append(args, params.MaxPrice.Amount)
+		idx++
+	}
+	if len(params.BrandID) > 0 {
+		where += fmt.Sprintf(" AND p.brand_id = ANY($%d::bigint[])", idx)
+		args = append(args, params.BrandID)
+		idx++
+	}
+	if params.InStock != nil {
+		where += fmt.Sprintf(" AND p.in_stock = $%d", idx)
+		args = append(args, *params.InStock)
+		idx++
+	}
+	for _, f := range params.Attributes {
+		switch f.Op {
+		case domain.AttributeOpEq:
+			where += fmt.Sprintf(" AND lower(p.attributes->>$%d) = ANY($%d::text[])", idx, idx+1)
+			args = append(args, f.Code, f.Values)
+		case domain.AttributeOpGt, domain.AttributeOpGte, domain.AttributeOpLt, domain.AttributeOpLte:
+			// the CASE keeps the cast away from values that are not numbers
+			where += fmt.Sprintf(" AND CASE WHEN jsonb_typeof(p.attributes->$%d) = 'number' THEN (p.attributes->>$%d)::float8 END %s $%d", idx, idx, f.Op, idx+1)
+			args = append(args, f.Code, f.Number)
+		default:
+			continue
+		}
+		idx += 2
+	}
+	if len(params.Options) > 0 {
+		// one variant has to match every option; values compare without case,
+		// as they are when variants are validated
+		names := make([]string, 0, len(params.Options))
+		for name := range params.Options {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		conds := make([]string, len(names))
+		for i, name := range names {
+			conds[i] = fmt.Sprintf("lower(v.options->>$%d) IN (SELECT lower(o) FROM unnest($%d::text[]) o)", idx, idx+1)
+			args = append(args, name, params.Options[name])
+			idx += 2
+		}
+		where += " AND EXISTS (SELECT 1 FROM product_variants v WHERE v.product_id = p.id AND " + strings.Join(conds, " AND ") + ")"
+	}
+
+	return where, args, price
+}
+
 var tsTokenRe = regexp.MustCompile(`[A-Za-z0-9]+`)
 
 func buildPrefixTSQuery(input string) string {
@@ -255,4 +653,215 @@ func buildPrefixTSQuery(input string) string {
 	}
 
 	return strings.Join(parts, " & ")
-}
\ No newline at end of file
+}
+func (r *ProductRepo) Create(ctx context.Context, p domain.Product) (int64, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	var id int64
+	err = tx.QueryRow(ctx, `
+		INSERT INTO products(name, price_minor, currency, description, rating, in_stock, attributes, brand_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id
+	`, p.Name, p.Price.Amount, p.Price.Currency, p.Description, p.Rating, p.InStock, attributeValues(p), brandID(p)).Scan(&id)
+	if err != nil {
+		return 0, productWriteError(err)
+	}
+	if err := writeProductRelations(ctx, tx, id, p); err != nil {
+		return 0, err
+	}
+	return id, tx.Commit(ctx)
+}
+
+func (r *ProductRepo) Update(ctx context.Context, p domain.Product) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE products
+		SET name = $2, price_minor = $3, currency = $4, description = $5, rating = $6, in_stock = $7, attributes = $8, brand_id = $9
+		WHERE id = $1
+	`, p.ID, p.Name, p.Price.Amount, p.Price.Currency, p.Description, p.Rating, p.InStock, attributeValues(p), brandID(p))
+	if err != nil {
+		return productWriteError(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return repository.ErrNotFound
+	}
+	if err := refreshProductRollup(ctx, tx, p.ID); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM product_images WHERE product_id = $1`, p.ID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM product_categories WHERE product_id = $1`, p.ID); err != nil {
+		return err
+	}
+	if err := writeProductRelations(ctx, tx, p.ID, p); err != nil {
+		return err
+	}
+	// a price list entry in the product's own currency would never be used
+	if _, err := tx.Exec(ctx, `DELETE FROM product_prices WHERE product_id = $1 AND currency = $2`, p.ID, p.Price.Currency); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// levelHasStock matches an inventory_levels row l that still holds stock on
+// hand or active reservations, which deleting it would lose.
+const levelHasStock = `(l.on_hand > 0 OR EXISTS (
+	SELECT 1 FROM inventory_reservations rv WHERE rv.level_id = l.id AND rv.status = 'active'
+))`
+
+func (r *ProductRepo) Delete(ctx context.Context, id int64) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// the lock keeps stock from arriving between the check and the delete
+	var stocked bool
+	err = tx.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM inventory_levels l WHERE l.product_id = p.id AND `+levelHasStock+`)
+		FROM products p
+		WHERE p.id = $1
+		FOR UPDATE OF p
+	`, id).Scan(&stocked)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return repository.ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if stocked {
+		return repository.ErrStockRemaining
+	}
+
+	// images, category links, variants and empty stock levels go with the
+	// product (ON DELETE CASCADE)
+	if _, err := tx.Exec(ctx, `DELETE FROM products WHERE id = $1`, id); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// exportBatchSize is how many rows Export fetches from its cursor at a time.
+const exportBatchSize = 500
+
+func (r *ProductRepo) Export(ctx context.Context, params domain.SearchParams, fn func(domain.Product) error) error {
+	// a read-only snapshot keeps the export consistent while it streams
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	where, args, _ := searchFilter(params)
+	// DECLARE cannot be prepared, so the arguments are sent inline
+	args = append([]any{pgx.QueryExecModeSimpleProtocol}, args...)
+	_, err = tx.Exec(ctx, `
+		DECLARE product_export NO SCROLL CURSOR FOR
+		SELECT
+			p.id,
+			p.external_key,
+			p.name,
+			p.price_minor,
+			p.currency,
+			p.description,
+			p.rating,
+			p.in_stock,
+			p.created_at,
+			ARRAY(SELECT pi.url FROM product_images pi WHERE pi.product_id = p.id ORDER BY pi.position ASC),
+			ARRAY(SELECT pi.position FROM product_images pi WHERE pi.product_id = p.id ORDER BY pi.position ASC),
+			COALESCE(
+				(SELECT jsonb_agg(jsonb_build_object('id', c.id, 'name', c.name) ORDER BY c.id)
+				FROM product_categories pc
+				JOIN categories c ON c.id = pc.category_id
+				WHERE pc.product_id = p.id),
+				'[]'::jsonb
+			)
+		FROM products p
+	`+where+`
+		ORDER BY p.id ASC
+	`, args...)
+	if err != nil {
+		return err
+	}
+
+	for {
+		rows, err := tx.Query(ctx, fmt.Sprintf("FETCH FORWARD %d FROM product_export", exportBatchSize))
+		if err != nil {
+			return err
+		}
+		batch, err := pgx.CollectRows(rows, scanExportedProduct)
+		if err != nil {
+			return err
+		}
+		for _, p := range batch {
+			if err := fn(p); err != nil {
+				return err
+			}
+		}
+		if len(batch) < exportBatchSize {
+			return nil
+		}
+	}
+}
+
+func scanExportedProduct(row pgx.CollectableRow) (domain.Product, error) {
+	var (
+		p         domain.Product
+		urls      []string
+		positions []int32
+		catsJSON  []byte
+	)
+	if err := row.Scan(&p.ID, &p.ExternalKey, &p.Name, &p.Price.Amount, &p.Price.Currency, &p.Description, &p.Rating, &p.InStock, &p.CreatedAt, &urls, &positions, &catsJSON); err != nil {
+		return domain.Product{}, err
+	}
+	p.Images = make([]domain.ProductImage, len(urls))
+	for i, url := range urls {
+		p.Images[i] = domain.ProductImage{URL: url, Position: positions[i]}
+	}
+	if err := json.Unmarshal(catsJSON, &p.Categories); err != nil {
+		return domain.Product{}, err
+	}
+	return p, nil
+}
+
+func writeProductRelations(ctx context.Context, tx pgx.Tx, productID int64, p domain.Product) error {
+	for _, img := range p.Images {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO product_images(product_id, url, position)
+			VALUES ($1, $2, $3)
+		`, productID, img.URL, img.Position); err != nil {
+			return err
+		}
+	}
+	for _, c := range p.Categories {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO product_categories(product_id, category_id)
+			VALUES ($1, $2)
+		`, productID, c.ID); err != nil {
+			return productWriteError(err)
+		}
+	}
+	return nil
+}
+
+func productWriteError(err error) error {
+	switch {
+	case isUniqueViolation(err):
+		return repository.ErrConflict
+	case isForeignKeyViolation(err):
+		return repository.ErrInvalidReference
+	}
+	return err
+}
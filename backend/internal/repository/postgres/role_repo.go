@@ -0,0 +1,76 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/soydoradesu/product_discovery/internal/domain"
+	"github.com/soydoradesu/product_discovery/internal/repository"
+)
+
+type RoleRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewRoleRepo(pool *pgxpool.Pool) repository.RoleRepository {
+	return &RoleRepo{pool: pool}
+}
+
+func (r *RoleRepo) GetUserAccess(ctx context.Context, userID int64) (domain.Access, error) {
+	access := domain.Access{Roles: []string{}, Permissions: []string{}}
+
+	err := r.pool.QueryRow(ctx, `
+		SELECT
+			COALESCE(array_agg(DISTINCT ro.name) FILTER (WHERE ro.name IS NOT NULL), '{}'),
+			COALESCE(array_agg(DISTINCT pe.name) FILTER (WHERE pe.name IS NOT NULL), '{}')
+		FROM user_roles ur
+		JOIN roles ro ON ro.id = ur.role_id
+		LEFT JOIN role_permissions rp ON rp.role_id = ro.id
+		LEFT JOIN permissions pe ON pe.id = rp.permission_id
+		WHERE ur.user_id = $1
+	`, userID).Scan(&access.Roles, &access.Permissions)
+	if err != nil {
+		return domain.Access{}, err
+	}
+	return access, nil
+}
+
+func (r *RoleRepo) GrantRole(ctx context.Context, userID int64, role string) error {
+	roleID, err := r.roleID(ctx, role)
+	if err != nil {
+		return err
+	}
+	_, err = r.pool.Exec(ctx, `
+		INSERT INTO user_roles(user_id, role_id)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id, role_id) DO NOTHING
+	`, userID, roleID)
+	return err
+}
+
+func (r *RoleRepo) RevokeRole(ctx context.Context, userID int64, role string) error {
+	roleID, err := r.roleID(ctx, role)
+	if err != nil {
+		return err
+	}
+	_, err = r.pool.Exec(ctx, `
+		DELETE FROM user_roles
+		WHERE user_id = $1 AND role_id = $2
+	`, userID, roleID)
+	return err
+}
+
+func (r *RoleRepo) roleID(ctx context.Context, role string) (int64, error) {
+	var id int64
+	err := r.pool.QueryRow(ctx, `SELECT id FROM roles WHERE name = $1`, role).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, repository.ErrNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
@@ -0,0 +1,115 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/soydoradesu/product_discovery/internal/domain"
+	"github.com/soydoradesu/product_discovery/internal/repository"
+)
+
+type SessionRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewSessionRepo(pool *pgxpool.Pool) repository.SessionRepository {
+	return &SessionRepo{pool: pool}
+}
+
+const sessionColumns = `id, user_id, user_agent, ip, created_at, last_seen_at, expires_at, revoked_at`
+
+func scanSession(row pgx.Row) (domain.Session, error) {
+	var s domain.Session
+	err := row.Scan(&s.ID, &s.UserID, &s.UserAgent, &s.IP, &s.CreatedAt, &s.LastSeenAt, &s.ExpiresAt, &s.RevokedAt)
+	return s, err
+}
+
+func (r *SessionRepo) Create(ctx context.Context, s domain.Session) error {
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO sessions(id, user_id, user_agent, ip, created_at, last_seen_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $5, $6)
+	`, s.ID, s.UserID, s.UserAgent, s.IP, s.CreatedAt, s.ExpiresAt)
+	return err
+}
+
+func (r *SessionRepo) Get(ctx context.Context, id string) (domain.Session, error) {
+	s, err := scanSession(r.pool.QueryRow(ctx, `
+		SELECT `+sessionColumns+`
+		FROM sessions
+		WHERE id = $1
+	`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.Session{}, repository.ErrNotFound
+	}
+	if err != nil {
+		return domain.Session{}, err
+	}
+	return s, nil
+}
+
+func (r *SessionRepo) ListActive(ctx context.Context, userID int64) ([]domain.Session, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+sessionColumns+`
+		FROM sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > now()
+		ORDER BY last_seen_at DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []domain.Session{}
+	for rows.Next() {
+		s, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	return out, nil
+}
+
+// Touch only writes when last_seen_at is more than a minute old.
+func (r *SessionRepo) Touch(ctx context.Context, id string, at time.Time) error {
+	_, err := r.pool.Exec(ctx, `
+		UPDATE sessions
+		SET last_seen_at = $2
+		WHERE id = $1 AND last_seen_at < $2::timestamptz - interval '1 minute'
+	`, id, at)
+	return err
+}
+
+func (r *SessionRepo) Revoke(ctx context.Context, userID int64, id string) error {
+	ct, err := r.pool.Exec(ctx, `
+		UPDATE sessions
+		SET revoked_at = now()
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+	`, id, userID)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+func (r *SessionRepo) RevokeAllExcept(ctx context.Context, userID int64, keepID string) (int64, error) {
+	ct, err := r.pool.Exec(ctx, `
+		UPDATE sessions
+		SET revoked_at = now()
+		WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL AND expires_at > now()
+	`, userID, keepID)
+	if err != nil {
+		return 0, err
+	}
+	return ct.RowsAffected(), nil
+}
@@ -0,0 +1,155 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/soydoradesu/product_discovery/internal/domain"
+	"github.com/soydoradesu/product_discovery/internal/repository"
+)
+
+type TwoFactorRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewTwoFactorRepo(pool *pgxpool.Pool) repository.TwoFactorRepository {
+	return &TwoFactorRepo{pool: pool}
+}
+
+func (r *TwoFactorRepo) GetTOTP(ctx context.Context, userID int64) (domain.UserTOTP, error) {
+	var t domain.UserTOTP
+	err := r.pool.QueryRow(ctx, `
+		SELECT user_id, secret, enabled_at, last_used_step, failed_attempts, locked_until
+		FROM user_totp
+		WHERE user_id = $1
+	`, userID).Scan(&t.UserID, &t.Secret, &t.EnabledAt, &t.LastUsedStep, &t.FailedAttempts, &t.LockedUntil)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.UserTOTP{}, repository.ErrNotFound
+	}
+	if err != nil {
+		return domain.UserTOTP{}, err
+	}
+	return t, nil
+}
+
+func (r *TwoFactorRepo) SavePendingTOTP(ctx context.Context, userID int64, secret string) error {
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO user_totp(user_id, secret)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = EXCLUDED.secret, last_used_step = 0, failed_attempts = 0, locked_until = NULL, created_at = now()
+		WHERE user_totp.enabled_at IS NULL
+	`, userID, secret)
+	return err
+}
+
+func (r *TwoFactorRepo) EnableTOTP(ctx context.Context, userID int64, step int64, recoveryCodeHashes []string) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	ct, err := tx.Exec(ctx, `
+		UPDATE user_totp
+		SET enabled_at = now(), last_used_step = $2
+		WHERE user_id = $1 AND enabled_at IS NULL
+	`, userID, step)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return repository.ErrNotFound
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM user_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+
+	rows := make([][]any, 0, len(recoveryCodeHashes))
+	for _, h := range recoveryCodeHashes {
+		rows = append(rows, []any{userID, h})
+	}
+	if _, err := tx.CopyFrom(
+		ctx,
+		pgx.Identifier{"user_recovery_codes"},
+		[]string{"user_id", "code_hash"},
+		pgx.CopyFromRows(rows),
+	); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *TwoFactorRepo) DeleteTOTP(ctx context.Context, userID int64) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if _, err := tx.Exec(ctx, `DELETE FROM user_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM user_totp WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *TwoFactorRepo) AdvanceTOTPStep(ctx context.Context, userID int64, step int64) (bool, error) {
+	ct, err := r.pool.Exec(ctx, `
+		UPDATE user_totp
+		SET last_used_step = $2
+		WHERE user_id = $1 AND last_used_step < $2
+	`, userID, step)
+	if err != nil {
+		return false, err
+	}
+	return ct.RowsAffected() == 1, nil
+}
+
+func (r *TwoFactorRepo) UseRecoveryCode(ctx context.Context, userID int64, codeHash string) (bool, error) {
+	ct, err := r.pool.Exec(ctx, `
+		UPDATE user_recovery_codes
+		SET used_at = now()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`, userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+	return ct.RowsAffected() == 1, nil
+}
+
+func (r *TwoFactorRepo) RecordTOTPFailure(ctx context.Context, userID int64, maxFailures int, lockUntil time.Time) (bool, error) {
+	// the increment happens in one statement, so concurrent guesses are all
+	// counted
+	var locked bool
+	err := r.pool.QueryRow(ctx, `
+		UPDATE user_totp
+		SET failed_attempts = CASE WHEN failed_attempts + 1 >= $2 THEN 0 ELSE failed_attempts + 1 END,
+			locked_until = CASE WHEN failed_attempts + 1 >= $2 THEN $3 ELSE locked_until END
+		WHERE user_id = $1
+		RETURNING failed_attempts = 0
+	`, userID, maxFailures, lockUntil).Scan(&locked)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	return locked, err
+}
+
+func (r *TwoFactorRepo) ResetTOTPFailures(ctx context.Context, userID int64) error {
+	_, err := r.pool.Exec(ctx, `
+		UPDATE user_totp
+		SET failed_attempts = 0, locked_until = NULL
+		WHERE user_id = $1 AND (failed_attempts > 0 OR locked_until IS NOT NULL)
+	`, userID)
+	return err
+}
@@ -3,6 +3,9 @@ package postgres
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/soydoradesu/product_discovery/internal/domain"
 	"github.com/soydoradesu/product_discovery/internal/repository"
@@ -18,14 +21,11 @@ func NewUserRepo(pool *pgxpool.Pool) repository.UserRepository {
 	return &UserRepo{pool: pool}
 }
 
-func (r *UserRepo) GetByEmail(ctx context.Context, email string) (domain.User, error) {
-	var u domain.User
-	err := r.pool.QueryRow(ctx, `
-		SELECT id, email, password_hash, google_id, created_at
-		FROM users
-		WHERE email = $1
-	`, email).Scan(&u.ID, &u.Email, &u.PasswordHash, &u.GoogleID, &u.CreatedAt)
+const userColumns = `u.id, u.email, u.password_hash, u.created_at, u.disabled_at`
 
+func scanUser(row pgx.Row) (domain.User, error) {
+	var u domain.User
+	err := row.Scan(&u.ID, &u.Email, &u.PasswordHash, &u.CreatedAt, &u.DisabledAt)
 	if errors.Is(err, pgx.ErrNoRows) {
 		return domain.User{}, repository.ErrNotFound
 	}
@@ -35,46 +35,97 @@ func (r *UserRepo) GetByEmail(ctx context.Context, email string) (domain.User, e
 	return u, nil
 }
 
+func (r *UserRepo) GetByEmail(ctx context.Context, email string) (domain.User, error) {
+	return scanUser(r.pool.QueryRow(ctx, `
+		SELECT `+userColumns+`
+		FROM users u
+		WHERE u.email = $1
+	`, email))
+}
+
 func (r *UserRepo) GetByID(ctx context.Context, id int64) (domain.User, error) {
-	var u domain.User
-	err := r.pool.QueryRow(ctx, `
-		SELECT id, email, password_hash, google_id, created_at
-		FROM users
+	return scanUser(r.pool.QueryRow(ctx, `
+		SELECT `+userColumns+`
+		FROM users u
+		WHERE u.id = $1
+	`, id))
+}
+
+func (r *UserRepo) SetPasswordHash(ctx context.Context, userID int64, hash string) error {
+	ct, err := r.pool.Exec(ctx, `
+		UPDATE users
+		SET password_hash = $2
 		WHERE id = $1
-	`, id).Scan(&u.ID, &u.Email, &u.PasswordHash, &u.GoogleID, &u.CreatedAt)
+	`, userID, hash)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
 
-	if errors.Is(err, pgx.ErrNoRows) {
-		return domain.User{}, repository.ErrNotFound
+func (r *UserRepo) List(ctx context.Context, params domain.UserListParams) ([]domain.User, int64, error) {
+	args := []any{}
+	where := "WHERE TRUE"
+	if params.Q != "" {
+		args = append(args, "%"+likeEscaper.Replace(params.Q)+"%")
+		where += fmt.Sprintf(" AND u.email ILIKE $%d", len(args))
+	}
+	switch params.Status {
+	case domain.UserStatusActive:
+		where += " AND u.disabled_at IS NULL"
+	case domain.UserStatusDisabled:
+		where += " AND u.disabled_at IS NOT NULL"
+	}
+
+	var total int64
+	if err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM users u `+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
 	}
+
+	args = append(args, params.PageSize, (params.Page-1)*params.PageSize)
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+userColumns+`
+		FROM users u
+		`+where+`
+		ORDER BY u.id ASC
+		LIMIT $`+fmt.Sprint(len(args)-1)+` OFFSET $`+fmt.Sprint(len(args)), args...)
 	if err != nil {
-		return domain.User{}, err
+		return nil, 0, err
 	}
-	return u, nil
+	users, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.User, error) { return scanUser(row) })
+	if err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
 }
 
-func (r *UserRepo) GetByGoogleID(ctx context.Context, googleID string) (domain.User, error) {
-	var u domain.User
-	err := r.pool.QueryRow(ctx, `
-		SELECT id, email, password_hash, google_id, created_at
-		FROM users
-		WHERE google_id = $1
-	`, googleID).Scan(&u.ID, &u.Email, &u.PasswordHash, &u.GoogleID, &u.CreatedAt)
+// likeEscaper keeps user input from acting as LIKE wildcards.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
 
-	if errors.Is(err, pgx.ErrNoRows) {
-		return domain.User{}, repository.ErrNotFound
-	}
+func (r *UserRepo) SetDisabled(ctx context.Context, userID int64, disabled bool) error {
+	ct, err := r.pool.Exec(ctx, `
+		UPDATE users
+		SET disabled_at = CASE WHEN $2 THEN COALESCE(disabled_at, now()) END
+		WHERE id = $1
+	`, userID, disabled)
 	if err != nil {
-		return domain.User{}, err
+		return err
 	}
-	return u, nil
+	if ct.RowsAffected() == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
 }
 
-func (r *UserRepo) SetGoogleID(ctx context.Context, userID int64, googleID string) error {
+func (r *UserRepo) ClearPassword(ctx context.Context, userID int64) error {
 	ct, err := r.pool.Exec(ctx, `
 		UPDATE users
-		SET google_id = $1
-		WHERE id = $2
-	`, googleID, userID)
+		SET password_hash = NULL
+		WHERE id = $1
+	`, userID)
 	if err != nil {
 		return err
 	}
@@ -84,15 +135,321 @@ func (r *UserRepo) SetGoogleID(ctx context.Context, userID int64, googleID strin
 	return nil
 }
 
-func (r *UserRepo) CreateOAuthUser(ctx context.Context, email, googleID string) (int64, error) {
+func (r *UserRepo) GetByIdentity(ctx context.Context, provider, subject string) (domain.User, error) {
+	return scanUser(r.pool.QueryRow(ctx, `
+		SELECT `+userColumns+`
+		FROM users u
+		JOIN user_identities ui ON ui.user_id = u.id
+		WHERE ui.provider = $1 AND ui.subject = $2
+	`, provider, subject))
+}
+
+func (r *UserRepo) ListIdentities(ctx context.Context, userID int64) ([]domain.UserIdentity, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT id, user_id, provider, subject, email, created_at
+		FROM user_identities
+		WHERE user_id = $1
+		ORDER BY created_at ASC, id ASC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []domain.UserIdentity{}
+	for rows.Next() {
+		var i domain.UserIdentity
+		if err := rows.Scan(&i.ID, &i.UserID, &i.Provider, &i.Subject, &i.Email, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, i)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	return out, nil
+}
+
+func (r *UserRepo) LinkIdentity(ctx context.Context, identity domain.UserIdentity) error {
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO user_identities(user_id, provider, subject, email)
+		VALUES ($1, $2, $3, $4)
+	`, identity.UserID, identity.Provider, identity.Subject, identity.Email)
+	if isUniqueViolation(err) {
+		return repository.ErrConflict
+	}
+	return err
+}
+
+func (r *UserRepo) CreateEmailUser(ctx context.Context, email string) (int64, error) {
 	var id int64
 	err := r.pool.QueryRow(ctx, `
-		INSERT INTO users(email, google_id)
-		VALUES ($1, $2)
+		INSERT INTO users(email)
+		VALUES ($1)
 		RETURNING id
-	`, email, googleID).Scan(&id)
+	`, email).Scan(&id)
+	if isUniqueViolation(err) {
+		return 0, repository.ErrConflict
+	}
 	if err != nil {
 		return 0, err
 	}
 	return id, nil
-}
\ No newline at end of file
+}
+
+func (r *UserRepo) CreateOAuthUser(ctx context.Context, email string, identity domain.UserIdentity) (int64, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var id int64
+	err = tx.QueryRow(ctx, `
+		INSERT INTO users(email)
+		VALUES ($1)
+		RETURNING id
+	`, email).Scan(&id)
+	if isUniqueViolation(err) {
+		return 0, repository.ErrConflict
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO user_identities(user_id, provider, subject, email)
+		VALUES ($1, $2, $3, $4)
+	`, id, identity.Provider, identity.Subject, identity.Email)
+	if isUniqueViolation(err) {
+		return 0, repository.ErrConflict
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *UserRepo) UnlinkIdentity(ctx context.Context, userID int64, provider string) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	// lock the user row so concurrent unlinks can't both pass the check below
+	var hasPassword bool
+	err = tx.QueryRow(ctx, `
+		SELECT password_hash IS NOT NULL
+		FROM users
+		WHERE id = $1
+		FOR UPDATE
+	`, userID).Scan(&hasPassword)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return repository.ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	var linked, others int
+	err = tx.QueryRow(ctx, `
+		SELECT
+			(SELECT COUNT(*) FROM user_identities WHERE user_id = $1 AND provider = $2),
+			(SELECT COUNT(*) FROM user_identities WHERE user_id = $1 AND provider <> $2)
+				+ (SELECT COUNT(*) FROM passkeys WHERE user_id = $1)
+	`, userID, provider).Scan(&linked, &others)
+	if err != nil {
+		return err
+	}
+	if linked == 0 {
+		return repository.ErrNotFound
+	}
+	if !hasPassword && others == 0 {
+		return repository.ErrConflict
+	}
+
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM user_identities
+		WHERE user_id = $1 AND provider = $2
+	`, userID, provider); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *UserRepo) Export(ctx context.Context, userID int64) (domain.UserExport, error) {
+	// one snapshot, so the parts of the export agree with each other
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return domain.UserExport{}, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	u, err := scanUser(tx.QueryRow(ctx, `
+		SELECT `+userColumns+`
+		FROM users u
+		WHERE u.id = $1
+	`, userID))
+	if err != nil {
+		return domain.UserExport{}, err
+	}
+	out := domain.UserExport{ExportedAt: time.Now().UTC(), Profile: u, HasPassword: u.PasswordHash != nil}
+
+	err = tx.QueryRow(ctx, `
+		SELECT EXISTS(SELECT 1 FROM user_totp WHERE user_id = $1 AND enabled_at IS NOT NULL)
+	`, userID).Scan(&out.TwoFactorEnabled)
+	if err != nil {
+		return domain.UserExport{}, err
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT r.name
+		FROM user_roles ur
+		JOIN roles r ON r.id = ur.role_id
+		WHERE ur.user_id = $1
+		ORDER BY r.name
+	`, userID)
+	if err != nil {
+		return domain.UserExport{}, err
+	}
+	if out.Roles, err = pgx.CollectRows(rows, pgx.RowTo[string]); err != nil {
+		return domain.UserExport{}, err
+	}
+
+	rows, err = tx.Query(ctx, `
+		SELECT provider, subject, email, created_at
+		FROM user_identities
+		WHERE user_id = $1
+		ORDER BY created_at ASC, id ASC
+	`, userID)
+	if err != nil {
+		return domain.UserExport{}, err
+	}
+	out.Identities, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.IdentityExport, error) {
+		var i domain.IdentityExport
+		err := row.Scan(&i.Provider, &i.Subject, &i.Email, &i.CreatedAt)
+		return i, err
+	})
+	if err != nil {
+		return domain.UserExport{}, err
+	}
+
+	rows, err = tx.Query(ctx, `
+		SELECT `+sessionColumns+`
+		FROM sessions
+		WHERE user_id = $1
+		ORDER BY created_at ASC
+	`, userID)
+	if err != nil {
+		return domain.UserExport{}, err
+	}
+	out.Sessions, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.Session, error) { return scanSession(row) })
+	if err != nil {
+		return domain.UserExport{}, err
+	}
+
+	rows, err = tx.Query(ctx, `
+		SELECT `+apiKeyColumns+`
+		FROM api_keys
+		WHERE user_id = $1
+		ORDER BY created_at ASC, id ASC
+	`, userID)
+	if err != nil {
+		return domain.UserExport{}, err
+	}
+	out.APIKeys, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.APIKey, error) { return scanAPIKey(row) })
+	if err != nil {
+		return domain.UserExport{}, err
+	}
+
+	rows, err = tx.Query(ctx, `
+		SELECT `+passkeyColumns+`
+		FROM passkeys
+		WHERE user_id = $1
+		ORDER BY created_at ASC, id ASC
+	`, userID)
+	if err != nil {
+		return domain.UserExport{}, err
+	}
+	out.Passkeys, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.Passkey, error) { return scanPasskey(row) })
+	if err != nil {
+		return domain.UserExport{}, err
+	}
+
+	rows, err = tx.Query(ctx, `
+		SELECT `+authEventColumns+`
+		FROM auth_events
+		WHERE user_id = $1
+		ORDER BY id ASC
+	`, userID)
+	if err != nil {
+		return domain.UserExport{}, err
+	}
+	out.AuthEvents, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.AuthEvent, error) { return scanAuthEvent(row) })
+	if err != nil {
+		return domain.UserExport{}, err
+	}
+
+	return out, nil
+}
+
+func (r *UserRepo) Delete(ctx context.Context, userID int64) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var email string
+	err = tx.QueryRow(ctx, `
+		SELECT email
+		FROM users
+		WHERE id = $1
+		FOR UPDATE
+	`, userID).Scan(&email)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return repository.ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	// The audit trail is kept but no longer says who or where; this has to run
+	// while the user row still exists.
+	if _, err := tx.Exec(ctx, `
+		UPDATE auth_events
+		SET user_id = NULL, email = NULL, ip = '', user_agent = ''
+		WHERE user_id = $1 OR email = $2
+	`, userID, email); err != nil {
+		return err
+	}
+
+	// Most of these would cascade; they are spelled out so every table holding
+	// personal data is visibly covered, including ones keyed by email.
+	for _, q := range []string{
+		`DELETE FROM sessions WHERE user_id = $1`,
+		`DELETE FROM api_keys WHERE user_id = $1`,
+		`DELETE FROM passkeys WHERE user_id = $1`,
+		`DELETE FROM user_identities WHERE user_id = $1`,
+		`DELETE FROM user_recovery_codes WHERE user_id = $1`,
+		`DELETE FROM user_totp WHERE user_id = $1`,
+		`DELETE FROM user_roles WHERE user_id = $1`,
+		`UPDATE inventory_adjustments SET actor_user_id = NULL WHERE actor_user_id = $1`,
+		`DELETE FROM users WHERE id = $1`,
+	} {
+		if _, err := tx.Exec(ctx, q, userID); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM magic_links WHERE email = $1`, email); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
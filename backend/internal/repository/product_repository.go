@@ -9,4 +9,43 @@ import (
 type ProductRepository interface {
 	GetByID(ctx context.Context, id int64) (domain.Product, error)
 	Search(ctx context.Context, params domain.SearchParams) ([]domain.ProductSummary, int64, error)
+	// AttributeFacets counts the products matching the search filters by
+	// attribute value, or gives the range of number attributes. Only Code,
+	// Type, Count, Values, Min and Max are filled in.
+	AttributeFacets(ctx context.Context, params domain.SearchParams) ([]domain.AttributeFacet, error)
+	// GetPrice is the product's price in currency: its own, its price list
+	// entry or a conversion at the exchange rates. ErrNotFound covers both a
+	// missing product and one without a price in currency.
+	GetPrice(ctx context.Context, id int64, currency string) (domain.Money, error)
+
+	// Create and Update write the product row, its images and its category
+	// links in one transaction. Update replaces images and categories.
+	// Both return ErrConflict when another product has the same name and
+	// description, and ErrInvalidReference for an unknown category.
+	Create(ctx context.Context, p domain.Product) (int64, error)
+	Update(ctx context.Context, p domain.Product) error
+	// Delete returns ErrStockRemaining while the product or one of its
+	// variants has stock on hand or reserved.
+	Delete(ctx context.Context, id int64) error
+
+	// SetPrice adds or replaces a price list entry; DeletePrice removes one.
+	// Both return ErrNotFound for an unknown product or, on delete, entry.
+	SetPrice(ctx context.Context, id int64, price domain.Money) error
+	DeletePrice(ctx context.Context, id int64, currency string) error
+
+	// VariantPrices maps each variant id of the product to its price in
+	// currency, converted at the same ratio as the product's price. Variants
+	// without a price in currency are missing from the map.
+	VariantPrices(ctx context.Context, id int64, currency string) (map[int64]domain.Money, error)
+	// ReplaceVariants upserts variants by SKU, deletes the product's other
+	// variants and rolls their prices and stock up onto the product. It
+	// returns ErrNotFound for an unknown product, ErrStockRemaining when a
+	// dropped variant still has stock or reservations and ErrConflict when a
+	// SKU belongs to another product or two variants share their options.
+	ReplaceVariants(ctx context.Context, id int64, variants []domain.Variant) error
+
+	// Export calls fn for every product matching the search filters, in id
+	// order, reading them from a cursor. Paging and sorting are ignored.
+	// It stops at the first error fn returns.
+	Export(ctx context.Context, params domain.SearchParams, fn func(domain.Product) error) error
 }
\ No newline at end of file
@@ -0,0 +1,14 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/soydoradesu/product_discovery/internal/domain"
+)
+
+type RoleRepository interface {
+	GetUserAccess(ctx context.Context, userID int64) (domain.Access, error)
+	// GrantRole and RevokeRole return ErrNotFound when the role doesn't exist.
+	GrantRole(ctx context.Context, userID int64, role string) error
+	RevokeRole(ctx context.Context, userID int64, role string) error
+}
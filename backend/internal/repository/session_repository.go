@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/soydoradesu/product_discovery/internal/domain"
+)
+
+type SessionRepository interface {
+	Create(ctx context.Context, s domain.Session) error
+	Get(ctx context.Context, id string) (domain.Session, error)
+	// ListActive returns the user's sessions that are neither revoked nor expired.
+	ListActive(ctx context.Context, userID int64) ([]domain.Session, error)
+	Touch(ctx context.Context, id string, at time.Time) error
+	// Revoke returns ErrNotFound unless the session belongs to userID and is active.
+	Revoke(ctx context.Context, userID int64, id string) error
+	// RevokeAllExcept revokes every active session of the user other than
+	// keepID (pass "" to revoke all) and returns how many were revoked.
+	RevokeAllExcept(ctx context.Context, userID int64, keepID string) (int64, error)
+}
@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/soydoradesu/product_discovery/internal/domain"
+)
+
+type TwoFactorRepository interface {
+	GetTOTP(ctx context.Context, userID int64) (domain.UserTOTP, error)
+	// SavePendingTOTP stores a secret that is not yet enabled, replacing any
+	// earlier unconfirmed enrollment.
+	SavePendingTOTP(ctx context.Context, userID int64, secret string) error
+	EnableTOTP(ctx context.Context, userID int64, step int64, recoveryCodeHashes []string) error
+	DeleteTOTP(ctx context.Context, userID int64) error
+
+	// AdvanceTOTPStep records step as used; it reports false if step (or a
+	// later one) was already used.
+	AdvanceTOTPStep(ctx context.Context, userID int64, step int64) (bool, error)
+	UseRecoveryCode(ctx context.Context, userID int64, codeHash string) (bool, error)
+
+	// RecordTOTPFailure counts a wrong code. The maxFailures-th in a row
+	// locks verification until lockUntil and restarts the count; it reports
+	// whether this failure set the lock.
+	RecordTOTPFailure(ctx context.Context, userID int64, maxFailures int, lockUntil time.Time) (bool, error)
+	// ResetTOTPFailures clears the count and any lock after an accepted code.
+	ResetTOTPFailures(ctx context.Context, userID int64) error
+}
@@ -9,9 +9,35 @@ import (
 type UserRepository interface {
 	GetByEmail(ctx context.Context, email string) (domain.User, error)
 	GetByID(ctx context.Context, id int64) (domain.User, error)
+	SetPasswordHash(ctx context.Context, userID int64, hash string) error
+	// CreateEmailUser creates an account with neither a password nor a linked
+	// identity, e.g. on first magic-link login. It returns ErrConflict if the
+	// email is taken.
+	CreateEmailUser(ctx context.Context, email string) (int64, error)
 
-	// OAuth
-	GetByGoogleID(ctx context.Context, googleID string) (domain.User, error)
-	SetGoogleID(ctx context.Context, userID int64, googleID string) error
-	CreateOAuthUser(ctx context.Context, email, googleID string) (int64, error)
+	// External login providers
+	GetByIdentity(ctx context.Context, provider, subject string) (domain.User, error)
+	ListIdentities(ctx context.Context, userID int64) ([]domain.UserIdentity, error)
+	// LinkIdentity returns ErrConflict if the identity or the provider is
+	// already linked.
+	LinkIdentity(ctx context.Context, identity domain.UserIdentity) error
+	CreateOAuthUser(ctx context.Context, email string, identity domain.UserIdentity) (int64, error)
+	// UnlinkIdentity returns ErrNotFound if the provider isn't linked and
+	// ErrConflict if it is the user's last way to log in.
+	UnlinkIdentity(ctx context.Context, userID int64, provider string) error
+
+	// Administration
+	List(ctx context.Context, params domain.UserListParams) ([]domain.User, int64, error)
+	// SetDisabled returns ErrNotFound if the user doesn't exist.
+	SetDisabled(ctx context.Context, userID int64, disabled bool) error
+	// ClearPassword removes the password so the user has to set a new one
+	// after logging in another way. It returns ErrNotFound if the user
+	// doesn't exist.
+	ClearPassword(ctx context.Context, userID int64) error
+
+	// Personal data
+	Export(ctx context.Context, userID int64) (domain.UserExport, error)
+	// Delete removes the user and everything stored about them in one
+	// transaction. It returns ErrNotFound if the user doesn't exist.
+	Delete(ctx context.Context, userID int64) error
 }
\ No newline at end of file
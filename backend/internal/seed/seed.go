@@ -2,13 +2,16 @@ package seed
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/rand"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/soydoradesu/product_discovery/internal/domain"
 	"github.com/soydoradesu/product_discovery/internal/service"
 )
 
@@ -16,6 +19,8 @@ type Options struct {
 	Users int
 	Products int
 	RandomSeed int64
+	// Currency of the seeded prices; defaults to IDR.
+	Currency string
 }
 
 func Run(ctx context.Context, pool *pgxpool.Pool, opt Options) error {
@@ -28,6 +33,9 @@ func Run(ctx context.Context, pool *pgxpool.Pool, opt Options) error {
 	if opt.RandomSeed == 0 {
 		opt.RandomSeed = 42
 	}
+	if opt.Currency == "" {
+		opt.Currency = "IDR"
+	}
 
 	rng := rand.New(rand.NewSource(opt.RandomSeed))
 
@@ -50,6 +58,10 @@ func Run(ctx context.Context, pool *pgxpool.Pool, opt Options) error {
 	if err != nil {
 		return err
 	}
+	brandIDs, err := ensureBrands(ctx, tx)
+	if err != nil {
+		return err
+	}
 
 	if userCount < int64(opt.Users) {
 		if err := seedUsers(ctx, tx, rng, opt.Users-int(userCount)); err != nil {
@@ -58,7 +70,7 @@ func Run(ctx context.Context, pool *pgxpool.Pool, opt Options) error {
 	}
 
 	if productCount < int64(opt.Products) {
-		if err := seedProducts(ctx, tx, rng, opt.Products-int(productCount), categoryIDs); err != nil {
+		if err := seedProducts(ctx, tx, rng, opt.Products-int(productCount), categoryIDs, brandIDs, opt.Currency); err != nil {
 			return err
 		}
 	}
@@ -101,6 +113,25 @@ func ensureCategories(ctx context.Context, tx pgx.Tx) ([]int64, error) {
 	return ids, nil
 }
 
+func ensureBrands(ctx context.Context, tx pgx.Tx) ([]int64, error) {
+	base := []string{
+		"Acme", "Northwind", "Globex", "Initech", "Umbrella",
+		"Soylent", "Stark", "Wayne", "Hooli", "Vandelay",
+	}
+	for _, name := range base {
+		_, err := tx.Exec(ctx, `INSERT INTO brands(name) VALUES($1) ON CONFLICT (lower(name)) DO NOTHING`, name)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	rows, err := tx.Query(ctx, `SELECT id FROM brands ORDER BY id ASC`)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[int64])
+}
+
 func seedUsers(ctx context.Context, tx pgx.Tx, rng *rand.Rand, n int) error {
 	// demo user always present
 	demoPass := "Password123!"
@@ -148,7 +179,7 @@ func seedUsers(ctx context.Context, tx pgx.Tx, rng *rand.Rand, n int) error {
 	return nil
 }
 
-func seedProducts(ctx context.Context, tx pgx.Tx, rng *rand.Rand, n int, categoryIDs []int64) error {
+func seedProducts(ctx context.Context, tx pgx.Tx, rng *rand.Rand, n int, categoryIDs, brandIDs []int64, currency string) error {
 	adjs := []string{"Ultra", "Pro", "Air", "Max", "Mini", "Prime", "Edge", "Nova", "Zen", "Core"}
 	nouns := []string{"Speaker", "Headphones", "Laptop", "Phone", "Mouse", "Keyboard", "Router", "SSD", "Camera", "Monitor"}
 
@@ -162,14 +193,24 @@ func seedProducts(ctx context.Context, tx pgx.Tx, rng *rand.Rand, n int, categor
 		inStock := rng.Intn(100) < 70 
 		createdAt := now.Add(-time.Duration(rng.Intn(180*24)) * time.Hour)
 
-		price := 10.0 + rng.Float64()*990.0 * 1000
+		// whole major units between 10 and 1,000,000
+		price, err := domain.ParseMoney(strconv.FormatInt(10+rng.Int63n(990_000), 10), currency)
+		if err != nil {
+			return err
+		}
+
+		// most products have a brand
+		var brandID *int64
+		if len(brandIDs) > 0 && rng.Intn(10) > 0 {
+			brandID = &brandIDs[rng.Intn(len(brandIDs))]
+		}
 
 		var productID int64
-		err := tx.QueryRow(ctx, `
-			INSERT INTO products(name, price, description, rating, in_stock, created_at)
-			VALUES ($1, $2, $3, $4, $5, $6)
+		err = tx.QueryRow(ctx, `
+			INSERT INTO products(name, price_minor, currency, description, rating, in_stock, created_at, brand_id)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 			RETURNING id
-		`, name, price, desc, rating, inStock, createdAt).Scan(&productID)
+		`, name, price.Amount, price.Currency, desc, rating, inStock, createdAt, brandID).Scan(&productID)
 		if err != nil {
 			return err
 		}
@@ -205,7 +246,73 @@ func seedProducts(ctx context.Context, tx pgx.Tx, rng *rand.Rand, n int, categor
 				return err
 			}
 		}
+
+		// about a quarter of the products come in several colors, which are
+		// stocked separately
+		if rng.Intn(4) == 0 {
+			if err := seedVariants(ctx, tx, rng, productID, price); err != nil {
+				return err
+			}
+		} else if err := seedStock(ctx, tx, rng, productID, nil, inStock); err != nil {
+			return err
+		}
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+func seedVariants(ctx context.Context, tx pgx.Tx, rng *rand.Rand, productID int64, base domain.Money) error {
+	colors := []string{"Black", "White", "Silver", "Blue"}
+	rng.Shuffle(len(colors), func(i, j int) { colors[i], colors[j] = colors[j], colors[i] })
+
+	for i, color := range colors[:2+rng.Intn(3)] {
+		// each further color costs 5% more
+		amount := base.Amount + base.Amount*int64(i)/20
+		inStock := rng.Intn(100) < 70
+		var variantID int64
+		err := tx.QueryRow(ctx, `
+			INSERT INTO product_variants(product_id, sku, options, price_minor, in_stock, position)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (sku) DO NOTHING
+			RETURNING id
+		`, productID, fmt.Sprintf("P%06d-%s", productID, strings.ToUpper(color[:3])), map[string]string{"color": color}, amount, inStock, i).Scan(&variantID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := seedStock(ctx, tx, rng, productID, &variantID, inStock); err != nil {
+			return err
+		}
+	}
+
+	// the same roll-up the product repository keeps
+	_, err := tx.Exec(ctx, `
+		UPDATE products p
+		SET price_minor = v.min_price, price_max_minor = v.max_price, in_stock = v.any_in_stock
+		FROM (
+			SELECT MIN(price_minor) AS min_price, MAX(price_minor) AS max_price, BOOL_OR(in_stock) AS any_in_stock
+			FROM product_variants
+			WHERE product_id = $1
+		) v
+		WHERE p.id = $1
+	`, productID)
+	return err
+}
+// seedStock gives an item a level in the default warehouse that agrees with
+// the in_stock it was seeded with.
+func seedStock(ctx context.Context, tx pgx.Tx, rng *rand.Rand, productID int64, variantID *int64, inStock bool) error {
+	onHand := 0
+	if inStock {
+		onHand = 1 + rng.Intn(50)
+	}
+	_, err := tx.Exec(ctx, `
+		INSERT INTO inventory_levels(product_id, variant_id, warehouse_id, on_hand)
+		SELECT $1, $2, id, $3
+		FROM warehouses
+		WHERE code = $4
+		ON CONFLICT ON CONSTRAINT inventory_levels_item_key DO NOTHING
+	`, productID, variantID, onHand, domain.DefaultWarehouse)
+	return err
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/soydoradesu/product_discovery/internal/domain"
+	"github.com/soydoradesu/product_discovery/internal/repository"
+)
+
+type AccessService struct {
+	Roles repository.RoleRepository
+	Users repository.UserRepository
+}
+
+func NewAccessService(roles repository.RoleRepository, users repository.UserRepository) *AccessService {
+	return &AccessService{Roles: roles, Users: users}
+}
+
+// Access is resolved on every request rather than baked into the session, so
+// granting or revoking a role takes effect immediately.
+func (s *AccessService) Access(ctx context.Context, userID int64) (domain.Access, error) {
+	return s.Roles.GetUserAccess(ctx, userID)
+}
+
+func (s *AccessService) GrantRole(ctx context.Context, email, role string) error {
+	userID, err := s.userIDByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+	if err := s.Roles.GrantRole(ctx, userID, strings.TrimSpace(role)); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrRoleNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *AccessService) RevokeRole(ctx context.Context, email, role string) error {
+	userID, err := s.userIDByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+	if err := s.Roles.RevokeRole(ctx, userID, strings.TrimSpace(role)); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrRoleNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *AccessService) userIDByEmail(ctx context.Context, email string) (int64, error) {
+	u, err := s.Users.GetByEmail(ctx, strings.TrimSpace(strings.ToLower(email)))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return 0, ErrUserNotFound
+		}
+		return 0, err
+	}
+	return u.ID, nil
+}
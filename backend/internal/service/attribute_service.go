@@ -0,0 +1,240 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/soydoradesu/product_discovery/internal/domain"
+	"github.com/soydoradesu/product_discovery/internal/repository"
+)
+
+const (
+	maxCategoryAttributes  = 50
+	maxAttributeLabelLen   = 100
+	maxAttributeUnitLen    = 20
+	maxAttributeEnumValues = 100
+	maxAttributeValueLen   = 200
+	maxFacetValues         = 50
+)
+
+// AttributeCodeRe is what an attribute may be called; search filters on
+// attributes with the same codes.
+var AttributeCodeRe = regexp.MustCompile(`^[a-z][a-z0-9_]{0,31}$`)
+
+type AttributeService struct {
+	Attributes repository.AttributeRepository
+	Categories repository.CategoryRepository
+}
+
+func NewAttributeService(attributes repository.AttributeRepository, categories repository.CategoryRepository) *AttributeService {
+	return &AttributeService{Attributes: attributes, Categories: categories}
+}
+
+// ForCategory lists the attributes products in the category can have,
+// including those inherited from its ancestors.
+func (s *AttributeService) ForCategory(ctx context.Context, categoryID int64) ([]domain.AttributeDefinition, error) {
+	if _, err := s.Categories.GetByID(ctx, categoryID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrCategoryNotFound
+		}
+		return nil, err
+	}
+	defs, err := s.Attributes.ForCategories(ctx, []int64{categoryID})
+	if err != nil {
+		return nil, err
+	}
+	return nearestDefinitions(defs), nil
+}
+
+// Replace sets the category's own attributes. A code has one type across
+// all categories, so filters on it mean the same everywhere. Field names in
+// a ValidationError are attributes[i].code, attributes[i].label,
+// attributes[i].type, attributes[i].unit, attributes[i].values and
+// attributes.
+func (s *AttributeService) Replace(ctx context.Context, categoryID int64, in []domain.AttributeDefinition) ([]domain.AttributeDefinition, error) {
+	if len(in) > maxCategoryAttributes {
+		return nil, &ValidationError{Fields: map[string]string{"attributes": fmt.Sprintf("must have at most %d entries", maxCategoryAttributes)}}
+	}
+	all, err := s.Attributes.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	types := map[string]string{}
+	for _, d := range all {
+		if d.CategoryID != categoryID {
+			types[d.Code] = d.Type
+		}
+	}
+
+	fields := map[string]string{}
+	defs := make([]domain.AttributeDefinition, len(in))
+	codes := map[string]bool{}
+	for i, d := range in {
+		prefix := fmt.Sprintf("attributes[%d].", i)
+		d.CategoryID = categoryID
+		d.Code = strings.ToLower(strings.TrimSpace(d.Code))
+		d.Label = strings.TrimSpace(d.Label)
+		d.Type = strings.ToLower(strings.TrimSpace(d.Type))
+		d.Unit = strings.TrimSpace(d.Unit)
+
+		switch {
+		case !AttributeCodeRe.MatchString(d.Code):
+			fields[prefix+"code"] = "must be a lowercase name of at most 32 letters, digits or underscores"
+		case codes[d.Code]:
+			fields[prefix+"code"] = "is listed twice"
+		}
+		codes[d.Code] = true
+		if d.Label == "" || utf8.RuneCountInString(d.Label) > maxAttributeLabelLen {
+			fields[prefix+"label"] = fmt.Sprintf("must be 1 to %d characters", maxAttributeLabelLen)
+		}
+		if utf8.RuneCountInString(d.Unit) > maxAttributeUnitLen {
+			fields[prefix+"unit"] = fmt.Sprintf("must be at most %d characters", maxAttributeUnitLen)
+		}
+
+		switch d.Type {
+		case domain.AttributeString, domain.AttributeNumber, domain.AttributeBoolean, domain.AttributeEnum:
+			if t, ok := types[d.Code]; ok && t != d.Type {
+				fields[prefix+"type"] = fmt.Sprintf("must be %s, as %s is in other categories", t, d.Code)
+			}
+		default:
+			fields[prefix+"type"] = "must be string, number, boolean or enum"
+		}
+
+		values, msg := normalizeEnumValues(d.Type, d.Values)
+		if msg != "" {
+			fields[prefix+"values"] = msg
+		}
+		d.Values = values
+		defs[i] = d
+	}
+	if len(fields) > 0 {
+		return nil, &ValidationError{Fields: fields}
+	}
+
+	if err := s.Attributes.Replace(ctx, categoryID, defs); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrCategoryNotFound
+		}
+		return nil, err
+	}
+	return s.ForCategory(ctx, categoryID)
+}
+
+func normalizeEnumValues(typ string, in []string) ([]string, string) {
+	if typ != domain.AttributeEnum {
+		if len(in) > 0 {
+			return nil, "are only allowed for enum attributes"
+		}
+		return nil, ""
+	}
+	if len(in) == 0 || len(in) > maxAttributeEnumValues {
+		return nil, fmt.Sprintf("must have 1 to %d entries", maxAttributeEnumValues)
+	}
+	out := make([]string, 0, len(in))
+	seen := map[string]bool{}
+	for _, v := range in {
+		v = strings.TrimSpace(v)
+		switch {
+		case v == "" || utf8.RuneCountInString(v) > maxAttributeValueLen:
+			return nil, fmt.Sprintf("must each be 1 to %d characters", maxAttributeValueLen)
+		case seen[strings.ToLower(v)]:
+			return nil, fmt.Sprintf("list %q twice", v)
+		}
+		seen[strings.ToLower(v)] = true
+		out = append(out, v)
+	}
+	return out, ""
+}
+
+// nearestDefinitions keeps the first definition of each code, which
+// ForCategories orders nearest category first.
+func nearestDefinitions(defs []domain.AttributeDefinition) []domain.AttributeDefinition {
+	out := make([]domain.AttributeDefinition, 0, len(defs))
+	seen := map[string]bool{}
+	for _, d := range defs {
+		if !seen[d.Code] {
+			seen[d.Code] = true
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// validateAttributeValues checks values against the definitions and returns
+// them with enum values spelled as defined.
+func validateAttributeValues(values map[string]any, defs []domain.AttributeDefinition) (map[string]any, map[string]string) {
+	byCode := map[string]domain.AttributeDefinition{}
+	for _, d := range defs {
+		byCode[d.Code] = d
+	}
+
+	out := make(map[string]any, len(values))
+	fields := map[string]string{}
+	for code, value := range values {
+		field := "attributes." + code
+		if value == nil {
+			continue
+		}
+		d, ok := byCode[code]
+		if !ok {
+			fields[field] = "is not an attribute of the product's categories"
+			continue
+		}
+		switch d.Type {
+		case domain.AttributeString:
+			s, ok := value.(string)
+			s = strings.TrimSpace(s)
+			if !ok || s == "" || utf8.RuneCountInString(s) > maxAttributeValueLen {
+				fields[field] = fmt.Sprintf("must be a string of 1 to %d characters", maxAttributeValueLen)
+				continue
+			}
+			out[code] = s
+		case domain.AttributeNumber:
+			n, ok := value.(float64)
+			if !ok || math.IsNaN(n) || math.IsInf(n, 0) {
+				fields[field] = "must be a number"
+				continue
+			}
+			out[code] = n
+		case domain.AttributeBoolean:
+			b, ok := value.(bool)
+			if !ok {
+				fields[field] = "must be true or false"
+				continue
+			}
+			out[code] = b
+		case domain.AttributeEnum:
+			s, _ := value.(string)
+			s = strings.TrimSpace(s)
+			matched := ""
+			for _, v := range d.Values {
+				if strings.EqualFold(v, s) {
+					matched = v
+				}
+			}
+			if matched == "" {
+				fields[field] = "must be one of " + strings.Join(d.Values, ", ")
+				continue
+			}
+			out[code] = matched
+		}
+	}
+	return out, fields
+}
+
+// productAttributes lists the values that have a definition, in the
+// definitions' order.
+func productAttributes(values map[string]any, defs []domain.AttributeDefinition) []domain.ProductAttribute {
+	var out []domain.ProductAttribute
+	for _, d := range defs {
+		if v, ok := values[d.Code]; ok {
+			out = append(out, domain.ProductAttribute{Code: d.Code, Label: d.Label, Type: d.Type, Unit: d.Unit, Value: v})
+		}
+	}
+	return out
+}
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"github.com/soydoradesu/product_discovery/internal/domain"
+	"github.com/soydoradesu/product_discovery/internal/repository"
+)
+
+const (
+	defaultAuthEventLimit = 50
+	maxAuthEventLimit     = 500
+)
+
+type AuditService struct {
+	Events repository.AuthEventRepository
+}
+
+func NewAuditService(events repository.AuthEventRepository) *AuditService {
+	return &AuditService{Events: events}
+}
+
+// Record stores an event. Auditing must never fail the request being
+// audited, so errors are only logged.
+func (s *AuditService) Record(ctx context.Context, e domain.AuthEvent) {
+	if e.Email != nil {
+		email := strings.TrimSpace(strings.ToLower(*e.Email))
+		e.Email = &email
+	}
+	if len(e.UserAgent) > 512 {
+		e.UserAgent = e.UserAgent[:512]
+	}
+	if err := s.Events.Insert(ctx, e); err != nil {
+		log.Printf("audit %s: %v", e.Type, err)
+	}
+}
+
+func (s *AuditService) List(ctx context.Context, f domain.AuthEventFilter) ([]domain.AuthEvent, error) {
+	if f.Limit <= 0 {
+		f.Limit = defaultAuthEventLimit
+	}
+	if f.Limit > maxAuthEventLimit {
+		f.Limit = maxAuthEventLimit
+	}
+	f.Email = strings.TrimSpace(strings.ToLower(f.Email))
+	return s.Events.List(ctx, f)
+}
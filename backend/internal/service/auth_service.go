@@ -3,17 +3,21 @@ package service
 import (
 	"context"
 	"errors"
+	"log"
 	"strings"
 
+	"github.com/soydoradesu/product_discovery/internal/domain"
 	"github.com/soydoradesu/product_discovery/internal/repository"
 )
 
 type AuthService struct {
-	Users repository.UserRepository
+	Users  repository.UserRepository
+	Policy PasswordPolicy
+	Hasher PasswordHasher
 }
 
 func NewAuthService(users repository.UserRepository) *AuthService {
-	return &AuthService{Users: users}
+	return &AuthService{Users: users, Policy: DefaultPasswordPolicy(), Hasher: DefaultPasswordHasher()}
 }
 
 func (s *AuthService) Login(ctx context.Context, email, password string) (int64, error) {
@@ -28,51 +32,195 @@ func (s *AuthService) Login(ctx context.Context, email, password string) (int64,
 	if u.PasswordHash == nil || *u.PasswordHash == "" {
 		return 0, ErrInvalidCredentials
 	}
-	if !CheckPassword(*u.PasswordHash, password) {
+	if !s.Hasher.Verify(*u.PasswordHash, password) {
 		return 0, ErrInvalidCredentials
 	}
+	// only revealed to someone who knows the password
+	if u.DisabledAt != nil {
+		return 0, ErrAccountDisabled
+	}
+
+	// upgrade hashes made with an older algorithm or weaker parameters while
+	// the plaintext is at hand; a failure here must not block the login
+	if s.Hasher.NeedsRehash(*u.PasswordHash) {
+		if hash, err := s.Hasher.Hash(password); err == nil {
+			if err := s.Users.SetPasswordHash(ctx, u.ID, hash); err != nil {
+				log.Printf("rehash password for user %d: %v", u.ID, err)
+			}
+		}
+	}
 
 	return u.ID, nil
 }
 
-func (s *AuthService) OAuthLogin(ctx context.Context, email, googleID string) (int64, error) {
+// OAuthLogin resolves an identity asserted by an external provider to a user,
+// linking it to an existing account with the same (verified) email or
+// creating a new account. The bool reports that the identity was linked to an
+// existing account.
+func (s *AuthService) OAuthLogin(ctx context.Context, provider, subject, email string) (int64, bool, error) {
 	email = strings.TrimSpace(strings.ToLower(email))
-	googleID = strings.TrimSpace(googleID)
+	subject = strings.TrimSpace(subject)
 
-	if email == "" || googleID == "" {
-		return 0, ErrInvalidCredentials
+	if provider == "" || email == "" || subject == "" {
+		return 0, false, ErrInvalidCredentials
 	}
 
-	// existing google account
-	u, err := s.Users.GetByGoogleID(ctx, googleID)
+	// existing linked account
+	u, err := s.Users.GetByIdentity(ctx, provider, subject)
 	if err == nil {
-		return u.ID, nil
+		if u.DisabledAt != nil {
+			return 0, false, ErrAccountDisabled
+		}
+		return u.ID, false, nil
 	}
 	if !errors.Is(err, repository.ErrNotFound) {
-		return 0, err
+		return 0, false, err
 	}
 
-	// existing email account then link with google ID
+	identity := domain.UserIdentity{Provider: provider, Subject: subject, Email: &email}
+
+	// existing email account then link the identity
 	u2, err := s.Users.GetByEmail(ctx, email)
 	if err == nil {
-		if u2.GoogleID != nil && *u2.GoogleID != "" && *u2.GoogleID != googleID {
-			return 0, ErrOAuthAccountConflict
+		if u2.DisabledAt != nil {
+			return 0, false, ErrAccountDisabled
 		}
-		if u2.GoogleID == nil || *u2.GoogleID == "" {
-			if err := s.Users.SetGoogleID(ctx, u2.ID, googleID); err != nil {
-				return 0, err
+		identity.UserID = u2.ID
+		if err := s.Users.LinkIdentity(ctx, identity); err != nil {
+			if errors.Is(err, repository.ErrConflict) {
+				return 0, false, ErrOAuthAccountConflict
 			}
+			return 0, false, err
 		}
-		return u2.ID, nil
+		return u2.ID, true, nil
 	}
 	if !errors.Is(err, repository.ErrNotFound) {
-		return 0, err
+		return 0, false, err
 	}
 
 	// new user
-	id, err := s.Users.CreateOAuthUser(ctx, email, googleID)
+	id, err := s.Users.CreateOAuthUser(ctx, email, identity)
 	if err != nil {
-		return 0, err
+		if errors.Is(err, repository.ErrConflict) {
+			return 0, false, ErrOAuthAccountConflict
+		}
+		return 0, false, err
+	}
+	return id, false, nil
+}
+
+// CheckUserActive fails with ErrAccountDisabled once an administrator has
+// disabled the user, so their existing sessions and API keys stop working.
+func (s *AuthService) CheckUserActive(ctx context.Context, userID int64) error {
+	u, err := s.Users.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	if u.DisabledAt != nil {
+		return ErrAccountDisabled
+	}
+	return nil
+}
+
+// VerifyPassword re-checks the password of an already signed-in user.
+func (s *AuthService) VerifyPassword(ctx context.Context, userID int64, password string) error {
+	u, err := s.Users.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	if u.PasswordHash == nil || *u.PasswordHash == "" || !s.Hasher.Verify(*u.PasswordHash, password) {
+		return ErrInvalidCredentials
+	}
+	return nil
+}
+
+func (s *AuthService) ListIdentities(ctx context.Context, userID int64) ([]domain.UserIdentity, error) {
+	return s.Users.ListIdentities(ctx, userID)
+}
+
+// LinkIdentity attaches an external identity to a signed-in user. Unlike
+// OAuthLogin it never matches by email: the user chose to link this account.
+func (s *AuthService) LinkIdentity(ctx context.Context, userID int64, provider, subject, email string) error {
+	identity := domain.UserIdentity{UserID: userID, Provider: provider, Subject: strings.TrimSpace(subject)}
+	if email = strings.TrimSpace(strings.ToLower(email)); email != "" {
+		identity.Email = &email
+	}
+	if identity.Subject == "" {
+		return ErrInvalidCredentials
+	}
+
+	if err := s.Users.LinkIdentity(ctx, identity); err != nil {
+		if errors.Is(err, repository.ErrConflict) {
+			return ErrOAuthAccountConflict
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *AuthService) UnlinkIdentity(ctx context.Context, userID int64, provider string) error {
+	if err := s.Users.UnlinkIdentity(ctx, userID, provider); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			return ErrIdentityNotFound
+		case errors.Is(err, repository.ErrConflict):
+			return ErrLastLoginMethod
+		}
+		return err
+	}
+	return nil
+}
+
+// ChangePassword replaces the user's password after checking the current one.
+// Accounts that only log in through a provider have no current password and
+// set their first one here, which also enables email login for them.
+func (s *AuthService) ChangePassword(ctx context.Context, userID int64, current, next string) error {
+	u, err := s.Users.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrUserNotFound
+		}
+		return err
 	}
-	return id, nil
-}
\ No newline at end of file
+
+	if u.PasswordHash != nil && *u.PasswordHash != "" {
+		if !s.Hasher.Verify(*u.PasswordHash, current) {
+			return ErrInvalidCredentials
+		}
+		if next == current {
+			return &PasswordPolicyError{Problems: []string{"must differ from the current password"}}
+		}
+	}
+	if err := s.Policy.Check(next, u.Email); err != nil {
+		return err
+	}
+
+	hash, err := s.Hasher.Hash(next)
+	if err != nil {
+		return err
+	}
+	return s.Users.SetPasswordHash(ctx, userID, hash)
+}
+
+func (s *AuthService) ExportAccount(ctx context.Context, userID int64) (domain.UserExport, error) {
+	out, err := s.Users.Export(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return domain.UserExport{}, ErrUserNotFound
+	}
+	return out, err
+}
+
+// DeleteAccount permanently removes the user and all their data.
+func (s *AuthService) DeleteAccount(ctx context.Context, userID int64) error {
+	err := s.Users.Delete(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrUserNotFound
+	}
+	return err
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/soydoradesu/product_discovery/internal/domain"
+	"github.com/soydoradesu/product_discovery/internal/repository"
+)
+
+const (
+	maxBrandNameLen = 100
+	maxBrandSlugLen = 100
+)
+
+type BrandService struct {
+	Brands repository.BrandRepository
+}
+
+func NewBrandService(brands repository.BrandRepository) *BrandService {
+	return &BrandService{Brands: brands}
+}
+
+func (s *BrandService) List(ctx context.Context) ([]domain.Brand, error) {
+	return s.Brands.List(ctx)
+}
+
+func (s *BrandService) GetByID(ctx context.Context, id int64) (domain.Brand, error) {
+	b, err := s.Brands.GetByID(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return domain.Brand{}, ErrBrandNotFound
+	}
+	return b, err
+}
+
+func (s *BrandService) Create(ctx context.Context, in domain.BrandInput) (domain.Brand, error) {
+	b, err := brandFromInput(0, in)
+	if err != nil {
+		return domain.Brand{}, err
+	}
+	created, err := s.Brands.Create(ctx, b)
+	if err != nil {
+		return domain.Brand{}, brandWriteError(err)
+	}
+	return created, nil
+}
+
+func (s *BrandService) Update(ctx context.Context, id int64, in domain.BrandInput) (domain.Brand, error) {
+	b, err := brandFromInput(id, in)
+	if err != nil {
+		return domain.Brand{}, err
+	}
+	updated, err := s.Brands.Update(ctx, b)
+	if err != nil {
+		return domain.Brand{}, brandWriteError(err)
+	}
+	return updated, nil
+}
+
+// Delete refuses brands products still have, with ErrBrandInUse.
+func (s *BrandService) Delete(ctx context.Context, id int64) error {
+	err := s.Brands.Delete(ctx, id)
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return ErrBrandNotFound
+	case errors.Is(err, repository.ErrConflict):
+		return ErrBrandInUse
+	}
+	return err
+}
+
+func brandWriteError(err error) error {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return ErrBrandNotFound
+	case errors.Is(err, repository.ErrConflict):
+		return ErrBrandExists
+	}
+	return err
+}
+
+func brandFromInput(id int64, in domain.BrandInput) (domain.Brand, error) {
+	b := domain.Brand{
+		ID:   id,
+		Name: strings.TrimSpace(in.Name),
+		Slug: strings.TrimSpace(in.Slug),
+	}
+
+	fields := map[string]string{}
+	switch {
+	case b.Name == "":
+		fields["name"] = "is required"
+	case utf8.RuneCountInString(b.Name) > maxBrandNameLen:
+		fields["name"] = fmt.Sprintf("must be at most %d characters", maxBrandNameLen)
+	}
+	switch {
+	case b.Slug == "":
+	case len(b.Slug) > maxBrandSlugLen:
+		fields["slug"] = fmt.Sprintf("must be at most %d characters", maxBrandSlugLen)
+	case !categorySlugRe.MatchString(b.Slug):
+		fields["slug"] = "must be lowercase letters and digits separated by single hyphens"
+	}
+
+	if len(fields) > 0 {
+		return domain.Brand{}, &ValidationError{Fields: fields}
+	}
+	return b, nil
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/soydoradesu/product_discovery/internal/domain"
+)
+
+// exportRecord is one exported product. It uses the import columns, so an
+// export can be fed back to the importer.
+type exportRecord struct {
+	ID          int64     `json:"id"`
+	ExternalKey *string   `json:"externalKey"`
+	Name        string    `json:"name"`
+	Price       string    `json:"price"`
+	Currency    string    `json:"currency"`
+	Description string    `json:"description"`
+	Rating      float64   `json:"rating"`
+	InStock     bool      `json:"inStock"`
+	CreatedAt   time.Time `json:"createdAt"`
+	Images      []string  `json:"images"`
+	Categories  []string  `json:"categories"`
+}
+
+var exportColumns = []string{"id", "externalKey", "name", "price", "currency", "description", "rating", "inStock", "createdAt", "images", "categories"}
+
+func newExportRecord(p domain.Product) exportRecord {
+	rec := exportRecord{
+		ID:          p.ID,
+		ExternalKey: p.ExternalKey,
+		Name:        p.Name,
+		Price:       p.Price.Decimal(),
+		Currency:    p.Price.Currency,
+		Description: p.Description,
+		Rating:      p.Rating,
+		InStock:     p.InStock,
+		CreatedAt:   p.CreatedAt,
+		Images:      make([]string, len(p.Images)),
+		Categories:  make([]string, len(p.Categories)),
+	}
+	for i, img := range p.Images {
+		rec.Images[i] = img.URL
+	}
+	for i, c := range p.Categories {
+		rec.Categories[i] = c.Name
+	}
+	return rec
+}
+
+func (rec exportRecord) csvRow() []string {
+	key := ""
+	if rec.ExternalKey != nil {
+		key = *rec.ExternalKey
+	}
+	return []string{
+		strconv.FormatInt(rec.ID, 10),
+		key,
+		rec.Name,
+		rec.Price,
+		rec.Currency,
+		rec.Description,
+		strconv.FormatFloat(rec.Rating, 'f', -1, 64),
+		strconv.FormatBool(rec.InStock),
+		rec.CreatedAt.UTC().Format(time.RFC3339),
+		strings.Join(rec.Images, importListSeparator),
+		strings.Join(rec.Categories, importListSeparator),
+	}
+}
+
+// IsExportFormat reports whether Export can write format.
+func IsExportFormat(format string) bool {
+	switch format {
+	case CatalogFormatCSV, CatalogFormatNDJSON, CatalogFormatJSON:
+		return true
+	}
+	return false
+}
+
+// Export writes every product matching the search filters to w as they are
+// read. Paging and sorting in params are ignored; products come in id order.
+// Once writing has started an error leaves w truncated.
+func (s *ProductService) Export(ctx context.Context, w io.Writer, format string, params domain.SearchParams) error {
+	if !IsExportFormat(format) {
+		return fmt.Errorf("%w: %q", ErrInvalidExportFormat, format)
+	}
+	params.Q = strings.TrimSpace(params.Q)
+
+	bw := bufio.NewWriter(w)
+	var write func(exportRecord) error
+	finish := func() error { return nil }
+
+	switch format {
+	case CatalogFormatCSV:
+		cw := csv.NewWriter(bw)
+		if err := cw.Write(exportColumns); err != nil {
+			return err
+		}
+		write = func(rec exportRecord) error { return cw.Write(rec.csvRow()) }
+		finish = func() error {
+			cw.Flush()
+			return cw.Error()
+		}
+	case CatalogFormatNDJSON:
+		enc := json.NewEncoder(bw)
+		write = func(rec exportRecord) error { return enc.Encode(rec) }
+	case CatalogFormatJSON:
+		if _, err := bw.WriteString("["); err != nil {
+			return err
+		}
+		enc := json.NewEncoder(bw)
+		first := true
+		write = func(rec exportRecord) error {
+			if !first {
+				if _, err := bw.WriteString(","); err != nil {
+					return err
+				}
+			}
+			first = false
+			return enc.Encode(rec)
+		}
+		finish = func() error {
+			_, err := bw.WriteString("]\n")
+			return err
+		}
+	}
+
+	err := s.Products.Export(ctx, params, func(p domain.Product) error {
+		return write(newExportRecord(p))
+	})
+	if err != nil {
+		return err
+	}
+	if err := finish(); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,364 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/soydoradesu/product_discovery/internal/domain"
+	"github.com/soydoradesu/product_discovery/internal/repository"
+)
+
+// Catalog file formats. Imports read CSV and NDJSON; exports also write a
+// single JSON array.
+const (
+	CatalogFormatCSV    = "csv"
+	CatalogFormatNDJSON = "ndjson"
+	CatalogFormatJSON   = "json"
+)
+
+const (
+	maxExternalKeyLen   = 200
+	maxCategoryNameLen  = 100
+	maxImportLineBytes  = 1 << 20
+	importListSeparator = "|"
+)
+
+// importColumns are the CSV header names and NDJSON keys an import accepts.
+// CSV cells for images and categories hold several values separated by "|".
+// The id and createdAt columns of an export are accepted and ignored, so an
+// export can be imported again.
+var importColumns = []string{"id", "externalKey", "name", "price", "currency", "description", "rating", "inStock", "createdAt", "images", "categories"}
+
+type CatalogImportService struct {
+	Imports repository.CatalogImportRepository
+	// MaxRows caps the rows in one file; 0 means no limit.
+	MaxRows int
+	// Currency applies to rows without a currency column.
+	Currency string
+}
+
+func NewCatalogImportService(imports repository.CatalogImportRepository) *CatalogImportService {
+	return &CatalogImportService{Imports: imports, MaxRows: 50000, Currency: DefaultCurrency}
+}
+
+// importRecord is one parsed line before validation. Absent values are nil.
+type importRecord struct {
+	ExternalKey *string      `json:"externalKey"`
+	Name        *string      `json:"name"`
+	Price       *json.Number `json:"price"`
+	Currency    *string      `json:"currency"`
+	Description *string      `json:"description"`
+	Rating      *float64     `json:"rating"`
+	InStock     *bool        `json:"inStock"`
+	Images      *[]string    `json:"images"`
+	Categories  *[]string    `json:"categories"`
+
+	// ignored, see importColumns
+	ID        json.RawMessage `json:"id"`
+	CreatedAt json.RawMessage `json:"createdAt"`
+}
+
+// parsedImport is a catalog file split into records, each with its line
+// number, and the rows that could not be parsed at all.
+type parsedImport struct {
+	records []importRecord
+	lines   []int
+	errs    []domain.ImportRowError
+	rows    int
+}
+
+// Import parses a catalog file and upserts its valid rows. Invalid rows are
+// reported in the summary and skipped; an error is returned only when the
+// file as a whole cannot be read.
+func (s *CatalogImportService) Import(ctx context.Context, r io.Reader, format string, dryRun bool) (domain.ImportSummary, error) {
+	var (
+		parsed parsedImport
+		err    error
+	)
+	switch strings.ToLower(format) {
+	case CatalogFormatCSV:
+		parsed, err = parseImportCSV(r)
+	case CatalogFormatNDJSON:
+		parsed, err = parseImportNDJSON(r)
+	default:
+		return domain.ImportSummary{}, fmt.Errorf("%w: unsupported format %q", ErrInvalidImportFile, format)
+	}
+	if err != nil {
+		return domain.ImportSummary{}, err
+	}
+	if s.MaxRows > 0 && parsed.rows > s.MaxRows {
+		return domain.ImportSummary{}, fmt.Errorf("%w: more than %d rows", ErrInvalidImportFile, s.MaxRows)
+	}
+
+	rows, rowErrs := validateImportRecords(parsed.records, parsed.lines, s.Currency)
+	errs := append(parsed.errs, rowErrs...)
+
+	summary, err := s.Imports.Import(ctx, rows, dryRun)
+	if err != nil {
+		return domain.ImportSummary{}, err
+	}
+
+	invalid := map[int]bool{}
+	for _, e := range errs {
+		invalid[e.Line] = true
+	}
+	summary.Errors = append(summary.Errors, errs...)
+	sort.SliceStable(summary.Errors, func(i, j int) bool { return summary.Errors[i].Line < summary.Errors[j].Line })
+	summary.DryRun = dryRun
+	summary.Rows = parsed.rows
+	summary.Skipped += len(invalid)
+	if summary.Errors == nil {
+		summary.Errors = []domain.ImportRowError{}
+	}
+	return summary, nil
+}
+
+func parseImportCSV(r io.Reader) (parsedImport, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+
+	var out parsedImport
+	header, err := cr.Read()
+	if err == io.EOF {
+		return out, nil
+	}
+	if err != nil {
+		return out, fmt.Errorf("%w: %w", ErrInvalidImportFile, err)
+	}
+	index := map[string]int{}
+	for i, name := range header {
+		name = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+		if !isImportColumn(name) {
+			return out, fmt.Errorf("%w: unknown column %q", ErrInvalidImportFile, name)
+		}
+		index[name] = i
+	}
+
+	for {
+		cells, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		out.rows++
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			out.errs = append(out.errs, domain.ImportRowError{Line: parseErr.StartLine, Message: parseErr.Err.Error()})
+			continue
+		}
+		if err != nil {
+			return out, err
+		}
+		line, _ := cr.FieldPos(0)
+
+		cell := func(name string) (string, bool) {
+			i, ok := index[name]
+			if !ok || i >= len(cells) {
+				return "", false
+			}
+			v := strings.TrimSpace(cells[i])
+			return v, v != ""
+		}
+
+		var rec importRecord
+		var rowErrs []domain.ImportRowError
+		if v, ok := cell("externalKey"); ok {
+			rec.ExternalKey = &v
+		}
+		if v, ok := cell("name"); ok {
+			rec.Name = &v
+		}
+		if v, ok := cell("description"); ok {
+			rec.Description = &v
+		}
+		if v, ok := cell("price"); ok {
+			price := json.Number(v)
+			rec.Price = &price
+		}
+		if v, ok := cell("currency"); ok {
+			rec.Currency = &v
+		}
+		if v, ok := cell("rating"); ok {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				rowErrs = append(rowErrs, domain.ImportRowError{Line: line, Field: "rating", Message: "must be a number"})
+			} else {
+				rec.Rating = &f
+			}
+		}
+		if v, ok := cell("inStock"); ok {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				rowErrs = append(rowErrs, domain.ImportRowError{Line: line, Field: "inStock", Message: "must be true or false"})
+			} else {
+				rec.InStock = &b
+			}
+		}
+		if v, ok := cell("images"); ok {
+			images := splitImportList(v)
+			rec.Images = &images
+		}
+		if v, ok := cell("categories"); ok {
+			categories := splitImportList(v)
+			rec.Categories = &categories
+		}
+
+		if len(rowErrs) > 0 {
+			out.errs = append(out.errs, rowErrs...)
+			continue
+		}
+		out.records = append(out.records, rec)
+		out.lines = append(out.lines, line)
+	}
+	return out, nil
+}
+
+func parseImportNDJSON(r io.Reader) (parsedImport, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), maxImportLineBytes)
+
+	var out parsedImport
+	line := 0
+	for sc.Scan() {
+		line++
+		raw := strings.TrimSpace(sc.Text())
+		if raw == "" {
+			continue
+		}
+		out.rows++
+		dec := json.NewDecoder(strings.NewReader(raw))
+		dec.DisallowUnknownFields()
+		var rec importRecord
+		if err := dec.Decode(&rec); err != nil {
+			out.errs = append(out.errs, domain.ImportRowError{Line: line, Message: "invalid json: " + err.Error()})
+			continue
+		}
+		out.records = append(out.records, rec)
+		out.lines = append(out.lines, line)
+	}
+	if err := sc.Err(); err != nil {
+		return out, fmt.Errorf("%w: line %d: %w", ErrInvalidImportFile, line+1, err)
+	}
+	return out, nil
+}
+
+// validateImportRecords applies the admin product rules to each record and
+// rejects keys or name/description pairs repeated within the file.
+func validateImportRecords(records []importRecord, lines []int, currency string) ([]domain.ImportRow, []domain.ImportRowError) {
+	var (
+		rows    []domain.ImportRow
+		errs    []domain.ImportRowError
+		keys    = map[string]int{}
+		content = map[[2]string]int{}
+	)
+	for i, rec := range records {
+		line := lines[i]
+		var rowErrs []domain.ImportRowError
+		fail := func(field, msg string) {
+			rowErrs = append(rowErrs, domain.ImportRowError{Line: line, Field: field, Message: msg})
+		}
+
+		key := ""
+		if rec.ExternalKey != nil {
+			key = strings.TrimSpace(*rec.ExternalKey)
+		}
+		switch {
+		case key == "":
+			fail("externalKey", "is required")
+		case utf8.RuneCountInString(key) > maxExternalKeyLen:
+			fail("externalKey", fmt.Sprintf("must be at most %d characters", maxExternalKeyLen))
+		case keys[key] != 0:
+			fail("externalKey", fmt.Sprintf("duplicates line %d", keys[key]))
+		}
+
+		var price *domain.MoneyInput
+		if rec.Price != nil {
+			price = &domain.MoneyInput{Amount: rec.Price.String()}
+			if rec.Currency != nil {
+				price.Currency = strings.ToUpper(strings.TrimSpace(*rec.Currency))
+			}
+		}
+		p, err := applyProductInput(domain.Product{}, domain.ProductInput{
+			Name:        rec.Name,
+			Price:       price,
+			Description: rec.Description,
+			Rating:      rec.Rating,
+			InStock:     rec.InStock,
+			ImageURLs:   rec.Images,
+		}, true, currency)
+		var invalid *ValidationError
+		if errors.As(err, &invalid) {
+			fields := make([]string, 0, len(invalid.Fields))
+			for field := range invalid.Fields {
+				fields = append(fields, field)
+			}
+			sort.Strings(fields)
+			for _, field := range fields {
+				fail(field, invalid.Fields[field])
+			}
+		}
+
+		var categories []string
+		if rec.Categories != nil {
+			seen := map[string]bool{}
+			for _, name := range *rec.Categories {
+				name = strings.TrimSpace(name)
+				switch {
+				case name == "":
+					fail("categories", "must not contain empty names")
+				case utf8.RuneCountInString(name) > maxCategoryNameLen:
+					fail("categories", fmt.Sprintf("names must be at most %d characters", maxCategoryNameLen))
+				case !seen[name]:
+					seen[name] = true
+					categories = append(categories, name)
+				}
+			}
+		}
+
+		if err == nil {
+			pair := [2]string{p.Name, p.Description}
+			if first := content[pair]; first != 0 {
+				fail("name", fmt.Sprintf("same name and description as line %d", first))
+			}
+		}
+
+		if len(rowErrs) > 0 {
+			errs = append(errs, rowErrs...)
+			continue
+		}
+		keys[key] = line
+		content[[2]string{p.Name, p.Description}] = line
+		rows = append(rows, domain.ImportRow{Line: line, ExternalKey: key, Product: p, Categories: categories})
+	}
+	return rows, errs
+}
+
+func isImportColumn(name string) bool {
+	for _, c := range importColumns {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+func splitImportList(v string) []string {
+	var out []string
+	for _, part := range strings.Split(v, importListSeparator) {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	if out == nil {
+		out = []string{}
+	}
+	return out
+}
@@ -2,11 +2,24 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/soydoradesu/product_discovery/internal/domain"
 	"github.com/soydoradesu/product_discovery/internal/repository"
 )
 
+const (
+	maxCategorySlugLen        = 100
+	maxCategoryDescriptionLen = 2000
+)
+
+var categorySlugRe = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
 type CategoryService struct {
 	Categories repository.CategoryRepository
 }
@@ -17,4 +30,139 @@ func NewCategoryService(categories repository.CategoryRepository) *CategoryServi
 
 func (s *CategoryService) List(ctx context.Context) ([]domain.Category, error) {
 	return s.Categories.List(ctx)
-}
\ No newline at end of file
+}
+
+func (s *CategoryService) GetByID(ctx context.Context, id int64) (domain.Category, error) {
+	c, err := s.Categories.GetByID(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return domain.Category{}, ErrCategoryNotFound
+	}
+	return c, err
+}
+
+// Tree nests every category under its parent, siblings ordered by sort order
+// and then name.
+func (s *CategoryService) Tree(ctx context.Context) ([]domain.CategoryNode, error) {
+	categories, err := s.Categories.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := s.Categories.ProductCounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	children := map[int64][]domain.Category{}
+	known := map[int64]bool{}
+	for _, c := range categories {
+		known[c.ID] = true
+	}
+	var roots []domain.Category
+	for _, c := range categories {
+		if c.ParentID == nil || !known[*c.ParentID] {
+			roots = append(roots, c)
+			continue
+		}
+		children[*c.ParentID] = append(children[*c.ParentID], c)
+	}
+
+	var build func(level []domain.Category) []domain.CategoryNode
+	build = func(level []domain.Category) []domain.CategoryNode {
+		sort.SliceStable(level, func(i, j int) bool {
+			if level[i].SortOrder != level[j].SortOrder {
+				return level[i].SortOrder < level[j].SortOrder
+			}
+			return level[i].Name < level[j].Name
+		})
+		nodes := make([]domain.CategoryNode, len(level))
+		for i, c := range level {
+			nodes[i] = domain.CategoryNode{Category: c, ProductCount: counts[c.ID], Children: build(children[c.ID])}
+		}
+		return nodes
+	}
+	return build(roots), nil
+}
+
+func (s *CategoryService) Create(ctx context.Context, in domain.CategoryInput) (domain.Category, error) {
+	c, err := categoryFromInput(0, in)
+	if err != nil {
+		return domain.Category{}, err
+	}
+	created, err := s.Categories.Create(ctx, c)
+	if err != nil {
+		return domain.Category{}, categoryWriteError(err)
+	}
+	return created, nil
+}
+
+func (s *CategoryService) Update(ctx context.Context, id int64, in domain.CategoryInput) (domain.Category, error) {
+	c, err := categoryFromInput(id, in)
+	if err != nil {
+		return domain.Category{}, err
+	}
+	updated, err := s.Categories.Update(ctx, c)
+	if err != nil {
+		return domain.Category{}, categoryWriteError(err)
+	}
+	return updated, nil
+}
+
+func (s *CategoryService) Delete(ctx context.Context, id int64) error {
+	err := s.Categories.Delete(ctx, id)
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return ErrCategoryNotFound
+	case errors.Is(err, repository.ErrConflict):
+		return ErrCategoryHasChildren
+	}
+	return err
+}
+
+func categoryWriteError(err error) error {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return ErrCategoryNotFound
+	case errors.Is(err, repository.ErrConflict):
+		return ErrCategoryExists
+	case errors.Is(err, repository.ErrInvalidReference):
+		return &ValidationError{Fields: map[string]string{"parentId": "must be an existing category outside this one's subtree"}}
+	}
+	return err
+}
+
+func categoryFromInput(id int64, in domain.CategoryInput) (domain.Category, error) {
+	c := domain.Category{
+		ID:          id,
+		Name:        strings.TrimSpace(in.Name),
+		Slug:        strings.TrimSpace(in.Slug),
+		ParentID:    in.ParentID,
+		SortOrder:   in.SortOrder,
+		Description: strings.TrimSpace(in.Description),
+	}
+
+	fields := map[string]string{}
+	switch {
+	case c.Name == "":
+		fields["name"] = "is required"
+	case utf8.RuneCountInString(c.Name) > maxCategoryNameLen:
+		fields["name"] = fmt.Sprintf("must be at most %d characters", maxCategoryNameLen)
+	}
+	switch {
+	case c.Slug == "":
+	case len(c.Slug) > maxCategorySlugLen:
+		fields["slug"] = fmt.Sprintf("must be at most %d characters", maxCategorySlugLen)
+	case !categorySlugRe.MatchString(c.Slug):
+		fields["slug"] = "must be lowercase letters and digits separated by single hyphens"
+	}
+	if c.ParentID != nil && (*c.ParentID <= 0 || *c.ParentID == id) {
+		fields["parentId"] = "must be an existing category outside this one's subtree"
+	}
+	if utf8.RuneCountInString(c.Description) > maxCategoryDescriptionLen {
+		fields["description"] = fmt.Sprintf("must be at most %d characters", maxCategoryDescriptionLen)
+	}
+
+	if len(fields) > 0 {
+		return domain.Category{}, &ValidationError{Fields: fields}
+	}
+	return c, nil
+}
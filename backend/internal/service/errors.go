@@ -6,5 +6,51 @@ var (
 	ErrInvalidCredentials = errors.New("invalid credentials")
 	ErrUserNotFound = errors.New("user not found")
 	ErrProductNotFound = errors.New("product not found")
+	ErrProductExists = errors.New("a product with this name and description already exists")
+	ErrProductHasStock = errors.New("product still has stock or reservations")
+	ErrInvalidImportFile = errors.New("invalid import file")
+	ErrInvalidExportFormat = errors.New("unsupported export format")
+	ErrNoPriceInCurrency = errors.New("product has no price in this currency")
+	ErrPriceNotFound = errors.New("price list entry not found")
+	ErrExchangeRateNotFound = errors.New("exchange rate not found")
+	ErrInvalidRatesFile = errors.New("invalid exchange rates file")
+	ErrVariantExists = errors.New("a variant SKU is already used by another product")
+	ErrVariantHasStock = errors.New("a removed variant still has stock or reservations")
+	ErrInsufficientStock = errors.New("not enough stock available")
+	ErrReservationNotFound = errors.New("reservation not found")
+	ErrReservationReleased = errors.New("reservation already released")
+	ErrWarehouseExists = errors.New("a warehouse with this code already exists")
+
+	ErrCategoryNotFound = errors.New("category not found")
+	ErrCategoryExists = errors.New("a category with this name or slug already exists")
+	ErrCategoryHasChildren = errors.New("category still has subcategories")
+	ErrBrandNotFound = errors.New("brand not found")
+	ErrBrandExists = errors.New("a brand with this name or slug already exists")
+	ErrBrandInUse = errors.New("brand is still used by products")
 	ErrOAuthAccountConflict = errors.New("oauth account conflict")
+	ErrIdentityNotFound = errors.New("identity not linked")
+	ErrLastLoginMethod = errors.New("cannot remove the last login method")
+	ErrAccountDisabled = errors.New("account disabled")
+
+	ErrTwoFactorNotEnrolled = errors.New("two-factor enrollment not started")
+	ErrTwoFactorNotEnabled = errors.New("two-factor authentication not enabled")
+	ErrTwoFactorAlreadyEnabled = errors.New("two-factor authentication already enabled")
+	ErrInvalidTwoFactorCode = errors.New("invalid two-factor code")
+	ErrTwoFactorLocked = errors.New("too many incorrect two-factor codes")
+
+	ErrRoleNotFound = errors.New("role not found")
+
+	ErrInvalidAPIKey = errors.New("invalid api key")
+	ErrInvalidAPIKeyInput = errors.New("invalid api key input")
+	ErrAPIKeyNotFound = errors.New("api key not found")
+
+	ErrInvalidSession = errors.New("invalid session")
+	ErrSessionNotFound = errors.New("session not found")
+
+	ErrInvalidEmail = errors.New("invalid email")
+	ErrInvalidMagicLink = errors.New("invalid or expired login link")
+
+	ErrInvalidPasskey = errors.New("passkey verification failed")
+	ErrPasskeyExists = errors.New("passkey already registered")
+	ErrPasskeyNotFound = errors.New("passkey not found")
 )
\ No newline at end of file
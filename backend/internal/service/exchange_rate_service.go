@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/soydoradesu/product_discovery/internal/domain"
+	"github.com/soydoradesu/product_discovery/internal/repository"
+)
+
+// Exchange rate files are CSV with a base,quote,rate header or a JSON array
+// of {"base","quote","rate"} objects.
+const (
+	RatesFormatCSV  = "csv"
+	RatesFormatJSON = "json"
+)
+
+// maxExchangeRates bounds a rates file; there are fewer than 200 currencies.
+const maxExchangeRates = 10000
+
+// exchangeRateRe matches what fits exchange_rates.rate, NUMERIC(24, 10).
+var exchangeRateRe = regexp.MustCompile(`^[0-9]{1,14}(\.[0-9]{1,10})?$`)
+
+type ExchangeRateService struct {
+	Rates repository.ExchangeRateRepository
+}
+
+func NewExchangeRateService(rates repository.ExchangeRateRepository) *ExchangeRateService {
+	return &ExchangeRateService{Rates: rates}
+}
+
+func (s *ExchangeRateService) List(ctx context.Context) ([]domain.ExchangeRate, error) {
+	return s.Rates.List(ctx)
+}
+
+// Replace validates every rate, then swaps the whole table for them. Field
+// names in a ValidationError are rates[i].base, rates[i].quote and
+// rates[i].rate.
+func (s *ExchangeRateService) Replace(ctx context.Context, rates []domain.ExchangeRate) ([]domain.ExchangeRate, error) {
+	if len(rates) > maxExchangeRates {
+		return nil, &ValidationError{Fields: map[string]string{"rates": fmt.Sprintf("must have at most %d entries", maxExchangeRates)}}
+	}
+	fields := map[string]string{}
+	seen := map[[2]string]bool{}
+	for i := range rates {
+		prefix := fmt.Sprintf("rates[%d].", i)
+		normalizeExchangeRate(&rates[i])
+		for name, msg := range validateExchangeRate(rates[i]) {
+			fields[prefix+name] = msg
+		}
+		pair := [2]string{rates[i].Base, rates[i].Quote}
+		if seen[pair] {
+			fields[prefix+"quote"] = "pair is listed twice"
+		}
+		seen[pair] = true
+	}
+	if len(fields) > 0 {
+		return nil, &ValidationError{Fields: fields}
+	}
+
+	if err := s.Rates.Replace(ctx, rates); err != nil {
+		return nil, err
+	}
+	return s.Rates.List(ctx)
+}
+
+// Set adds or updates the rate of one pair.
+func (s *ExchangeRateService) Set(ctx context.Context, rate domain.ExchangeRate) (domain.ExchangeRate, error) {
+	normalizeExchangeRate(&rate)
+	if fields := validateExchangeRate(rate); len(fields) > 0 {
+		return domain.ExchangeRate{}, &ValidationError{Fields: fields}
+	}
+	return s.Rates.Upsert(ctx, rate)
+}
+
+func (s *ExchangeRateService) Delete(ctx context.Context, base, quote string) error {
+	err := s.Rates.Delete(ctx, strings.ToUpper(base), strings.ToUpper(quote))
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrExchangeRateNotFound
+	}
+	return err
+}
+
+func normalizeExchangeRate(rate *domain.ExchangeRate) {
+	rate.Base = strings.ToUpper(strings.TrimSpace(rate.Base))
+	rate.Quote = strings.ToUpper(strings.TrimSpace(rate.Quote))
+	rate.Rate = strings.TrimSpace(rate.Rate)
+}
+
+func validateExchangeRate(rate domain.ExchangeRate) map[string]string {
+	fields := map[string]string{}
+	if !domain.IsCurrencyCode(rate.Base) {
+		fields["base"] = "must be an ISO 4217 code"
+	}
+	if !domain.IsCurrencyCode(rate.Quote) {
+		fields["quote"] = "must be an ISO 4217 code"
+	} else if rate.Quote == rate.Base {
+		fields["quote"] = "must differ from base"
+	}
+	if !exchangeRateRe.MatchString(rate.Rate) {
+		fields["rate"] = "must be a decimal with at most 14 digits before and 10 after the point"
+	} else if strings.Trim(rate.Rate, "0.") == "" {
+		fields["rate"] = "must be positive"
+	}
+	return fields
+}
+
+// ParseExchangeRates reads a rates file. It only checks the file's shape;
+// Replace validates the rates themselves.
+func ParseExchangeRates(r io.Reader, format string) ([]domain.ExchangeRate, error) {
+	switch format {
+	case RatesFormatCSV:
+		return parseRatesCSV(r)
+	case RatesFormatJSON:
+		var raw []struct {
+			Base  string      `json:"base"`
+			Quote string      `json:"quote"`
+			Rate  json.Number `json:"rate"`
+		}
+		dec := json.NewDecoder(r)
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&raw); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidRatesFile, err)
+		}
+		rates := make([]domain.ExchangeRate, len(raw))
+		for i, v := range raw {
+			rates[i] = domain.ExchangeRate{Base: v.Base, Quote: v.Quote, Rate: v.Rate.String()}
+		}
+		return rates, nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported format %q", ErrInvalidRatesFile, format)
+	}
+}
+
+func parseRatesCSV(r io.Reader) ([]domain.ExchangeRate, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRatesFile, err)
+	}
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+	if strings.Join(header, ",") != "base,quote,rate" {
+		return nil, fmt.Errorf("%w: header must be base,quote,rate", ErrInvalidRatesFile)
+	}
+
+	var rates []domain.ExchangeRate
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidRatesFile, err)
+		}
+		rates = append(rates, domain.ExchangeRate{Base: rec[0], Quote: rec[1], Rate: rec[2]})
+	}
+	return rates, nil
+}
@@ -0,0 +1,212 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/soydoradesu/product_discovery/internal/domain"
+	"github.com/soydoradesu/product_discovery/internal/repository"
+)
+
+const (
+	// maxStockQuantity bounds a single quantity so sums stay far from overflow.
+	maxStockQuantity      = 1_000_000
+	maxAdjustmentReason   = 500
+	maxReservationRef     = 100
+	maxWarehouseNameLen   = 100
+	defaultInventoryLimit = 50
+	maxInventoryLimit     = 500
+)
+
+var warehouseCodeRe = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+type InventoryService struct {
+	Inventory repository.InventoryRepository
+	Products  repository.ProductRepository
+}
+
+func NewInventoryService(inventory repository.InventoryRepository, products repository.ProductRepository) *InventoryService {
+	return &InventoryService{Inventory: inventory, Products: products}
+}
+
+func (s *InventoryService) ListWarehouses(ctx context.Context) ([]domain.Warehouse, error) {
+	return s.Inventory.ListWarehouses(ctx)
+}
+
+func (s *InventoryService) CreateWarehouse(ctx context.Context, code, name string) (domain.Warehouse, error) {
+	w := domain.Warehouse{Code: strings.ToLower(strings.TrimSpace(code)), Name: strings.TrimSpace(name)}
+	fields := map[string]string{}
+	if !warehouseCodeRe.MatchString(w.Code) {
+		fields["code"] = "must be at most 32 lowercase letters, digits, dashes or underscores"
+	}
+	if w.Name == "" || utf8.RuneCountInString(w.Name) > maxWarehouseNameLen {
+		fields["name"] = fmt.Sprintf("must be 1 to %d characters", maxWarehouseNameLen)
+	}
+	if len(fields) > 0 {
+		return domain.Warehouse{}, &ValidationError{Fields: fields}
+	}
+
+	w, err := s.Inventory.CreateWarehouse(ctx, w)
+	if errors.Is(err, repository.ErrConflict) {
+		return domain.Warehouse{}, ErrWarehouseExists
+	}
+	return w, err
+}
+
+func (s *InventoryService) ProductStock(ctx context.Context, productID int64) (domain.ProductStock, error) {
+	stock, err := s.Inventory.ProductStock(ctx, productID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return domain.ProductStock{}, ErrProductNotFound
+	}
+	return stock, err
+}
+
+func (s *InventoryService) SetLowStockThreshold(ctx context.Context, productID int64, threshold int) (domain.ProductStock, error) {
+	if threshold < 0 || threshold > maxStockQuantity {
+		return domain.ProductStock{}, &ValidationError{Fields: map[string]string{"lowStockThreshold": fmt.Sprintf("must be between 0 and %d", maxStockQuantity)}}
+	}
+	if err := s.Inventory.SetLowStockThreshold(ctx, productID, threshold); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return domain.ProductStock{}, ErrProductNotFound
+		}
+		return domain.ProductStock{}, err
+	}
+	return s.ProductStock(ctx, productID)
+}
+
+// LowStock lists tracked items at or below their product's threshold, the
+// least available first.
+func (s *InventoryService) LowStock(ctx context.Context, limit int) ([]domain.LowStockItem, error) {
+	return s.Inventory.LowStock(ctx, clampInventoryLimit(limit))
+}
+
+// Adjust changes on-hand stock and records who did it and why. Field names
+// in a ValidationError are variantId, warehouse, delta, onHand and reason.
+func (s *InventoryService) Adjust(ctx context.Context, in domain.StockAdjustmentInput) (domain.StockAdjustment, error) {
+	in.Warehouse = normalizeWarehouse(in.Warehouse)
+	in.Reason = strings.TrimSpace(in.Reason)
+
+	fields := map[string]string{}
+	switch {
+	case in.OnHand != nil && in.Delta != 0:
+		fields["onHand"] = "cannot be combined with delta"
+	case in.OnHand != nil && (*in.OnHand < 0 || *in.OnHand > maxStockQuantity):
+		fields["onHand"] = fmt.Sprintf("must be between 0 and %d", maxStockQuantity)
+	case in.OnHand == nil && in.Delta == 0:
+		fields["delta"] = "must not be zero"
+	case in.Delta < -maxStockQuantity || in.Delta > maxStockQuantity:
+		fields["delta"] = fmt.Sprintf("must be between -%d and %d", maxStockQuantity, maxStockQuantity)
+	}
+	if in.Reason == "" || utf8.RuneCountInString(in.Reason) > maxAdjustmentReason {
+		fields["reason"] = fmt.Sprintf("must be 1 to %d characters", maxAdjustmentReason)
+	}
+	if len(fields) > 0 {
+		return domain.StockAdjustment{}, &ValidationError{Fields: fields}
+	}
+	if err := s.checkItem(ctx, in.ProductID, in.VariantID); err != nil {
+		return domain.StockAdjustment{}, err
+	}
+
+	adj, err := s.Inventory.Adjust(ctx, in)
+	return adj, inventoryWriteError(err)
+}
+
+// Adjustments is a product's adjustment log, newest first.
+func (s *InventoryService) Adjustments(ctx context.Context, productID int64, beforeID int64, limit int) ([]domain.StockAdjustment, error) {
+	return s.Inventory.Adjustments(ctx, productID, beforeID, clampInventoryLimit(limit))
+}
+
+// Reserve holds stock back from sale. Field names in a ValidationError are
+// variantId, warehouse, quantity and reference.
+func (s *InventoryService) Reserve(ctx context.Context, in domain.ReservationInput) (domain.Reservation, error) {
+	in.Warehouse = strings.ToLower(strings.TrimSpace(in.Warehouse))
+	in.Reference = strings.TrimSpace(in.Reference)
+
+	fields := map[string]string{}
+	if in.Quantity <= 0 || in.Quantity > maxStockQuantity {
+		fields["quantity"] = fmt.Sprintf("must be between 1 and %d", maxStockQuantity)
+	}
+	if utf8.RuneCountInString(in.Reference) > maxReservationRef {
+		fields["reference"] = fmt.Sprintf("must be at most %d characters", maxReservationRef)
+	}
+	if len(fields) > 0 {
+		return domain.Reservation{}, &ValidationError{Fields: fields}
+	}
+	if err := s.checkItem(ctx, in.ProductID, in.VariantID); err != nil {
+		return domain.Reservation{}, err
+	}
+
+	res, err := s.Inventory.Reserve(ctx, in)
+	return res, inventoryWriteError(err)
+}
+
+func (s *InventoryService) Release(ctx context.Context, id int64) (domain.Reservation, error) {
+	res, err := s.Inventory.Release(ctx, id)
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return domain.Reservation{}, ErrReservationNotFound
+	case errors.Is(err, repository.ErrConflict):
+		return domain.Reservation{}, ErrReservationReleased
+	}
+	return res, err
+}
+
+// checkItem makes sure stock is kept per variant for products with variants
+// and per product for the others.
+func (s *InventoryService) checkItem(ctx context.Context, productID int64, variantID *int64) error {
+	p, err := s.Products.GetByID(ctx, productID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrProductNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case len(p.Variants) == 0 && variantID != nil:
+		return &ValidationError{Fields: map[string]string{"variantId": "must be empty for a product without variants"}}
+	case len(p.Variants) > 0 && variantID == nil:
+		return &ValidationError{Fields: map[string]string{"variantId": "is required for a product with variants"}}
+	case variantID != nil:
+		for _, v := range p.Variants {
+			if v.ID == *variantID {
+				return nil
+			}
+		}
+		return &ValidationError{Fields: map[string]string{"variantId": "is not a variant of this product"}}
+	}
+	return nil
+}
+
+func inventoryWriteError(err error) error {
+	switch {
+	case errors.Is(err, repository.ErrConflict):
+		return ErrInsufficientStock
+	case errors.Is(err, repository.ErrInvalidReference):
+		// the product and variant were checked, so it is the warehouse
+		return &ValidationError{Fields: map[string]string{"warehouse": "is not a known warehouse"}}
+	}
+	return err
+}
+
+func normalizeWarehouse(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	if code == "" {
+		return domain.DefaultWarehouse
+	}
+	return code
+}
+
+func clampInventoryLimit(limit int) int {
+	if limit <= 0 {
+		return defaultInventoryLimit
+	}
+	if limit > maxInventoryLimit {
+		return maxInventoryLimit
+	}
+	return limit
+}
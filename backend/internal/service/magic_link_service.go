@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"log"
+	netmail "net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/soydoradesu/product_discovery/internal/auth"
+	"github.com/soydoradesu/product_discovery/internal/mail"
+	"github.com/soydoradesu/product_discovery/internal/repository"
+)
+
+// At most magicLinkLimit links are sent to one address per magicLinkWindow.
+const (
+	magicLinkLimit  = 5
+	magicLinkWindow = 15 * time.Minute
+)
+
+// MagicLinkService emails single-use login links. Links are tokens signed
+// with the session keyring; the server-side record they name is consumed on
+// first use.
+type MagicLinkService struct {
+	Links  repository.MagicLinkRepository
+	Users  repository.UserRepository
+	Mailer mail.Mailer
+	Keys   *auth.Keyring
+
+	TTL time.Duration
+	// VerifyURL is the absolute URL of GET /api/auth/magic-link/verify.
+	VerifyURL string
+	// AllowSignup creates an account the first time an unknown address logs
+	// in; otherwise links are only sent to existing accounts.
+	AllowSignup bool
+}
+
+func NewMagicLinkService(links repository.MagicLinkRepository, users repository.UserRepository, mailer mail.Mailer, keys *auth.Keyring) *MagicLinkService {
+	return &MagicLinkService{Links: links, Users: users, Mailer: mailer, Keys: keys, TTL: 15 * time.Minute}
+}
+
+// Request sends a login link to email. It reports success whether or not a
+// link was sent, so it can't be used to find out which addresses have
+// accounts.
+func (s *MagicLinkService) Request(ctx context.Context, email string) error {
+	email = strings.TrimSpace(strings.ToLower(email))
+	if addr, err := netmail.ParseAddress(email); err != nil || addr.Address != email {
+		return ErrInvalidEmail
+	}
+
+	if !s.AllowSignup {
+		if _, err := s.Users.GetByEmail(ctx, email); err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return nil
+			}
+			return err
+		}
+	}
+
+	n, err := s.Links.CountSince(ctx, email, time.Now().Add(-magicLinkWindow))
+	if err != nil {
+		return err
+	}
+	if n >= magicLinkLimit {
+		log.Printf("magic link: rate limited for %s", email)
+		return nil
+	}
+
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	id := base64.RawURLEncoding.EncodeToString(b)
+	if err := s.Links.Create(ctx, id, email, time.Now().Add(s.TTL)); err != nil {
+		return err
+	}
+	token, err := auth.SignMagicLink(s.Keys, email, id, s.TTL)
+	if err != nil {
+		return err
+	}
+
+	link := s.VerifyURL + "?token=" + url.QueryEscape(token)
+	return s.Mailer.Send(ctx, mail.Message{
+		To:      email,
+		Subject: "Your login link",
+		Text: "Use this link to log in. It works once and expires in " + s.TTL.String() + ".\n\n" +
+			link + "\n\nIf you didn't ask for it, you can ignore this email.\n",
+	})
+}
+
+// Consume redeems a link and returns the user it logs in.
+func (s *MagicLinkService) Consume(ctx context.Context, token string) (int64, error) {
+	claims, err := auth.VerifyChallenge(s.Keys, token, auth.PurposeMagicLink)
+	if err != nil || claims.ID == "" {
+		return 0, ErrInvalidMagicLink
+	}
+
+	email, err := s.Links.Consume(ctx, claims.ID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return 0, ErrInvalidMagicLink
+		}
+		return 0, err
+	}
+	if email != claims.Subject {
+		return 0, ErrInvalidMagicLink
+	}
+
+	u, err := s.Users.GetByEmail(ctx, email)
+	if err == nil {
+		if u.DisabledAt != nil {
+			return 0, ErrAccountDisabled
+		}
+		return u.ID, nil
+	}
+	if !errors.Is(err, repository.ErrNotFound) {
+		return 0, err
+	}
+	if !s.AllowSignup {
+		return 0, ErrInvalidMagicLink
+	}
+
+	id, err := s.Users.CreateEmailUser(ctx, email)
+	if errors.Is(err, repository.ErrConflict) {
+		// created concurrently, e.g. by a provider login
+		u, err := s.Users.GetByEmail(ctx, email)
+		if err != nil {
+			return 0, err
+		}
+		return u.ID, nil
+	}
+	return id, err
+}
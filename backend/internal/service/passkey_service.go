@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/soydoradesu/product_discovery/internal/auth"
+	"github.com/soydoradesu/product_discovery/internal/domain"
+	"github.com/soydoradesu/product_discovery/internal/repository"
+	"github.com/soydoradesu/product_discovery/internal/webauthn"
+)
+
+const (
+	passkeyCeremonyTTL = 5 * time.Minute
+	maxPasskeyName     = 64
+)
+
+// PasskeyService registers passkeys and logs users in with them. The
+// challenge of each ceremony travels to the browser and back in a signed
+// token, so nothing is stored until a ceremony succeeds.
+type PasskeyService struct {
+	Passkeys repository.PasskeyRepository
+	Users    repository.UserRepository
+	RP       webauthn.RelyingParty
+	Keys     *auth.Keyring
+}
+
+func NewPasskeyService(passkeys repository.PasskeyRepository, users repository.UserRepository, rp webauthn.RelyingParty, keys *auth.Keyring) *PasskeyService {
+	return &PasskeyService{Passkeys: passkeys, Users: users, RP: rp, Keys: keys}
+}
+
+// userHandle is the WebAuthn user id. It's the account id rather than
+// anything personal like the email.
+func userHandle(userID int64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(userID))
+	return b
+}
+
+func (s *PasskeyService) BeginRegistration(ctx context.Context, userID int64) (webauthn.CreationOptions, string, error) {
+	u, err := s.Users.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return webauthn.CreationOptions{}, "", ErrUserNotFound
+		}
+		return webauthn.CreationOptions{}, "", err
+	}
+	existing, err := s.Passkeys.ListByUser(ctx, userID)
+	if err != nil {
+		return webauthn.CreationOptions{}, "", err
+	}
+
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		return webauthn.CreationOptions{}, "", err
+	}
+	token, err := auth.SignCeremony(s.Keys, userID, auth.PurposePasskeyRegister, challenge, passkeyCeremonyTTL)
+	if err != nil {
+		return webauthn.CreationOptions{}, "", err
+	}
+
+	exclude := make([]webauthn.CredentialDescriptor, 0, len(existing))
+	for _, p := range existing {
+		exclude = append(exclude, descriptor(p))
+	}
+	user := webauthn.User{Handle: userHandle(userID), Name: u.Email, DisplayName: u.Email}
+	return s.RP.CreationOptions(challenge, user, exclude), token, nil
+}
+
+func (s *PasskeyService) FinishRegistration(ctx context.Context, userID int64, token, name string, resp webauthn.AttestationResponse) (domain.Passkey, error) {
+	claims, err := auth.VerifyChallenge(s.Keys, token, auth.PurposePasskeyRegister)
+	if err != nil || claims.UserID != userID {
+		return domain.Passkey{}, ErrInvalidPasskey
+	}
+	cred, err := s.RP.VerifyRegistration(resp, claims.ID)
+	if err != nil {
+		log.Printf("passkey registration for user %d: %v", userID, err)
+		return domain.Passkey{}, ErrInvalidPasskey
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = "Passkey"
+	}
+	if r := []rune(name); len(r) > maxPasskeyName {
+		name = string(r[:maxPasskeyName])
+	}
+
+	p, err := s.Passkeys.Create(ctx, domain.Passkey{
+		UserID:       userID,
+		CredentialID: cred.ID,
+		PublicKey:    cred.PublicKey,
+		SignCount:    cred.SignCount,
+		AAGUID:       cred.AAGUID,
+		Transports:   resp.Response.Transports,
+		Name:         name,
+	})
+	if errors.Is(err, repository.ErrConflict) {
+		return domain.Passkey{}, ErrPasskeyExists
+	}
+	return p, err
+}
+
+// BeginLogin starts a login without asking who the user is; the browser
+// offers the passkeys it has for this site.
+func (s *PasskeyService) BeginLogin(ctx context.Context) (webauthn.RequestOptions, string, error) {
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		return webauthn.RequestOptions{}, "", err
+	}
+	token, err := auth.SignCeremony(s.Keys, 0, auth.PurposePasskeyLogin, challenge, passkeyCeremonyTTL)
+	if err != nil {
+		return webauthn.RequestOptions{}, "", err
+	}
+	return s.RP.RequestOptions(challenge, nil), token, nil
+}
+
+// FinishLogin verifies the assertion and returns the user it logs in.
+func (s *PasskeyService) FinishLogin(ctx context.Context, token string, resp webauthn.AssertionResponse) (int64, error) {
+	claims, err := auth.VerifyChallenge(s.Keys, token, auth.PurposePasskeyLogin)
+	if err != nil {
+		return 0, ErrInvalidPasskey
+	}
+	credID, err := resp.CredentialID()
+	if err != nil || len(credID) == 0 {
+		return 0, ErrInvalidPasskey
+	}
+	p, err := s.Passkeys.GetByCredentialID(ctx, credID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return 0, ErrInvalidPasskey
+		}
+		return 0, err
+	}
+	if handle, err := resp.UserHandle(); err != nil || (handle != nil && string(handle) != string(userHandle(p.UserID))) {
+		return 0, ErrInvalidPasskey
+	}
+
+	signCount, err := s.RP.VerifyAssertion(resp, claims.ID, webauthn.Credential{ID: p.CredentialID, PublicKey: p.PublicKey, SignCount: p.SignCount})
+	if err != nil {
+		log.Printf("passkey login for user %d: %v", p.UserID, err)
+		return 0, ErrInvalidPasskey
+	}
+
+	u, err := s.Users.GetByID(ctx, p.UserID)
+	if err != nil {
+		return 0, err
+	}
+	if u.DisabledAt != nil {
+		return 0, ErrAccountDisabled
+	}
+	if err := s.Passkeys.RecordUse(ctx, p.ID, signCount, time.Now()); err != nil {
+		return 0, err
+	}
+	return p.UserID, nil
+}
+
+func (s *PasskeyService) List(ctx context.Context, userID int64) ([]domain.Passkey, error) {
+	return s.Passkeys.ListByUser(ctx, userID)
+}
+
+func (s *PasskeyService) Delete(ctx context.Context, userID, id int64) error {
+	if err := s.Passkeys.Delete(ctx, userID, id); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			return ErrPasskeyNotFound
+		case errors.Is(err, repository.ErrConflict):
+			return ErrLastLoginMethod
+		}
+		return err
+	}
+	return nil
+}
+
+func descriptor(p domain.Passkey) webauthn.CredentialDescriptor {
+	return webauthn.CredentialDescriptor{
+		Type:       "public-key",
+		ID:         base64.RawURLEncoding.EncodeToString(p.CredentialID),
+		Transports: p.Transports,
+	}
+}
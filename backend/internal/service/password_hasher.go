@@ -0,0 +1,176 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Supported password hashing algorithms. Stored hashes carry their own
+// algorithm and parameters, so every algorithm here can always be verified;
+// the hasher's Algorithm only decides what new hashes use.
+const (
+	HashArgon2id = "argon2id"
+	HashBcrypt   = "bcrypt"
+)
+
+type Argon2Params struct {
+	MemoryKiB   uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLen     uint32
+	KeyLen      uint32
+}
+
+type PasswordHasher struct {
+	Algorithm  string
+	BcryptCost int
+	Argon2     Argon2Params
+}
+
+// DefaultPasswordHasher uses Argon2id with the OWASP minimum parameters.
+func DefaultPasswordHasher() PasswordHasher {
+	return PasswordHasher{
+		Algorithm:  HashArgon2id,
+		BcryptCost: bcrypt.DefaultCost,
+		Argon2: Argon2Params{
+			MemoryKiB:   19 * 1024,
+			Iterations:  2,
+			Parallelism: 1,
+			SaltLen:     16,
+			KeyLen:      32,
+		},
+	}
+}
+
+var errMalformedHash = errors.New("malformed password hash")
+
+func (h PasswordHasher) Hash(password string) (string, error) {
+	switch h.Algorithm {
+	case HashBcrypt:
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), h.bcryptCost())
+		if err != nil {
+			return "", err
+		}
+		return string(hash), nil
+	case HashArgon2id:
+		p := h.argon2Params()
+		salt := make([]byte, p.SaltLen)
+		if _, err := rand.Read(salt); err != nil {
+			return "", err
+		}
+		key := argon2.IDKey([]byte(password), salt, p.Iterations, p.MemoryKiB, p.Parallelism, p.KeyLen)
+		return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+			argon2.Version, p.MemoryKiB, p.Iterations, p.Parallelism,
+			base64.RawStdEncoding.EncodeToString(salt),
+			base64.RawStdEncoding.EncodeToString(key)), nil
+	}
+	return "", fmt.Errorf("unknown password hash algorithm %q", h.Algorithm)
+}
+
+// Verify checks password against a hash made by any supported algorithm.
+func (h PasswordHasher) Verify(hash, password string) bool {
+	if isBcryptHash(hash) {
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+	}
+	p, salt, key, err := decodeArgon2id(hash)
+	if err != nil {
+		return false
+	}
+	got := argon2.IDKey([]byte(password), salt, p.Iterations, p.MemoryKiB, p.Parallelism, uint32(len(key)))
+	return subtle.ConstantTimeCompare(got, key) == 1
+}
+
+// NeedsRehash reports whether hash is weaker than what the hasher makes: a
+// bcrypt hash when the hasher uses Argon2id, or a hash of the same algorithm
+// with a lower cost, memory or iteration count. Stronger hashes are kept, so
+// lowering the settings or switching to bcrypt never downgrades them.
+func (h PasswordHasher) NeedsRehash(hash string) bool {
+	if isBcryptHash(hash) {
+		if h.Algorithm == HashArgon2id {
+			return true
+		}
+		cost, err := bcrypt.Cost([]byte(hash))
+		return err == nil && cost < h.bcryptCost()
+	}
+	p, _, _, err := decodeArgon2id(hash)
+	if err != nil || h.Algorithm != HashArgon2id {
+		return false
+	}
+	want := h.argon2Params()
+	return p.MemoryKiB < want.MemoryKiB || p.Iterations < want.Iterations
+}
+
+// bcryptCost mirrors bcrypt's own fallback for out-of-range costs so
+// NeedsRehash agrees with what Hash produced.
+func (h PasswordHasher) bcryptCost() int {
+	if h.BcryptCost < bcrypt.MinCost || h.BcryptCost > bcrypt.MaxCost {
+		return bcrypt.DefaultCost
+	}
+	return h.BcryptCost
+}
+
+// argon2Params fills unset (zero) parameters with the defaults; argon2 panics
+// on zero iterations or parallelism.
+func (h PasswordHasher) argon2Params() Argon2Params {
+	p, def := h.Argon2, DefaultPasswordHasher().Argon2
+	if p.MemoryKiB == 0 {
+		p.MemoryKiB = def.MemoryKiB
+	}
+	if p.Iterations == 0 {
+		p.Iterations = def.Iterations
+	}
+	if p.Parallelism == 0 {
+		p.Parallelism = def.Parallelism
+	}
+	if p.SaltLen == 0 {
+		p.SaltLen = def.SaltLen
+	}
+	if p.KeyLen == 0 {
+		p.KeyLen = def.KeyLen
+	}
+	return p
+}
+
+func isBcryptHash(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "$2y$")
+}
+
+// decodeArgon2id parses the PHC string format
+// $argon2id$v=19$m=<KiB>,t=<iterations>,p=<parallelism>$<salt>$<key>.
+func decodeArgon2id(hash string) (Argon2Params, []byte, []byte, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != HashArgon2id {
+		return Argon2Params{}, nil, nil, errMalformedHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return Argon2Params{}, nil, nil, errMalformedHash
+	}
+	var p Argon2Params
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.MemoryKiB, &p.Iterations, &p.Parallelism); err != nil {
+		return Argon2Params{}, nil, nil, errMalformedHash
+	}
+	if p.MemoryKiB == 0 || p.Iterations == 0 || p.Parallelism == 0 {
+		return Argon2Params{}, nil, nil, errMalformedHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return Argon2Params{}, nil, nil, errMalformedHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return Argon2Params{}, nil, nil, errMalformedHash
+	}
+	p.SaltLen = uint32(len(salt))
+	p.KeyLen = uint32(len(key))
+	return p, salt, key, nil
+}
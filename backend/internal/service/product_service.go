@@ -3,18 +3,33 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"sort"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/soydoradesu/product_discovery/internal/domain"
 	"github.com/soydoradesu/product_discovery/internal/repository"
 )
 
+// DefaultCurrency is the catalog currency when none is configured.
+const DefaultCurrency = "IDR"
+
 type ProductService struct {
 	Products repository.ProductRepository
+	// Currency is used for admin writes that give a price without one.
+	Currency string
+	// Attributes, when set, defines the attributes products can have;
+	// without it products take no attribute values.
+	Attributes repository.AttributeRepository
+	// Brands, when set, is checked for the brand of admin writes.
+	Brands repository.BrandRepository
 }
 
 func NewProductService(products repository.ProductRepository) *ProductService {
-	return &ProductService{Products: products}
+	return &ProductService{Products: products, Currency: DefaultCurrency}
 }
 
 func (s *ProductService) GetByID(ctx context.Context, id int64) (domain.Product, error) {
@@ -25,10 +40,54 @@ func (s *ProductService) GetByID(ctx context.Context, id int64) (domain.Product,
 		}
 		return domain.Product{}, err
 	}
+	if len(p.AttributeValues) > 0 {
+		defs, err := s.attributeDefinitions(ctx, p.Categories)
+		if err != nil {
+			return domain.Product{}, err
+		}
+		p.Attributes = productAttributes(p.AttributeValues, defs)
+	}
+	return p, nil
+}
+
+// GetByIDInCurrency is GetByID with the price in currency, taken from the
+// price list or converted at the exchange rates. An empty currency keeps the
+// product's own price.
+func (s *ProductService) GetByIDInCurrency(ctx context.Context, id int64, currency string) (domain.Product, error) {
+	p, err := s.GetByID(ctx, id)
+	if err != nil || currency == "" || currency == p.Price.Currency {
+		return p, err
+	}
+	price, err := s.Products.GetPrice(ctx, id, currency)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return domain.Product{}, ErrNoPriceInCurrency
+		}
+		return domain.Product{}, err
+	}
+	p.Price = price
+
+	if len(p.Variants) > 0 {
+		prices, err := s.Products.VariantPrices(ctx, id, currency)
+		if err != nil {
+			return domain.Product{}, err
+		}
+		variants := make([]domain.Variant, 0, len(p.Variants))
+		for _, v := range p.Variants {
+			if price, ok := prices[v.ID]; ok {
+				v.Price = price
+				variants = append(variants, v)
+			}
+		}
+		p.Variants = variants
+	}
 	return p, nil
 }
 
 func (s *ProductService) Search(ctx context.Context, params domain.SearchParams) ([]domain.ProductSummary, int64, domain.SearchParams, error) {
+	if params.Currency == "" {
+		params.Currency = s.Currency
+	}
 	if params.Page <= 0 {
 		params.Page = 1
 	}
@@ -82,4 +141,378 @@ func (s *ProductService) Search(ctx context.Context, params domain.SearchParams)
 		return nil, 0, params, err
 	}
 	return items, total, params, nil
-}
\ No newline at end of file
+}
+
+// AttributeFacets counts the products matching normalized search params
+// by attribute value. Only defined attributes are counted, and each lists
+// at most its 50 most common values.
+func (s *ProductService) AttributeFacets(ctx context.Context, params domain.SearchParams) ([]domain.AttributeFacet, error) {
+	if s.Attributes == nil {
+		return nil, nil
+	}
+	defs, err := s.Attributes.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byCode := map[string]domain.AttributeDefinition{}
+	for _, d := range defs {
+		if _, ok := byCode[d.Code]; !ok {
+			byCode[d.Code] = d
+		}
+	}
+
+	facets, err := s.Products.AttributeFacets(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]domain.AttributeFacet, 0, len(facets))
+	for _, f := range facets {
+		d, ok := byCode[f.Code]
+		if !ok {
+			continue
+		}
+		f.Label, f.Type, f.Unit = d.Label, d.Type, d.Unit
+		if len(f.Values) > maxFacetValues {
+			f.Values = f.Values[:maxFacetValues]
+		}
+		out = append(out, f)
+	}
+	return out, nil
+}
+
+const (
+	maxProductNameLen        = 200
+	maxProductDescriptionLen = 5000
+	maxProductImages         = 20
+)
+
+// ValidationError maps each invalid field of an admin write (by its JSON
+// name) to what is wrong with it.
+type ValidationError struct {
+	Fields map[string]string
+}
+
+func (e *ValidationError) Error() string {
+	names := make([]string, 0, len(e.Fields))
+	for name := range e.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	problems := make([]string, len(names))
+	for i, name := range names {
+		problems[i] = name + " " + e.Fields[name]
+	}
+	return "invalid input: " + strings.Join(problems, "; ")
+}
+
+// Create requires every scalar field; images, categories and the brand
+// default to none.
+func (s *ProductService) Create(ctx context.Context, in domain.ProductInput) (domain.Product, error) {
+	p, err := applyProductInput(domain.Product{}, in, true, s.Currency)
+	if err != nil {
+		return domain.Product{}, err
+	}
+	if err := s.checkBrand(ctx, p); err != nil {
+		return domain.Product{}, err
+	}
+	if err := s.applyAttributes(ctx, &p, in); err != nil {
+		return domain.Product{}, err
+	}
+	id, err := s.Products.Create(ctx, p)
+	if err != nil {
+		return domain.Product{}, productWriteError(err)
+	}
+	return s.GetByID(ctx, id)
+}
+
+// Replace is a full update: it takes the same input as Create, and omitted
+// images, categories or brand are cleared.
+func (s *ProductService) Replace(ctx context.Context, id int64, in domain.ProductInput) (domain.Product, error) {
+	if in.ImageURLs == nil {
+		in.ImageURLs = &[]string{}
+	}
+	if in.CategoryIDs == nil {
+		in.CategoryIDs = &[]int64{}
+	}
+	p, err := applyProductInput(domain.Product{ID: id}, in, true, s.Currency)
+	if err != nil {
+		return domain.Product{}, err
+	}
+	if err := s.checkBrand(ctx, p); err != nil {
+		return domain.Product{}, err
+	}
+	if err := s.applyAttributes(ctx, &p, in); err != nil {
+		return domain.Product{}, err
+	}
+	return s.update(ctx, p)
+}
+
+// Patch changes only the fields set in the input.
+func (s *ProductService) Patch(ctx context.Context, id int64, in domain.ProductInput) (domain.Product, error) {
+	current, err := s.GetByID(ctx, id)
+	if err != nil {
+		return domain.Product{}, err
+	}
+	// a price given without a currency stays in the product's own
+	p, err := applyProductInput(current, in, false, current.Price.Currency)
+	if err != nil {
+		return domain.Product{}, err
+	}
+	if in.BrandID != nil {
+		if err := s.checkBrand(ctx, p); err != nil {
+			return domain.Product{}, err
+		}
+	}
+	if err := s.applyAttributes(ctx, &p, in); err != nil {
+		return domain.Product{}, err
+	}
+	return s.update(ctx, p)
+}
+
+// Delete removes the product, which is refused while it has stock on hand or
+// reserved.
+func (s *ProductService) Delete(ctx context.Context, id int64) error {
+	err := s.Products.Delete(ctx, id)
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return ErrProductNotFound
+	case errors.Is(err, repository.ErrStockRemaining):
+		return ErrProductHasStock
+	}
+	return err
+}
+
+// SetPrice puts an explicit price in another currency on the product's
+// price list, overriding the exchange-rate conversion.
+func (s *ProductService) SetPrice(ctx context.Context, id int64, in domain.MoneyInput) (domain.Product, error) {
+	p, err := s.GetByID(ctx, id)
+	if err != nil {
+		return domain.Product{}, err
+	}
+	if !domain.IsCurrencyCode(strings.ToUpper(in.Currency)) {
+		return domain.Product{}, &ValidationError{Fields: map[string]string{"currency": "must be an ISO 4217 code"}}
+	}
+	price, err := domain.ParseMoney(in.Amount, in.Currency)
+	switch {
+	case err != nil:
+		return domain.Product{}, &ValidationError{Fields: map[string]string{"amount": strings.TrimPrefix(err.Error(), domain.ErrInvalidMoney.Error()+": ")}}
+	case price.Amount < 0:
+		return domain.Product{}, &ValidationError{Fields: map[string]string{"amount": "must not be negative"}}
+	case price.Currency == p.Price.Currency:
+		return domain.Product{}, &ValidationError{Fields: map[string]string{"currency": "is the product's own currency"}}
+	}
+
+	if err := s.Products.SetPrice(ctx, id, price); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return domain.Product{}, ErrProductNotFound
+		}
+		return domain.Product{}, err
+	}
+	return s.GetByID(ctx, id)
+}
+
+func (s *ProductService) DeletePrice(ctx context.Context, id int64, currency string) error {
+	err := s.Products.DeletePrice(ctx, id, strings.ToUpper(currency))
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrPriceNotFound
+	}
+	return err
+}
+
+func (s *ProductService) update(ctx context.Context, p domain.Product) (domain.Product, error) {
+	// variant prices are in the product's currency, so it stays fixed
+	// while there are variants
+	current, err := s.GetByID(ctx, p.ID)
+	if err != nil {
+		return domain.Product{}, err
+	}
+	if len(current.Variants) > 0 && p.Price.Currency != current.Price.Currency {
+		return domain.Product{}, &ValidationError{Fields: map[string]string{"price": "currency cannot change while the product has variants"}}
+	}
+
+	if err := s.Products.Update(ctx, p); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return domain.Product{}, ErrProductNotFound
+		}
+		return domain.Product{}, productWriteError(err)
+	}
+	return s.GetByID(ctx, p.ID)
+}
+
+// applyAttributes validates the attribute values of in, if given, against
+// the attributes of p's categories and sets them on p. Values already stored
+// stay when only the categories change; the detail shows just the defined
+// ones.
+func (s *ProductService) applyAttributes(ctx context.Context, p *domain.Product, in domain.ProductInput) error {
+	if in.Attributes == nil {
+		return nil
+	}
+	var defs []domain.AttributeDefinition
+	if len(*in.Attributes) > 0 {
+		var err error
+		if defs, err = s.attributeDefinitions(ctx, p.Categories); err != nil {
+			return err
+		}
+	}
+	values, fields := validateAttributeValues(*in.Attributes, defs)
+	if len(fields) > 0 {
+		return &ValidationError{Fields: fields}
+	}
+	p.AttributeValues = values
+	return nil
+}
+
+// checkBrand makes sure p's brand exists, so an unknown one is reported on
+// brandId rather than as a failed write.
+func (s *ProductService) checkBrand(ctx context.Context, p domain.Product) error {
+	if s.Brands == nil || p.Brand == nil {
+		return nil
+	}
+	if _, err := s.Brands.GetByID(ctx, p.Brand.ID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return &ValidationError{Fields: map[string]string{"brandId": "is not a known brand"}}
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *ProductService) attributeDefinitions(ctx context.Context, categories []domain.Category) ([]domain.AttributeDefinition, error) {
+	if s.Attributes == nil || len(categories) == 0 {
+		return nil, nil
+	}
+	ids := make([]int64, len(categories))
+	for i, c := range categories {
+		ids[i] = c.ID
+	}
+	defs, err := s.Attributes.ForCategories(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	return nearestDefinitions(defs), nil
+}
+
+func productWriteError(err error) error {
+	switch {
+	case errors.Is(err, repository.ErrConflict):
+		return ErrProductExists
+	case errors.Is(err, repository.ErrInvalidReference):
+		return &ValidationError{Fields: map[string]string{"categoryIds": "contains an unknown category"}}
+	}
+	return err
+}
+
+// applyProductInput copies the set fields of in onto p and validates the
+// result, collecting every problem rather than stopping at the first. With
+// requireAll, omitted scalar fields are problems too. Prices without a
+// currency are in the given one.
+func applyProductInput(p domain.Product, in domain.ProductInput, requireAll bool, currency string) (domain.Product, error) {
+	fields := map[string]string{}
+	if requireAll {
+		required := map[string]bool{
+			"name":        in.Name != nil,
+			"price":       in.Price != nil,
+			"description": in.Description != nil,
+			"rating":      in.Rating != nil,
+			"inStock":     in.InStock != nil,
+		}
+		for name, set := range required {
+			if !set {
+				fields[name] = "is required"
+			}
+		}
+	}
+
+	if in.Name != nil {
+		p.Name = strings.TrimSpace(*in.Name)
+		switch {
+		case p.Name == "":
+			fields["name"] = "must not be empty"
+		case utf8.RuneCountInString(p.Name) > maxProductNameLen:
+			fields["name"] = fmt.Sprintf("must be at most %d characters", maxProductNameLen)
+		}
+	}
+	if in.Description != nil {
+		p.Description = strings.TrimSpace(*in.Description)
+		switch {
+		case p.Description == "":
+			fields["description"] = "must not be empty"
+		case utf8.RuneCountInString(p.Description) > maxProductDescriptionLen:
+			fields["description"] = fmt.Sprintf("must be at most %d characters", maxProductDescriptionLen)
+		}
+	}
+	if in.Price != nil {
+		if in.Price.Currency != "" {
+			currency = in.Price.Currency
+		}
+		price, err := domain.ParseMoney(in.Price.Amount, currency)
+		switch {
+		case err != nil:
+			fields["price"] = strings.TrimPrefix(err.Error(), domain.ErrInvalidMoney.Error()+": ")
+		case price.Amount < 0:
+			fields["price"] = "must not be negative"
+		default:
+			p.Price = price
+		}
+	}
+	if in.Rating != nil {
+		p.Rating = *in.Rating
+		if math.IsNaN(p.Rating) || p.Rating < 0 || p.Rating > 5 {
+			fields["rating"] = "must be between 0 and 5"
+		}
+	}
+	if in.InStock != nil {
+		p.InStock = *in.InStock
+	}
+
+	if in.ImageURLs != nil {
+		urls := *in.ImageURLs
+		p.Images = make([]domain.ProductImage, 0, len(urls))
+		if len(urls) > maxProductImages {
+			fields["images"] = fmt.Sprintf("must have at most %d entries", maxProductImages)
+		}
+		for i, raw := range urls {
+			raw = strings.TrimSpace(raw)
+			if !isImageURL(raw) {
+				fields[fmt.Sprintf("images[%d]", i)] = "must be an absolute http(s) URL"
+				continue
+			}
+			p.Images = append(p.Images, domain.ProductImage{URL: raw, Position: int32(i + 1)})
+		}
+	}
+	if in.CategoryIDs != nil {
+		p.Categories = make([]domain.Category, 0, len(*in.CategoryIDs))
+		seen := map[int64]bool{}
+		for _, id := range *in.CategoryIDs {
+			if id <= 0 {
+				fields["categoryIds"] = "must contain positive ids"
+				continue
+			}
+			if !seen[id] {
+				seen[id] = true
+				p.Categories = append(p.Categories, domain.Category{ID: id})
+			}
+		}
+	}
+
+	if in.BrandID != nil {
+		switch id := *in.BrandID; {
+		case id < 0:
+			fields["brandId"] = "must be a positive id, or 0 for none"
+		case id == 0:
+			p.Brand = nil
+		default:
+			p.Brand = &domain.Brand{ID: id}
+		}
+	}
+
+	if len(fields) > 0 {
+		return domain.Product{}, &ValidationError{Fields: fields}
+	}
+	return p, nil
+}
+
+func isImageURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/soydoradesu/product_discovery/internal/domain"
+	"github.com/soydoradesu/product_discovery/internal/repository"
+)
+
+const (
+	maxProductVariants = 100
+	maxVariantOptions  = 10
+	maxSKULen          = 64
+	maxOptionValueLen  = 100
+)
+
+var (
+	skuRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+	// OptionNameRe is what a variant option may be called; search filters
+	// on options with the same names.
+	OptionNameRe = regexp.MustCompile(`^[a-z][a-z0-9_]{0,31}$`)
+)
+
+// ReplaceVariants sets the product's full list of variants. Variants are
+// matched by SKU, so those that stay keep their ids; an empty list removes
+// them all and the product goes back to its own price and stock. Variants
+// with stock on hand or reserved cannot be removed. Field names in a
+// ValidationError are variants[i].sku, variants[i].options, variants[i].price
+// and variants.
+func (s *ProductService) ReplaceVariants(ctx context.Context, id int64, in []domain.VariantInput) (domain.Product, error) {
+	p, err := s.GetByID(ctx, id)
+	if err != nil {
+		return domain.Product{}, err
+	}
+	variants, err := validateVariants(in, p.Price.Currency)
+	if err != nil {
+		return domain.Product{}, err
+	}
+
+	if err := s.Products.ReplaceVariants(ctx, id, variants); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			return domain.Product{}, ErrProductNotFound
+		case errors.Is(err, repository.ErrStockRemaining):
+			return domain.Product{}, ErrVariantHasStock
+		case errors.Is(err, repository.ErrConflict):
+			return domain.Product{}, ErrVariantExists
+		}
+		return domain.Product{}, err
+	}
+	return s.GetByID(ctx, id)
+}
+
+func validateVariants(in []domain.VariantInput, currency string) ([]domain.Variant, error) {
+	if len(in) > maxProductVariants {
+		return nil, &ValidationError{Fields: map[string]string{"variants": fmt.Sprintf("must have at most %d entries", maxProductVariants)}}
+	}
+
+	fields := map[string]string{}
+	out := make([]domain.Variant, len(in))
+	skus := map[string]bool{}
+	combos := map[string]bool{}
+	var optionNames string
+	for i, v := range in {
+		prefix := fmt.Sprintf("variants[%d].", i)
+		sku := strings.TrimSpace(v.SKU)
+		switch {
+		case sku == "":
+			fields[prefix+"sku"] = "must not be empty"
+		case len(sku) > maxSKULen || !skuRe.MatchString(sku):
+			fields[prefix+"sku"] = fmt.Sprintf("must be at most %d letters, digits, dots, dashes or underscores", maxSKULen)
+		case skus[strings.ToLower(sku)]:
+			fields[prefix+"sku"] = "is listed twice"
+		}
+		skus[strings.ToLower(sku)] = true
+
+		options, names, msg := normalizeVariantOptions(v.Options)
+		switch {
+		case msg != "":
+			fields[prefix+"options"] = msg
+		case i > 0 && names != optionNames:
+			fields[prefix+"options"] = "must name the same options as the other variants"
+		case combos[optionsKey(options)]:
+			fields[prefix+"options"] = "are the same as another variant's"
+		}
+		if i == 0 {
+			optionNames = names
+		}
+		combos[optionsKey(options)] = true
+
+		if v.Price.Currency != "" && !strings.EqualFold(v.Price.Currency, currency) {
+			fields[prefix+"price"] = "must be in the product's currency, " + currency
+		} else if price, err := domain.ParseMoney(v.Price.Amount, currency); err != nil {
+			fields[prefix+"price"] = strings.TrimPrefix(err.Error(), domain.ErrInvalidMoney.Error()+": ")
+		} else if price.Amount < 0 {
+			fields[prefix+"price"] = "must not be negative"
+		} else {
+			out[i].Price = price
+		}
+
+		out[i].SKU = sku
+		out[i].Options = options
+		out[i].InStock = v.InStock
+	}
+	if len(fields) > 0 {
+		return nil, &ValidationError{Fields: fields}
+	}
+	return out, nil
+}
+
+// normalizeVariantOptions lowercases option names and trims values. It
+// returns the sorted names joined by commas, or what is wrong with them.
+func normalizeVariantOptions(in map[string]string) (map[string]string, string, string) {
+	if len(in) == 0 {
+		return nil, "", "must not be empty"
+	}
+	if len(in) > maxVariantOptions {
+		return nil, "", fmt.Sprintf("must have at most %d options", maxVariantOptions)
+	}
+	out := make(map[string]string, len(in))
+	names := make([]string, 0, len(in))
+	for name, value := range in {
+		name = strings.ToLower(strings.TrimSpace(name))
+		value = strings.TrimSpace(value)
+		switch {
+		case !OptionNameRe.MatchString(name):
+			return nil, "", fmt.Sprintf("option %q must be a lowercase name of at most 32 letters, digits or underscores", name)
+		case value == "" || utf8.RuneCountInString(value) > maxOptionValueLen:
+			return nil, "", fmt.Sprintf("option %q must have a value of at most %d characters", name, maxOptionValueLen)
+		}
+		if _, dup := out[name]; dup {
+			return nil, "", fmt.Sprintf("option %q is listed twice", name)
+		}
+		out[name] = value
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return out, strings.Join(names, ","), ""
+}
+
+// optionsKey identifies a combination of option values; values compare
+// case-insensitively.
+func optionsKey(options map[string]string) string {
+	names := make([]string, 0, len(options))
+	for name := range options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(name + "=" + strings.ToLower(options[name]) + "\x00")
+	}
+	return b.String()
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"time"
+
+	"github.com/soydoradesu/product_discovery/internal/domain"
+	"github.com/soydoradesu/product_discovery/internal/repository"
+)
+
+// SessionService keeps a server-side record of every browser session so a
+// session can be listed and revoked before its token expires.
+type SessionService struct {
+	Sessions repository.SessionRepository
+}
+
+func NewSessionService(sessions repository.SessionRepository) *SessionService {
+	return &SessionService{Sessions: sessions}
+}
+
+func (s *SessionService) Create(ctx context.Context, userID int64, userAgent, ip string, ttl time.Duration) (domain.Session, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return domain.Session{}, err
+	}
+	if len(userAgent) > 512 {
+		userAgent = userAgent[:512]
+	}
+
+	now := time.Now()
+	sess := domain.Session{
+		ID:         base64.RawURLEncoding.EncodeToString(b),
+		UserID:     userID,
+		UserAgent:  userAgent,
+		IP:         ip,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(ttl),
+	}
+	if err := s.Sessions.Create(ctx, sess); err != nil {
+		return domain.Session{}, err
+	}
+	return sess, nil
+}
+
+// ValidateSession checks that the session is still active for the user and
+// records that it was used.
+func (s *SessionService) ValidateSession(ctx context.Context, userID int64, sessionID string) error {
+	if sessionID == "" {
+		return ErrInvalidSession
+	}
+	sess, err := s.Sessions.Get(ctx, sessionID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrInvalidSession
+		}
+		return err
+	}
+
+	now := time.Now()
+	if sess.UserID != userID || sess.RevokedAt != nil || !sess.ExpiresAt.After(now) {
+		return ErrInvalidSession
+	}
+	return s.Sessions.Touch(ctx, sessionID, now)
+}
+
+func (s *SessionService) List(ctx context.Context, userID int64) ([]domain.Session, error) {
+	return s.Sessions.ListActive(ctx, userID)
+}
+
+func (s *SessionService) Revoke(ctx context.Context, userID int64, sessionID string) error {
+	if err := s.Sessions.Revoke(ctx, userID, sessionID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrSessionNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// RevokeOthers signs the user out everywhere except the session keepID.
+func (s *SessionService) RevokeOthers(ctx context.Context, userID int64, keepID string) (int64, error) {
+	return s.Sessions.RevokeAllExcept(ctx, userID, keepID)
+}
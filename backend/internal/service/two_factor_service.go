@@ -0,0 +1,193 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/soydoradesu/product_discovery/internal/auth"
+	"github.com/soydoradesu/product_discovery/internal/domain"
+	"github.com/soydoradesu/product_discovery/internal/repository"
+)
+
+const (
+	recoveryCodeCount = 10
+	// maxTwoFactorFailures wrong codes in a row lock verification for
+	// twoFactorLockout, which keeps guessing a 6-digit code impractical.
+	maxTwoFactorFailures = 5
+	twoFactorLockout     = 15 * time.Minute
+)
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+type TwoFactorService struct {
+	TwoFactor repository.TwoFactorRepository
+	Issuer    string
+}
+
+func NewTwoFactorService(twoFactor repository.TwoFactorRepository, issuer string) *TwoFactorService {
+	return &TwoFactorService{TwoFactor: twoFactor, Issuer: issuer}
+}
+
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauthUri"`
+}
+
+func (s *TwoFactorService) Enabled(ctx context.Context, userID int64) (bool, error) {
+	t, err := s.TwoFactor.GetTOTP(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return t.EnabledAt != nil, nil
+}
+
+// Enroll starts (or restarts) an enrollment. 2FA stays off until Confirm
+// succeeds with a code from the new secret.
+func (s *TwoFactorService) Enroll(ctx context.Context, userID int64, account string) (TOTPEnrollment, error) {
+	enabled, err := s.Enabled(ctx, userID)
+	if err != nil {
+		return TOTPEnrollment{}, err
+	}
+	if enabled {
+		return TOTPEnrollment{}, ErrTwoFactorAlreadyEnabled
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		return TOTPEnrollment{}, err
+	}
+	if err := s.TwoFactor.SavePendingTOTP(ctx, userID, secret); err != nil {
+		return TOTPEnrollment{}, err
+	}
+
+	return TOTPEnrollment{Secret: secret, URI: auth.TOTPURI(s.Issuer, account, secret)}, nil
+}
+
+// Confirm enables 2FA and returns the plaintext recovery codes. They are only
+// stored hashed, so this is the one time they can be shown.
+func (s *TwoFactorService) Confirm(ctx context.Context, userID int64, code string) ([]string, error) {
+	t, err := s.TwoFactor.GetTOTP(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrTwoFactorNotEnrolled
+		}
+		return nil, err
+	}
+	if t.EnabledAt != nil {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	step, ok := auth.ValidateTOTP(t.Secret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		c, err := newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, c)
+		hashes = append(hashes, hashRecoveryCode(c))
+	}
+
+	if err := s.TwoFactor.EnableTOTP(ctx, userID, step, hashes); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrTwoFactorNotEnrolled
+		}
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Verify accepts either a current TOTP code or an unused recovery code. Each
+// code is accepted at most once. After maxTwoFactorFailures wrong codes in a
+// row it returns ErrTwoFactorLocked, without checking the code, until the
+// lockout ends.
+func (s *TwoFactorService) Verify(ctx context.Context, userID int64, code string) error {
+	t, err := s.TwoFactor.GetTOTP(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrTwoFactorNotEnabled
+		}
+		return err
+	}
+	if t.EnabledAt == nil {
+		return ErrTwoFactorNotEnabled
+	}
+	now := time.Now()
+	if t.LockedUntil != nil && now.Before(*t.LockedUntil) {
+		return ErrTwoFactorLocked
+	}
+
+	ok, err := s.checkCode(ctx, t, code, now)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		locked, err := s.TwoFactor.RecordTOTPFailure(ctx, userID, maxTwoFactorFailures, now.Add(twoFactorLockout))
+		if err != nil {
+			return err
+		}
+		if locked {
+			return ErrTwoFactorLocked
+		}
+		return ErrInvalidTwoFactorCode
+	}
+	if t.FailedAttempts > 0 || t.LockedUntil != nil {
+		return s.TwoFactor.ResetTOTPFailures(ctx, userID)
+	}
+	return nil
+}
+
+func (s *TwoFactorService) checkCode(ctx context.Context, t domain.UserTOTP, code string, now time.Time) (bool, error) {
+	if step, ok := auth.ValidateTOTP(t.Secret, code, now); ok {
+		return s.TwoFactor.AdvanceTOTPStep(ctx, t.UserID, step)
+	}
+
+	normalized := normalizeRecoveryCode(code)
+	if normalized == "" {
+		return false, nil
+	}
+	return s.TwoFactor.UseRecoveryCode(ctx, t.UserID, hashRecoveryCode(normalized))
+}
+
+func (s *TwoFactorService) Disable(ctx context.Context, userID int64, code string) error {
+	if err := s.Verify(ctx, userID, code); err != nil {
+		return err
+	}
+	return s.TwoFactor.DeleteTOTP(ctx, userID)
+}
+
+func newRecoveryCode() (string, error) {
+	b := make([]byte, 5)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	s := strings.ToLower(recoveryEncoding.EncodeToString(b))
+	return s[:4] + "-" + s[4:], nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
+
+// Recovery codes carry 40 random bits, so a plain digest is enough here; a
+// slow password hash would only make the lookup by hash impossible.
+func hashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(normalizeRecoveryCode(code)))
+	return hex.EncodeToString(sum[:])
+}
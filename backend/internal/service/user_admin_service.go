@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/soydoradesu/product_discovery/internal/domain"
+	"github.com/soydoradesu/product_discovery/internal/repository"
+)
+
+// UserAdminService backs the administrators' user management endpoints.
+type UserAdminService struct {
+	Users    repository.UserRepository
+	Roles    repository.RoleRepository
+	Sessions repository.SessionRepository
+}
+
+func NewUserAdminService(users repository.UserRepository, roles repository.RoleRepository, sessions repository.SessionRepository) *UserAdminService {
+	return &UserAdminService{Users: users, Roles: roles, Sessions: sessions}
+}
+
+func (s *UserAdminService) List(ctx context.Context, params domain.UserListParams) ([]domain.User, int64, domain.UserListParams, error) {
+	params.Q = strings.TrimSpace(strings.ToLower(params.Q))
+	if params.Status != domain.UserStatusActive && params.Status != domain.UserStatusDisabled {
+		params.Status = ""
+	}
+	if params.Page <= 0 {
+		params.Page = 1
+	}
+	if params.PageSize <= 0 {
+		params.PageSize = 20
+	}
+	if params.PageSize > 100 {
+		params.PageSize = 100
+	}
+
+	users, total, err := s.Users.List(ctx, params)
+	if err != nil {
+		return nil, 0, params, err
+	}
+	return users, total, params, nil
+}
+
+func (s *UserAdminService) Details(ctx context.Context, userID int64) (domain.UserDetails, error) {
+	u, err := s.getUser(ctx, userID)
+	if err != nil {
+		return domain.UserDetails{}, err
+	}
+	identities, err := s.Users.ListIdentities(ctx, userID)
+	if err != nil {
+		return domain.UserDetails{}, err
+	}
+	access, err := s.Roles.GetUserAccess(ctx, userID)
+	if err != nil {
+		return domain.UserDetails{}, err
+	}
+	sessions, err := s.Sessions.ListActive(ctx, userID)
+	if err != nil {
+		return domain.UserDetails{}, err
+	}
+
+	roles := access.Roles
+	if roles == nil {
+		roles = []string{}
+	}
+	return domain.UserDetails{
+		User:           u,
+		HasPassword:    u.PasswordHash != nil && *u.PasswordHash != "",
+		Roles:          roles,
+		Identities:     identities,
+		ActiveSessions: len(sessions),
+	}, nil
+}
+
+// SetDisabled disables or re-enables the account. Disabling also signs the
+// user out everywhere, so re-enabling doesn't bring old sessions back.
+func (s *UserAdminService) SetDisabled(ctx context.Context, userID int64, disabled bool) error {
+	if err := s.Users.SetDisabled(ctx, userID, disabled); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	if disabled {
+		_, err := s.Sessions.RevokeAllExcept(ctx, userID, "")
+		return err
+	}
+	return nil
+}
+
+// ForcePasswordReset removes the user's password and signs them out. They get
+// back in with a login link or a linked provider and then choose a new
+// password, which for an account without one requires a fresh login.
+func (s *UserAdminService) ForcePasswordReset(ctx context.Context, userID int64) (domain.User, error) {
+	u, err := s.getUser(ctx, userID)
+	if err != nil {
+		return domain.User{}, err
+	}
+	if err := s.Users.ClearPassword(ctx, userID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return domain.User{}, ErrUserNotFound
+		}
+		return domain.User{}, err
+	}
+	if _, err := s.Sessions.RevokeAllExcept(ctx, userID, ""); err != nil {
+		return domain.User{}, err
+	}
+	u.PasswordHash = nil
+	return u, nil
+}
+
+// RevokeSessions signs the user out of every browser session.
+func (s *UserAdminService) RevokeSessions(ctx context.Context, userID int64) (int64, error) {
+	if _, err := s.getUser(ctx, userID); err != nil {
+		return 0, err
+	}
+	return s.Sessions.RevokeAllExcept(ctx, userID, "")
+}
+
+func (s *UserAdminService) getUser(ctx context.Context, userID int64) (domain.User, error) {
+	u, err := s.Users.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return domain.User{}, ErrUserNotFound
+		}
+		return domain.User{}, err
+	}
+	return u, nil
+}
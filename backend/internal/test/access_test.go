@@ -0,0 +1,81 @@
+package internal_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/soydoradesu/product_discovery/internal/auth"
+	"github.com/soydoradesu/product_discovery/internal/domain"
+	"github.com/soydoradesu/product_discovery/internal/http/middleware"
+)
+
+type fakeAccess struct {
+	byUser map[int64]domain.Access
+}
+
+func (f *fakeAccess) Access(ctx context.Context, userID int64) (domain.Access, error) {
+	return f.byUser[userID], nil
+}
+
+func adminRouter(t *testing.T, keys *auth.Keyring, guard func(http.Handler) http.Handler) http.Handler {
+	t.Helper()
+
+	r := chi.NewRouter()
+	r.Use(middleware.RequireAuth(keys, middleware.Authenticators{}))
+	r.Use(guard)
+	r.Get("/api/admin/ping", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	return r
+}
+
+func serveAs(t *testing.T, h http.Handler, keys *auth.Keyring, userID int64) *httptest.ResponseRecorder {
+	t.Helper()
+
+	token, err := auth.SignJWT(keys, userID, 10*time.Minute)
+	if err != nil {
+		t.Fatalf("sign jwt: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/api/admin/ping", nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestRequireRole(t *testing.T) {
+	keys := auth.NewHMACKeyring("test-secret")
+	access := &fakeAccess{byUser: map[int64]domain.Access{
+		1: {Roles: []string{domain.RoleAdmin}, Permissions: []string{domain.PermCatalogWrite}},
+	}}
+	h := adminRouter(t, keys, middleware.RequireRole(access, domain.RoleAdmin))
+
+	if rr := serveAs(t, h, keys, 1); rr.Code != http.StatusOK {
+		t.Fatalf("expected 200 for admin got %d body=%s", rr.Code, rr.Body.String())
+	}
+	if rr := serveAs(t, h, keys, 2); rr.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for non-admin got %d body=%s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestRequirePermission(t *testing.T) {
+	keys := auth.NewHMACKeyring("test-secret")
+	access := &fakeAccess{byUser: map[int64]domain.Access{
+		1: {Roles: []string{domain.RoleAdmin}, Permissions: []string{domain.PermCatalogWrite}},
+	}}
+
+	h := adminRouter(t, keys, middleware.RequirePermission(access, domain.PermCatalogWrite))
+	if rr := serveAs(t, h, keys, 1); rr.Code != http.StatusOK {
+		t.Fatalf("expected 200 got %d body=%s", rr.Code, rr.Body.String())
+	}
+
+	h = adminRouter(t, keys, middleware.RequirePermission(access, domain.PermUsersManage))
+	if rr := serveAs(t, h, keys, 1); rr.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 got %d body=%s", rr.Code, rr.Body.String())
+	}
+}
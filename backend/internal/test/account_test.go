@@ -0,0 +1,70 @@
+package internal_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/soydoradesu/product_discovery/internal/auth"
+	"github.com/soydoradesu/product_discovery/internal/domain"
+	"github.com/soydoradesu/product_discovery/internal/http/handlers"
+	"github.com/soydoradesu/product_discovery/internal/http/middleware"
+	"github.com/soydoradesu/product_discovery/internal/service"
+)
+
+func accountRouter(users *fakeUsers) (http.Handler, *auth.Keyring, *service.SessionService) {
+	keys := auth.NewHMACKeyring("test-secret")
+	sessions := service.NewSessionService(newFakeSessions())
+	h := &handlers.AuthHandlers{Keys: keys, Auth: service.NewAuthService(users), Sessions: sessions}
+
+	r := chi.NewRouter()
+	r.Use(middleware.RequireAuth(keys, middleware.Authenticators{Sessions: sessions}))
+	r.Get("/api/me/export", h.ExportAccount)
+	r.With(middleware.RequireRecentAuth(10*time.Minute)).Delete("/api/me", h.DeleteAccount)
+	return r, keys, sessions
+}
+
+func TestAccountExportAndDelete(t *testing.T) {
+	ctx := context.Background()
+	users := &fakeUsers{byEmail: map[string]domain.User{}, byID: map[int64]domain.User{}}
+	uid, _, err := service.NewAuthService(users).OAuthLogin(ctx, "google", "g-1", "shopper@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	router, keys, sessions := accountRouter(users)
+
+	sess, _ := sessions.Create(ctx, uid, "test", "127.0.0.1", time.Hour)
+	token, _ := auth.SignSession(keys, uid, sess.ID, time.Hour)
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.AddCookie(&http.Cookie{Name: "session", Value: token})
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := serve(http.MethodGet, "/api/me/export")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("export: expected 200 got %d body=%s", rr.Code, rr.Body.String())
+	}
+	var export domain.UserExport
+	if err := json.Unmarshal(rr.Body.Bytes(), &export); err != nil {
+		t.Fatal(err)
+	}
+	if export.Profile.Email != "shopper@example.com" || len(export.Identities) != 1 || export.Identities[0].Subject != "g-1" {
+		t.Fatalf("unexpected export %+v", export)
+	}
+
+	rr = serve(http.MethodDelete, "/api/me")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("delete: expected 200 got %d body=%s", rr.Code, rr.Body.String())
+	}
+	if _, ok := users.byID[uid]; ok || len(users.identities) != 0 {
+		t.Fatal("expected the user and their identities to be gone")
+	}
+}
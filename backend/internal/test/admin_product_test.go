@@ -0,0 +1,150 @@
+package internal_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/soydoradesu/product_discovery/internal/domain"
+	"github.com/soydoradesu/product_discovery/internal/http/handlers"
+	"github.com/soydoradesu/product_discovery/internal/service"
+)
+
+func adminProductRouter(products *fakeProducts) http.Handler {
+	h := &handlers.ProductHandlers{Products: service.NewProductService(products)}
+	r := chi.NewRouter()
+	r.Post("/api/admin/products", h.Create)
+	r.Put("/api/admin/products/{id}", h.Replace)
+	r.Patch("/api/admin/products/{id}", h.Patch)
+	r.Delete("/api/admin/products/{id}", h.Delete)
+	return r
+}
+
+func sendJSON(router http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestAdminProducts_CreateValidatesPerField(t *testing.T) {
+	router := adminProductRouter(&fakeProducts{categories: map[int64]string{1: "Audio"}})
+
+	rr := sendJSON(router, http.MethodPost, "/api/admin/products",
+		`{"name":"  ","price":-5,"description":"Loud","rating":7,"images":["https://img.example/a.jpg","ftp://x"],"categoryIds":[1]}`)
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 got %d", rr.Code)
+	}
+	var body struct {
+		Error struct {
+			Code   string            `json:"code"`
+			Fields map[string]string `json:"fields"`
+		} `json:"error"`
+	}
+	_ = json.Unmarshal(rr.Body.Bytes(), &body)
+	if body.Error.Code != "VALIDATION_ERROR" {
+		t.Fatalf("expected VALIDATION_ERROR got %q", body.Error.Code)
+	}
+	for _, field := range []string{"name", "price", "rating", "inStock", "images[1]"} {
+		if body.Error.Fields[field] == "" {
+			t.Fatalf("expected an error for %s, got %v", field, body.Error.Fields)
+		}
+	}
+	if body.Error.Fields["description"] != "" || body.Error.Fields["images[0]"] != "" {
+		t.Fatalf("valid fields should not be reported, got %v", body.Error.Fields)
+	}
+
+	rr = sendJSON(router, http.MethodPost, "/api/admin/products",
+		`{"name":"Speaker","price":100,"description":"Loud","rating":4,"inStock":true,"categoryIds":[9]}`)
+	if rr.Code != http.StatusBadRequest || !bytes.Contains(rr.Body.Bytes(), []byte("categoryIds")) {
+		t.Fatalf("expected 400 for an unknown category, got %d %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestAdminProducts_WriteLifecycle(t *testing.T) {
+	products := &fakeProducts{categories: map[int64]string{1: "Audio", 2: "Home"}}
+	router := adminProductRouter(products)
+
+	create := `{"name":"Speaker","price":100,"description":"Loud","rating":4,"inStock":true,"images":["https://img.example/a.jpg","https://img.example/b.jpg"],"categoryIds":[1,2,1]}`
+	rr := sendJSON(router, http.MethodPost, "/api/admin/products", create)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("create: %d %s", rr.Code, rr.Body.String())
+	}
+	var created domain.Product
+	_ = json.Unmarshal(rr.Body.Bytes(), &created)
+	if created.ID == 0 || len(created.Images) != 2 || created.Images[1].Position != 2 || len(created.Categories) != 2 {
+		t.Fatalf("unexpected product %+v", created)
+	}
+
+	if rr := sendJSON(router, http.MethodPost, "/api/admin/products", create); rr.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for a duplicate name and description got %d", rr.Code)
+	}
+
+	rr = sendJSON(router, http.MethodPatch, "/api/admin/products/1", `{"price":150,"inStock":false}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("patch: %d %s", rr.Code, rr.Body.String())
+	}
+	if p := products.byID[1]; p.Price != (domain.Money{Amount: 15000, Currency: "IDR"}) || p.InStock || p.Name != "Speaker" || len(p.Images) != 2 {
+		t.Fatalf("patch should only change the given fields, got %+v", p)
+	}
+
+	rr = sendJSON(router, http.MethodPatch, "/api/admin/products/1", `{"price":{"amount":"19.99","currency":"USD"}}`)
+	if p := products.byID[1]; rr.Code != http.StatusOK || p.Price != (domain.Money{Amount: 1999, Currency: "USD"}) {
+		t.Fatalf("patch with a price object: %d %+v", rr.Code, p.Price)
+	}
+	// a price alone keeps the product's currency rather than the catalog's
+	rr = sendJSON(router, http.MethodPatch, "/api/admin/products/1", `{"price":{"amount":"24.50"}}`)
+	if p := products.byID[1]; rr.Code != http.StatusOK || p.Price != (domain.Money{Amount: 2450, Currency: "USD"}) {
+		t.Fatalf("patch of the amount only: %d %+v", rr.Code, p.Price)
+	}
+	rr = sendJSON(router, http.MethodPatch, "/api/admin/products/1", `{"price":"1.999"}`)
+	if rr.Code != http.StatusBadRequest || !bytes.Contains(rr.Body.Bytes(), []byte("decimal places")) {
+		t.Fatalf("expected 400 for too many decimals got %d %s", rr.Code, rr.Body.String())
+	}
+
+	rr = sendJSON(router, http.MethodPut, "/api/admin/products/1", `{"name":"Speaker 2","price":90,"description":"Louder"}`)
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an incomplete PUT got %d", rr.Code)
+	}
+	rr = sendJSON(router, http.MethodPut, "/api/admin/products/1", `{"name":"Speaker 2","price":90,"description":"Louder","rating":3,"inStock":true}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("put: %d %s", rr.Code, rr.Body.String())
+	}
+	if p := products.byID[1]; p.Name != "Speaker 2" || len(p.Images) != 0 || len(p.Categories) != 0 {
+		t.Fatalf("put should replace images and categories, got %+v", p)
+	}
+
+	if rr := sendJSON(router, http.MethodDelete, "/api/admin/products/1", ""); rr.Code != http.StatusOK {
+		t.Fatalf("delete: %d", rr.Code)
+	}
+	if rr := sendJSON(router, http.MethodDelete, "/api/admin/products/1", ""); rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 deleting twice got %d", rr.Code)
+	}
+	if rr := sendJSON(router, http.MethodPatch, "/api/admin/products/1", `{"price":1}`); rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 patching a deleted product got %d", rr.Code)
+	}
+}
+
+func TestAdminProducts_DeleteRefusesStockedProduct(t *testing.T) {
+	products := &fakeProducts{
+		byID:    map[int64]domain.Product{1: {ID: 1, Name: "Speaker"}},
+		stocked: map[int64]bool{1: true},
+	}
+	router := adminProductRouter(products)
+
+	rr := sendJSON(router, http.MethodDelete, "/api/admin/products/1", "")
+	if rr.Code != http.StatusConflict || !bytes.Contains(rr.Body.Bytes(), []byte(service.ErrProductHasStock.Error())) {
+		t.Fatalf("expected 409 for a product with stock, got %d %s", rr.Code, rr.Body.String())
+	}
+	if _, ok := products.byID[1]; !ok {
+		t.Fatal("expected the product kept")
+	}
+	products.stocked = nil
+	if rr := sendJSON(router, http.MethodDelete, "/api/admin/products/1", ""); rr.Code != http.StatusOK {
+		t.Fatalf("delete: %d %s", rr.Code, rr.Body.String())
+	}
+}
@@ -0,0 +1,171 @@
+package internal_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/soydoradesu/product_discovery/internal/auth"
+	"github.com/soydoradesu/product_discovery/internal/domain"
+	"github.com/soydoradesu/product_discovery/internal/http/handlers"
+	"github.com/soydoradesu/product_discovery/internal/http/middleware"
+	"github.com/soydoradesu/product_discovery/internal/service"
+)
+
+type fakeRoles struct {
+	byUser map[int64]domain.Access
+}
+
+func (f *fakeRoles) GetUserAccess(ctx context.Context, userID int64) (domain.Access, error) {
+	return f.byUser[userID], nil
+}
+
+func (f *fakeRoles) GrantRole(ctx context.Context, userID int64, role string) error  { return nil }
+func (f *fakeRoles) RevokeRole(ctx context.Context, userID int64, role string) error { return nil }
+
+func adminUsersFixture(t *testing.T) (*fakeUsers, *fakeSessions) {
+	t.Helper()
+	hash, _ := service.HashPassword("Password123!")
+	users := &fakeUsers{byEmail: map[string]domain.User{}, byID: map[int64]domain.User{}}
+	for i, email := range []string{"admin@example.com", "alice@example.com", "bob@example.com", "alina@shop.test"} {
+		u := domain.User{ID: int64(i + 1), Email: email, PasswordHash: &hash}
+		users.byID[u.ID] = u
+		users.byEmail[email] = u
+	}
+	return users, newFakeSessions()
+}
+
+func TestAdminUsers_DisabledAccountIsLockedOut(t *testing.T) {
+	ctx := context.Background()
+	users, sessionRepo := adminUsersFixture(t)
+	keys := auth.NewHMACKeyring("test-secret")
+	authSvc := service.NewAuthService(users)
+	sessions := service.NewSessionService(sessionRepo)
+	admin := service.NewUserAdminService(users, &fakeRoles{}, sessionRepo)
+
+	sess, _ := sessions.Create(ctx, 2, "test", "127.0.0.1", time.Hour)
+	token, _ := auth.SignSession(keys, 2, sess.ID, time.Hour)
+	protected := middleware.RequireAuth(keys, middleware.Authenticators{Sessions: sessions, Users: authSvc})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	callWith := func(token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/me", nil)
+		req.AddCookie(&http.Cookie{Name: "session", Value: token})
+		rr := httptest.NewRecorder()
+		protected.ServeHTTP(rr, req)
+		return rr.Code
+	}
+	if code := callWith(token); code != http.StatusOK {
+		t.Fatalf("expected 200 before disabling got %d", code)
+	}
+
+	if err := admin.SetDisabled(ctx, 2, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := authSvc.Login(ctx, "alice@example.com", "Password123!"); !errors.Is(err, service.ErrAccountDisabled) {
+		t.Fatalf("expected ErrAccountDisabled got %v", err)
+	}
+	if _, err := authSvc.Login(ctx, "alice@example.com", "wrong"); !errors.Is(err, service.ErrInvalidCredentials) {
+		t.Fatalf("a wrong password must not reveal the account is disabled, got %v", err)
+	}
+	if err := sessions.ValidateSession(ctx, 2, sess.ID); !errors.Is(err, service.ErrInvalidSession) {
+		t.Fatalf("disabling should revoke sessions, got %v", err)
+	}
+
+	// a token whose session check is skipped is still refused
+	stale, _ := auth.SignJWT(keys, 2, time.Hour)
+	lenient := middleware.RequireAuth(keys, middleware.Authenticators{Users: authSvc})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/api/me", nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: stale})
+	rr := httptest.NewRecorder()
+	lenient.ServeHTTP(rr, req)
+	if rr.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for disabled user got %d", rr.Code)
+	}
+
+	if err := admin.SetDisabled(ctx, 2, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := authSvc.Login(ctx, "alice@example.com", "Password123!"); err != nil {
+		t.Fatalf("expected login after enabling, got %v", err)
+	}
+}
+
+func TestAdminUsers_Endpoints(t *testing.T) {
+	ctx := context.Background()
+	users, sessionRepo := adminUsersFixture(t)
+	keys := auth.NewHMACKeyring("test-secret")
+	sessions := service.NewSessionService(sessionRepo)
+	access := &fakeAccess{byUser: map[int64]domain.Access{1: {Permissions: []string{domain.PermUsersManage}}}}
+	h := &handlers.AdminUserHandlers{Users: service.NewUserAdminService(users, &fakeRoles{}, sessionRepo)}
+
+	r := chi.NewRouter()
+	r.Use(middleware.RequireAuth(keys, middleware.Authenticators{}))
+	r.Use(middleware.RequirePermission(access, domain.PermUsersManage))
+	r.Get("/api/admin/users", h.List)
+	r.Get("/api/admin/users/{id}", h.Get)
+	r.Post("/api/admin/users/{id}/disable", h.Disable)
+	r.Post("/api/admin/users/{id}/password-reset", h.ForcePasswordReset)
+	r.Delete("/api/admin/users/{id}/sessions", h.RevokeSessions)
+
+	serve := func(uid int64, method, path string) *httptest.ResponseRecorder {
+		token, _ := auth.SignJWT(keys, uid, time.Minute)
+		req := httptest.NewRequest(method, path, nil)
+		req.AddCookie(&http.Cookie{Name: "session", Value: token})
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := serve(2, http.MethodGet, "/api/admin/users"); rr.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 without users:manage got %d", rr.Code)
+	}
+
+	rr := serve(1, http.MethodGet, "/api/admin/users?q=ALI&pageSize=1&page=2")
+	var page struct {
+		Items      []domain.User `json:"items"`
+		Total      int64         `json:"total"`
+		TotalPages int           `json:"totalPages"`
+	}
+	_ = json.Unmarshal(rr.Body.Bytes(), &page)
+	if rr.Code != http.StatusOK || page.Total != 2 || page.TotalPages != 2 || len(page.Items) != 1 || page.Items[0].Email != "alina@shop.test" {
+		t.Fatalf("search: code=%d body=%s", rr.Code, rr.Body.String())
+	}
+
+	if rr := serve(1, http.MethodPost, "/api/admin/users/1/disable"); rr.Code != http.StatusConflict {
+		t.Fatalf("expected 409 disabling self got %d", rr.Code)
+	}
+	if rr := serve(1, http.MethodGet, "/api/admin/users/99"); rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 got %d", rr.Code)
+	}
+
+	for i := 0; i < 2; i++ {
+		_, _ = sessions.Create(ctx, 3, "test", "127.0.0.1", time.Hour)
+	}
+	rr = serve(1, http.MethodGet, "/api/admin/users/3")
+	var details domain.UserDetails
+	_ = json.Unmarshal(rr.Body.Bytes(), &details)
+	if rr.Code != http.StatusOK || !details.HasPassword || details.ActiveSessions != 2 {
+		t.Fatalf("details: code=%d body=%s", rr.Code, rr.Body.String())
+	}
+
+	rr = serve(1, http.MethodDelete, "/api/admin/users/3/sessions")
+	if rr.Code != http.StatusOK || rr.Body.String() != "{\"ok\":true,\"revokedSessions\":2}\n" {
+		t.Fatalf("revoke: code=%d body=%s", rr.Code, rr.Body.String())
+	}
+
+	if rr := serve(1, http.MethodPost, "/api/admin/users/3/password-reset"); rr.Code != http.StatusOK {
+		t.Fatalf("password reset: code=%d body=%s", rr.Code, rr.Body.String())
+	}
+	if users.byID[3].PasswordHash != nil {
+		t.Fatal("expected the password to be cleared")
+	}
+}
@@ -0,0 +1,214 @@
+package internal_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/soydoradesu/product_discovery/internal/auth"
+	"github.com/soydoradesu/product_discovery/internal/domain"
+	"github.com/soydoradesu/product_discovery/internal/http/middleware"
+	"github.com/soydoradesu/product_discovery/internal/repository"
+	"github.com/soydoradesu/product_discovery/internal/service"
+)
+
+type fakeAPIKeys struct {
+	byHash map[string]domain.APIKey
+	nextID int64
+}
+
+func (f *fakeAPIKeys) Create(ctx context.Context, key domain.APIKey, keyHash string) (domain.APIKey, error) {
+	f.nextID++
+	key.ID = f.nextID
+	key.CreatedAt = time.Now()
+	f.byHash[keyHash] = key
+	return key, nil
+}
+
+func (f *fakeAPIKeys) ListByUser(ctx context.Context, userID int64) ([]domain.APIKey, error) {
+	var out []domain.APIKey
+	for _, k := range f.byHash {
+		if k.UserID == userID {
+			out = append(out, k)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeAPIKeys) GetByHash(ctx context.Context, keyHash string) (domain.APIKey, error) {
+	k, ok := f.byHash[keyHash]
+	if !ok {
+		return domain.APIKey{}, repository.ErrNotFound
+	}
+	return k, nil
+}
+
+func (f *fakeAPIKeys) Revoke(ctx context.Context, userID, id int64) error {
+	for h, k := range f.byHash {
+		if k.ID == id && k.UserID == userID && k.RevokedAt == nil {
+			now := time.Now()
+			k.RevokedAt = &now
+			f.byHash[h] = k
+			return nil
+		}
+	}
+	return repository.ErrNotFound
+}
+
+func (f *fakeAPIKeys) TouchLastUsed(ctx context.Context, id int64, at time.Time) error {
+	for h, k := range f.byHash {
+		if k.ID == id {
+			k.LastUsedAt = &at
+			f.byHash[h] = k
+		}
+	}
+	return nil
+}
+
+func TestAPIKeyService_CreateAuthenticateRevoke(t *testing.T) {
+	ctx := context.Background()
+	svc := service.NewAPIKeyService(&fakeAPIKeys{byHash: map[string]domain.APIKey{}})
+
+	if _, _, err := svc.Create(ctx, 1, service.CreateAPIKeyInput{Name: "ci", Scopes: []string{"everything"}}); err != service.ErrInvalidAPIKeyInput {
+		t.Fatalf("expected ErrInvalidAPIKeyInput for unknown scope, got %v", err)
+	}
+
+	key, raw, err := svc.Create(ctx, 1, service.CreateAPIKeyInput{Name: "ci", Scopes: []string{domain.PermCatalogWrite}})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	got, err := svc.AuthenticateAPIKey(ctx, raw)
+	if err != nil {
+		t.Fatalf("authenticate: %v", err)
+	}
+	if got.ID != key.ID || got.UserID != 1 || got.LastUsedAt == nil {
+		t.Fatalf("unexpected key %+v", got)
+	}
+
+	if err := svc.Revoke(ctx, 2, key.ID); err != service.ErrAPIKeyNotFound {
+		t.Fatalf("expected other users to be unable to revoke, got %v", err)
+	}
+	if err := svc.Revoke(ctx, 1, key.ID); err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+	if _, err := svc.AuthenticateAPIKey(ctx, raw); err != service.ErrInvalidAPIKey {
+		t.Fatalf("expected revoked key to fail, got %v", err)
+	}
+}
+
+func TestAPIKeyService_ExpiredKeyRejected(t *testing.T) {
+	ctx := context.Background()
+	repo := &fakeAPIKeys{byHash: map[string]domain.APIKey{}}
+	svc := service.NewAPIKeyService(repo)
+
+	soon := time.Now().Add(time.Hour)
+	_, raw, err := svc.Create(ctx, 1, service.CreateAPIKeyInput{Name: "tmp", ExpiresAt: &soon})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	past := time.Now().Add(-time.Minute)
+	for h, k := range repo.byHash {
+		k.ExpiresAt = &past
+		repo.byHash[h] = k
+	}
+
+	if _, err := svc.AuthenticateAPIKey(ctx, raw); err != service.ErrInvalidAPIKey {
+		t.Fatalf("expected expired key to fail, got %v", err)
+	}
+}
+
+func TestRequireAuth_BearerAPIKey(t *testing.T) {
+	ctx := context.Background()
+	svc := service.NewAPIKeyService(&fakeAPIKeys{byHash: map[string]domain.APIKey{}})
+	_, raw, err := svc.Create(ctx, 42, service.CreateAPIKeyInput{Name: "script"})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	keys := auth.NewHMACKeyring("test-secret")
+	r := chi.NewRouter()
+	r.Use(middleware.RequireAuth(keys, middleware.Authenticators{APIKeys: svc}))
+	r.Get("/api/me", func(w http.ResponseWriter, r *http.Request) {
+		uid, _ := middleware.UserIDFromContext(r.Context())
+		if uid != 42 {
+			t.Fatalf("expected uid=42 got %d", uid)
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	r.With(middleware.RequireSession).Post("/api/me/api-keys", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/me", nil)
+	req.Header.Set("Authorization", "Bearer "+raw)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200 got %d body=%s", rr.Code, rr.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/me", nil)
+	req.Header.Set("Authorization", "Bearer pd_wrong")
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 got %d body=%s", rr.Code, rr.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/me/api-keys", nil)
+	req.Header.Set("Authorization", "Bearer "+raw)
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for key management via api key, got %d", rr.Code)
+	}
+}
+
+func TestRequireAccess_ScopedAPIKeyDropsRoles(t *testing.T) {
+	ctx := context.Background()
+	svc := service.NewAPIKeyService(&fakeAPIKeys{byHash: map[string]domain.APIKey{}})
+	_, scoped, err := svc.Create(ctx, 1, service.CreateAPIKeyInput{Name: "catalog", Scopes: []string{domain.PermCatalogWrite}})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	_, full, err := svc.Create(ctx, 1, service.CreateAPIKeyInput{Name: "full"})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	access := &fakeAccess{byUser: map[int64]domain.Access{
+		1: {Roles: []string{domain.RoleAdmin}, Permissions: []string{domain.PermCatalogWrite, domain.PermUsersManage}},
+	}}
+
+	keys := auth.NewHMACKeyring("test-secret")
+	r := chi.NewRouter()
+	r.Use(middleware.RequireAuth(keys, middleware.Authenticators{APIKeys: svc}))
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+	r.With(middleware.RequireRole(access, domain.RoleAdmin)).Get("/role", ok)
+	r.With(middleware.RequirePermission(access, domain.PermCatalogWrite)).Get("/catalog", ok)
+	r.With(middleware.RequirePermission(access, domain.PermUsersManage)).Get("/users", ok)
+
+	for _, tc := range []struct {
+		name, key, path string
+		want            int
+	}{
+		{"scoped", scoped, "/catalog", http.StatusOK},
+		{"scoped", scoped, "/users", http.StatusForbidden},
+		{"scoped", scoped, "/role", http.StatusForbidden},
+		{"full", full, "/role", http.StatusOK},
+		{"full", full, "/users", http.StatusOK},
+	} {
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		req.Header.Set("Authorization", "Bearer "+tc.key)
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		if rr.Code != tc.want {
+			t.Fatalf("%s with the %s key: expected %d got %d", tc.path, tc.name, tc.want, rr.Code)
+		}
+	}
+}
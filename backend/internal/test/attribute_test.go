@@ -0,0 +1,213 @@
+package internal_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/soydoradesu/product_discovery/internal/config"
+	"github.com/soydoradesu/product_discovery/internal/domain"
+	"github.com/soydoradesu/product_discovery/internal/http/handlers"
+	"github.com/soydoradesu/product_discovery/internal/service"
+)
+
+// fakeAttributes keeps definitions per category; parents gives the category
+// tree for inheritance.
+type fakeAttributes struct {
+	byCategory map[int64][]domain.AttributeDefinition
+	parents    map[int64]int64
+	nextID     int64
+}
+
+func (f *fakeAttributes) List(ctx context.Context) ([]domain.AttributeDefinition, error) {
+	var out []domain.AttributeDefinition
+	for _, defs := range f.byCategory {
+		out = append(out, defs...)
+	}
+	return out, nil
+}
+
+func (f *fakeAttributes) ForCategories(ctx context.Context, categoryIDs []int64) ([]domain.AttributeDefinition, error) {
+	var out []domain.AttributeDefinition
+	for _, id := range categoryIDs {
+		for ; id != 0; id = f.parents[id] {
+			out = append(out, f.byCategory[id]...)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeAttributes) Replace(ctx context.Context, categoryID int64, defs []domain.AttributeDefinition) error {
+	if f.byCategory == nil {
+		f.byCategory = map[int64][]domain.AttributeDefinition{}
+	}
+	for i := range defs {
+		f.nextID++
+		defs[i].ID = f.nextID
+	}
+	f.byCategory[categoryID] = defs
+	return nil
+}
+
+func TestAttributes_ReplaceDefinitionsValidates(t *testing.T) {
+	attrs := &fakeAttributes{byCategory: map[int64][]domain.AttributeDefinition{
+		3: {{CategoryID: 3, Code: "weight", Label: "Weight", Type: domain.AttributeNumber}},
+	}}
+	categories := &fakeCategories{items: []domain.Category{{ID: 1, Name: "Laptops"}, {ID: 3, Name: "Audio"}}}
+	h := &handlers.AttributeHandlers{Attributes: service.NewAttributeService(attrs, categories)}
+	router := chi.NewRouter()
+	router.Get("/api/categories/{id}/attributes", h.ForCategory)
+	router.Put("/api/admin/categories/{id}/attributes", h.Replace)
+
+	rr := sendJSON(router, http.MethodPut, "/api/admin/categories/1/attributes", `{"attributes":[
+		{"code":"RAM GB","label":"RAM","type":"number"},
+		{"code":"color","label":"","type":"enum","values":[]},
+		{"code":"weight","label":"Weight","type":"string"},
+		{"code":"ssd","label":"SSD","type":"number","values":["256"]},
+		{"code":"ssd","label":"SSD","type":"decimal"}
+	]}`)
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 got %d %s", rr.Code, rr.Body.String())
+	}
+	var body struct {
+		Error struct {
+			Fields map[string]string `json:"fields"`
+		} `json:"error"`
+	}
+	_ = json.Unmarshal(rr.Body.Bytes(), &body)
+	for _, field := range []string{"attributes[0].code", "attributes[1].label", "attributes[1].values", "attributes[2].type", "attributes[3].values", "attributes[4].code", "attributes[4].type"} {
+		if body.Error.Fields[field] == "" {
+			t.Fatalf("expected an error on %s, got %v", field, body.Error.Fields)
+		}
+	}
+
+	rr = sendJSON(router, http.MethodPut, "/api/admin/categories/1/attributes", `{"attributes":[
+		{"code":" RAM_GB ","label":"RAM","type":"Number","unit":"GB"},
+		{"code":"color","label":"Color","type":"enum","values":["Black"," White "]}
+	]}`)
+	var list struct {
+		Items []domain.AttributeDefinition `json:"items"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &list); rr.Code != http.StatusOK || err != nil || len(list.Items) != 2 || list.Items[0].Code != "ram_gb" || list.Items[1].Values[1] != "White" {
+		t.Fatalf("replace: %d %s", rr.Code, rr.Body.String())
+	}
+	if rr := sendJSON(router, http.MethodGet, "/api/categories/9/attributes", ""); rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown category got %d", rr.Code)
+	}
+}
+
+func TestAttributes_ProductValuesAndDetail(t *testing.T) {
+	attrs := &fakeAttributes{
+		byCategory: map[int64][]domain.AttributeDefinition{
+			1: {
+				{CategoryID: 1, Code: "ram_gb", Label: "RAM", Type: domain.AttributeNumber, Unit: "GB"},
+				{CategoryID: 1, Code: "color", Label: "Color", Type: domain.AttributeEnum, Values: []string{"Black", "White"}},
+			},
+			2: {{CategoryID: 2, Code: "touchscreen", Label: "Touchscreen", Type: domain.AttributeBoolean}},
+		},
+		parents: map[int64]int64{2: 1},
+	}
+	products := &fakeProducts{categories: map[int64]string{1: "Laptops", 2: "Convertibles", 3: "Audio"}}
+	svc := service.NewProductService(products)
+	svc.Attributes = attrs
+	h := &handlers.ProductHandlers{Products: svc, Visibility: config.ProductVisibilityFull}
+	router := chi.NewRouter()
+	router.Post("/api/admin/products", h.Create)
+	router.Patch("/api/admin/products/{id}", h.Patch)
+	router.Get("/api/products/{id}", h.GetByID)
+
+	const product = `"name":"Flip 14","price":15000000,"description":"Convertible laptop","rating":4.5,"inStock":true,"categoryIds":[2]`
+	rr := sendJSON(router, http.MethodPost, "/api/admin/products", `{`+product+`,"attributes":{"ram_gb":"16","color":"red","weight":1.2,"touchscreen":true}}`)
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 got %d %s", rr.Code, rr.Body.String())
+	}
+	for _, field := range []string{"attributes.ram_gb", "attributes.color", "attributes.weight"} {
+		if !strings.Contains(rr.Body.String(), field) {
+			t.Fatalf("expected an error on %s, got %s", field, rr.Body.String())
+		}
+	}
+
+	// the subcategory inherits its parent's attributes
+	rr = sendJSON(router, http.MethodPost, "/api/admin/products", `{`+product+`,"attributes":{"touchscreen":true,"color":"black","ram_gb":16}}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("create: %d %s", rr.Code, rr.Body.String())
+	}
+	var created domain.Product
+	_ = json.Unmarshal(rr.Body.Bytes(), &created)
+	if got := products.byID[created.ID].AttributeValues; got["color"] != "Black" || got["ram_gb"] != 16.0 {
+		t.Fatalf("expected the enum value as defined, got %v", got)
+	}
+
+	rr = sendJSON(router, http.MethodGet, "/api/products/1", "")
+	var detail struct {
+		Attributes []domain.ProductAttribute `json:"attributes"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &detail); err != nil || len(detail.Attributes) != 3 || detail.Attributes[0].Code != "touchscreen" || detail.Attributes[1].Unit != "GB" || detail.Attributes[2].Value != "Black" {
+		t.Fatalf("expected the attributes on the detail, got %s", rr.Body.String())
+	}
+
+	// moving it out of the category hides, but keeps, values it no longer has
+	if rr := sendJSON(router, http.MethodPatch, "/api/admin/products/1", `{"categoryIds":[3]}`); rr.Code != http.StatusOK || strings.Contains(rr.Body.String(), `"attributes"`) {
+		t.Fatalf("patch: %d %s", rr.Code, rr.Body.String())
+	}
+	if len(products.byID[1].AttributeValues) != 3 {
+		t.Fatalf("expected the stored values kept, got %v", products.byID[1].AttributeValues)
+	}
+	if rr := sendJSON(router, http.MethodPatch, "/api/admin/products/1", `{"attributes":{}}`); rr.Code != http.StatusOK || len(products.byID[1].AttributeValues) != 0 {
+		t.Fatalf("expected an empty object to clear the values, got %d %v", rr.Code, products.byID[1].AttributeValues)
+	}
+}
+
+func TestProductSearch_AttributeFiltersAndFacets(t *testing.T) {
+	products := &fakeProducts{facets: []domain.AttributeFacet{
+		{Code: "color", Type: "string", Count: 3, Values: []domain.FacetValue{{Value: "Black", Count: 2}, {Value: "White", Count: 1}}},
+		{Code: "legacy", Type: "string", Count: 1, Values: []domain.FacetValue{{Value: "x", Count: 1}}},
+		{Code: "ram_gb", Type: "number", Count: 3, Min: ptrFloat(8), Max: ptrFloat(32)},
+	}}
+	svc := service.NewProductService(products)
+	svc.Attributes = &fakeAttributes{byCategory: map[int64][]domain.AttributeDefinition{1: {
+		{CategoryID: 1, Code: "ram_gb", Label: "RAM", Type: domain.AttributeNumber, Unit: "GB"},
+		{CategoryID: 1, Code: "color", Label: "Color", Type: domain.AttributeEnum, Values: []string{"Black", "White"}},
+	}}}
+	h := &handlers.ProductHandlers{Products: svc}
+
+	rr := httptest.NewRecorder()
+	h.Search(rr, httptest.NewRequest(http.MethodGet, "/api/products/search?attr.Color=Black&attr.color=%20white&attr.ram_gb>=16&attr.ram_gb<32&attr.bad-code=x&attr.weight>=heavy&facets=true", nil))
+	got := products.searchParams.Attributes
+	want := []domain.AttributeFilter{
+		{Code: "color", Op: "=", Values: []string{"black", "white"}},
+		{Code: "ram_gb", Op: "<", Number: 32},
+		{Code: "ram_gb", Op: ">=", Number: 16},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("unexpected attribute filters %+v", got)
+	}
+	for i := range want {
+		if got[i].Code != want[i].Code || got[i].Op != want[i].Op || got[i].Number != want[i].Number || strings.Join(got[i].Values, ",") != strings.Join(want[i].Values, ",") {
+			t.Fatalf("filter %d: got %+v want %+v", i, got[i], want[i])
+		}
+	}
+
+	var body struct {
+		Facets []domain.AttributeFacet `json:"facets"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil || len(body.Facets) != 2 {
+		t.Fatalf("expected facets for the defined attributes only, got %s", rr.Body.String())
+	}
+	if f := body.Facets[1]; f.Label != "RAM" || f.Unit != "GB" || *f.Min != 8 || *f.Max != 32 {
+		t.Fatalf("unexpected number facet %+v", f)
+	}
+
+	rr = httptest.NewRecorder()
+	h.Search(rr, httptest.NewRequest(http.MethodGet, "/api/products/search", nil))
+	if strings.Contains(rr.Body.String(), `"facets"`) {
+		t.Fatalf("expected no facets unless asked for, got %s", rr.Body.String())
+	}
+}
+
+func ptrFloat(f float64) *float64 { return &f }
@@ -0,0 +1,152 @@
+package internal_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	chimw "github.com/go-chi/chi/v5/middleware"
+
+	"github.com/soydoradesu/product_discovery/internal/auth"
+	"github.com/soydoradesu/product_discovery/internal/domain"
+	"github.com/soydoradesu/product_discovery/internal/http/handlers"
+	"github.com/soydoradesu/product_discovery/internal/http/middleware"
+	"github.com/soydoradesu/product_discovery/internal/service"
+)
+
+type fakeAuthEvents struct {
+	events []domain.AuthEvent
+}
+
+func (f *fakeAuthEvents) Insert(ctx context.Context, e domain.AuthEvent) error {
+	e.ID = int64(len(f.events) + 1)
+	e.CreatedAt = time.Now()
+	f.events = append(f.events, e)
+	return nil
+}
+
+func (f *fakeAuthEvents) List(ctx context.Context, filter domain.AuthEventFilter) ([]domain.AuthEvent, error) {
+	out := []domain.AuthEvent{}
+	for i := len(f.events) - 1; i >= 0 && len(out) < filter.Limit; i-- {
+		e := f.events[i]
+		if filter.UserID != nil && (e.UserID == nil || *e.UserID != *filter.UserID) {
+			continue
+		}
+		if filter.Type != "" && e.Type != filter.Type {
+			continue
+		}
+		if filter.BeforeID > 0 && e.ID >= filter.BeforeID {
+			continue
+		}
+		out = append(out, e)
+	}
+	return out, nil
+}
+
+func TestAudit_LoginRecordsOutcomeAndOrigin(t *testing.T) {
+	hash, _ := service.HashPassword("Password123!")
+	users := &fakeUsers{
+		byEmail: map[string]domain.User{"demo@example.com": {ID: 1, Email: "demo@example.com", PasswordHash: &hash}},
+		byID:    map[int64]domain.User{1: {ID: 1, Email: "demo@example.com", PasswordHash: &hash}},
+	}
+	events := &fakeAuthEvents{}
+	h := &handlers.AuthHandlers{
+		Keys:      auth.NewHMACKeyring("test-secret"),
+		Auth:      service.NewAuthService(users),
+		TwoFactor: service.NewTwoFactorService(newFakeTwoFactor(), "test"),
+		Sessions:  service.NewSessionService(newFakeSessions()),
+		Audit:     service.NewAuditService(events),
+	}
+	r := chi.NewRouter()
+	r.Use(chimw.RequestID)
+	r.Use(chimw.RealIP)
+	r.Post("/api/auth/login", h.Login)
+
+	login := func(email, password string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/auth/login", strings.NewReader(`{"email":"`+email+`","password":"`+password+`"}`))
+		req.Header.Set("X-Forwarded-For", "203.0.113.7")
+		req.Header.Set("User-Agent", "audit-test")
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	if code := login("nobody@example.com", "whatever"); code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 got %d", code)
+	}
+	if code := login("demo@example.com", "wrong"); code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 got %d", code)
+	}
+	if code := login("demo@example.com", "Password123!"); code != http.StatusOK {
+		t.Fatalf("expected 200 got %d", code)
+	}
+
+	if len(events.events) != 3 {
+		t.Fatalf("expected 3 events got %+v", events.events)
+	}
+	unknown, bad, ok := events.events[0], events.events[1], events.events[2]
+	if unknown.Type != domain.AuthEventLoginFailed || unknown.Reason != "unknown_email" || unknown.UserID != nil {
+		t.Fatalf("unexpected event %+v", unknown)
+	}
+	if bad.Type != domain.AuthEventLoginFailed || bad.Reason != "bad_password" || *bad.Email != "demo@example.com" {
+		t.Fatalf("unexpected event %+v", bad)
+	}
+	if ok.Type != domain.AuthEventLoginSucceeded || ok.UserID == nil || *ok.UserID != 1 {
+		t.Fatalf("unexpected event %+v", ok)
+	}
+	if ok.IP != "203.0.113.7" || ok.UserAgent != "audit-test" || ok.RequestID == "" {
+		t.Fatalf("origin not recorded: %+v", ok)
+	}
+}
+
+func TestAudit_AdminQueryAndOwnActivity(t *testing.T) {
+	ctx := context.Background()
+	keys := auth.NewHMACKeyring("test-secret")
+	events := &fakeAuthEvents{}
+	audit := service.NewAuditService(events)
+	for _, uid := range []int64{1, 2, 1} {
+		uid := uid
+		audit.Record(ctx, domain.AuthEvent{UserID: &uid, Type: domain.AuthEventLogout})
+	}
+	access := &fakeAccess{byUser: map[int64]domain.Access{
+		1: {Permissions: []string{domain.PermAuditRead}},
+	}}
+
+	h := &handlers.AuditHandlers{Audit: audit}
+	r := chi.NewRouter()
+	r.Use(middleware.RequireAuth(keys, middleware.Authenticators{}))
+	r.Get("/api/me/activity", h.MyActivity)
+	r.With(middleware.RequirePermission(access, domain.PermAuditRead)).Get("/api/admin/auth-events", h.List)
+
+	get := func(uid int64, path string) (int, []domain.AuthEvent) {
+		token, _ := auth.SignJWT(keys, uid, time.Minute)
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.AddCookie(&http.Cookie{Name: "session", Value: token})
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		var body struct {
+			Items []domain.AuthEvent `json:"items"`
+		}
+		_ = json.Unmarshal(rr.Body.Bytes(), &body)
+		return rr.Code, body.Items
+	}
+
+	if code, items := get(2, "/api/me/activity"); code != http.StatusOK || len(items) != 1 || *items[0].UserID != 2 {
+		t.Fatalf("own activity: code=%d items=%+v", code, items)
+	}
+	if code, _ := get(2, "/api/admin/auth-events"); code != http.StatusForbidden {
+		t.Fatalf("expected 403 without audit:read got %d", code)
+	}
+	code, items := get(1, "/api/admin/auth-events?userId=1")
+	if code != http.StatusOK || len(items) != 2 || items[0].ID != 3 {
+		t.Fatalf("admin query: code=%d items=%+v", code, items)
+	}
+	if code, _ := get(1, "/api/admin/auth-events?since=yesterday"); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for bad since got %d", code)
+	}
+}
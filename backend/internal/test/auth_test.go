@@ -2,7 +2,9 @@ package internal_test
 
 import (
 	"context"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/soydoradesu/product_discovery/internal/domain"
 	"github.com/soydoradesu/product_discovery/internal/repository"
@@ -10,8 +12,10 @@ import (
 )
 
 type fakeUsers struct {
-	byEmail map[string]domain.User
-	byID    map[int64]domain.User
+	byEmail    map[string]domain.User
+	byID       map[int64]domain.User
+	identities []domain.UserIdentity
+	passkeys   *fakePasskeys
 }
 
 func (f *fakeUsers) GetByEmail(ctx context.Context, email string) (domain.User, error) {
@@ -72,32 +76,40 @@ func TestAuthLogin_BadPassword(t *testing.T) {
 	}
 }
 
-func (f *fakeUsers) GetByGoogleID(ctx context.Context, googleID string) (domain.User, error) {
-	for _, u := range f.byEmail {
-		if u.GoogleID != nil && *u.GoogleID == googleID {
-			return u, nil
+func (f *fakeUsers) GetByIdentity(ctx context.Context, provider, subject string) (domain.User, error) {
+	for _, i := range f.identities {
+		if i.Provider == provider && i.Subject == subject {
+			return f.GetByID(ctx, i.UserID)
 		}
 	}
 	return domain.User{}, repository.ErrNotFound
 }
 
-func (f *fakeUsers) SetGoogleID(ctx context.Context, userID int64, googleID string) error {
-	u, ok := f.byID[userID]
-	if !ok {
-		return repository.ErrNotFound
+func (f *fakeUsers) ListIdentities(ctx context.Context, userID int64) ([]domain.UserIdentity, error) {
+	var out []domain.UserIdentity
+	for _, i := range f.identities {
+		if i.UserID == userID {
+			out = append(out, i)
+		}
 	}
-	u.GoogleID = &googleID
-	f.byID[userID] = u
-	// keep byEmail in sync
-	if u.Email != "" {
-		f.byEmail[u.Email] = u
+	return out, nil
+}
+
+func (f *fakeUsers) LinkIdentity(ctx context.Context, identity domain.UserIdentity) error {
+	for _, i := range f.identities {
+		if (i.Provider == identity.Provider && i.Subject == identity.Subject) ||
+			(i.UserID == identity.UserID && i.Provider == identity.Provider) {
+			return repository.ErrConflict
+		}
 	}
+	identity.ID = int64(len(f.identities) + 1)
+	f.identities = append(f.identities, identity)
 	return nil
 }
 
-func (f *fakeUsers) CreateOAuthUser(ctx context.Context, email, googleID string) (int64, error) {
+func (f *fakeUsers) CreateOAuthUser(ctx context.Context, email string, identity domain.UserIdentity) (int64, error) {
 	id := int64(len(f.byID) + 1)
-	u := domain.User{ID: id, Email: email, GoogleID: &googleID}
+	u := domain.User{ID: id, Email: email}
 	if f.byEmail == nil {
 		f.byEmail = map[string]domain.User{}
 	}
@@ -106,5 +118,131 @@ func (f *fakeUsers) CreateOAuthUser(ctx context.Context, email, googleID string)
 	}
 	f.byEmail[email] = u
 	f.byID[id] = u
+
+	identity.UserID = id
+	if err := f.LinkIdentity(ctx, identity); err != nil {
+		return 0, err
+	}
 	return id, nil
-}
\ No newline at end of file
+}
+
+func (f *fakeUsers) UnlinkIdentity(ctx context.Context, userID int64, provider string) error {
+	idx, others := -1, 0
+	for i, id := range f.identities {
+		if id.UserID != userID {
+			continue
+		}
+		if id.Provider == provider {
+			idx = i
+		} else {
+			others++
+		}
+	}
+	if idx < 0 {
+		return repository.ErrNotFound
+	}
+	if f.passkeys != nil {
+		others += f.passkeys.countFor(userID)
+	}
+	if u := f.byID[userID]; u.PasswordHash == nil && others == 0 {
+		return repository.ErrConflict
+	}
+	f.identities = append(f.identities[:idx], f.identities[idx+1:]...)
+	return nil
+}
+
+func (f *fakeUsers) SetPasswordHash(ctx context.Context, userID int64, hash string) error {
+	u, ok := f.byID[userID]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	u.PasswordHash = &hash
+	f.byID[userID] = u
+	f.byEmail[u.Email] = u
+	return nil
+}
+
+func (f *fakeUsers) CreateEmailUser(ctx context.Context, email string) (int64, error) {
+	if _, ok := f.byEmail[email]; ok {
+		return 0, repository.ErrConflict
+	}
+	id := int64(len(f.byID) + 1)
+	u := domain.User{ID: id, Email: email}
+	f.byEmail[email] = u
+	f.byID[id] = u
+	return id, nil
+}
+
+func (f *fakeUsers) List(ctx context.Context, params domain.UserListParams) ([]domain.User, int64, error) {
+	matched := []domain.User{}
+	for id := int64(1); id <= int64(len(f.byID)); id++ {
+		u, ok := f.byID[id]
+		if !ok || !strings.Contains(u.Email, params.Q) {
+			continue
+		}
+		if (params.Status == domain.UserStatusActive && u.DisabledAt != nil) || (params.Status == domain.UserStatusDisabled && u.DisabledAt == nil) {
+			continue
+		}
+		matched = append(matched, u)
+	}
+	start := min((params.Page-1)*params.PageSize, len(matched))
+	end := min(start+params.PageSize, len(matched))
+	return matched[start:end], int64(len(matched)), nil
+}
+
+func (f *fakeUsers) SetDisabled(ctx context.Context, userID int64, disabled bool) error {
+	u, ok := f.byID[userID]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	u.DisabledAt = nil
+	if disabled {
+		now := time.Now()
+		u.DisabledAt = &now
+	}
+	f.byID[userID] = u
+	f.byEmail[u.Email] = u
+	return nil
+}
+
+func (f *fakeUsers) ClearPassword(ctx context.Context, userID int64) error {
+	u, ok := f.byID[userID]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	u.PasswordHash = nil
+	f.byID[userID] = u
+	f.byEmail[u.Email] = u
+	return nil
+}
+
+func (f *fakeUsers) Export(ctx context.Context, userID int64) (domain.UserExport, error) {
+	u, ok := f.byID[userID]
+	if !ok {
+		return domain.UserExport{}, repository.ErrNotFound
+	}
+	out := domain.UserExport{ExportedAt: time.Now(), Profile: u, HasPassword: u.PasswordHash != nil, Roles: []string{}}
+	for _, i := range f.identities {
+		if i.UserID == userID {
+			out.Identities = append(out.Identities, domain.IdentityExport{Provider: i.Provider, Subject: i.Subject, Email: i.Email, CreatedAt: i.CreatedAt})
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeUsers) Delete(ctx context.Context, userID int64) error {
+	u, ok := f.byID[userID]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	delete(f.byID, userID)
+	delete(f.byEmail, u.Email)
+	kept := f.identities[:0]
+	for _, i := range f.identities {
+		if i.UserID != userID {
+			kept = append(kept, i)
+		}
+	}
+	f.identities = kept
+	return nil
+}
@@ -0,0 +1,177 @@
+package internal_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/soydoradesu/product_discovery/internal/config"
+	"github.com/soydoradesu/product_discovery/internal/domain"
+	"github.com/soydoradesu/product_discovery/internal/http/handlers"
+	"github.com/soydoradesu/product_discovery/internal/repository"
+	"github.com/soydoradesu/product_discovery/internal/service"
+)
+
+// fakeBrands refuses to delete the brands listed in inUse.
+type fakeBrands struct {
+	items  []domain.Brand
+	inUse  map[int64]bool
+	nextID int64
+}
+
+func (f *fakeBrands) List(ctx context.Context) ([]domain.Brand, error) {
+	return f.items, nil
+}
+
+func (f *fakeBrands) GetByID(ctx context.Context, id int64) (domain.Brand, error) {
+	for _, b := range f.items {
+		if b.ID == id {
+			return b, nil
+		}
+	}
+	return domain.Brand{}, repository.ErrNotFound
+}
+
+func (f *fakeBrands) Create(ctx context.Context, b domain.Brand) (domain.Brand, error) {
+	if b.Slug == "" {
+		b.Slug = strings.ToLower(strings.ReplaceAll(b.Name, " ", "-"))
+	}
+	if err := f.check(b); err != nil {
+		return domain.Brand{}, err
+	}
+	f.nextID++
+	b.ID = f.nextID
+	f.items = append(f.items, b)
+	return b, nil
+}
+
+func (f *fakeBrands) Update(ctx context.Context, b domain.Brand) (domain.Brand, error) {
+	for i, cur := range f.items {
+		if cur.ID != b.ID {
+			continue
+		}
+		if b.Slug == "" {
+			b.Slug = cur.Slug
+		}
+		if err := f.check(b); err != nil {
+			return domain.Brand{}, err
+		}
+		f.items[i] = b
+		return b, nil
+	}
+	return domain.Brand{}, repository.ErrNotFound
+}
+
+func (f *fakeBrands) Delete(ctx context.Context, id int64) error {
+	if f.inUse[id] {
+		return repository.ErrConflict
+	}
+	for i, b := range f.items {
+		if b.ID == id {
+			f.items = append(f.items[:i], f.items[i+1:]...)
+			return nil
+		}
+	}
+	return repository.ErrNotFound
+}
+
+func (f *fakeBrands) check(b domain.Brand) error {
+	for _, other := range f.items {
+		if other.ID != b.ID && (strings.EqualFold(other.Name, b.Name) || other.Slug == b.Slug) {
+			return repository.ErrConflict
+		}
+	}
+	return nil
+}
+
+func TestBrands_AdminWrites(t *testing.T) {
+	brands := &fakeBrands{inUse: map[int64]bool{1: true}}
+	h := &handlers.BrandHandlers{Brands: service.NewBrandService(brands)}
+	router := chi.NewRouter()
+	router.Get("/api/brands", h.List)
+	router.Post("/api/admin/brands", h.Create)
+	router.Put("/api/admin/brands/{id}", h.Update)
+	router.Delete("/api/admin/brands/{id}", h.Delete)
+
+	rr := sendJSON(router, http.MethodPost, "/api/admin/brands", `{"name":" ","slug":"Not A Slug"}`)
+	if rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), `"name"`) || !strings.Contains(rr.Body.String(), `"slug"`) {
+		t.Fatalf("expected name and slug errors, got %d %s", rr.Code, rr.Body.String())
+	}
+
+	rr = sendJSON(router, http.MethodPost, "/api/admin/brands", `{"name":" Acme "}`)
+	var created domain.Brand
+	if err := json.Unmarshal(rr.Body.Bytes(), &created); rr.Code != http.StatusCreated || err != nil || created.Name != "Acme" || created.Slug != "acme" {
+		t.Fatalf("create: %d %s", rr.Code, rr.Body.String())
+	}
+	if rr := sendJSON(router, http.MethodPost, "/api/admin/brands", `{"name":"ACME"}`); rr.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for a taken name got %d", rr.Code)
+	}
+	if rr := sendJSON(router, http.MethodPost, "/api/admin/brands", `{"name":"Globex"}`); rr.Code != http.StatusCreated {
+		t.Fatalf("create: %d %s", rr.Code, rr.Body.String())
+	}
+
+	// an empty slug keeps the current one
+	rr = sendJSON(router, http.MethodPut, "/api/admin/brands/2", `{"name":"Globex Corp"}`)
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"slug":"globex"`) {
+		t.Fatalf("update: %d %s", rr.Code, rr.Body.String())
+	}
+	if rr := sendJSON(router, http.MethodPut, "/api/admin/brands/9", `{"name":"Initech"}`); rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 got %d", rr.Code)
+	}
+
+	if rr := sendJSON(router, http.MethodDelete, "/api/admin/brands/1", ""); rr.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for a brand in use got %d", rr.Code)
+	}
+	if rr := sendJSON(router, http.MethodDelete, "/api/admin/brands/2", ""); rr.Code != http.StatusOK {
+		t.Fatalf("delete: %d %s", rr.Code, rr.Body.String())
+	}
+
+	rr = sendJSON(router, http.MethodGet, "/api/brands", "")
+	var list struct {
+		Items []domain.Brand `json:"items"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &list); err != nil || len(list.Items) != 1 || list.Items[0].Name != "Acme" {
+		t.Fatalf("list: %s", rr.Body.String())
+	}
+}
+
+func TestBrands_ProductWritesAndSearchFilter(t *testing.T) {
+	products := &fakeProducts{categories: map[int64]string{1: "Audio"}}
+	svc := service.NewProductService(products)
+	svc.Brands = &fakeBrands{items: []domain.Brand{{ID: 1, Name: "Acme", Slug: "acme"}}}
+	h := &handlers.ProductHandlers{Products: svc, Visibility: config.ProductVisibilityFull}
+	router := chi.NewRouter()
+	router.Post("/api/admin/products", h.Create)
+	router.Patch("/api/admin/products/{id}", h.Patch)
+
+	const product = `"name":"Boom 2","price":1500000,"description":"Portable speaker","rating":4.5,"inStock":true,"categoryIds":[1]`
+	rr := sendJSON(router, http.MethodPost, "/api/admin/products", `{`+product+`,"brandId":7}`)
+	if rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), `"brandId"`) {
+		t.Fatalf("expected a brandId error, got %d %s", rr.Code, rr.Body.String())
+	}
+	if rr := sendJSON(router, http.MethodPost, "/api/admin/products", `{`+product+`,"brandId":1}`); rr.Code != http.StatusCreated {
+		t.Fatalf("create: %d %s", rr.Code, rr.Body.String())
+	}
+	if b := products.byID[1].Brand; b == nil || b.ID != 1 {
+		t.Fatalf("expected brand 1, got %+v", b)
+	}
+
+	// a patch without brandId keeps the brand and 0 removes it
+	if rr := sendJSON(router, http.MethodPatch, "/api/admin/products/1", `{"rating":4}`); rr.Code != http.StatusOK || products.byID[1].Brand == nil {
+		t.Fatalf("expected the brand kept, got %d %s", rr.Code, rr.Body.String())
+	}
+	if rr := sendJSON(router, http.MethodPatch, "/api/admin/products/1", `{"brandId":0}`); rr.Code != http.StatusOK || products.byID[1].Brand != nil {
+		t.Fatalf("expected the brand removed, got %d %s", rr.Code, rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	h.Search(rr, httptest.NewRequest(http.MethodGet, "/api/products/search?brand=1&brand=x&brand=-2&brand=3", nil))
+	if got := products.searchParams.BrandID; len(got) != 2 || got[0] != 1 || got[1] != 3 {
+		t.Fatalf("expected brands [1 3], got %v", got)
+	}
+}
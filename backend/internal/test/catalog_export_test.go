@@ -0,0 +1,105 @@
+package internal_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/soydoradesu/product_discovery/internal/domain"
+	"github.com/soydoradesu/product_discovery/internal/http/handlers"
+	"github.com/soydoradesu/product_discovery/internal/service"
+)
+
+func exportFixture() *fakeProducts {
+	key := "sku-1"
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	return &fakeProducts{byID: map[int64]domain.Product{
+		2: {ID: 2, Name: "Lamp", Price: domain.Money{Amount: 2500, Currency: "IDR"}, Description: "Bright, warm", Rating: 3, InStock: false, CreatedAt: created},
+		1: {
+			ID: 1, ExternalKey: &key, Name: "Speaker", Price: domain.Money{Amount: 10050, Currency: "IDR"}, Description: "Loud", Rating: 4.5, InStock: true, CreatedAt: created,
+			Images:     []domain.ProductImage{{URL: "https://img.example/a.jpg", Position: 1}, {URL: "https://img.example/b.jpg", Position: 2}},
+			Categories: []domain.Category{{ID: 1, Name: "Audio"}, {ID: 2, Name: "Home"}},
+		},
+	}}
+}
+
+func TestProductExport_CSVRoundTripsThroughImport(t *testing.T) {
+	svc := service.NewProductService(exportFixture())
+
+	var out bytes.Buffer
+	if err := svc.Export(context.Background(), &out, service.CatalogFormatCSV, domain.SearchParams{}); err != nil {
+		t.Fatal(err)
+	}
+	want := "id,externalKey,name,price,currency,description,rating,inStock,createdAt,images,categories\n" +
+		"1,sku-1,Speaker,100.50,IDR,Loud,4.5,true,2026-01-02T03:04:05Z,https://img.example/a.jpg|https://img.example/b.jpg,Audio|Home\n" +
+		"2,,Lamp,25.00,IDR,\"Bright, warm\",3,false,2026-01-02T03:04:05Z,,\n"
+	if out.String() != want {
+		t.Fatalf("unexpected csv:\n%s", out.String())
+	}
+
+	imports := &fakeCatalogImports{}
+	summary, err := service.NewCatalogImportService(imports).Import(context.Background(), &out, service.CatalogFormatCSV, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the second product has no external key, so only the first comes back
+	if len(imports.rows) != 1 || len(summary.Errors) != 1 || summary.Errors[0].Field != "externalKey" {
+		t.Fatalf("unexpected re-import %+v rows=%+v", summary, imports.rows)
+	}
+	if row := imports.rows[0]; row.Product.Name != "Speaker" || row.Product.Price.Amount != 10050 || len(row.Product.Images) != 2 || strings.Join(row.Categories, ",") != "Audio,Home" {
+		t.Fatalf("unexpected re-imported row %+v", row)
+	}
+}
+
+func TestProductExport_JSONAndFilters(t *testing.T) {
+	svc := service.NewProductService(exportFixture())
+
+	var out bytes.Buffer
+	inStock := true
+	if err := svc.Export(context.Background(), &out, service.CatalogFormatJSON, domain.SearchParams{InStock: &inStock}); err != nil {
+		t.Fatal(err)
+	}
+	var items []map[string]any
+	if err := json.Unmarshal(out.Bytes(), &items); err != nil {
+		t.Fatalf("expected a json array, got %q: %v", out.String(), err)
+	}
+	if len(items) != 1 || items[0]["externalKey"] != "sku-1" {
+		t.Fatalf("unexpected items %v", items)
+	}
+
+	out.Reset()
+	empty := &fakeProducts{}
+	if err := service.NewProductService(empty).Export(context.Background(), &out, service.CatalogFormatJSON, domain.SearchParams{}); err != nil || out.String() != "[]\n" {
+		t.Fatalf("expected an empty array, got %q err=%v", out.String(), err)
+	}
+
+	if err := svc.Export(context.Background(), &out, "xml", domain.SearchParams{}); !errors.Is(err, service.ErrInvalidExportFormat) {
+		t.Fatalf("expected ErrInvalidExportFormat got %v", err)
+	}
+}
+
+func TestProductExport_Handler(t *testing.T) {
+	h := &handlers.ProductHandlers{Products: service.NewProductService(exportFixture())}
+
+	rr := httptest.NewRecorder()
+	h.Export(rr, httptest.NewRequest(http.MethodGet, "/api/admin/products/export?format=ndjson&inStock=false", nil))
+	if rr.Code != http.StatusOK || rr.Header().Get("Content-Type") != "application/x-ndjson" {
+		t.Fatalf("unexpected response %d %q", rr.Code, rr.Header().Get("Content-Type"))
+	}
+	lines := strings.Split(strings.TrimSpace(rr.Body.String()), "\n")
+	if len(lines) != 1 || !strings.Contains(lines[0], `"name":"Lamp"`) {
+		t.Fatalf("expected only the out-of-stock product, got %q", rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	h.Export(rr, httptest.NewRequest(http.MethodGet, "/api/admin/products/export?format=xml", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown format got %d", rr.Code)
+	}
+}
@@ -0,0 +1,299 @@
+package internal_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/soydoradesu/product_discovery/internal/domain"
+	"github.com/soydoradesu/product_discovery/internal/http/handlers"
+	"github.com/soydoradesu/product_discovery/internal/repository/postgres"
+	"github.com/soydoradesu/product_discovery/internal/service"
+)
+
+type fakeCatalogImports struct {
+	rows   []domain.ImportRow
+	dryRun bool
+	result domain.ImportSummary
+	// ctxErr and deadline are what the last Import's context had
+	ctxErr   error
+	deadline time.Time
+}
+
+func (f *fakeCatalogImports) Import(ctx context.Context, rows []domain.ImportRow, dryRun bool) (domain.ImportSummary, error) {
+	f.rows, f.dryRun, f.ctxErr = rows, dryRun, ctx.Err()
+	f.deadline, _ = ctx.Deadline()
+	out := f.result
+	out.Created = len(rows) - out.Updated - out.Skipped
+	return out, nil
+}
+
+func TestCatalogImport_CSVReportsRowErrors(t *testing.T) {
+	repo := &fakeCatalogImports{result: domain.ImportSummary{Updated: 1}}
+	svc := service.NewCatalogImportService(repo)
+
+	csv := strings.Join([]string{
+		"externalKey,name,price,description,rating,inStock,images,categories",
+		"sku-1,Speaker,100,Loud,4.5,true,https://img.example/a.jpg|https://img.example/b.jpg,Audio|Home",
+		"sku-2,Headphones,abc,Quiet,4,false,,Audio",
+		"sku-1,Speaker Mini,80,Small,4,true,,",
+		"sku-3,Lamp,25,Bright,3,yes,,Home",
+		"sku-4,Lamp,30,Brighter,3.5,false,ftp://img,Home",
+		`sku-5,"Desk, oak",200,Sturdy,4,true,,Furniture`,
+	}, "\n")
+
+	summary, err := svc.Import(context.Background(), strings.NewReader(csv), service.CatalogFormatCSV, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !repo.dryRun || !summary.DryRun {
+		t.Fatalf("expected the dry run to reach the repository")
+	}
+	if len(repo.rows) != 2 || repo.rows[0].ExternalKey != "sku-1" || repo.rows[1].Product.Name != "Desk, oak" {
+		t.Fatalf("unexpected rows %+v", repo.rows)
+	}
+	first := repo.rows[0]
+	if len(first.Product.Images) != 2 || first.Product.Images[1].Position != 2 || strings.Join(first.Categories, ",") != "Audio,Home" {
+		t.Fatalf("unexpected first row %+v", first)
+	}
+
+	want := []domain.ImportRowError{
+		{Line: 3, Field: "price", Message: "must be a number"},
+		{Line: 4, Field: "externalKey", Message: "duplicates line 2"},
+		{Line: 5, Field: "inStock", Message: "must be true or false"},
+		{Line: 6, Field: "images[0]", Message: "must be an absolute http(s) URL"},
+	}
+	if len(summary.Errors) != len(want) {
+		t.Fatalf("expected %d errors got %+v", len(want), summary.Errors)
+	}
+	for i, e := range want {
+		if summary.Errors[i] != e {
+			t.Fatalf("error %d: expected %+v got %+v", i, e, summary.Errors[i])
+		}
+	}
+	if summary.Rows != 6 || summary.Created != 1 || summary.Updated != 1 || summary.Skipped != 4 {
+		t.Fatalf("unexpected summary %+v", summary)
+	}
+}
+
+func TestCatalogImport_NDJSONAndBadFiles(t *testing.T) {
+	repo := &fakeCatalogImports{}
+	svc := service.NewCatalogImportService(repo)
+
+	ndjson := `{"externalKey":"sku-1","name":"Speaker","price":100,"description":"Loud","rating":4,"inStock":true,"images":["https://img.example/a.jpg"],"categories":["Audio"]}
+
+{"externalKey":"sku-2","name":"Speaker","price":100,"description":"Loud","rating":4,"inStock":true}
+{"externalKey":"sku-3","colour":"red"}
+not json
+`
+	summary, err := svc.Import(context.Background(), strings.NewReader(ndjson), service.CatalogFormatNDJSON, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(repo.rows) != 1 || summary.Rows != 4 || summary.Created != 1 || summary.Skipped != 3 {
+		t.Fatalf("unexpected summary %+v rows=%+v", summary, repo.rows)
+	}
+	if e := summary.Errors[0]; e.Line != 3 || e.Message != "same name and description as line 1" {
+		t.Fatalf("expected the duplicate product on line 3, got %+v", e)
+	}
+	if e := summary.Errors[1]; e.Line != 4 || !strings.Contains(e.Message, "colour") {
+		t.Fatalf("expected the unknown field on line 4, got %+v", e)
+	}
+
+	if _, err := svc.Import(context.Background(), strings.NewReader("sku,name\n"), service.CatalogFormatCSV, true); !errors.Is(err, service.ErrInvalidImportFile) {
+		t.Fatalf("expected ErrInvalidImportFile for an unknown column, got %v", err)
+	}
+	if _, err := svc.Import(context.Background(), strings.NewReader(""), "xml", true); !errors.Is(err, service.ErrInvalidImportFile) {
+		t.Fatalf("expected ErrInvalidImportFile for an unknown format, got %v", err)
+	}
+}
+
+func TestImportHandlers_Upload(t *testing.T) {
+	repo := &fakeCatalogImports{}
+	h := &handlers.ImportHandlers{Imports: service.NewCatalogImportService(repo)}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, _ := mw.CreateFormFile("file", "catalog.csv")
+	_, _ = part.Write([]byte("externalKey,name,price,description,rating,inStock\nsku-1,Speaker,100,Loud,4,true\n"))
+	_ = mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/admin/products/import?dryRun=true", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	rr := httptest.NewRecorder()
+	h.Import(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200 got %d %s", rr.Code, rr.Body.String())
+	}
+	var summary domain.ImportSummary
+	_ = json.Unmarshal(rr.Body.Bytes(), &summary)
+	if !summary.DryRun || summary.Created != 1 || len(repo.rows) != 1 {
+		t.Fatalf("unexpected summary %+v", summary)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/admin/products/import", strings.NewReader("{}"))
+	req.Header.Set("Content-Type", "application/json")
+	rr = httptest.NewRecorder()
+	h.Import(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 without a known format got %d", rr.Code)
+	}
+
+	h.MaxBytes = 16
+	req = httptest.NewRequest(http.MethodPost, "/api/admin/products/import?format=ndjson", strings.NewReader(strings.Repeat(`{"externalKey":"x"}`+"\n", 4)))
+	rr = httptest.NewRecorder()
+	h.Import(rr, req)
+	if rr.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413 for an oversized body got %d", rr.Code)
+	}
+}
+
+func TestImportHandlers_OutlivesTheRequestContext(t *testing.T) {
+	repo := &fakeCatalogImports{}
+	h := &handlers.ImportHandlers{Imports: service.NewCatalogImportService(repo), Timeout: time.Minute}
+
+	// the router's per-request timeout cancels the request context long
+	// before a large file is imported
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest(http.MethodPost, "/api/admin/products/import?format=csv",
+		strings.NewReader("externalKey,name,price,description,rating,inStock\nsku-1,Speaker,100,Loud,4,true\n")).WithContext(ctx)
+	rr := httptest.NewRecorder()
+	h.Import(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200 got %d %s", rr.Code, rr.Body.String())
+	}
+	if repo.ctxErr != nil {
+		t.Fatalf("expected the import to run detached from the request, got %v", repo.ctxErr)
+	}
+	if repo.deadline.IsZero() || time.Until(repo.deadline) > time.Minute {
+		t.Fatalf("expected the import bounded by the timeout, got %v", repo.deadline)
+	}
+}
+
+func TestCatalogImportRepo_KeepsVariantRollupAndCurrency(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+	key := uniqueKey(t)
+	t.Cleanup(func() {
+		_, _ = pool.Exec(context.Background(), `DELETE FROM products WHERE external_key LIKE $1`, key+"%")
+	})
+	imports := postgres.NewCatalogImportRepo(pool)
+	products := postgres.NewProductRepo(pool)
+
+	row := func(suffix string, amount int64, currency string, inStock bool) domain.ImportRow {
+		return domain.ImportRow{Line: 2, ExternalKey: key + suffix, Product: domain.Product{
+			Name: "Import " + key + suffix, Price: domain.Money{Amount: amount, Currency: currency},
+			Description: "Imported", Rating: 4, InStock: inStock,
+		}}
+	}
+	productID := func(suffix string) int64 {
+		var id int64
+		if err := pool.QueryRow(ctx, `SELECT id FROM products WHERE external_key = $1`, key+suffix).Scan(&id); err != nil {
+			t.Fatalf("product %s: %v", suffix, err)
+		}
+		return id
+	}
+
+	if _, err := imports.Import(ctx, []domain.ImportRow{row("-v", 100000, "IDR", false), row("-p", 100000, "IDR", true)}, false); err != nil {
+		t.Fatal(err)
+	}
+	withVariants, plain := productID("-v"), productID("-p")
+	if err := products.ReplaceVariants(ctx, withVariants, []domain.Variant{
+		{SKU: key + "-a", Options: map[string]string{"color": "black"}, Price: domain.Money{Amount: 50000, Currency: "IDR"}, InStock: false},
+		{SKU: key + "-b", Options: map[string]string{"color": "white"}, Price: domain.Money{Amount: 90000, Currency: "IDR"}, InStock: true},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := products.SetPrice(ctx, plain, domain.Money{Amount: 700, Currency: "USD"}); err != nil {
+		t.Fatal(err)
+	}
+
+	summary, err := imports.Import(ctx, []domain.ImportRow{row("-v", 2000, "USD", true)}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(summary.Errors) != 1 || summary.Errors[0].Field != "currency" || summary.Updated != 0 {
+		t.Fatalf("expected a currency error, got %+v", summary)
+	}
+
+	if _, err := imports.Import(ctx, []domain.ImportRow{row("-v", 200000, "IDR", false), row("-p", 2000, "USD", true)}, false); err != nil {
+		t.Fatal(err)
+	}
+	var (
+		price, priceMax int64
+		currency        string
+		inStock         bool
+	)
+	if err := pool.QueryRow(ctx, `SELECT price_minor, price_max_minor, currency, in_stock FROM products WHERE id = $1`, withVariants).Scan(&price, &priceMax, &currency, &inStock); err != nil {
+		t.Fatal(err)
+	}
+	if price != 50000 || priceMax != 90000 || currency != "IDR" || !inStock {
+		t.Fatalf("expected the variant roll-up kept, got %d-%d %s inStock=%v", price, priceMax, currency, inStock)
+	}
+	var ownCurrencyPrices int
+	if err := pool.QueryRow(ctx, `SELECT COUNT(*) FROM product_prices WHERE product_id = $1 AND currency = 'USD'`, plain).Scan(&ownCurrencyPrices); err != nil {
+		t.Fatal(err)
+	}
+	if ownCurrencyPrices != 0 {
+		t.Fatal("expected the price list entry in the new own currency removed")
+	}
+}
+
+func TestCatalogImportRepo_TrackedStockOutranksFile(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+	key := uniqueKey(t)
+	t.Cleanup(func() {
+		_, _ = pool.Exec(context.Background(), `DELETE FROM products WHERE external_key = $1`, key)
+	})
+	imports := postgres.NewCatalogImportRepo(pool)
+	row := func(inStock bool) []domain.ImportRow {
+		return []domain.ImportRow{{Line: 2, ExternalKey: key, Product: domain.Product{
+			Name: "Import " + key, Price: domain.Money{Amount: 100000, Currency: "IDR"},
+			Description: "Imported", Rating: 4, InStock: inStock,
+		}}}
+	}
+
+	if _, err := imports.Import(ctx, row(true), false); err != nil {
+		t.Fatal(err)
+	}
+	var id int64
+	if err := pool.QueryRow(ctx, `SELECT id FROM products WHERE external_key = $1`, key).Scan(&id); err != nil {
+		t.Fatal(err)
+	}
+	zero := 0
+	if _, err := postgres.NewInventoryRepo(pool).Adjust(ctx, domain.StockAdjustmentInput{
+		ProductID: id, Warehouse: "main", OnHand: &zero, Reason: "count",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	summary, err := imports.Import(ctx, row(true), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Skipped != 1 || summary.Updated != 0 {
+		t.Fatalf("expected the row skipped, got %+v", summary)
+	}
+	// a changed row still takes its stock from the inventory
+	changed := row(true)
+	changed[0].Product.Rating = 5
+	if _, err := imports.Import(ctx, changed, false); err != nil {
+		t.Fatal(err)
+	}
+	var inStock bool
+	if err := pool.QueryRow(ctx, `SELECT in_stock FROM products WHERE id = $1`, id).Scan(&inStock); err != nil {
+		t.Fatal(err)
+	}
+	if inStock {
+		t.Fatal("expected the product out of stock with nothing on hand")
+	}
+}
@@ -2,21 +2,106 @@ package internal_test
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	"github.com/soydoradesu/product_discovery/internal/domain"
+	"github.com/soydoradesu/product_discovery/internal/repository"
 	"github.com/soydoradesu/product_discovery/internal/service"
 )
 
 type fakeCategories struct {
-	items []domain.Category
-	err   error
+	items  []domain.Category
+	counts map[int64]int64
+	err    error
 }
 
 func (f *fakeCategories) List(ctx context.Context) ([]domain.Category, error) {
 	return f.items, f.err
 }
 
+func (f *fakeCategories) GetByID(ctx context.Context, id int64) (domain.Category, error) {
+	for _, c := range f.items {
+		if c.ID == id {
+			return c, nil
+		}
+	}
+	return domain.Category{}, repository.ErrNotFound
+}
+
+func (f *fakeCategories) ProductCounts(ctx context.Context) (map[int64]int64, error) {
+	return f.counts, f.err
+}
+
+func (f *fakeCategories) Create(ctx context.Context, c domain.Category) (domain.Category, error) {
+	c.ID = int64(len(f.items) + 1)
+	if err := f.check(c); err != nil {
+		return domain.Category{}, err
+	}
+	f.items = append(f.items, c)
+	return c, nil
+}
+
+func (f *fakeCategories) Update(ctx context.Context, c domain.Category) (domain.Category, error) {
+	idx := -1
+	for i, existing := range f.items {
+		if existing.ID == c.ID {
+			idx = i
+		}
+	}
+	if idx < 0 {
+		return domain.Category{}, repository.ErrNotFound
+	}
+	// walk up from the new parent; reaching c means a cycle
+	for p := c.ParentID; p != nil; {
+		if *p == c.ID {
+			return domain.Category{}, repository.ErrInvalidReference
+		}
+		parent, err := f.GetByID(ctx, *p)
+		if err != nil {
+			break
+		}
+		p = parent.ParentID
+	}
+	if c.Slug == "" {
+		c.Slug = f.items[idx].Slug
+	}
+	if err := f.check(c); err != nil {
+		return domain.Category{}, err
+	}
+	f.items[idx] = c
+	return c, nil
+}
+
+func (f *fakeCategories) check(c domain.Category) error {
+	if c.ParentID != nil {
+		if _, err := f.GetByID(context.Background(), *c.ParentID); err != nil {
+			return repository.ErrInvalidReference
+		}
+	}
+	for _, other := range f.items {
+		if other.ID != c.ID && (other.Name == c.Name || (c.Slug != "" && other.Slug == c.Slug)) {
+			return repository.ErrConflict
+		}
+	}
+	return nil
+}
+
+func (f *fakeCategories) Delete(ctx context.Context, id int64) error {
+	for _, c := range f.items {
+		if c.ParentID != nil && *c.ParentID == id {
+			return repository.ErrConflict
+		}
+	}
+	for i, c := range f.items {
+		if c.ID == id {
+			f.items = append(f.items[:i], f.items[i+1:]...)
+			return nil
+		}
+	}
+	return repository.ErrNotFound
+}
+
 func TestCategoryService_List_OK(t *testing.T) {
 	svc := service.NewCategoryService(&fakeCategories{
 		items: []domain.Category{{ID: 1, Name: "Laptop"}, {ID: 2, Name: "Phone"}},
@@ -30,3 +115,78 @@ func TestCategoryService_List_OK(t *testing.T) {
 		t.Fatalf("expected 2, got %d", len(got))
 	}
 }
+
+func TestCategoryService_Tree(t *testing.T) {
+	electronics, audio := int64(1), int64(3)
+	svc := service.NewCategoryService(&fakeCategories{
+		items: []domain.Category{
+			{ID: 1, Name: "Electronics"},
+			{ID: 2, Name: "Phones", ParentID: &electronics, SortOrder: 2},
+			{ID: 3, Name: "Audio", ParentID: &electronics, SortOrder: 1},
+			{ID: 4, Name: "Headphones", ParentID: &audio},
+			{ID: 5, Name: "Books"},
+		},
+		counts: map[int64]int64{1: 7, 2: 3, 3: 4, 4: 4},
+	})
+
+	tree, err := svc.Tree(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tree) != 2 || tree[0].Name != "Books" || tree[1].Name != "Electronics" {
+		t.Fatalf("unexpected roots %+v", tree)
+	}
+	e := tree[1]
+	if e.ProductCount != 7 || len(e.Children) != 2 || e.Children[0].Name != "Audio" || e.Children[1].Name != "Phones" {
+		t.Fatalf("unexpected electronics node %+v", e)
+	}
+	if h := e.Children[0].Children; len(h) != 1 || h[0].Name != "Headphones" || h[0].ProductCount != 4 || h[0].Children == nil {
+		t.Fatalf("unexpected audio children %+v", h)
+	}
+	if tree[0].ProductCount != 0 {
+		t.Fatalf("expected no products in Books, got %d", tree[0].ProductCount)
+	}
+}
+
+func TestCategoryService_Writes(t *testing.T) {
+	ctx := context.Background()
+	svc := service.NewCategoryService(&fakeCategories{})
+
+	_, err := svc.Create(ctx, domain.CategoryInput{Name: " ", Slug: "Not A Slug"})
+	var invalid *service.ValidationError
+	if !errors.As(err, &invalid) || invalid.Fields["name"] == "" || invalid.Fields["slug"] == "" {
+		t.Fatalf("expected name and slug errors, got %v", err)
+	}
+
+	root, err := svc.Create(ctx, domain.CategoryInput{Name: "Electronics", Slug: "electronics"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	child, err := svc.Create(ctx, domain.CategoryInput{Name: "Audio", ParentID: &root.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Create(ctx, domain.CategoryInput{Name: "Audio"}); err != service.ErrCategoryExists {
+		t.Fatalf("expected ErrCategoryExists got %v", err)
+	}
+
+	// moving the root under its own child would form a cycle
+	_, err = svc.Update(ctx, root.ID, domain.CategoryInput{Name: "Electronics", ParentID: &child.ID})
+	if !errors.As(err, &invalid) || invalid.Fields["parentId"] == "" {
+		t.Fatalf("expected a parentId error, got %v", err)
+	}
+	updated, err := svc.Update(ctx, root.ID, domain.CategoryInput{Name: "Electronics & Gadgets", SortOrder: 3})
+	if err != nil || updated.Slug != "electronics" || updated.SortOrder != 3 {
+		t.Fatalf("expected the slug to be kept, got %+v err=%v", updated, err)
+	}
+
+	if err := svc.Delete(ctx, root.ID); err != service.ErrCategoryHasChildren {
+		t.Fatalf("expected ErrCategoryHasChildren got %v", err)
+	}
+	if err := svc.Delete(ctx, child.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.Delete(ctx, child.ID); err != service.ErrCategoryNotFound {
+		t.Fatalf("expected ErrCategoryNotFound got %v", err)
+	}
+}
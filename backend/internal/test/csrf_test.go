@@ -0,0 +1,87 @@
+package internal_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/soydoradesu/product_discovery/internal/http/handlers"
+	"github.com/soydoradesu/product_discovery/internal/http/middleware"
+)
+
+func csrfRouter() http.Handler {
+	h := &handlers.AuthHandlers{}
+	r := chi.NewRouter()
+	r.Use(middleware.RequireCSRF)
+	r.Get("/api/auth/csrf", h.CSRFToken)
+	r.Get("/api/thing", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	r.Post("/api/thing", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	return r
+}
+
+func TestCSRF(t *testing.T) {
+	router := csrfRouter()
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/auth/csrf", nil))
+	var body struct {
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil || body.Token == "" {
+		t.Fatalf("expected a token, got %s", rr.Body.String())
+	}
+	var cookie *http.Cookie
+	for _, c := range rr.Result().Cookies() {
+		if c.Name == middleware.CSRFCookie {
+			cookie = c
+		}
+	}
+	if cookie == nil || cookie.Value != body.Token {
+		t.Fatal("expected the token in the csrf cookie")
+	}
+
+	for _, tc := range []struct {
+		name   string
+		method string
+		cookie bool
+		header string
+		bearer bool
+		want   int
+	}{
+		{"safe method", http.MethodGet, false, "", false, http.StatusOK},
+		{"no token", http.MethodPost, false, "", false, http.StatusForbidden},
+		{"cookie only", http.MethodPost, true, "", false, http.StatusForbidden},
+		{"header mismatch", http.MethodPost, true, "forged", false, http.StatusForbidden},
+		{"header without cookie", http.MethodPost, false, body.Token, false, http.StatusForbidden},
+		{"matching", http.MethodPost, true, body.Token, false, http.StatusOK},
+		{"bearer exempt", http.MethodPost, false, "", true, http.StatusOK},
+	} {
+		req := httptest.NewRequest(tc.method, "/api/thing", nil)
+		if tc.cookie {
+			req.AddCookie(cookie)
+		}
+		if tc.header != "" {
+			req.Header.Set(middleware.CSRFHeader, tc.header)
+		}
+		if tc.bearer {
+			req.Header.Set("Authorization", "Bearer pd_test")
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		if rr.Code != tc.want {
+			t.Fatalf("%s: expected %d got %d", tc.name, tc.want, rr.Code)
+		}
+	}
+
+	// asking again keeps the existing token
+	req := httptest.NewRequest(http.MethodGet, "/api/auth/csrf", nil)
+	req.AddCookie(cookie)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil || body.Token != cookie.Value {
+		t.Fatalf("expected the existing token back, got %s", rr.Body.String())
+	}
+}
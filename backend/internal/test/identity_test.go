@@ -0,0 +1,98 @@
+package internal_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/soydoradesu/product_discovery/internal/auth"
+	"github.com/soydoradesu/product_discovery/internal/domain"
+	"github.com/soydoradesu/product_discovery/internal/http/middleware"
+	"github.com/soydoradesu/product_discovery/internal/service"
+)
+
+func TestUnlinkIdentity_RefusesLastLoginMethod(t *testing.T) {
+	ctx := context.Background()
+	f := &fakeUsers{byEmail: map[string]domain.User{}, byID: map[int64]domain.User{}}
+	svc := service.NewAuthService(f)
+
+	id, _, err := svc.OAuthLogin(ctx, "google", "g-1", "oauth@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.LinkIdentity(ctx, id, "okta", "o-1", ""); err != nil {
+		t.Fatalf("link: %v", err)
+	}
+	if err := svc.LinkIdentity(ctx, id, "okta", "o-2", ""); err != service.ErrOAuthAccountConflict {
+		t.Fatalf("expected second okta identity to conflict, got %v", err)
+	}
+
+	if err := svc.UnlinkIdentity(ctx, id, "google"); err != nil {
+		t.Fatalf("unlink google: %v", err)
+	}
+	if err := svc.UnlinkIdentity(ctx, id, "google"); err != service.ErrIdentityNotFound {
+		t.Fatalf("expected ErrIdentityNotFound got %v", err)
+	}
+	if err := svc.UnlinkIdentity(ctx, id, "okta"); err != service.ErrLastLoginMethod {
+		t.Fatalf("expected ErrLastLoginMethod got %v", err)
+	}
+}
+
+func TestUnlinkIdentity_AllowedWithPassword(t *testing.T) {
+	ctx := context.Background()
+	hash, _ := service.HashPassword("Password123!")
+	u := domain.User{ID: 1, Email: "demo@example.com", PasswordHash: &hash}
+	f := &fakeUsers{byEmail: map[string]domain.User{u.Email: u}, byID: map[int64]domain.User{1: u}}
+	svc := service.NewAuthService(f)
+
+	if _, linked, err := svc.OAuthLogin(ctx, "google", "g-1", "demo@example.com"); err != nil || !linked {
+		t.Fatalf("expected the identity linked by email, got %v %v", linked, err)
+	}
+	if err := svc.UnlinkIdentity(ctx, 1, "google"); err != nil {
+		t.Fatalf("expected unlink to succeed for password users, got %v", err)
+	}
+}
+
+func TestRequireRecentAuth(t *testing.T) {
+	keys := auth.NewHMACKeyring("test-secret")
+
+	r := chi.NewRouter()
+	r.Use(middleware.RequireAuth(keys, middleware.Authenticators{}))
+	r.With(middleware.RequireRecentAuth(10*time.Minute)).Delete("/api/me/identities/google", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	fresh, _ := auth.SignJWT(keys, 1, time.Hour)
+
+	old := time.Now().Add(-time.Hour)
+	staleTok := jwt.NewWithClaims(jwt.SigningMethodHS256, auth.Claims{
+		UserID: 1,
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(old),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	})
+	staleTok.Header["kid"] = auth.HMACKeyID
+	stale, _ := staleTok.SignedString([]byte("test-secret"))
+
+	for _, tc := range []struct {
+		token string
+		want  int
+	}{
+		{fresh, http.StatusOK},
+		{stale, http.StatusForbidden},
+	} {
+		req := httptest.NewRequest(http.MethodDelete, "/api/me/identities/google", nil)
+		req.AddCookie(&http.Cookie{Name: "session", Value: tc.token})
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		if rr.Code != tc.want {
+			t.Fatalf("expected %d got %d body=%s", tc.want, rr.Code, rr.Body.String())
+		}
+	}
+}
@@ -0,0 +1,407 @@
+package internal_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/soydoradesu/product_discovery/internal/domain"
+	"github.com/soydoradesu/product_discovery/internal/http/handlers"
+	"github.com/soydoradesu/product_discovery/internal/repository"
+	"github.com/soydoradesu/product_discovery/internal/repository/postgres"
+	"github.com/soydoradesu/product_discovery/internal/service"
+)
+
+type stockKey struct {
+	productID int64
+	variantID int64
+	warehouse string
+}
+
+type fakeReservation struct {
+	domain.Reservation
+	key stockKey
+}
+
+// fakeInventory keeps on-hand and reserved quantities per item and warehouse
+// the way the inventory tables do.
+type fakeInventory struct {
+	warehouses   []string
+	onHand       map[stockKey]int
+	reserved     map[stockKey]int
+	adjustments  []domain.StockAdjustment
+	reservations map[int64]*fakeReservation
+	nextID       int64
+}
+
+func newFakeInventory(warehouses ...string) *fakeInventory {
+	return &fakeInventory{
+		warehouses:   warehouses,
+		onHand:       map[stockKey]int{},
+		reserved:     map[stockKey]int{},
+		reservations: map[int64]*fakeReservation{},
+	}
+}
+
+func itemKey(productID int64, variantID *int64, warehouse string) stockKey {
+	k := stockKey{productID: productID, warehouse: warehouse}
+	if variantID != nil {
+		k.variantID = *variantID
+	}
+	return k
+}
+
+func (f *fakeInventory) knownWarehouse(code string) bool {
+	for _, w := range f.warehouses {
+		if w == code {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *fakeInventory) ListWarehouses(ctx context.Context) ([]domain.Warehouse, error) {
+	out := make([]domain.Warehouse, len(f.warehouses))
+	for i, code := range f.warehouses {
+		out[i] = domain.Warehouse{ID: int64(i + 1), Code: code, Name: code}
+	}
+	return out, nil
+}
+
+func (f *fakeInventory) CreateWarehouse(ctx context.Context, w domain.Warehouse) (domain.Warehouse, error) {
+	if f.knownWarehouse(w.Code) {
+		return domain.Warehouse{}, repository.ErrConflict
+	}
+	f.warehouses = append(f.warehouses, w.Code)
+	w.ID = int64(len(f.warehouses))
+	return w, nil
+}
+
+func (f *fakeInventory) ProductStock(ctx context.Context, productID int64) (domain.ProductStock, error) {
+	stock := domain.ProductStock{ProductID: productID, LowStockThreshold: 5}
+	for k, onHand := range f.onHand {
+		if k.productID != productID {
+			continue
+		}
+		l := domain.StockLevel{Warehouse: k.warehouse, OnHand: onHand, Reserved: f.reserved[k]}
+		if k.variantID != 0 {
+			id := k.variantID
+			l.VariantID = &id
+		}
+		l.Available = l.OnHand - l.Reserved
+		stock.InStock = stock.InStock || l.Available > 0
+		stock.Levels = append(stock.Levels, l)
+	}
+	return stock, nil
+}
+
+func (f *fakeInventory) SetLowStockThreshold(ctx context.Context, productID int64, threshold int) error {
+	return nil
+}
+
+func (f *fakeInventory) LowStock(ctx context.Context, limit int) ([]domain.LowStockItem, error) {
+	return nil, nil
+}
+
+func (f *fakeInventory) Adjust(ctx context.Context, in domain.StockAdjustmentInput) (domain.StockAdjustment, error) {
+	if !f.knownWarehouse(in.Warehouse) {
+		return domain.StockAdjustment{}, repository.ErrInvalidReference
+	}
+	k := itemKey(in.ProductID, in.VariantID, in.Warehouse)
+	after := f.onHand[k] + in.Delta
+	if in.OnHand != nil {
+		after = *in.OnHand
+	}
+	if after < 0 || after < f.reserved[k] {
+		return domain.StockAdjustment{}, repository.ErrConflict
+	}
+	f.nextID++
+	adj := domain.StockAdjustment{
+		ID:          f.nextID,
+		ProductID:   in.ProductID,
+		VariantID:   in.VariantID,
+		Warehouse:   in.Warehouse,
+		Delta:       after - f.onHand[k],
+		OnHandAfter: after,
+		Reason:      in.Reason,
+		ActorUserID: in.ActorUserID,
+		CreatedAt:   time.Now(),
+	}
+	f.onHand[k] = after
+	f.adjustments = append(f.adjustments, adj)
+	return adj, nil
+}
+
+func (f *fakeInventory) Adjustments(ctx context.Context, productID int64, beforeID int64, limit int) ([]domain.StockAdjustment, error) {
+	var out []domain.StockAdjustment
+	for i := len(f.adjustments) - 1; i >= 0 && len(out) < limit; i-- {
+		a := f.adjustments[i]
+		if a.ProductID == productID && (beforeID == 0 || a.ID < beforeID) {
+			out = append(out, a)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeInventory) Reserve(ctx context.Context, in domain.ReservationInput) (domain.Reservation, error) {
+	if in.Warehouse != "" && !f.knownWarehouse(in.Warehouse) {
+		return domain.Reservation{}, repository.ErrInvalidReference
+	}
+	var pick *stockKey
+	best := 0
+	for _, w := range f.warehouses {
+		if in.Warehouse != "" && w != in.Warehouse {
+			continue
+		}
+		k := itemKey(in.ProductID, in.VariantID, w)
+		if available := f.onHand[k] - f.reserved[k]; available >= in.Quantity && available > best {
+			pick, best = &k, available
+		}
+	}
+	if pick == nil {
+		return domain.Reservation{}, repository.ErrConflict
+	}
+	f.reserved[*pick] += in.Quantity
+	f.nextID++
+	res := domain.Reservation{
+		ID:        f.nextID,
+		ProductID: in.ProductID,
+		VariantID: in.VariantID,
+		Warehouse: pick.warehouse,
+		Quantity:  in.Quantity,
+		Reference: in.Reference,
+		Status:    domain.ReservationActive,
+		CreatedAt: time.Now(),
+	}
+	f.reservations[res.ID] = &fakeReservation{Reservation: res, key: *pick}
+	return res, nil
+}
+
+func (f *fakeInventory) Release(ctx context.Context, id int64) (domain.Reservation, error) {
+	res, ok := f.reservations[id]
+	if !ok {
+		return domain.Reservation{}, repository.ErrNotFound
+	}
+	if res.Status != domain.ReservationActive {
+		return domain.Reservation{}, repository.ErrConflict
+	}
+	f.reserved[res.key] -= res.Quantity
+	now := time.Now()
+	res.Status, res.ReleasedAt = domain.ReservationReleased, &now
+	return res.Reservation, nil
+}
+
+func inventoryRouter(inv *fakeInventory, products *fakeProducts) http.Handler {
+	h := &handlers.InventoryHandlers{Inventory: service.NewInventoryService(inv, products)}
+	router := chi.NewRouter()
+	router.Get("/api/admin/products/{id}/inventory", h.Get)
+	router.Get("/api/admin/products/{id}/inventory/adjustments", h.Adjustments)
+	router.Post("/api/admin/products/{id}/inventory/adjustments", h.Adjust)
+	router.Post("/api/admin/products/{id}/inventory/reservations", h.Reserve)
+	router.Post("/api/admin/inventory/reservations/{id}/release", h.Release)
+	router.Post("/api/admin/inventory/warehouses", h.CreateWarehouse)
+	return router
+}
+
+func TestInventory_AdjustWritesTheLogAndGuardsReservedStock(t *testing.T) {
+	inv := newFakeInventory("main", "east")
+	products := &fakeProducts{byID: map[int64]domain.Product{1: {ID: 1, Name: "Speaker"}}}
+	router := inventoryRouter(inv, products)
+
+	rr := sendJSON(router, http.MethodPost, "/api/admin/products/1/inventory/adjustments", `{"delta":0,"onHand":-1,"reason":" ","variantId":3}`)
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 got %d %s", rr.Code, rr.Body.String())
+	}
+	var body struct {
+		Error struct {
+			Fields map[string]string `json:"fields"`
+		} `json:"error"`
+	}
+	_ = json.Unmarshal(rr.Body.Bytes(), &body)
+	if body.Error.Fields["onHand"] == "" || body.Error.Fields["reason"] == "" {
+		t.Fatalf("expected errors on onHand and reason, got %v", body.Error.Fields)
+	}
+	if rr := sendJSON(router, http.MethodPost, "/api/admin/products/1/inventory/adjustments", `{"delta":5,"reason":"restock","variantId":3}`); rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), "variantId") {
+		t.Fatalf("expected 400 on variantId for a product without variants, got %d %s", rr.Code, rr.Body.String())
+	}
+	if rr := sendJSON(router, http.MethodPost, "/api/admin/products/1/inventory/adjustments", `{"delta":5,"reason":"restock","warehouse":"west"}`); rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), "warehouse") {
+		t.Fatalf("expected 400 for an unknown warehouse, got %d %s", rr.Code, rr.Body.String())
+	}
+
+	// without a warehouse stock goes to the default one
+	rr = sendJSON(router, http.MethodPost, "/api/admin/products/1/inventory/adjustments", `{"delta":10,"reason":"restock"}`)
+	var adj domain.StockAdjustment
+	if err := json.Unmarshal(rr.Body.Bytes(), &adj); rr.Code != http.StatusCreated || err != nil || adj.Warehouse != domain.DefaultWarehouse || adj.OnHandAfter != 10 {
+		t.Fatalf("adjust: %d %s", rr.Code, rr.Body.String())
+	}
+	if rr := sendJSON(router, http.MethodPost, "/api/admin/products/1/inventory/reservations", `{"quantity":4,"reference":"order-1"}`); rr.Code != http.StatusCreated {
+		t.Fatalf("reserve: %d %s", rr.Code, rr.Body.String())
+	}
+
+	// a count below what is reserved is refused
+	if rr := sendJSON(router, http.MethodPost, "/api/admin/products/1/inventory/adjustments", `{"onHand":3,"reason":"stock count"}`); rr.Code != http.StatusConflict || !strings.Contains(rr.Body.String(), "INSUFFICIENT_STOCK") {
+		t.Fatalf("expected 409 setting on-hand below reserved, got %d %s", rr.Code, rr.Body.String())
+	}
+	rr = sendJSON(router, http.MethodPost, "/api/admin/products/1/inventory/adjustments", `{"onHand":6,"reason":"stock count"}`)
+	if err := json.Unmarshal(rr.Body.Bytes(), &adj); rr.Code != http.StatusCreated || err != nil || adj.Delta != -4 {
+		t.Fatalf("expected the count recorded as a delta of -4, got %d %s", rr.Code, rr.Body.String())
+	}
+
+	rr = sendJSON(router, http.MethodGet, "/api/admin/products/1/inventory/adjustments?limit=1", "")
+	var log struct {
+		Items      []domain.StockAdjustment `json:"items"`
+		NextBefore int64                    `json:"nextBefore"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &log); err != nil || len(log.Items) != 1 || log.Items[0].Reason != "stock count" || log.NextBefore != log.Items[0].ID {
+		t.Fatalf("expected the newest adjustment first, got %s", rr.Body.String())
+	}
+
+	rr = sendJSON(router, http.MethodGet, "/api/admin/products/1/inventory", "")
+	var stock domain.ProductStock
+	if err := json.Unmarshal(rr.Body.Bytes(), &stock); err != nil || len(stock.Levels) != 1 || stock.Levels[0].Available != 2 || !stock.InStock {
+		t.Fatalf("unexpected stock %s", rr.Body.String())
+	}
+	if rr := sendJSON(router, http.MethodGet, "/api/admin/products/9/inventory/adjustments", ""); rr.Code != http.StatusOK {
+		t.Fatalf("expected an empty log for an unknown product, got %d", rr.Code)
+	}
+}
+
+func TestInventory_ReserveAndReleaseVariants(t *testing.T) {
+	inv := newFakeInventory("main", "east")
+	products := &fakeProducts{byID: map[int64]domain.Product{
+		1: {ID: 1, Name: "Tee", Variants: []domain.Variant{{ID: 11, SKU: "TEE-M"}, {ID: 12, SKU: "TEE-L"}}},
+	}}
+	router := inventoryRouter(inv, products)
+
+	if rr := sendJSON(router, http.MethodPost, "/api/admin/products/1/inventory/adjustments", `{"delta":3,"reason":"restock"}`); rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), "variantId") {
+		t.Fatalf("expected 400 without a variant, got %d %s", rr.Code, rr.Body.String())
+	}
+	if rr := sendJSON(router, http.MethodPost, "/api/admin/products/1/inventory/adjustments", `{"delta":3,"reason":"restock","variantId":99}`); rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for another product's variant, got %d", rr.Code)
+	}
+	for _, body := range []string{
+		`{"delta":3,"reason":"restock","variantId":11}`,
+		`{"delta":5,"reason":"restock","variantId":11,"warehouse":"EAST"}`,
+	} {
+		if rr := sendJSON(router, http.MethodPost, "/api/admin/products/1/inventory/adjustments", body); rr.Code != http.StatusCreated {
+			t.Fatalf("adjust %s: %d %s", body, rr.Code, rr.Body.String())
+		}
+	}
+
+	// the warehouse with the most available is picked
+	rr := sendJSON(router, http.MethodPost, "/api/admin/products/1/inventory/reservations", `{"variantId":11,"quantity":4}`)
+	var res domain.Reservation
+	if err := json.Unmarshal(rr.Body.Bytes(), &res); rr.Code != http.StatusCreated || err != nil || res.Warehouse != "east" || res.Status != domain.ReservationActive {
+		t.Fatalf("reserve: %d %s", rr.Code, rr.Body.String())
+	}
+	// 3 left in main and 1 in east: no single warehouse has 4
+	if rr := sendJSON(router, http.MethodPost, "/api/admin/products/1/inventory/reservations", `{"variantId":11,"quantity":4}`); rr.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for more than a warehouse has, got %d", rr.Code)
+	}
+	if rr := sendJSON(router, http.MethodPost, "/api/admin/products/1/inventory/reservations", `{"variantId":12,"quantity":0}`); rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a zero quantity, got %d", rr.Code)
+	}
+	if rr := sendJSON(router, http.MethodPost, "/api/admin/products/2/inventory/reservations", `{"quantity":1}`); rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown product, got %d", rr.Code)
+	}
+
+	release := "/api/admin/inventory/reservations/" + strconv.FormatInt(res.ID, 10) + "/release"
+	if rr := sendJSON(router, http.MethodPost, release, ""); rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"status":"released"`) {
+		t.Fatalf("release: %d %s", rr.Code, rr.Body.String())
+	}
+	if rr := sendJSON(router, http.MethodPost, release, ""); rr.Code != http.StatusConflict {
+		t.Fatalf("expected 409 releasing twice, got %d", rr.Code)
+	}
+	if rr := sendJSON(router, http.MethodPost, "/api/admin/inventory/reservations/999/release", ""); rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown reservation, got %d", rr.Code)
+	}
+	if rr := sendJSON(router, http.MethodPost, "/api/admin/products/1/inventory/reservations", `{"variantId":11,"quantity":5,"warehouse":"east"}`); rr.Code != http.StatusCreated {
+		t.Fatalf("expected the released quantity to be available again, got %d %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestInventory_CreateWarehouse(t *testing.T) {
+	router := inventoryRouter(newFakeInventory("main"), &fakeProducts{})
+
+	if rr := sendJSON(router, http.MethodPost, "/api/admin/inventory/warehouses", `{"code":"East Coast","name":""}`); rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 got %d", rr.Code)
+	}
+	if rr := sendJSON(router, http.MethodPost, "/api/admin/inventory/warehouses", `{"code":" EAST ","name":"East coast"}`); rr.Code != http.StatusCreated || !strings.Contains(rr.Body.String(), `"code":"east"`) {
+		t.Fatalf("create: %d %s", rr.Code, rr.Body.String())
+	}
+	if rr := sendJSON(router, http.MethodPost, "/api/admin/inventory/warehouses", `{"code":"main","name":"Main"}`); rr.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for a taken code got %d", rr.Code)
+	}
+}
+
+func TestInventoryRepo_StockedVariantsAndAuditTrailOutliveReplaces(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+	key := uniqueKey(t)
+	if _, err := postgres.NewCatalogImportRepo(pool).Import(ctx, []domain.ImportRow{{Line: 2, ExternalKey: key, Product: domain.Product{
+		Name: "Stock " + key, Price: domain.Money{Amount: 100000, Currency: "IDR"}, Description: "Stocked", Rating: 4,
+	}}}, false); err != nil {
+		t.Fatal(err)
+	}
+	var id int64
+	if err := pool.QueryRow(ctx, `SELECT id FROM products WHERE external_key = $1`, key).Scan(&id); err != nil {
+		t.Fatal(err)
+	}
+	products := postgres.NewProductRepo(pool)
+	inventory := postgres.NewInventoryRepo(pool)
+	t.Cleanup(func() {
+		_ = products.Delete(context.Background(), id)
+		_, _ = pool.Exec(context.Background(), `DELETE FROM inventory_adjustments WHERE sku LIKE $1`, key+"%")
+	})
+
+	black := domain.Variant{SKU: key + "-black", Options: map[string]string{"color": "black"}, Price: domain.Money{Amount: 100000, Currency: "IDR"}}
+	white := domain.Variant{SKU: key + "-white", Options: map[string]string{"color": "white"}, Price: domain.Money{Amount: 100000, Currency: "IDR"}}
+	if err := products.ReplaceVariants(ctx, id, []domain.Variant{black, white}); err != nil {
+		t.Fatal(err)
+	}
+	var blackID int64
+	if err := pool.QueryRow(ctx, `SELECT id FROM product_variants WHERE sku = $1`, black.SKU).Scan(&blackID); err != nil {
+		t.Fatal(err)
+	}
+	adjust := func(onHand int) {
+		t.Helper()
+		if _, err := inventory.Adjust(ctx, domain.StockAdjustmentInput{
+			ProductID: id, VariantID: &blackID, Warehouse: "main", OnHand: &onHand, Reason: "count",
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	adjust(3)
+	if err := products.ReplaceVariants(ctx, id, []domain.Variant{white}); !errors.Is(err, repository.ErrStockRemaining) || !errors.Is(err, repository.ErrConflict) {
+		t.Fatalf("expected a stocked variant kept, got %v", err)
+	}
+	if err := products.Delete(ctx, id); !errors.Is(err, repository.ErrStockRemaining) {
+		t.Fatalf("expected a stocked product kept, got %v", err)
+	}
+	adjust(0)
+	if err := products.ReplaceVariants(ctx, id, []domain.Variant{white}); err != nil {
+		t.Fatal(err)
+	}
+	if err := products.Delete(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+
+	var kept int
+	if err := pool.QueryRow(ctx, `
+		SELECT COUNT(*) FROM inventory_adjustments
+		WHERE sku = $1 AND product_id IS NULL AND variant_id IS NULL
+	`, black.SKU).Scan(&kept); err != nil {
+		t.Fatal(err)
+	}
+	if kept != 2 {
+		t.Fatalf("expected both adjustments kept, got %d", kept)
+	}
+}
@@ -0,0 +1,140 @@
+package internal_test
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/soydoradesu/product_discovery/internal/auth"
+	"github.com/soydoradesu/product_discovery/internal/config"
+)
+
+func pemKey(t *testing.T, key crypto.PrivateKey) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+func newRSAKey(t *testing.T, id string) (auth.Key, *rsa.PrivateKey) {
+	t.Helper()
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k, err := auth.NewPrivateKey(id, auth.AlgRS256, pemKey(t, priv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k, priv
+}
+
+func newEdKey(t *testing.T, id string) auth.Key {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k, err := auth.NewPrivateKey(id, auth.AlgEdDSA, pemKey(t, priv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func TestKeyring_RotationVerifiesOldTokens(t *testing.T) {
+	oldKey, _ := newRSAKey(t, "2024-01")
+	newKey := newEdKey(t, "2024-06")
+
+	before, _ := auth.NewKeyring(oldKey)
+	oldToken, err := auth.SignJWT(before, 1, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotated, _ := auth.NewKeyring(newKey, oldKey)
+	newToken, err := auth.SignJWT(rotated, 2, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tok, _, _ := jwt.NewParser().ParseUnverified(newToken, &auth.Claims{}); tok.Header["kid"] != "2024-06" || tok.Method.Alg() != auth.AlgEdDSA {
+		t.Fatalf("expected the newest key to sign, got header %v", tok.Header)
+	}
+
+	for name, token := range map[string]string{"old": oldToken, "new": newToken} {
+		if _, err := auth.VerifyJWT(rotated, token); err != nil {
+			t.Fatalf("%s token: %v", name, err)
+		}
+	}
+
+	retired, _ := auth.NewKeyring(newKey)
+	if _, err := auth.VerifyJWT(retired, oldToken); err == nil {
+		t.Fatal("expected a token from a removed key to be rejected")
+	}
+}
+
+func TestKeyring_RejectsAlgorithmConfusion(t *testing.T) {
+	rsaKey, priv := newRSAKey(t, "rsa")
+	ring, _ := auth.NewKeyring(rsaKey)
+
+	// HS256 "signed" with the public key, which an attacker can fetch from
+	// the JWKS, must not verify against the RSA key
+	pubDER, _ := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+	pubPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER})
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, auth.Claims{
+		UserID:           1,
+		RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour))},
+	})
+	forged.Header["kid"] = "rsa"
+	for _, secret := range [][]byte{pubPEM, pubDER} {
+		token, _ := forged.SignedString(secret)
+		if _, err := auth.VerifyJWT(ring, token); err == nil {
+			t.Fatal("expected HS256 token to be rejected by an RS256 key")
+		}
+	}
+
+	unsigned, _ := jwt.NewWithClaims(jwt.SigningMethodNone, auth.Claims{UserID: 1}).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if _, err := auth.VerifyJWT(ring, unsigned); err == nil {
+		t.Fatal("expected alg=none to be rejected")
+	}
+}
+
+func TestKeyring_JWKSOnlyPublishesPublicKeys(t *testing.T) {
+	rsaKey, _ := newRSAKey(t, "rsa")
+	hmacKey, _ := auth.NewHMACKey("hmac", []byte("secret"))
+	ring, _ := auth.NewKeyring(newEdKey(t, "ed"), rsaKey, hmacKey)
+
+	set := ring.JWKS()
+	if len(set.Keys) != 2 || set.Keys[0].Kid != "ed" || set.Keys[0].Kty != "OKP" || set.Keys[1].Kty != "RSA" {
+		t.Fatalf("unexpected jwks %+v", set)
+	}
+	for _, k := range set.Keys {
+		if _, err := k.PublicKey(); err != nil {
+			t.Fatalf("published key %s does not decode: %v", k.Kid, err)
+		}
+	}
+	if auth.NewHMACKeyring("secret").HasPublicKeys() {
+		t.Fatal("expected an hmac-only keyring to have no public keys")
+	}
+}
+
+func TestLoadKeyring_RefusesDefaultSecretWhenSecure(t *testing.T) {
+	if _, err := auth.LoadKeyring(config.Config{JWTSecret: config.DefaultJWTSecret, CookieSecure: true}); err == nil {
+		t.Fatal("expected the default secret to be refused in secure mode")
+	}
+	if _, err := auth.LoadKeyring(config.Config{JWTSecret: config.DefaultJWTSecret}); err != nil {
+		t.Fatalf("expected the default secret to be allowed in development, got %v", err)
+	}
+	if _, err := auth.LoadKeyring(config.Config{JWTSecret: "a-real-secret", CookieSecure: true}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,188 @@
+package internal_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/soydoradesu/product_discovery/internal/auth"
+	"github.com/soydoradesu/product_discovery/internal/config"
+	"github.com/soydoradesu/product_discovery/internal/domain"
+	"github.com/soydoradesu/product_discovery/internal/http/handlers"
+	"github.com/soydoradesu/product_discovery/internal/mail"
+	"github.com/soydoradesu/product_discovery/internal/repository"
+	"github.com/soydoradesu/product_discovery/internal/service"
+)
+
+type fakeMagicLinks struct {
+	mu    sync.Mutex
+	links map[string]fakeMagicLink
+}
+
+type fakeMagicLink struct {
+	email   string
+	created time.Time
+	expires time.Time
+	used    bool
+}
+
+func (f *fakeMagicLinks) Create(ctx context.Context, id, email string, expiresAt time.Time) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.links[id] = fakeMagicLink{email: email, created: time.Now(), expires: expiresAt}
+	return nil
+}
+
+func (f *fakeMagicLinks) Consume(ctx context.Context, id string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	l, ok := f.links[id]
+	if !ok || l.used || !l.expires.After(time.Now()) {
+		return "", repository.ErrNotFound
+	}
+	l.used = true
+	f.links[id] = l
+	return l.email, nil
+}
+
+func (f *fakeMagicLinks) CountSince(ctx context.Context, email string, since time.Time) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := 0
+	for _, l := range f.links {
+		if l.email == email && !l.created.Before(since) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+type outbox struct {
+	mu   sync.Mutex
+	sent []mail.Message
+}
+
+func (o *outbox) Send(ctx context.Context, msg mail.Message) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.sent = append(o.sent, msg)
+	return nil
+}
+
+func (o *outbox) lastLink(t *testing.T) string {
+	t.Helper()
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if len(o.sent) == 0 {
+		t.Fatal("expected an email")
+	}
+	for _, line := range strings.Split(o.sent[len(o.sent)-1].Text, "\n") {
+		if strings.HasPrefix(line, "http") {
+			return line
+		}
+	}
+	t.Fatal("expected a link in the email")
+	return ""
+}
+
+func magicLinkRouter(users *fakeUsers, signup bool) (http.Handler, *outbox) {
+	keys := auth.NewHMACKeyring("test-secret")
+	box := &outbox{}
+	links := service.NewMagicLinkService(&fakeMagicLinks{links: map[string]fakeMagicLink{}}, users, box, keys)
+	links.VerifyURL = "http://backend.test/api/auth/magic-link/verify"
+	links.AllowSignup = signup
+
+	h := &handlers.AuthHandlers{
+		Cfg:        config.Config{FrontendURL: "http://frontend.test"},
+		Keys:       keys,
+		Auth:       service.NewAuthService(users),
+		TwoFactor:  service.NewTwoFactorService(newFakeTwoFactor(), "test"),
+		Sessions:   service.NewSessionService(newFakeSessions()),
+		MagicLinks: links,
+	}
+	r := chi.NewRouter()
+	r.Post("/api/auth/magic-link", h.RequestMagicLink)
+	r.Get("/api/auth/magic-link/verify", h.VerifyMagicLink)
+	return r, box
+}
+
+func requestMagicLink(t *testing.T, router http.Handler, email string) {
+	t.Helper()
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/api/auth/magic-link", bytes.NewBufferString(`{"email":"`+email+`"}`)))
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("expected 202 got %d body=%s", rr.Code, rr.Body.String())
+	}
+}
+
+func openLink(router http.Handler, link string) *httptest.ResponseRecorder {
+	u, _ := url.Parse(link)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, u.RequestURI(), nil))
+	return rr
+}
+
+func TestMagicLink_LogsInOnce(t *testing.T) {
+	u := domain.User{ID: 1, Email: "demo@example.com"}
+	users := &fakeUsers{byEmail: map[string]domain.User{u.Email: u}, byID: map[int64]domain.User{1: u}}
+	router, box := magicLinkRouter(users, false)
+
+	requestMagicLink(t, router, "Demo@Example.com")
+	link := box.lastLink(t)
+
+	rr := openLink(router, link)
+	if rr.Code != http.StatusFound || rr.Header().Get("Location") != "http://frontend.test/?magic=success" || sessionCookie(rr) == "" {
+		t.Fatalf("expected a session and a redirect, got %d %s", rr.Code, rr.Header().Get("Location"))
+	}
+
+	rr = openLink(router, link)
+	if !strings.Contains(rr.Header().Get("Location"), "magic=invalid") || sessionCookie(rr) != "" {
+		t.Fatalf("expected the second use to fail, got %s", rr.Header().Get("Location"))
+	}
+
+	rr = openLink(router, strings.Replace(link, "token=", "token=x", 1))
+	if !strings.Contains(rr.Header().Get("Location"), "magic=invalid") {
+		t.Fatalf("expected a tampered link to fail, got %s", rr.Header().Get("Location"))
+	}
+}
+
+func TestMagicLink_UnknownEmail(t *testing.T) {
+	users := &fakeUsers{byEmail: map[string]domain.User{}, byID: map[int64]domain.User{}}
+	router, box := magicLinkRouter(users, false)
+
+	requestMagicLink(t, router, "new@example.com")
+	if len(box.sent) != 0 {
+		t.Fatal("expected no email for an unknown address without signup")
+	}
+
+	router, box = magicLinkRouter(users, true)
+	requestMagicLink(t, router, "new@example.com")
+	rr := openLink(router, box.lastLink(t))
+	if sessionCookie(rr) == "" {
+		t.Fatalf("expected a session, got %d %s", rr.Code, rr.Header().Get("Location"))
+	}
+	if u, ok := users.byEmail["new@example.com"]; !ok || u.PasswordHash != nil {
+		t.Fatalf("expected a passwordless account, got %+v", u)
+	}
+}
+
+func TestMagicLink_RateLimited(t *testing.T) {
+	u := domain.User{ID: 1, Email: "demo@example.com"}
+	users := &fakeUsers{byEmail: map[string]domain.User{u.Email: u}, byID: map[int64]domain.User{1: u}}
+	router, box := magicLinkRouter(users, false)
+
+	for i := 0; i < 8; i++ {
+		requestMagicLink(t, router, "demo@example.com")
+	}
+	if len(box.sent) != 5 {
+		t.Fatalf("expected 5 emails, got %d", len(box.sent))
+	}
+}
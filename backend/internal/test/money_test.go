@@ -0,0 +1,64 @@
+package internal_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/soydoradesu/product_discovery/internal/domain"
+)
+
+func TestParseMoney(t *testing.T) {
+	cases := []struct {
+		amount, currency string
+		want             int64
+	}{
+		{"1250", "IDR", 125000},
+		{"1250.5", "idr", 125050},
+		{"0.07", "USD", 7},
+		{"-3.10", "EUR", -310},
+		{"1500", "JPY", 1500},
+		{"1.234", "KWD", 1234},
+	}
+	for _, c := range cases {
+		m, err := domain.ParseMoney(c.amount, c.currency)
+		if err != nil || m.Amount != c.want {
+			t.Fatalf("ParseMoney(%q, %q) = %+v, %v; want %d", c.amount, c.currency, m, err, c.want)
+		}
+	}
+
+	for _, c := range [][2]string{{"1.005", "USD"}, {"10.5", "JPY"}, {"abc", "IDR"}, {"", "IDR"}, {"1e3", "IDR"}, {"10", "RUPIAH"}, {"99999999999999999999", "IDR"}} {
+		if _, err := domain.ParseMoney(c[0], c[1]); !errors.Is(err, domain.ErrInvalidMoney) {
+			t.Fatalf("ParseMoney(%q, %q): expected ErrInvalidMoney got %v", c[0], c[1], err)
+		}
+	}
+}
+
+func TestMoney_DecimalAndJSON(t *testing.T) {
+	for _, c := range []struct {
+		m    domain.Money
+		want string
+	}{
+		{domain.Money{Amount: 125050, Currency: "IDR"}, "1250.50"},
+		{domain.Money{Amount: 7, Currency: "USD"}, "0.07"},
+		{domain.Money{Amount: -310, Currency: "EUR"}, "-3.10"},
+		{domain.Money{Amount: 1500, Currency: "JPY"}, "1500"},
+	} {
+		if got := c.m.Decimal(); got != c.want {
+			t.Fatalf("%+v.Decimal() = %q want %q", c.m, got, c.want)
+		}
+	}
+
+	b, err := json.Marshal(domain.Money{Amount: 125050, Currency: "IDR"})
+	if err != nil || string(b) != `{"amount":"1250.50","currency":"IDR"}` {
+		t.Fatalf("unexpected json %s %v", b, err)
+	}
+
+	var m domain.Money
+	if err := json.Unmarshal([]byte(`{"amount":19.99,"currency":"USD"}`), &m); err != nil || m.Amount != 1999 || m.Currency != "USD" {
+		t.Fatalf("unexpected decode %+v %v", m, err)
+	}
+	if err := json.Unmarshal([]byte(`{"amount":"19.999","currency":"USD"}`), &m); !errors.Is(err, domain.ErrInvalidMoney) {
+		t.Fatalf("expected ErrInvalidMoney got %v", err)
+	}
+}
@@ -0,0 +1,339 @@
+package internal_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/soydoradesu/product_discovery/internal/auth"
+	"github.com/soydoradesu/product_discovery/internal/config"
+	"github.com/soydoradesu/product_discovery/internal/domain"
+	"github.com/soydoradesu/product_discovery/internal/http/handlers"
+	"github.com/soydoradesu/product_discovery/internal/oidc"
+	"github.com/soydoradesu/product_discovery/internal/service"
+)
+
+// stubIdP is a minimal OpenID provider: discovery, JWKS, an authorize endpoint
+// that approves immediately, and a token endpoint that checks PKCE.
+type stubIdP struct {
+	t      *testing.T
+	srv    *httptest.Server
+	key    *rsa.PrivateKey
+	client string
+
+	subject  string
+	email    string
+	badNonce bool
+
+	mu    sync.Mutex
+	codes map[string]stubGrant
+}
+
+type stubGrant struct {
+	nonce     string
+	challenge string
+	redirect  string
+}
+
+func newStubIdP(t *testing.T) *stubIdP {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &stubIdP{t: t, key: key, client: "test-client", subject: "sub-123", email: "Shopper@Example.com", codes: map[string]stubGrant{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", s.discovery)
+	mux.HandleFunc("/jwks", s.jwks)
+	mux.HandleFunc("/authorize", s.authorize)
+	mux.HandleFunc("/token", s.token)
+	s.srv = httptest.NewServer(mux)
+	t.Cleanup(s.srv.Close)
+	return s
+}
+
+func (s *stubIdP) discovery(w http.ResponseWriter, r *http.Request) {
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"issuer":                 s.srv.URL,
+		"authorization_endpoint": s.srv.URL + "/authorize",
+		"token_endpoint":         s.srv.URL + "/token",
+		"jwks_uri":               s.srv.URL + "/jwks",
+	})
+}
+
+func (s *stubIdP) jwks(w http.ResponseWriter, r *http.Request) {
+	pub := s.key.PublicKey
+	_ = json.NewEncoder(w).Encode(auth.JWKSet{Keys: []auth.JWK{{
+		Kty: "RSA",
+		Kid: "k1",
+		Use: "sig",
+		Alg: "RS256",
+		N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+	}}})
+}
+
+func (s *stubIdP) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("client_id") != s.client || q.Get("code_challenge_method") != "S256" || q.Get("nonce") == "" {
+		http.Error(w, "bad authorize request", http.StatusBadRequest)
+		return
+	}
+
+	code := "code-" + q.Get("state")[:8]
+	s.mu.Lock()
+	s.codes[code] = stubGrant{nonce: q.Get("nonce"), challenge: q.Get("code_challenge"), redirect: q.Get("redirect_uri")}
+	s.mu.Unlock()
+
+	u, _ := url.Parse(q.Get("redirect_uri"))
+	back := u.Query()
+	back.Set("code", code)
+	back.Set("state", q.Get("state"))
+	u.RawQuery = back.Encode()
+	http.Redirect(w, r, u.String(), http.StatusFound)
+}
+
+func (s *stubIdP) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "bad form", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	g, ok := s.codes[r.PostForm.Get("code")]
+	delete(s.codes, r.PostForm.Get("code"))
+	s.mu.Unlock()
+	if !ok || r.PostForm.Get("redirect_uri") != g.redirect {
+		http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+		return
+	}
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != g.challenge {
+		http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+		return
+	}
+
+	nonce := g.nonce
+	if s.badNonce {
+		nonce = "something-else"
+	}
+	now := time.Now()
+	tok := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":            s.srv.URL,
+		"sub":            s.subject,
+		"aud":            s.client,
+		"iat":            now.Unix(),
+		"exp":            now.Add(5 * time.Minute).Unix(),
+		"nonce":          nonce,
+		"email":          s.email,
+		"email_verified": true,
+	})
+	tok.Header["kid"] = "k1"
+	idToken, err := tok.SignedString(s.key)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"access_token": "at",
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+func oidcRouter(idp *stubIdP, users *fakeUsers, twoFactor *fakeTwoFactor, events *fakeAuthEvents) (http.Handler, config.Config, *auth.Keyring) {
+	cfg := config.Config{FrontendURL: "http://frontend.test"}
+	keys := auth.NewHMACKeyring("test-secret")
+	registry := oidc.NewRegistry([]oidc.Config{{
+		Name:        "stub",
+		Issuer:      idp.srv.URL,
+		ClientID:    idp.client,
+		RedirectURL: "http://backend.test/api/auth/oidc/stub/callback",
+	}}, idp.srv.Client())
+
+	h := &handlers.AuthHandlers{Cfg: cfg, Keys: keys, Auth: service.NewAuthService(users), OIDC: registry, Sessions: service.NewSessionService(newFakeSessions()), TwoFactor: service.NewTwoFactorService(twoFactor, "test"), Audit: service.NewAuditService(events)}
+	r := chi.NewRouter()
+	r.Get("/api/auth/oidc/{provider}/start", h.OIDCStart)
+	r.Get("/api/auth/oidc/{provider}/callback", h.OIDCCallback)
+	return r, cfg, keys
+}
+
+// runOIDCLogin drives start -> provider -> callback and returns the callback
+// response.
+func runOIDCLogin(t *testing.T, idp *stubIdP, router http.Handler) *httptest.ResponseRecorder {
+	t.Helper()
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/auth/oidc/stub/start", nil))
+	if rr.Code != http.StatusFound {
+		t.Fatalf("start: expected 302 got %d body=%s", rr.Code, rr.Body.String())
+	}
+	authorizeURL := rr.Header().Get("Location")
+	cookies := rr.Result().Cookies()
+
+	client := idp.srv.Client()
+	client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	resp, err := client.Get(authorizeURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize: expected 302 got %d", resp.StatusCode)
+	}
+
+	cb, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodGet, cb.RequestURI(), nil)
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	return rr
+}
+
+func sessionCookie(rr *httptest.ResponseRecorder) string {
+	for _, c := range rr.Result().Cookies() {
+		if c.Name == "session" {
+			return c.Value
+		}
+	}
+	return ""
+}
+
+func TestOIDC_FullFlow_CreatesAndReusesUser(t *testing.T) {
+	idp := newStubIdP(t)
+	users := &fakeUsers{byEmail: map[string]domain.User{}, byID: map[int64]domain.User{}}
+	router, cfg, keys := oidcRouter(idp, users, newFakeTwoFactor(), &fakeAuthEvents{})
+
+	rr := runOIDCLogin(t, idp, router)
+	if rr.Code != http.StatusFound || !strings.HasPrefix(rr.Header().Get("Location"), cfg.FrontendURL) {
+		t.Fatalf("callback: expected redirect to frontend, got %d %s body=%s", rr.Code, rr.Header().Get("Location"), rr.Body.String())
+	}
+
+	claims, err := auth.VerifyJWT(keys, sessionCookie(rr))
+	if err != nil {
+		t.Fatalf("expected a valid session cookie: %v", err)
+	}
+	u, ok := users.byEmail["shopper@example.com"]
+	if !ok || u.ID != claims.UserID {
+		t.Fatalf("expected user shopper@example.com with id %d, got %+v", claims.UserID, u)
+	}
+	if len(users.identities) != 1 || users.identities[0].Provider != "stub" || users.identities[0].Subject != "sub-123" {
+		t.Fatalf("unexpected identities %+v", users.identities)
+	}
+
+	// second login resolves the same user through the stored identity
+	rr = runOIDCLogin(t, idp, router)
+	claims2, err := auth.VerifyJWT(keys, sessionCookie(rr))
+	if err != nil || claims2.UserID != claims.UserID {
+		t.Fatalf("expected same user on second login, got %v err=%v", claims2, err)
+	}
+	if len(users.byID) != 1 {
+		t.Fatalf("expected a single user, got %d", len(users.byID))
+	}
+}
+
+func TestOIDC_LoginAsksForSecondFactor(t *testing.T) {
+	idp := newStubIdP(t)
+	users := &fakeUsers{byEmail: map[string]domain.User{}, byID: map[int64]domain.User{}}
+	twoFactor := newFakeTwoFactor()
+	router, cfg, keys := oidcRouter(idp, users, twoFactor, &fakeAuthEvents{})
+
+	rr := runOIDCLogin(t, idp, router)
+	claims, err := auth.VerifyJWT(keys, sessionCookie(rr))
+	if err != nil {
+		t.Fatalf("expected a session before 2fa is enabled: %v", err)
+	}
+	enabledAt := time.Now()
+	twoFactor.totp[claims.UserID] = domain.UserTOTP{UserID: claims.UserID, Secret: "JBSWY3DPEHPK3PXP", EnabledAt: &enabledAt}
+
+	rr = runOIDCLogin(t, idp, router)
+	if sessionCookie(rr) != "" {
+		t.Fatal("expected no session before the second factor")
+	}
+	loc := rr.Header().Get("Location")
+	prefix := cfg.FrontendURL + "/login#twoFactor="
+	if rr.Code != http.StatusFound || !strings.HasPrefix(loc, prefix) {
+		t.Fatalf("expected a redirect to the 2fa step, got %d %s", rr.Code, loc)
+	}
+	challenge, _ := url.QueryUnescape(strings.TrimPrefix(loc, prefix))
+	got, err := auth.VerifyChallenge(keys, challenge, auth.PurposeTwoFactor)
+	if err != nil || got.UserID != claims.UserID {
+		t.Fatalf("expected a 2fa challenge for user %d, got %v err=%v", claims.UserID, got, err)
+	}
+}
+
+func TestOIDC_LoginRecordsLinkByEmail(t *testing.T) {
+	idp := newStubIdP(t)
+	u := domain.User{ID: 1, Email: "shopper@example.com"}
+	users := &fakeUsers{byEmail: map[string]domain.User{u.Email: u}, byID: map[int64]domain.User{1: u}}
+	events := &fakeAuthEvents{}
+	router, _, _ := oidcRouter(idp, users, newFakeTwoFactor(), events)
+
+	if rr := runOIDCLogin(t, idp, router); rr.Code != http.StatusFound {
+		t.Fatalf("callback: %d %s", rr.Code, rr.Body.String())
+	}
+	// the second login goes through the stored identity and links nothing
+	if rr := runOIDCLogin(t, idp, router); rr.Code != http.StatusFound {
+		t.Fatalf("callback: %d %s", rr.Code, rr.Body.String())
+	}
+
+	var linked []domain.AuthEvent
+	for _, e := range events.events {
+		if e.Type == domain.AuthEventIdentityLinked {
+			linked = append(linked, e)
+		}
+	}
+	if len(linked) != 1 || linked[0].UserID == nil || *linked[0].UserID != 1 || linked[0].Provider != "stub" {
+		t.Fatalf("expected one identity_linked event for user 1, got %+v", events.events)
+	}
+}
+
+func TestOIDC_RejectsNonceMismatch(t *testing.T) {
+	idp := newStubIdP(t)
+	idp.badNonce = true
+	users := &fakeUsers{byEmail: map[string]domain.User{}, byID: map[int64]domain.User{}}
+	router, _, _ := oidcRouter(idp, users, newFakeTwoFactor(), &fakeAuthEvents{})
+
+	rr := runOIDCLogin(t, idp, router)
+	if rr.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 got %d body=%s", rr.Code, rr.Body.String())
+	}
+	if sessionCookie(rr) != "" || len(users.byID) != 0 {
+		t.Fatal("expected no session and no user")
+	}
+}
+
+func TestOIDC_RejectsMissingStateCookie(t *testing.T) {
+	idp := newStubIdP(t)
+	users := &fakeUsers{byEmail: map[string]domain.User{}, byID: map[int64]domain.User{}}
+	router, _, _ := oidcRouter(idp, users, newFakeTwoFactor(), &fakeAuthEvents{})
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/auth/oidc/stub/callback?code=x&state=y", nil))
+	if rr.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 got %d body=%s", rr.Code, rr.Body.String())
+	}
+}
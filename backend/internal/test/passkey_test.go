@@ -0,0 +1,379 @@
+package internal_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/soydoradesu/product_discovery/internal/auth"
+	"github.com/soydoradesu/product_discovery/internal/domain"
+	"github.com/soydoradesu/product_discovery/internal/http/handlers"
+	"github.com/soydoradesu/product_discovery/internal/http/middleware"
+	"github.com/soydoradesu/product_discovery/internal/repository"
+	"github.com/soydoradesu/product_discovery/internal/service"
+	"github.com/soydoradesu/product_discovery/internal/webauthn"
+)
+
+type fakePasskeys struct {
+	users  *fakeUsers
+	byID   map[int64]domain.Passkey
+	nextID int64
+}
+
+func newFakePasskeys(users *fakeUsers) *fakePasskeys {
+	f := &fakePasskeys{users: users, byID: map[int64]domain.Passkey{}}
+	users.passkeys = f
+	return f
+}
+
+func (f *fakePasskeys) countFor(userID int64) int {
+	n := 0
+	for _, p := range f.byID {
+		if p.UserID == userID {
+			n++
+		}
+	}
+	return n
+}
+
+func (f *fakePasskeys) Create(ctx context.Context, p domain.Passkey) (domain.Passkey, error) {
+	for _, existing := range f.byID {
+		if bytes.Equal(existing.CredentialID, p.CredentialID) {
+			return domain.Passkey{}, repository.ErrConflict
+		}
+	}
+	f.nextID++
+	p.ID = f.nextID
+	p.CreatedAt = time.Now()
+	f.byID[p.ID] = p
+	return p, nil
+}
+
+func (f *fakePasskeys) ListByUser(ctx context.Context, userID int64) ([]domain.Passkey, error) {
+	out := []domain.Passkey{}
+	for _, p := range f.byID {
+		if p.UserID == userID {
+			out = append(out, p)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, nil
+}
+
+func (f *fakePasskeys) GetByCredentialID(ctx context.Context, credentialID []byte) (domain.Passkey, error) {
+	for _, p := range f.byID {
+		if bytes.Equal(p.CredentialID, credentialID) {
+			return p, nil
+		}
+	}
+	return domain.Passkey{}, repository.ErrNotFound
+}
+
+func (f *fakePasskeys) RecordUse(ctx context.Context, id int64, signCount uint32, at time.Time) error {
+	p := f.byID[id]
+	p.SignCount = signCount
+	p.LastUsedAt = &at
+	f.byID[id] = p
+	return nil
+}
+
+func (f *fakePasskeys) Delete(ctx context.Context, userID, id int64) error {
+	p, ok := f.byID[id]
+	if !ok || p.UserID != userID {
+		return repository.ErrNotFound
+	}
+	identities := 0
+	for _, i := range f.users.identities {
+		if i.UserID == userID {
+			identities++
+		}
+	}
+	if f.users.byID[userID].PasswordHash == nil && identities == 0 && f.countFor(userID) == 1 {
+		return repository.ErrConflict
+	}
+	delete(f.byID, id)
+	return nil
+}
+
+// cborEncode writes the few CBOR types a WebAuthn authenticator produces.
+// Map keys are written in sorted order so output is deterministic.
+func cborEncode(v any) []byte {
+	head := func(major byte, n uint64) []byte {
+		switch {
+		case n < 24:
+			return []byte{major<<5 | byte(n)}
+		case n < 1<<8:
+			return []byte{major<<5 | 24, byte(n)}
+		case n < 1<<16:
+			return binary.BigEndian.AppendUint16([]byte{major<<5 | 25}, uint16(n))
+		}
+		return binary.BigEndian.AppendUint32([]byte{major<<5 | 26}, uint32(n))
+	}
+	switch x := v.(type) {
+	case int:
+		if x < 0 {
+			return head(1, uint64(-1-x))
+		}
+		return head(0, uint64(x))
+	case []byte:
+		return append(head(2, uint64(len(x))), x...)
+	case string:
+		return append(head(3, uint64(len(x))), x...)
+	case map[any]any:
+		keys := make([][]byte, 0, len(x))
+		vals := map[string][]byte{}
+		for k, val := range x {
+			ek := cborEncode(k)
+			keys = append(keys, ek)
+			vals[string(ek)] = cborEncode(val)
+		}
+		sort.Slice(keys, func(i, j int) bool { return bytes.Compare(keys[i], keys[j]) < 0 })
+		out := head(5, uint64(len(x)))
+		for _, k := range keys {
+			out = append(append(out, k...), vals[string(k)]...)
+		}
+		return out
+	}
+	panic("cborEncode: unsupported type")
+}
+
+// softAuthenticator is a software passkey holding one ES256 credential.
+type softAuthenticator struct {
+	origin    string
+	key       *ecdsa.PrivateKey
+	credID    []byte
+	handle    []byte
+	signCount uint32
+}
+
+func newSoftAuthenticator(t *testing.T, origin string) *softAuthenticator {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	credID := make([]byte, 16)
+	_, _ = rand.Read(credID)
+	return &softAuthenticator{origin: origin, key: key, credID: credID}
+}
+
+func (a *softAuthenticator) clientData(typ, challenge string) []byte {
+	b, _ := json.Marshal(map[string]any{"type": typ, "challenge": challenge, "origin": a.origin, "crossOrigin": false})
+	return b
+}
+
+func (a *softAuthenticator) authData(rpID string, attested bool) []byte {
+	a.signCount++
+	rpHash := sha256.Sum256([]byte(rpID))
+	flags := byte(0x01 | 0x04) // user present, user verified
+	if attested {
+		flags |= 0x40
+	}
+	out := append(rpHash[:], flags)
+	out = binary.BigEndian.AppendUint32(out, a.signCount)
+	if attested {
+		out = append(out, make([]byte, 16)...) // AAGUID
+		out = binary.BigEndian.AppendUint16(out, uint16(len(a.credID)))
+		out = append(out, a.credID...)
+		x, y := make([]byte, 32), make([]byte, 32)
+		a.key.X.FillBytes(x)
+		a.key.Y.FillBytes(y)
+		out = append(out, cborEncode(map[any]any{1: 2, 3: -7, -1: 1, -2: x, -3: y})...)
+	}
+	return out
+}
+
+func (a *softAuthenticator) create(opts webauthn.CreationOptions) webauthn.AttestationResponse {
+	a.handle, _ = base64.RawURLEncoding.DecodeString(opts.User.ID)
+	att := cborEncode(map[any]any{"fmt": "none", "attStmt": map[any]any{}, "authData": a.authData(opts.RP.ID, true)})
+
+	var resp webauthn.AttestationResponse
+	resp.ID = base64.RawURLEncoding.EncodeToString(a.credID)
+	resp.RawID = resp.ID
+	resp.Type = "public-key"
+	resp.Response.ClientDataJSON = base64.RawURLEncoding.EncodeToString(a.clientData("webauthn.create", opts.Challenge))
+	resp.Response.AttestationObject = base64.RawURLEncoding.EncodeToString(att)
+	resp.Response.Transports = []string{"internal"}
+	return resp
+}
+
+func (a *softAuthenticator) get(opts webauthn.RequestOptions) webauthn.AssertionResponse {
+	authData := a.authData(opts.RPID, false)
+	clientData := a.clientData("webauthn.get", opts.Challenge)
+	clientHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte(nil), authData...), clientHash[:]...))
+	sig, _ := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+
+	var resp webauthn.AssertionResponse
+	resp.ID = base64.RawURLEncoding.EncodeToString(a.credID)
+	resp.RawID = resp.ID
+	resp.Type = "public-key"
+	resp.Response.ClientDataJSON = base64.RawURLEncoding.EncodeToString(clientData)
+	resp.Response.AuthenticatorData = base64.RawURLEncoding.EncodeToString(authData)
+	resp.Response.Signature = base64.RawURLEncoding.EncodeToString(sig)
+	resp.Response.UserHandle = base64.RawURLEncoding.EncodeToString(a.handle)
+	return resp
+}
+
+const passkeyOrigin = "https://shop.example"
+
+func passkeyRouter(users *fakeUsers) (http.Handler, *auth.Keyring, *fakePasskeys) {
+	keys := auth.NewHMACKeyring("test-secret")
+	passkeys := newFakePasskeys(users)
+	sessions := service.NewSessionService(newFakeSessions())
+	rp := webauthn.RelyingParty{ID: "shop.example", Name: "Shop", Origins: []string{passkeyOrigin}}
+	h := &handlers.AuthHandlers{
+		Keys:     keys,
+		Auth:     service.NewAuthService(users),
+		Sessions: sessions,
+		Passkeys: service.NewPasskeyService(passkeys, users, rp, keys),
+	}
+
+	r := chi.NewRouter()
+	r.Post("/api/auth/passkey/login/begin", h.PasskeyLoginBegin)
+	r.Post("/api/auth/passkey/login/finish", h.PasskeyLoginFinish)
+	r.Route("/api/me/passkeys", func(pr chi.Router) {
+		pr.Use(middleware.RequireAuth(keys, middleware.Authenticators{}))
+		pr.Get("/", h.ListPasskeys)
+		pr.Post("/register/begin", h.PasskeyRegisterBegin)
+		pr.Post("/register/finish", h.PasskeyRegisterFinish)
+		pr.Delete("/{id}", h.DeletePasskey)
+	})
+	return r, keys, passkeys
+}
+
+func postJSON(router http.Handler, path, cookie string, body any) *httptest.ResponseRecorder {
+	b, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(b))
+	if cookie != "" {
+		req.AddCookie(&http.Cookie{Name: "session", Value: cookie})
+	}
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	return rr
+}
+
+func registerPasskey(t *testing.T, router http.Handler, cookie string, a *softAuthenticator) *httptest.ResponseRecorder {
+	t.Helper()
+	rr := postJSON(router, "/api/me/passkeys/register/begin", cookie, nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("register begin: %d %s", rr.Code, rr.Body.String())
+	}
+	var begin struct {
+		ChallengeToken string                   `json:"challengeToken"`
+		PublicKey      webauthn.CreationOptions `json:"publicKey"`
+	}
+	_ = json.Unmarshal(rr.Body.Bytes(), &begin)
+	return postJSON(router, "/api/me/passkeys/register/finish", cookie, map[string]any{
+		"challengeToken": begin.ChallengeToken,
+		"name":           "Laptop",
+		"credential":     a.create(begin.PublicKey),
+	})
+}
+
+func loginWithPasskey(t *testing.T, router http.Handler, a *softAuthenticator, tamper func(*webauthn.AssertionResponse)) *httptest.ResponseRecorder {
+	t.Helper()
+	rr := postJSON(router, "/api/auth/passkey/login/begin", "", nil)
+	var begin struct {
+		ChallengeToken string                  `json:"challengeToken"`
+		PublicKey      webauthn.RequestOptions `json:"publicKey"`
+	}
+	_ = json.Unmarshal(rr.Body.Bytes(), &begin)
+	assertion := a.get(begin.PublicKey)
+	if tamper != nil {
+		tamper(&assertion)
+	}
+	return postJSON(router, "/api/auth/passkey/login/finish", "", map[string]any{
+		"challengeToken": begin.ChallengeToken,
+		"credential":     assertion,
+	})
+}
+
+func TestPasskey_RegisterAndLogin(t *testing.T) {
+	ctx := context.Background()
+	users := &fakeUsers{byEmail: map[string]domain.User{}, byID: map[int64]domain.User{}}
+	uid, _, _ := service.NewAuthService(users).OAuthLogin(ctx, "google", "g-1", "admin@example.com")
+	router, keys, passkeys := passkeyRouter(users)
+	token, _ := auth.SignJWT(keys, uid, time.Hour)
+
+	a := newSoftAuthenticator(t, passkeyOrigin)
+	if rr := registerPasskey(t, router, token, a); rr.Code != http.StatusCreated {
+		t.Fatalf("register finish: %d %s", rr.Code, rr.Body.String())
+	}
+	if rr := registerPasskey(t, router, token, a); rr.Code != http.StatusConflict {
+		t.Fatalf("expected 409 registering the same credential twice got %d", rr.Code)
+	}
+
+	rr := loginWithPasskey(t, router, a, nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("login: %d %s", rr.Code, rr.Body.String())
+	}
+	claims, err := auth.VerifyJWT(keys, sessionCookie(rr))
+	if err != nil || claims.UserID != uid || claims.ID == "" {
+		t.Fatalf("expected a session for user %d, got %+v err=%v", uid, claims, err)
+	}
+	if p := passkeys.byID[1]; p.SignCount != a.signCount || p.LastUsedAt == nil {
+		t.Fatalf("expected use to be recorded, got %+v", p)
+	}
+
+	// a replayed assertion carries a stale signature counter
+	a.signCount = 0
+	if rr := loginWithPasskey(t, router, a, nil); rr.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for stale counter got %d", rr.Code)
+	}
+	a.signCount = 10
+
+	if rr := loginWithPasskey(t, router, a, func(r *webauthn.AssertionResponse) {
+		sig, _ := base64.RawURLEncoding.DecodeString(r.Response.Signature)
+		sig[len(sig)-1] ^= 1
+		r.Response.Signature = base64.RawURLEncoding.EncodeToString(sig)
+	}); rr.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for bad signature got %d", rr.Code)
+	}
+
+	phished := newSoftAuthenticator(t, "https://shop.example.evil")
+	phished.key, phished.credID, phished.handle, phished.signCount = a.key, a.credID, a.handle, 20
+	if rr := loginWithPasskey(t, router, phished, nil); rr.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for foreign origin got %d", rr.Code)
+	}
+}
+
+func TestPasskey_CountsAsLoginMethod(t *testing.T) {
+	ctx := context.Background()
+	users := &fakeUsers{byEmail: map[string]domain.User{}, byID: map[int64]domain.User{}}
+	authSvc := service.NewAuthService(users)
+	uid, _, _ := authSvc.OAuthLogin(ctx, "google", "g-1", "admin@example.com")
+	router, keys, _ := passkeyRouter(users)
+	token, _ := auth.SignJWT(keys, uid, time.Hour)
+
+	if err := authSvc.UnlinkIdentity(ctx, uid, "google"); err != service.ErrLastLoginMethod {
+		t.Fatalf("expected ErrLastLoginMethod without a passkey, got %v", err)
+	}
+	if rr := registerPasskey(t, router, token, newSoftAuthenticator(t, passkeyOrigin)); rr.Code != http.StatusCreated {
+		t.Fatalf("register: %d %s", rr.Code, rr.Body.String())
+	}
+	if err := authSvc.UnlinkIdentity(ctx, uid, "google"); err != nil {
+		t.Fatalf("expected unlink to succeed with a passkey, got %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/me/passkeys/1", nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusConflict {
+		t.Fatalf("expected 409 removing the last login method got %d", rr.Code)
+	}
+}
@@ -0,0 +1,334 @@
+package internal_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/soydoradesu/product_discovery/internal/auth"
+	"github.com/soydoradesu/product_discovery/internal/domain"
+	"github.com/soydoradesu/product_discovery/internal/http/handlers"
+	"github.com/soydoradesu/product_discovery/internal/http/middleware"
+	"github.com/soydoradesu/product_discovery/internal/repository"
+	"github.com/soydoradesu/product_discovery/internal/service"
+)
+
+type fakeSessions struct {
+	mu   sync.Mutex
+	byID map[string]domain.Session
+}
+
+func newFakeSessions() *fakeSessions {
+	return &fakeSessions{byID: map[string]domain.Session{}}
+}
+
+func (f *fakeSessions) Create(ctx context.Context, s domain.Session) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.byID[s.ID] = s
+	return nil
+}
+
+func (f *fakeSessions) Get(ctx context.Context, id string) (domain.Session, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s, ok := f.byID[id]
+	if !ok {
+		return domain.Session{}, repository.ErrNotFound
+	}
+	return s, nil
+}
+
+func (f *fakeSessions) ListActive(ctx context.Context, userID int64) ([]domain.Session, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out := []domain.Session{}
+	for _, s := range f.byID {
+		if s.UserID == userID && s.RevokedAt == nil && s.ExpiresAt.After(time.Now()) {
+			out = append(out, s)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, nil
+}
+
+func (f *fakeSessions) Touch(ctx context.Context, id string, at time.Time) error {
+	return nil
+}
+
+func (f *fakeSessions) Revoke(ctx context.Context, userID int64, id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s, ok := f.byID[id]
+	if !ok || s.UserID != userID || s.RevokedAt != nil {
+		return repository.ErrNotFound
+	}
+	now := time.Now()
+	s.RevokedAt = &now
+	f.byID[id] = s
+	return nil
+}
+
+func (f *fakeSessions) RevokeAllExcept(ctx context.Context, userID int64, keepID string) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var n int64
+	now := time.Now()
+	for id, s := range f.byID {
+		if s.UserID == userID && id != keepID && s.RevokedAt == nil {
+			s.RevokedAt = &now
+			f.byID[id] = s
+			n++
+		}
+	}
+	return n, nil
+}
+
+func TestPasswordPolicy(t *testing.T) {
+	p := service.PasswordPolicy{MinLength: 10, RequireUpper: true, RequireDigit: true, RequireSymbol: true}
+
+	var policyErr *service.PasswordPolicyError
+	if err := p.Check("short", ""); !errors.As(err, &policyErr) || len(policyErr.Problems) != 4 {
+		t.Fatalf("expected 4 problems, got %v", err)
+	}
+	if err := p.Check("Longer-Passw0rd", ""); err != nil {
+		t.Fatalf("expected password to pass, got %v", err)
+	}
+	if err := p.Check(" Longer-Passw0rd", ""); !errors.As(err, &policyErr) || len(policyErr.Problems) != 1 {
+		t.Fatalf("expected leading whitespace to be rejected, got %v", err)
+	}
+	if err := service.DefaultPasswordPolicy().Check("Demo@Example.com", "demo@example.com"); err == nil {
+		t.Fatal("expected the email to be rejected as a password")
+	}
+}
+
+func TestChangePassword_RequiresCurrentPassword(t *testing.T) {
+	ctx := context.Background()
+	hash, _ := service.HashPassword("Password123!")
+	u := domain.User{ID: 1, Email: "demo@example.com", PasswordHash: &hash}
+	f := &fakeUsers{byEmail: map[string]domain.User{u.Email: u}, byID: map[int64]domain.User{1: u}}
+	svc := service.NewAuthService(f)
+
+	if err := svc.ChangePassword(ctx, 1, "wrong", "NewPassword456!"); err != service.ErrInvalidCredentials {
+		t.Fatalf("expected ErrInvalidCredentials got %v", err)
+	}
+	if err := svc.ChangePassword(ctx, 1, "Password123!", "NewPassword456!"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Login(ctx, "demo@example.com", "NewPassword456!"); err != nil {
+		t.Fatalf("expected login with the new password, got %v", err)
+	}
+}
+
+func TestChangePassword_OAuthOnlyUserSetsFirstPassword(t *testing.T) {
+	ctx := context.Background()
+	f := &fakeUsers{byEmail: map[string]domain.User{}, byID: map[int64]domain.User{}}
+	svc := service.NewAuthService(f)
+
+	id, _, err := svc.OAuthLogin(ctx, "google", "g-1", "oauth@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Login(ctx, "oauth@example.com", "FirstPassword1"); err != service.ErrInvalidCredentials {
+		t.Fatalf("expected email login to be unavailable, got %v", err)
+	}
+
+	if err := svc.ChangePassword(ctx, id, "", "FirstPassword1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Login(ctx, "oauth@example.com", "FirstPassword1"); err != nil {
+		t.Fatalf("expected email login after setting a password, got %v", err)
+	}
+}
+
+func TestChangePasswordHandler_RevokesOtherSessions(t *testing.T) {
+	ctx := context.Background()
+	keys := auth.NewHMACKeyring("test-secret")
+	hash, _ := service.HashPassword("Password123!")
+	u := domain.User{ID: 1, Email: "demo@example.com", PasswordHash: &hash}
+	users := &fakeUsers{byEmail: map[string]domain.User{u.Email: u}, byID: map[int64]domain.User{1: u}}
+	sessions := service.NewSessionService(newFakeSessions())
+
+	h := &handlers.AuthHandlers{Keys: keys, Auth: service.NewAuthService(users), Sessions: sessions, RecentAuth: 10 * time.Minute}
+	r := chi.NewRouter()
+	r.Use(middleware.RequireAuth(keys, middleware.Authenticators{Sessions: sessions}))
+	r.Post("/api/me/password", h.ChangePassword)
+	r.Get("/api/me", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+
+	cookieFor := func() *http.Cookie {
+		s, err := sessions.Create(ctx, 1, "test", "127.0.0.1", time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		token, _ := auth.SignSession(keys, 1, s.ID, time.Hour)
+		return &http.Cookie{Name: "session", Value: token}
+	}
+	current, other := cookieFor(), cookieFor()
+
+	body, _ := json.Marshal(map[string]string{"currentPassword": "Password123!", "newPassword": "short"})
+	req := httptest.NewRequest(http.MethodPost, "/api/me/password", bytes.NewReader(body))
+	req.AddCookie(current)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a weak password, got %d body=%s", rr.Code, rr.Body.String())
+	}
+
+	body, _ = json.Marshal(map[string]string{"currentPassword": "Password123!", "newPassword": "NewPassword456!"})
+	req = httptest.NewRequest(http.MethodPost, "/api/me/password", bytes.NewReader(body))
+	req.AddCookie(current)
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200 got %d body=%s", rr.Code, rr.Body.String())
+	}
+
+	for name, tc := range map[string]struct {
+		cookie *http.Cookie
+		want   int
+	}{
+		"current": {current, http.StatusOK},
+		"other":   {other, http.StatusUnauthorized},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/api/me", nil)
+		req.AddCookie(tc.cookie)
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		if rr.Code != tc.want {
+			t.Fatalf("%s session: expected %d got %d", name, tc.want, rr.Code)
+		}
+	}
+}
+
+func TestChangePasswordHandler_NewPasswordWorksAtLogin(t *testing.T) {
+	ctx := context.Background()
+	keys := auth.NewHMACKeyring("test-secret")
+	hash, _ := service.HashPassword("Password123!")
+	u := domain.User{ID: 1, Email: "demo@example.com", PasswordHash: &hash}
+	users := &fakeUsers{byEmail: map[string]domain.User{u.Email: u}, byID: map[int64]domain.User{1: u}}
+	sessions := service.NewSessionService(newFakeSessions())
+
+	h := &handlers.AuthHandlers{
+		Keys:       keys,
+		Auth:       service.NewAuthService(users),
+		TwoFactor:  service.NewTwoFactorService(newFakeTwoFactor(), "test"),
+		Sessions:   sessions,
+		RecentAuth: 10 * time.Minute,
+	}
+	r := chi.NewRouter()
+	r.Post("/api/auth/login", h.Login)
+	r.With(middleware.RequireAuth(keys, middleware.Authenticators{Sessions: sessions})).Post("/api/me/password", h.ChangePassword)
+
+	s, err := sessions.Create(ctx, 1, "test", "127.0.0.1", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, _ := auth.SignSession(keys, 1, s.ID, time.Hour)
+	change := func(newPassword string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]string{"currentPassword": "Password123!", "newPassword": newPassword})
+		req := httptest.NewRequest(http.MethodPost, "/api/me/password", bytes.NewReader(body))
+		req.AddCookie(&http.Cookie{Name: "session", Value: token})
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+	login := func(password string) int {
+		body, _ := json.Marshal(map[string]string{"email": "demo@example.com", "password": password})
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/api/auth/login", bytes.NewReader(body)))
+		return rr.Code
+	}
+
+	// login trims the password, so one padded with spaces could never be used
+	if rr := change(" NewPassword456! "); rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), "whitespace") {
+		t.Fatalf("expected the padded password refused, got %d %s", rr.Code, rr.Body.String())
+	}
+	if rr := change("NewPassword456!"); rr.Code != http.StatusOK {
+		t.Fatalf("change: %d %s", rr.Code, rr.Body.String())
+	}
+	if code := login("NewPassword456!"); code != http.StatusOK {
+		t.Fatalf("expected login with the new password, got %d", code)
+	}
+}
+
+func TestPasswordHasher_VerifiesEveryAlgorithm(t *testing.T) {
+	argon := service.DefaultPasswordHasher()
+	bc := service.PasswordHasher{Algorithm: service.HashBcrypt, BcryptCost: 4}
+
+	for name, h := range map[string]service.PasswordHasher{"argon2id": argon, "bcrypt": bc} {
+		hash, err := h.Hash("Password123!")
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		// verification doesn't depend on the hasher's configured algorithm
+		if !argon.Verify(hash, "Password123!") || !bc.Verify(hash, "Password123!") {
+			t.Fatalf("%s: expected hash to verify", name)
+		}
+		if argon.Verify(hash, "wrong") {
+			t.Fatalf("%s: expected wrong password to fail", name)
+		}
+		if h.NeedsRehash(hash) {
+			t.Fatalf("%s: fresh hash should not need a rehash", name)
+		}
+	}
+
+	stronger := argon
+	stronger.Argon2.Iterations++
+	hash, _ := argon.Hash("Password123!")
+	if !stronger.NeedsRehash(hash) {
+		t.Fatal("expected a rehash after raising argon2 iterations")
+	}
+	// lowering the settings or switching to bcrypt keeps stronger hashes
+	strongHash, _ := stronger.Hash("Password123!")
+	if argon.NeedsRehash(strongHash) {
+		t.Fatal("expected no rehash to lower argon2 parameters")
+	}
+	if bc.NeedsRehash(strongHash) {
+		t.Fatal("expected no rehash from argon2id to bcrypt")
+	}
+	costly, _ := service.PasswordHasher{Algorithm: service.HashBcrypt, BcryptCost: 6}.Hash("Password123!")
+	if bc.NeedsRehash(costly) {
+		t.Fatal("expected no rehash to a lower bcrypt cost")
+	}
+	if !(service.PasswordHasher{Algorithm: service.HashBcrypt, BcryptCost: 8}).NeedsRehash(costly) {
+		t.Fatal("expected a rehash after raising the bcrypt cost")
+	}
+	if !argon.NeedsRehash(costly) {
+		t.Fatal("expected bcrypt hashes upgraded to argon2id")
+	}
+	if argon.Verify("$argon2id$v=19$m=0,t=0,p=0$$", "x") {
+		t.Fatal("expected malformed hash to fail")
+	}
+}
+
+func TestLogin_UpgradesOutdatedHash(t *testing.T) {
+	ctx := context.Background()
+	legacy, _ := service.PasswordHasher{Algorithm: service.HashBcrypt, BcryptCost: 4}.Hash("Password123!")
+	u := domain.User{ID: 1, Email: "demo@example.com", PasswordHash: &legacy}
+	f := &fakeUsers{byEmail: map[string]domain.User{u.Email: u}, byID: map[int64]domain.User{1: u}}
+	svc := service.NewAuthService(f)
+
+	if _, err := svc.Login(ctx, "demo@example.com", "Password123!"); err != nil {
+		t.Fatal(err)
+	}
+	upgraded := *f.byID[1].PasswordHash
+	if !strings.HasPrefix(upgraded, "$argon2id$") {
+		t.Fatalf("expected an argon2id hash after login, got %q", upgraded)
+	}
+	if _, err := svc.Login(ctx, "demo@example.com", "Password123!"); err != nil {
+		t.Fatalf("expected login with the upgraded hash, got %v", err)
+	}
+	if *f.byID[1].PasswordHash != upgraded {
+		t.Fatal("expected no second rehash")
+	}
+}
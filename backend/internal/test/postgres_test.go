@@ -0,0 +1,41 @@
+package internal_test
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/soydoradesu/product_discovery/internal/db"
+)
+
+// testPool connects to TEST_DATABASE_URL and applies the migrations. Tests of
+// SQL that the fakes cannot stand in for use it and are skipped without a
+// database.
+func testPool(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set; see Testing in the README")
+	}
+	ctx := context.Background()
+	pool, err := db.Connect(ctx, dsn)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(pool.Close)
+	if err := db.ApplyMigrations(ctx, pool, "../../migrations"); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return pool
+}
+
+// uniqueKey gives rows a test creates a prefix no other run shares, since
+// the database is not reset between tests.
+func uniqueKey(t *testing.T) string {
+	t.Helper()
+	return "test-" + strconv.FormatInt(time.Now().UnixNano(), 36)
+}
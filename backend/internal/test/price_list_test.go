@@ -0,0 +1,220 @@
+package internal_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/soydoradesu/product_discovery/internal/config"
+	"github.com/soydoradesu/product_discovery/internal/domain"
+	"github.com/soydoradesu/product_discovery/internal/http/handlers"
+	"github.com/soydoradesu/product_discovery/internal/repository"
+	"github.com/soydoradesu/product_discovery/internal/service"
+)
+
+type fakeExchangeRates struct {
+	rates map[[2]string]domain.ExchangeRate
+}
+
+func (f *fakeExchangeRates) List(ctx context.Context) ([]domain.ExchangeRate, error) {
+	out := make([]domain.ExchangeRate, 0, len(f.rates))
+	for _, r := range f.rates {
+		out = append(out, r)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Base+out[i].Quote < out[j].Base+out[j].Quote })
+	return out, nil
+}
+
+func (f *fakeExchangeRates) Replace(ctx context.Context, rates []domain.ExchangeRate) error {
+	f.rates = map[[2]string]domain.ExchangeRate{}
+	for _, r := range rates {
+		if _, err := f.Upsert(ctx, r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *fakeExchangeRates) Upsert(ctx context.Context, rate domain.ExchangeRate) (domain.ExchangeRate, error) {
+	if f.rates == nil {
+		f.rates = map[[2]string]domain.ExchangeRate{}
+	}
+	rate.UpdatedAt = time.Now()
+	f.rates[[2]string{rate.Base, rate.Quote}] = rate
+	return rate, nil
+}
+
+func (f *fakeExchangeRates) Delete(ctx context.Context, base, quote string) error {
+	if _, ok := f.rates[[2]string{base, quote}]; !ok {
+		return repository.ErrNotFound
+	}
+	delete(f.rates, [2]string{base, quote})
+	return nil
+}
+
+func TestProductPrices_AdminPriceListAndDetailCurrency(t *testing.T) {
+	products := &fakeProducts{byID: map[int64]domain.Product{
+		1: {ID: 1, Name: "Speaker", Price: domain.Money{Amount: 15000000, Currency: "IDR"}},
+	}}
+	h := &handlers.ProductHandlers{Products: service.NewProductService(products), Visibility: config.ProductVisibilityFull}
+	router := chi.NewRouter()
+	router.Get("/api/products/{id}", h.GetByID)
+	router.Put("/api/admin/products/{id}/prices/{currency}", h.SetPrice)
+	router.Delete("/api/admin/products/{id}/prices/{currency}", h.DeletePrice)
+
+	detail := func(query string) (int, map[string]any) {
+		rr := sendJSON(router, http.MethodGet, "/api/products/1"+query, "")
+		var body map[string]any
+		_ = json.Unmarshal(rr.Body.Bytes(), &body)
+		return rr.Code, body
+	}
+
+	if code, body := detail("?currency=USD"); code != http.StatusNotFound || !strings.Contains(fmt.Sprint(body["error"]), "NO_PRICE") {
+		t.Fatalf("expected NO_PRICE before a USD price exists, got %d %v", code, body)
+	}
+	if code, _ := detail("?currency=dollars"); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an invalid currency got %d", code)
+	}
+
+	if rr := sendJSON(router, http.MethodPut, "/api/admin/products/1/prices/usd", `{"amount":"9.99"}`); rr.Code != http.StatusOK {
+		t.Fatalf("set price: %d %s", rr.Code, rr.Body.String())
+	}
+	if rr := sendJSON(router, http.MethodPut, "/api/admin/products/1/prices/IDR", `{"amount":"1"}`); rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), "own currency") {
+		t.Fatalf("expected 400 for the product's own currency got %d %s", rr.Code, rr.Body.String())
+	}
+	if rr := sendJSON(router, http.MethodPut, "/api/admin/products/1/prices/JPY", `{"amount":"10.5"}`); rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for decimals in JPY got %d", rr.Code)
+	}
+	if rr := sendJSON(router, http.MethodPut, "/api/admin/products/9/prices/USD", `{"amount":"1"}`); rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown product got %d", rr.Code)
+	}
+
+	code, body := detail("?currency=usd")
+	if code != http.StatusOK || priceAmount(body) != "9.99" || body["price"].(map[string]any)["currency"] != "USD" {
+		t.Fatalf("expected the USD price, got %d %v", code, body)
+	}
+	if prices, _ := body["prices"].([]any); len(prices) != 1 {
+		t.Fatalf("expected the price list on the detail, got %v", body["prices"])
+	}
+	if _, body := detail(""); priceAmount(body) != "150000.00" {
+		t.Fatalf("expected the own price without a currency, got %v", body)
+	}
+	// without a currency the detail is in the catalog's, as search is
+	svc := h.Products
+	svc.Currency = "USD"
+	code, body = detail("")
+	if code != http.StatusOK || priceAmount(body) != "9.99" || body["price"].(map[string]any)["currency"] != "USD" {
+		t.Fatalf("expected the catalog currency by default, got %d %v", code, body)
+	}
+	svc.Currency = service.DefaultCurrency
+
+	// a product search would leave out is not shown by default either
+	products.byID[2] = domain.Product{ID: 2, Name: "Import", Price: domain.Money{Amount: 500, Currency: "USD"}}
+	rr := sendJSON(router, http.MethodGet, "/api/products/2", "")
+	if rr.Code != http.StatusNotFound || !strings.Contains(rr.Body.String(), "NO_PRICE") || !strings.Contains(rr.Body.String(), "IDR") {
+		t.Fatalf("expected NO_PRICE in the catalog currency, got %d %s", rr.Code, rr.Body.String())
+	}
+
+	if rr := sendJSON(router, http.MethodDelete, "/api/admin/products/1/prices/USD", ""); rr.Code != http.StatusOK {
+		t.Fatalf("delete price: %d", rr.Code)
+	}
+	if rr := sendJSON(router, http.MethodDelete, "/api/admin/products/1/prices/USD", ""); rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 deleting twice got %d", rr.Code)
+	}
+}
+
+func TestProductSearch_Currency(t *testing.T) {
+	products := &fakeProducts{}
+	h := &handlers.ProductHandlers{Products: service.NewProductService(products)}
+
+	rr := httptest.NewRecorder()
+	h.Search(rr, httptest.NewRequest(http.MethodGet, "/api/products/search?currency=usd&minPrice=1.50&maxPrice=1.505&sort=price", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("search: %d", rr.Code)
+	}
+	got := products.searchParams
+	if got.Currency != "USD" || got.MinPrice == nil || *got.MinPrice != (domain.Money{Amount: 150, Currency: "USD"}) || got.MaxPrice != nil {
+		t.Fatalf("unexpected params %+v", got)
+	}
+	var body struct {
+		Currency string `json:"currency"`
+	}
+	_ = json.Unmarshal(rr.Body.Bytes(), &body)
+	if body.Currency != "USD" {
+		t.Fatalf("expected the response currency, got %q", body.Currency)
+	}
+
+	// without one, search runs in the catalog currency
+	rr = httptest.NewRecorder()
+	h.Search(rr, httptest.NewRequest(http.MethodGet, "/api/products/search?minPrice=1000", nil))
+	if got := products.searchParams; got.Currency != "IDR" || got.MinPrice.Amount != 100000 {
+		t.Fatalf("unexpected default params %+v", got)
+	}
+}
+
+func TestExchangeRates_ReplaceFromFileAndEndpoints(t *testing.T) {
+	rates, err := service.ParseExchangeRates(strings.NewReader("\ufeffbase,quote,rate\nUSD,IDR,16250.5\neur, usd ,1.08\n"), service.RatesFormatCSV)
+	if err != nil || len(rates) != 2 {
+		t.Fatalf("parse: %v %v", rates, err)
+	}
+	if _, err := service.ParseExchangeRates(strings.NewReader("from,to,rate\n"), service.RatesFormatCSV); !errors.Is(err, service.ErrInvalidRatesFile) {
+		t.Fatalf("expected ErrInvalidRatesFile for a wrong header got %v", err)
+	}
+
+	repo := &fakeExchangeRates{}
+	svc := service.NewExchangeRateService(repo)
+	loaded, err := svc.Replace(context.Background(), rates)
+	if err != nil || len(loaded) != 2 || loaded[0].Base != "EUR" || loaded[0].Quote != "USD" {
+		t.Fatalf("replace: %+v %v", loaded, err)
+	}
+
+	bad := []domain.ExchangeRate{{Base: "USD", Quote: "USD", Rate: "1"}, {Base: "USD", Quote: "IDR", Rate: "0.00"}, {Base: "USD", Quote: "IDR", Rate: "1e3"}}
+	var invalid *service.ValidationError
+	if _, err := svc.Replace(context.Background(), bad); !errors.As(err, &invalid) {
+		t.Fatalf("expected a ValidationError got %v", err)
+	}
+	for _, field := range []string{"rates[0].quote", "rates[1].rate", "rates[2].rate", "rates[2].quote"} {
+		if invalid.Fields[field] == "" {
+			t.Fatalf("expected an error on %s, got %v", field, invalid.Fields)
+		}
+	}
+	if len(repo.rates) != 2 {
+		t.Fatalf("an invalid table must not replace the old one, got %v", repo.rates)
+	}
+
+	h := &handlers.ExchangeRateHandlers{Rates: svc}
+	router := chi.NewRouter()
+	router.Put("/api/admin/exchange-rates", h.Replace)
+	router.Put("/api/admin/exchange-rates/{base}/{quote}", h.Set)
+	router.Delete("/api/admin/exchange-rates/{base}/{quote}", h.Delete)
+
+	req := httptest.NewRequest(http.MethodPut, "/api/admin/exchange-rates", strings.NewReader("base,quote,rate\nSGD,IDR,12100\n"))
+	req.Header.Set("Content-Type", "text/csv; charset=utf-8")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK || len(repo.rates) != 1 {
+		t.Fatalf("replace from csv: %d %s", rr.Code, rr.Body.String())
+	}
+
+	if rr := sendJSON(router, http.MethodPut, "/api/admin/exchange-rates/usd/idr", `{"rate":16300}`); rr.Code != http.StatusOK || repo.rates[[2]string{"USD", "IDR"}].Rate != "16300" {
+		t.Fatalf("set: %d %s", rr.Code, rr.Body.String())
+	}
+	if rr := sendJSON(router, http.MethodPut, "/api/admin/exchange-rates/usd/idr", `{"rate":"-1"}`); rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a negative rate got %d", rr.Code)
+	}
+	if rr := sendJSON(router, http.MethodDelete, "/api/admin/exchange-rates/USD/IDR", ""); rr.Code != http.StatusOK {
+		t.Fatalf("delete: %d", rr.Code)
+	}
+	if rr := sendJSON(router, http.MethodDelete, "/api/admin/exchange-rates/USD/IDR", ""); rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 deleting twice got %d", rr.Code)
+	}
+}
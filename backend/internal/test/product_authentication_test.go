@@ -1,8 +1,10 @@
 package internal_test
 
 import (
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
 	"time"
 
@@ -10,14 +12,17 @@ import (
 
 	"github.com/soydoradesu/product_discovery/internal/auth"
 	"github.com/soydoradesu/product_discovery/internal/config"
+	"github.com/soydoradesu/product_discovery/internal/domain"
+	"github.com/soydoradesu/product_discovery/internal/http/handlers"
 	"github.com/soydoradesu/product_discovery/internal/http/middleware"
+	"github.com/soydoradesu/product_discovery/internal/service"
 )
 
 func TestRequireAuth_Unauthorized_WhenMissingCookie(t *testing.T) {
-	cfg := config.Config{JWTSecret: "test-secret"}
+	keys := auth.NewHMACKeyring("test-secret")
 
 	r := chi.NewRouter()
-	r.Use(middleware.RequireAuth(cfg))
+	r.Use(middleware.RequireAuth(keys, middleware.Authenticators{}))
 	r.Get("/api/products/1", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	})
@@ -33,10 +38,10 @@ func TestRequireAuth_Unauthorized_WhenMissingCookie(t *testing.T) {
 }
 
 func TestRequireAuth_Unauthorized_WhenCookieEmpty(t *testing.T) {
-	cfg := config.Config{JWTSecret: "test-secret"}
+	keys := auth.NewHMACKeyring("test-secret")
 
 	r := chi.NewRouter()
-	r.Use(middleware.RequireAuth(cfg))
+	r.Use(middleware.RequireAuth(keys, middleware.Authenticators{}))
 	r.Get("/api/products/1", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	})
@@ -53,10 +58,10 @@ func TestRequireAuth_Unauthorized_WhenCookieEmpty(t *testing.T) {
 }
 
 func TestRequireAuth_Unauthorized_WhenTokenInvalid(t *testing.T) {
-	cfg := config.Config{JWTSecret: "test-secret"}
+	keys := auth.NewHMACKeyring("test-secret")
 
 	r := chi.NewRouter()
-	r.Use(middleware.RequireAuth(cfg))
+	r.Use(middleware.RequireAuth(keys, middleware.Authenticators{}))
 	r.Get("/api/products/1", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	})
@@ -73,15 +78,15 @@ func TestRequireAuth_Unauthorized_WhenTokenInvalid(t *testing.T) {
 }
 
 func TestRequireAuth_OK_WhenTokenValid_AndUserIDInContext(t *testing.T) {
-	cfg := config.Config{JWTSecret: "test-secret"}
+	keys := auth.NewHMACKeyring("test-secret")
 
-	token, err := auth.SignJWT(cfg.JWTSecret, 123, 10*time.Minute)
+	token, err := auth.SignJWT(keys, 123, 10*time.Minute)
 	if err != nil {
 		t.Fatalf("sign jwt: %v", err)
 	}
 
 	r := chi.NewRouter()
-	r.Use(middleware.RequireAuth(cfg))
+	r.Use(middleware.RequireAuth(keys, middleware.Authenticators{}))
 	r.Get("/api/products/1", func(w http.ResponseWriter, r *http.Request) {
 		uid, ok := middleware.UserIDFromContext(r.Context())
 		if !ok || uid != 123 {
@@ -102,15 +107,15 @@ func TestRequireAuth_OK_WhenTokenValid_AndUserIDInContext(t *testing.T) {
 }
 
 func TestRequireAuth_Unauthorized_WhenTokenExpired(t *testing.T) {
-	cfg := config.Config{JWTSecret: "test-secret"}
+	keys := auth.NewHMACKeyring("test-secret")
 
-	token, err := auth.SignJWT(cfg.JWTSecret, 123, -1*time.Minute) // already expired
+	token, err := auth.SignJWT(keys, 123, -1*time.Minute) // already expired
 	if err != nil {
 		t.Fatalf("sign jwt: %v", err)
 	}
 
 	r := chi.NewRouter()
-	r.Use(middleware.RequireAuth(cfg))
+	r.Use(middleware.RequireAuth(keys, middleware.Authenticators{}))
 	r.Get("/api/products/1", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	})
@@ -125,3 +130,90 @@ func TestRequireAuth_Unauthorized_WhenTokenExpired(t *testing.T) {
 		t.Fatalf("expected 401 got %d body=%s", rr.Code, rr.Body.String())
 	}
 }
+
+func TestOptionalAuth_AttachesUserOrContinues(t *testing.T) {
+	keys := auth.NewHMACKeyring("test-secret")
+	token, _ := auth.SignJWT(keys, 123, 10*time.Minute)
+
+	r := chi.NewRouter()
+	r.Use(middleware.OptionalAuth(keys, middleware.Authenticators{}))
+	r.Get("/api/products/1", func(w http.ResponseWriter, r *http.Request) {
+		if uid, ok := middleware.UserIDFromContext(r.Context()); ok {
+			w.Header().Set("X-User", strconv.FormatInt(uid, 10))
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	for _, tc := range []struct {
+		cookie string
+		user   string
+	}{
+		{"", ""},
+		{"not-a-jwt", ""},
+		{token, "123"},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/api/products/1", nil)
+		if tc.cookie != "" {
+			req.AddCookie(&http.Cookie{Name: "session", Value: tc.cookie})
+		}
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK || rr.Header().Get("X-User") != tc.user {
+			t.Fatalf("cookie %q: code=%d user=%q", tc.cookie, rr.Code, rr.Header().Get("X-User"))
+		}
+	}
+}
+
+func TestProductDetail_Visibility(t *testing.T) {
+	keys := auth.NewHMACKeyring("test-secret")
+	token, _ := auth.SignJWT(keys, 1, 10*time.Minute)
+	products := service.NewProductService(&fakeProducts{byID: map[int64]domain.Product{
+		1: {ID: 1, Name: "Desk", Price: domain.Money{Amount: 12000, Currency: "IDR"}, InStock: true, Images: []domain.ProductImage{{URL: "a.jpg"}, {URL: "b.jpg"}}},
+	}})
+
+	get := func(visibility string, signedIn bool) (int, map[string]any) {
+		h := &handlers.ProductHandlers{Products: products, Visibility: visibility}
+		r := chi.NewRouter()
+		r.With(middleware.OptionalAuth(keys, middleware.Authenticators{})).Get("/api/products/{id}", h.GetByID)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/products/1", nil)
+		if signedIn {
+			req.AddCookie(&http.Cookie{Name: "session", Value: token})
+		}
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		var body map[string]any
+		_ = json.Unmarshal(rr.Body.Bytes(), &body)
+		return rr.Code, body
+	}
+
+	if code, _ := get(config.ProductVisibilityLogin, false); code != http.StatusUnauthorized {
+		t.Fatalf("login mode: expected 401 got %d", code)
+	}
+	if code, body := get(config.ProductVisibilityLogin, true); code != http.StatusOK || priceAmount(body) != "120.00" {
+		t.Fatalf("login mode signed in: code=%d body=%v", code, body)
+	}
+	if code, body := get(config.ProductVisibilityFull, false); code != http.StatusOK || priceAmount(body) != "120.00" {
+		t.Fatalf("full mode: code=%d body=%v", code, body)
+	}
+
+	code, body := get(config.ProductVisibilityPreview, false)
+	if code != http.StatusOK || body["preview"] != true || body["name"] != "Desk" {
+		t.Fatalf("preview mode: code=%d body=%v", code, body)
+	}
+	if _, ok := body["price"]; ok {
+		t.Fatalf("preview must hide the price: %v", body)
+	}
+	if images := body["images"].([]any); len(images) != 1 {
+		t.Fatalf("preview should show one image, got %v", images)
+	}
+	if _, body := get(config.ProductVisibilityPreview, true); priceAmount(body) != "120.00" || body["preview"] != nil {
+		t.Fatalf("signed-in users get the full product in preview mode: %v", body)
+	}
+}
+
+// priceAmount is the decimal amount of a decoded product's price, or nil.
+func priceAmount(body map[string]any) any {
+	price, _ := body["price"].(map[string]any)
+	return price["amount"]
+}
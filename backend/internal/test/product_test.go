@@ -3,6 +3,7 @@ package internal_test
 import (
 	"context"
 	"errors"
+	"sort"
 	"testing"
 	"time"
 
@@ -17,6 +18,17 @@ type fakeProducts struct {
 	searchItems []domain.ProductSummary
 	searchTotal int64
 	searchErr   error
+	// replaceVariantsErr, when set, is what ReplaceVariants fails with
+	replaceVariantsErr error
+	// stocked lists the products with stock, which Delete refuses
+	stocked map[int64]bool
+	// searchParams records what the last Search was asked for
+	searchParams domain.SearchParams
+	facets       []domain.AttributeFacet
+
+	// categories, when set, are the category ids writes may link to
+	categories map[int64]string
+	nextID     int64
 }
 
 func (f *fakeProducts) GetByID(ctx context.Context, id int64) (domain.Product, error) {
@@ -28,16 +40,173 @@ func (f *fakeProducts) GetByID(ctx context.Context, id int64) (domain.Product, e
 }
 
 func (f *fakeProducts) Search(ctx context.Context, params domain.SearchParams) ([]domain.ProductSummary, int64, error) {
+	f.searchParams = params
 	if f.searchErr != nil {
 		return nil, 0, f.searchErr
 	}
 	return f.searchItems, f.searchTotal, nil
 }
 
+func (f *fakeProducts) AttributeFacets(ctx context.Context, params domain.SearchParams) ([]domain.AttributeFacet, error) {
+	f.searchParams = params
+	return f.facets, nil
+}
+
+// GetPrice has no exchange rates: a product is priced in its own currency
+// and those on its price list.
+func (f *fakeProducts) GetPrice(ctx context.Context, id int64, currency string) (domain.Money, error) {
+	p, ok := f.byID[id]
+	if !ok {
+		return domain.Money{}, repository.ErrNotFound
+	}
+	for _, m := range append([]domain.Money{p.Price}, p.Prices...) {
+		if m.Currency == currency {
+			return m, nil
+		}
+	}
+	return domain.Money{}, repository.ErrNotFound
+}
+
+func (f *fakeProducts) SetPrice(ctx context.Context, id int64, price domain.Money) error {
+	p, ok := f.byID[id]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	prices := []domain.Money{price}
+	for _, m := range p.Prices {
+		if m.Currency != price.Currency {
+			prices = append(prices, m)
+		}
+	}
+	p.Prices = prices
+	f.byID[id] = p
+	return nil
+}
+
+func (f *fakeProducts) DeletePrice(ctx context.Context, id int64, currency string) error {
+	p, ok := f.byID[id]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	for i, m := range p.Prices {
+		if m.Currency == currency {
+			p.Prices = append(p.Prices[:i:i], p.Prices[i+1:]...)
+			f.byID[id] = p
+			return nil
+		}
+	}
+	return repository.ErrNotFound
+}
+
+// VariantPrices, like GetPrice, has no exchange rates.
+func (f *fakeProducts) VariantPrices(ctx context.Context, id int64, currency string) (map[int64]domain.Money, error) {
+	prices := map[int64]domain.Money{}
+	for _, v := range f.byID[id].Variants {
+		if v.Price.Currency == currency {
+			prices[v.ID] = v.Price
+		}
+	}
+	return prices, nil
+}
+
+func (f *fakeProducts) ReplaceVariants(ctx context.Context, id int64, variants []domain.Variant) error {
+	if f.replaceVariantsErr != nil {
+		return f.replaceVariantsErr
+	}
+	p, ok := f.byID[id]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	kept := map[string]int64{}
+	for _, v := range p.Variants {
+		kept[v.SKU] = v.ID
+	}
+	p.Variants = nil
+	for _, v := range variants {
+		if v.ID = kept[v.SKU]; v.ID == 0 {
+			f.nextID++
+			v.ID = f.nextID
+		}
+		if len(p.Variants) == 0 || v.Price.Amount < p.Price.Amount {
+			p.Price = v.Price
+		}
+		p.InStock = len(p.Variants) > 0 && p.InStock || v.InStock
+		p.Variants = append(p.Variants, v)
+	}
+	f.byID[id] = p
+	return nil
+}
+
+func (f *fakeProducts) Create(ctx context.Context, p domain.Product) (int64, error) {
+	if f.byID == nil {
+		f.byID = map[int64]domain.Product{}
+	}
+	f.nextID++
+	p.ID = f.nextID
+	p.CreatedAt = time.Now()
+	if err := f.Update(ctx, p); err != nil {
+		return 0, err
+	}
+	return p.ID, nil
+}
+
+func (f *fakeProducts) Update(ctx context.Context, p domain.Product) error {
+	current, ok := f.byID[p.ID]
+	if !ok && p.CreatedAt.IsZero() {
+		return repository.ErrNotFound
+	}
+	for id, other := range f.byID {
+		if id != p.ID && other.Name == p.Name && other.Description == p.Description {
+			return repository.ErrConflict
+		}
+	}
+	for i, c := range p.Categories {
+		name, ok := f.categories[c.ID]
+		if !ok {
+			return repository.ErrInvalidReference
+		}
+		p.Categories[i].Name = name
+	}
+	if ok {
+		p.CreatedAt = current.CreatedAt
+	}
+	f.byID[p.ID] = p
+	return nil
+}
+
+func (f *fakeProducts) Delete(ctx context.Context, id int64) error {
+	if _, ok := f.byID[id]; !ok {
+		return repository.ErrNotFound
+	}
+	if f.stocked[id] {
+		return repository.ErrStockRemaining
+	}
+	delete(f.byID, id)
+	return nil
+}
+
+func (f *fakeProducts) Export(ctx context.Context, params domain.SearchParams, fn func(domain.Product) error) error {
+	ids := make([]int64, 0, len(f.byID))
+	for id := range f.byID {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	for _, id := range ids {
+		p := f.byID[id]
+		if params.InStock != nil && p.InStock != *params.InStock {
+			continue
+		}
+		if err := fn(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func TestProductService_GetByID_OK(t *testing.T) {
 	fp := &fakeProducts{
 		byID: map[int64]domain.Product{
-			1: {ID: 1, Name: "X", Price: 10, Description: "D", Rating: 4.5, InStock: true, CreatedAt: time.Now()},
+			1: {ID: 1, Name: "X", Price: domain.Money{Amount: 1000, Currency: "IDR"}, Description: "D", Rating: 4.5, InStock: true, CreatedAt: time.Now()},
 		},
 	}
 	svc := service.NewProductService(fp)
@@ -64,8 +233,8 @@ func TestProductService_GetByID_NotFound(t *testing.T) {
 func TestProductService_Search_OK(t *testing.T) {
 	fp := &fakeProducts{
 		searchItems: []domain.ProductSummary{
-			{ID: 1, Name: "A", Price: 10.5, Rating: 4.2, InStock: true, CreatedAt: time.Now()},
-			{ID: 2, Name: "B", Price: 20.0, Rating: 4.8, InStock: false, CreatedAt: time.Now()},
+			{ID: 1, Name: "A", Price: domain.Money{Amount: 1050, Currency: "IDR"}, Rating: 4.2, InStock: true, CreatedAt: time.Now()},
+			{ID: 2, Name: "B", Price: domain.Money{Amount: 2000, Currency: "IDR"}, Rating: 4.8, InStock: false, CreatedAt: time.Now()},
 		},
 		searchTotal: 2,
 	}
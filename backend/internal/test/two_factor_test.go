@@ -0,0 +1,239 @@
+package internal_test
+
+import (
+	"context"
+	"encoding/base32"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/soydoradesu/product_discovery/internal/auth"
+	"github.com/soydoradesu/product_discovery/internal/domain"
+	"github.com/soydoradesu/product_discovery/internal/http/handlers"
+	"github.com/soydoradesu/product_discovery/internal/repository"
+	"github.com/soydoradesu/product_discovery/internal/service"
+)
+
+type fakeTwoFactor struct {
+	totp  map[int64]domain.UserTOTP
+	codes map[int64]map[string]bool
+}
+
+func newFakeTwoFactor() *fakeTwoFactor {
+	return &fakeTwoFactor{totp: map[int64]domain.UserTOTP{}, codes: map[int64]map[string]bool{}}
+}
+
+func (f *fakeTwoFactor) GetTOTP(ctx context.Context, userID int64) (domain.UserTOTP, error) {
+	t, ok := f.totp[userID]
+	if !ok {
+		return domain.UserTOTP{}, repository.ErrNotFound
+	}
+	return t, nil
+}
+
+func (f *fakeTwoFactor) SavePendingTOTP(ctx context.Context, userID int64, secret string) error {
+	if t, ok := f.totp[userID]; ok && t.EnabledAt != nil {
+		return nil
+	}
+	f.totp[userID] = domain.UserTOTP{UserID: userID, Secret: secret}
+	return nil
+}
+
+func (f *fakeTwoFactor) EnableTOTP(ctx context.Context, userID int64, step int64, hashes []string) error {
+	t, ok := f.totp[userID]
+	if !ok || t.EnabledAt != nil {
+		return repository.ErrNotFound
+	}
+	now := time.Now()
+	t.EnabledAt = &now
+	t.LastUsedStep = step
+	f.totp[userID] = t
+
+	f.codes[userID] = map[string]bool{}
+	for _, h := range hashes {
+		f.codes[userID][h] = false
+	}
+	return nil
+}
+
+func (f *fakeTwoFactor) DeleteTOTP(ctx context.Context, userID int64) error {
+	delete(f.totp, userID)
+	delete(f.codes, userID)
+	return nil
+}
+
+func (f *fakeTwoFactor) AdvanceTOTPStep(ctx context.Context, userID int64, step int64) (bool, error) {
+	t, ok := f.totp[userID]
+	if !ok || t.LastUsedStep >= step {
+		return false, nil
+	}
+	t.LastUsedStep = step
+	f.totp[userID] = t
+	return true, nil
+}
+
+func (f *fakeTwoFactor) UseRecoveryCode(ctx context.Context, userID int64, codeHash string) (bool, error) {
+	used, ok := f.codes[userID][codeHash]
+	if !ok || used {
+		return false, nil
+	}
+	f.codes[userID][codeHash] = true
+	return true, nil
+}
+
+func (f *fakeTwoFactor) RecordTOTPFailure(ctx context.Context, userID int64, maxFailures int, lockUntil time.Time) (bool, error) {
+	t, ok := f.totp[userID]
+	if !ok {
+		return false, nil
+	}
+	t.FailedAttempts++
+	locked := t.FailedAttempts >= maxFailures
+	if locked {
+		t.FailedAttempts = 0
+		t.LockedUntil = &lockUntil
+	}
+	f.totp[userID] = t
+	return locked, nil
+}
+
+func (f *fakeTwoFactor) ResetTOTPFailures(ctx context.Context, userID int64) error {
+	if t, ok := f.totp[userID]; ok {
+		t.FailedAttempts = 0
+		t.LockedUntil = nil
+		f.totp[userID] = t
+	}
+	return nil
+}
+
+func TestTOTPCode_RFC6238Vector(t *testing.T) {
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+	got, err := auth.TOTPCode(secret, auth.TOTPStep(time.Unix(59, 0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "287082" {
+		t.Fatalf("expected 287082 got %s", got)
+	}
+}
+
+func TestTwoFactor_EnrollConfirmVerify(t *testing.T) {
+	ctx := context.Background()
+	svc := service.NewTwoFactorService(newFakeTwoFactor(), "Test")
+
+	enrollment, err := svc.Enroll(ctx, 1, "demo@example.com")
+	if err != nil {
+		t.Fatalf("enroll: %v", err)
+	}
+	if !strings.HasPrefix(enrollment.URI, "otpauth://totp/") {
+		t.Fatalf("unexpected uri %s", enrollment.URI)
+	}
+
+	enabled, _ := svc.Enabled(ctx, 1)
+	if enabled {
+		t.Fatal("expected 2fa to stay disabled before confirm")
+	}
+
+	if _, err := svc.Confirm(ctx, 1, "not-a-code"); err != service.ErrInvalidTwoFactorCode {
+		t.Fatalf("expected ErrInvalidTwoFactorCode got %v", err)
+	}
+
+	code, _ := auth.TOTPCode(enrollment.Secret, auth.TOTPStep(time.Now()))
+	recovery, err := svc.Confirm(ctx, 1, code)
+	if err != nil {
+		t.Fatalf("confirm: %v", err)
+	}
+	if len(recovery) != 10 {
+		t.Fatalf("expected 10 recovery codes got %d", len(recovery))
+	}
+
+	// the code used to confirm can't be replayed at login
+	if err := svc.Verify(ctx, 1, code); err != service.ErrInvalidTwoFactorCode {
+		t.Fatalf("expected replayed code to fail, got %v", err)
+	}
+
+	if err := svc.Verify(ctx, 1, strings.ToUpper(recovery[0])); err != nil {
+		t.Fatalf("expected recovery code to work, got %v", err)
+	}
+	if err := svc.Verify(ctx, 1, recovery[0]); err != service.ErrInvalidTwoFactorCode {
+		t.Fatalf("expected used recovery code to fail, got %v", err)
+	}
+
+	if err := svc.Disable(ctx, 1, recovery[1]); err != nil {
+		t.Fatalf("disable: %v", err)
+	}
+	enabled, _ = svc.Enabled(ctx, 1)
+	if enabled {
+		t.Fatal("expected 2fa disabled")
+	}
+}
+
+func TestTwoFactor_LoginLocksAfterRepeatedWrongCodes(t *testing.T) {
+	keys := auth.NewHMACKeyring("test-secret")
+	twoFactor := newFakeTwoFactor()
+	secret, _ := auth.GenerateTOTPSecret()
+	enabledAt := time.Now()
+	twoFactor.totp[1] = domain.UserTOTP{UserID: 1, Secret: secret, EnabledAt: &enabledAt}
+	events := &fakeAuthEvents{}
+	h := &handlers.AuthHandlers{
+		Keys:      keys,
+		TwoFactor: service.NewTwoFactorService(twoFactor, "test"),
+		Sessions:  service.NewSessionService(newFakeSessions()),
+		Audit:     service.NewAuditService(events),
+	}
+
+	challenge, _ := auth.SignChallenge(keys, 1, auth.PurposeTwoFactor, time.Minute)
+	login := func(code string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		h.LoginTwoFactor(rr, httptest.NewRequest(http.MethodPost, "/api/auth/login/2fa", strings.NewReader(`{"challengeToken":"`+challenge+`","code":"`+code+`"}`)))
+		return rr
+	}
+
+	// a wrong code never matches the current one, which is all digits
+	for i := 1; i < 5; i++ {
+		if rr := login("abcdef"); rr.Code != http.StatusUnauthorized {
+			t.Fatalf("attempt %d: expected 401 got %d", i, rr.Code)
+		}
+	}
+	if rr := login("abcdef"); rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected the fifth wrong code to lock, got %d %s", rr.Code, rr.Body.String())
+	}
+	// while locked even the right code is refused
+	code, _ := auth.TOTPCode(secret, auth.TOTPStep(time.Now()))
+	if rr := login(code); rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 while locked got %d", rr.Code)
+	}
+	if last := events.events[len(events.events)-1]; last.Type != domain.AuthEventLoginFailed || last.Reason != "2fa_locked" {
+		t.Fatalf("unexpected event %+v", last)
+	}
+
+	// once the lock has passed the right code works and clears the count
+	past := time.Now().Add(-time.Second)
+	locked := twoFactor.totp[1]
+	locked.LockedUntil = &past
+	locked.FailedAttempts = 3
+	twoFactor.totp[1] = locked
+	if rr := login(code); rr.Code != http.StatusOK {
+		t.Fatalf("expected 200 after the lockout got %d %s", rr.Code, rr.Body.String())
+	}
+	if got := twoFactor.totp[1]; got.FailedAttempts != 0 || got.LockedUntil != nil {
+		t.Fatalf("expected the failures reset, got %+v", got)
+	}
+}
+
+func TestChallengeToken_NotAcceptedAsSession(t *testing.T) {
+	keys := auth.NewHMACKeyring("test-secret")
+	token, err := auth.SignChallenge(keys, 7, auth.PurposeTwoFactor, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := auth.VerifyJWT(keys, token); err == nil {
+		t.Fatal("expected challenge token to be rejected as a session")
+	}
+	claims, err := auth.VerifyChallenge(keys, token, auth.PurposeTwoFactor)
+	if err != nil || claims.UserID != 7 {
+		t.Fatalf("expected valid challenge, got claims=%v err=%v", claims, err)
+	}
+}
@@ -0,0 +1,153 @@
+package internal_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/soydoradesu/product_discovery/internal/config"
+	"github.com/soydoradesu/product_discovery/internal/domain"
+	"github.com/soydoradesu/product_discovery/internal/http/handlers"
+	"github.com/soydoradesu/product_discovery/internal/repository"
+	"github.com/soydoradesu/product_discovery/internal/repository/postgres"
+	"github.com/soydoradesu/product_discovery/internal/service"
+)
+
+func TestProductVariants_ReplaceAndDetail(t *testing.T) {
+	products := &fakeProducts{nextID: 100, byID: map[int64]domain.Product{
+		1: {ID: 1, Name: "Tee", Price: domain.Money{Amount: 10000000, Currency: "IDR"}, InStock: false},
+	}}
+	h := &handlers.ProductHandlers{Products: service.NewProductService(products), Visibility: config.ProductVisibilityFull}
+	router := chi.NewRouter()
+	router.Get("/api/products/{id}", h.GetByID)
+	router.Patch("/api/admin/products/{id}", h.Patch)
+	router.Put("/api/admin/products/{id}/variants", h.ReplaceVariants)
+
+	rr := sendJSON(router, http.MethodPut, "/api/admin/products/1/variants", `{"variants":[
+		{"sku":"TEE-BLK-M","options":{"Color":"Black","size":"M"},"price":"120000","inStock":false},
+		{"sku":"tee-blk-m","options":{"color":"Black","size":"L"},"price":"90000.5"},
+		{"sku":"TEE-WHT-M","options":{"color":"white"},"price":{"amount":"1","currency":"USD"}},
+		{"sku":"TEE WHT L","options":{"color":"black","size":"m"},"price":"-1"}
+	]}`)
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 got %d %s", rr.Code, rr.Body.String())
+	}
+	var body struct {
+		Error struct {
+			Fields map[string]string `json:"fields"`
+		} `json:"error"`
+	}
+	_ = json.Unmarshal(rr.Body.Bytes(), &body)
+	for _, field := range []string{"variants[1].sku", "variants[2].options", "variants[2].price", "variants[3].sku", "variants[3].options", "variants[3].price"} {
+		if body.Error.Fields[field] == "" {
+			t.Fatalf("expected an error on %s, got %v", field, body.Error.Fields)
+		}
+	}
+
+	replace := `{"variants":[
+		{"sku":"TEE-BLK-M","options":{"Color":"Black","size":"M"},"price":"120000","inStock":false},
+		{"sku":"TEE-BLK-L","options":{"color":"Black","size":"L"},"price":"90000.50","inStock":true}
+	]}`
+	if rr := sendJSON(router, http.MethodPut, "/api/admin/products/1/variants", replace); rr.Code != http.StatusOK {
+		t.Fatalf("replace variants: %d %s", rr.Code, rr.Body.String())
+	}
+	p := products.byID[1]
+	if len(p.Variants) != 2 || p.Price.Amount != 9000050 || !p.InStock || p.Variants[0].Options["color"] != "Black" {
+		t.Fatalf("expected the variants rolled up onto the product, got %+v", p)
+	}
+	firstID := p.Variants[0].ID
+
+	rr = sendJSON(router, http.MethodGet, "/api/products/1", "")
+	var detail domain.Product
+	if err := json.Unmarshal(rr.Body.Bytes(), &detail); err != nil || len(detail.Variants) != 2 || detail.Variants[1].SKU != "TEE-BLK-L" || detail.Variants[1].Price.Decimal() != "90000.50" {
+		t.Fatalf("expected variants on the detail, got %s", rr.Body.String())
+	}
+
+	if rr := sendJSON(router, http.MethodPatch, "/api/admin/products/1", `{"price":{"amount":"5","currency":"USD"}}`); rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 changing the currency of a product with variants, got %d", rr.Code)
+	}
+
+	// re-sending a SKU keeps its id
+	if rr := sendJSON(router, http.MethodPut, "/api/admin/products/1/variants", `{"variants":[{"sku":"TEE-BLK-M","options":{"color":"Black","size":"M"},"price":"120000"}]}`); rr.Code != http.StatusOK {
+		t.Fatalf("replace again: %d", rr.Code)
+	}
+	if p := products.byID[1]; len(p.Variants) != 1 || p.Variants[0].ID != firstID {
+		t.Fatalf("expected the kept variant to keep id %d, got %+v", firstID, p.Variants)
+	}
+	if rr := sendJSON(router, http.MethodPut, "/api/admin/products/9/variants", `{"variants":[]}`); rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown product got %d", rr.Code)
+	}
+}
+
+func TestProductSearch_VariantOptionFilters(t *testing.T) {
+	products := &fakeProducts{searchItems: []domain.ProductSummary{{
+		ID:       1,
+		Price:    domain.Money{Amount: 9000050, Currency: "IDR"},
+		PriceMax: domain.Money{Amount: 12000000, Currency: "IDR"},
+	}}, searchTotal: 1}
+	h := &handlers.ProductHandlers{Products: service.NewProductService(products)}
+
+	rr := httptest.NewRecorder()
+	h.Search(rr, httptest.NewRequest(http.MethodGet, "/api/products/search?option.Color=Black&option.size=M&option.size=%20L%20&option.bad-name=x&option.fit=", nil))
+	got := products.searchParams.Options
+	if len(got) != 2 || len(got["color"]) != 1 || got["color"][0] != "Black" || len(got["size"]) != 2 || got["size"][1] != "L" {
+		t.Fatalf("unexpected option filters %v", got)
+	}
+	if !bytes.Contains(rr.Body.Bytes(), []byte(`"priceMax":{"amount":"120000.00","currency":"IDR"}`)) {
+		t.Fatalf("expected the price range in the summary, got %s", rr.Body.String())
+	}
+}
+
+func TestProductVariants_ReplaceRefusesToDropStock(t *testing.T) {
+	products := &fakeProducts{byID: map[int64]domain.Product{1: {ID: 1, Price: domain.Money{Amount: 100000, Currency: "IDR"}}}}
+	products.replaceVariantsErr = repository.ErrStockRemaining
+	h := &handlers.ProductHandlers{Products: service.NewProductService(products)}
+	router := chi.NewRouter()
+	router.Put("/api/admin/products/{id}/variants", h.ReplaceVariants)
+
+	rr := sendJSON(router, http.MethodPut, "/api/admin/products/1/variants", `{"variants":[]}`)
+	if rr.Code != http.StatusConflict || !strings.Contains(rr.Body.String(), service.ErrVariantHasStock.Error()) {
+		t.Fatalf("expected 409 for a stocked variant, got %d %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestProductRepo_OptionFilterIgnoresCase(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+	key := uniqueKey(t)
+	t.Cleanup(func() {
+		_, _ = pool.Exec(context.Background(), `DELETE FROM products WHERE external_key = $1`, key)
+	})
+	if _, err := postgres.NewCatalogImportRepo(pool).Import(ctx, []domain.ImportRow{{Line: 2, ExternalKey: key, Product: domain.Product{
+		Name: "Tee " + key, Price: domain.Money{Amount: 100000, Currency: "IDR"}, Description: "Cotton", Rating: 4,
+	}}}, false); err != nil {
+		t.Fatal(err)
+	}
+	var id int64
+	if err := pool.QueryRow(ctx, `SELECT id FROM products WHERE external_key = $1`, key).Scan(&id); err != nil {
+		t.Fatal(err)
+	}
+	products := postgres.NewProductRepo(pool)
+	if err := products.ReplaceVariants(ctx, id, []domain.Variant{
+		{SKU: key + "-1", Options: map[string]string{"color": "Black " + key}, Price: domain.Money{Amount: 100000, Currency: "IDR"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, total, err := products.Search(ctx, domain.SearchParams{
+		Currency: "IDR", Sort: "created_at", Page: 1, PageSize: 20,
+		Options: map[string][]string{"color": {strings.ToUpper("black " + key)}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 1 {
+		t.Fatalf("expected the variant matched without case, got %d", total)
+	}
+}
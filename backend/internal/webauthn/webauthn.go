@@ -0,0 +1,337 @@
+// Package webauthn implements the relying party side of WebAuthn
+// registration and authentication ceremonies for passkeys. Attestation
+// statements are not verified: the relying party asks for "none" attestation
+// and accepts any authenticator.
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+var ErrVerification = errors.New("webauthn: verification failed")
+
+// Authenticator data flags.
+const (
+	flagUserPresent  = 0x01
+	flagUserVerified = 0x04
+	flagAttested     = 0x40
+)
+
+// RelyingParty identifies this site to authenticators. ID is the domain
+// passkeys are scoped to and Origins are the page origins allowed to use
+// them.
+type RelyingParty struct {
+	ID      string
+	Name    string
+	Origins []string
+}
+
+// User is the account a passkey is created for. Handle must not contain
+// personal data since authenticators may show or sync it.
+type User struct {
+	Handle      []byte
+	Name        string
+	DisplayName string
+}
+
+// Credential is what the relying party stores for a registered passkey.
+type Credential struct {
+	ID        []byte
+	PublicKey []byte // COSE_Key
+	SignCount uint32
+	AAGUID    []byte
+}
+
+// NewChallenge returns a random challenge, base64url encoded.
+func NewChallenge() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// The options below are the JSON form of PublicKeyCredentialCreationOptions
+// and PublicKeyCredentialRequestOptions, with binary fields base64url
+// encoded as PublicKeyCredential.parseCreationOptionsFromJSON expects.
+
+type rpEntity struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type userEntity struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+type credParam struct {
+	Type string `json:"type"`
+	Alg  int64  `json:"alg"`
+}
+
+type CredentialDescriptor struct {
+	Type       string   `json:"type"`
+	ID         string   `json:"id"`
+	Transports []string `json:"transports,omitempty"`
+}
+
+type authenticatorSelection struct {
+	ResidentKey      string `json:"residentKey"`
+	UserVerification string `json:"userVerification"`
+}
+
+type CreationOptions struct {
+	Challenge              string                 `json:"challenge"`
+	RP                     rpEntity               `json:"rp"`
+	User                   userEntity             `json:"user"`
+	PubKeyCredParams       []credParam            `json:"pubKeyCredParams"`
+	Timeout                int                    `json:"timeout"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection authenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                 `json:"attestation"`
+}
+
+type RequestOptions struct {
+	Challenge        string                 `json:"challenge"`
+	RPID             string                 `json:"rpId"`
+	Timeout          int                    `json:"timeout"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+const ceremonyTimeoutMillis = 5 * 60 * 1000
+
+// CreationOptions starts a registration. exclude lists the user's existing
+// passkeys so the same authenticator isn't registered twice.
+func (rp RelyingParty) CreationOptions(challenge string, user User, exclude []CredentialDescriptor) CreationOptions {
+	params := make([]credParam, 0, len(supportedAlgs))
+	for _, alg := range supportedAlgs {
+		params = append(params, credParam{Type: "public-key", Alg: alg})
+	}
+	if exclude == nil {
+		exclude = []CredentialDescriptor{}
+	}
+	return CreationOptions{
+		Challenge:          challenge,
+		RP:                 rpEntity{ID: rp.ID, Name: rp.Name},
+		User:               userEntity{ID: base64.RawURLEncoding.EncodeToString(user.Handle), Name: user.Name, DisplayName: user.DisplayName},
+		PubKeyCredParams:   params,
+		Timeout:            ceremonyTimeoutMillis,
+		ExcludeCredentials: exclude,
+		AuthenticatorSelection: authenticatorSelection{
+			ResidentKey:      "required",
+			UserVerification: "required",
+		},
+		Attestation: "none",
+	}
+}
+
+// RequestOptions starts an authentication. With no allowed credentials the
+// browser offers every passkey it holds for the site.
+func (rp RelyingParty) RequestOptions(challenge string, allow []CredentialDescriptor) RequestOptions {
+	if allow == nil {
+		allow = []CredentialDescriptor{}
+	}
+	return RequestOptions{
+		Challenge:        challenge,
+		RPID:             rp.ID,
+		Timeout:          ceremonyTimeoutMillis,
+		AllowCredentials: allow,
+		UserVerification: "required",
+	}
+}
+
+// AttestationResponse is the JSON form (PublicKeyCredential.toJSON) of the
+// credential navigator.credentials.create() returns.
+type AttestationResponse struct {
+	ID       string `json:"id"`
+	RawID    string `json:"rawId"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string   `json:"clientDataJSON"`
+		AttestationObject string   `json:"attestationObject"`
+		Transports        []string `json:"transports"`
+	} `json:"response"`
+}
+
+// AssertionResponse is the JSON form of the credential
+// navigator.credentials.get() returns.
+type AssertionResponse struct {
+	ID       string `json:"id"`
+	RawID    string `json:"rawId"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string `json:"clientDataJSON"`
+		AuthenticatorData string `json:"authenticatorData"`
+		Signature         string `json:"signature"`
+		UserHandle        string `json:"userHandle"`
+	} `json:"response"`
+}
+
+// CredentialID decodes the credential's raw id.
+func (a AssertionResponse) CredentialID() ([]byte, error) {
+	return decodeB64(a.RawID)
+}
+
+// UserHandle decodes the user handle the authenticator returned, if any.
+func (a AssertionResponse) UserHandle() ([]byte, error) {
+	if a.Response.UserHandle == "" {
+		return nil, nil
+	}
+	return decodeB64(a.Response.UserHandle)
+}
+
+// VerifyRegistration checks a registration response against the challenge
+// from CreationOptions and returns the new credential.
+func (rp RelyingParty) VerifyRegistration(resp AttestationResponse, challenge string) (Credential, error) {
+	if resp.Type != "public-key" {
+		return Credential{}, fmt.Errorf("%w: unexpected credential type", ErrVerification)
+	}
+	if _, err := rp.verifyClientData(resp.Response.ClientDataJSON, "webauthn.create", challenge); err != nil {
+		return Credential{}, err
+	}
+
+	raw, err := decodeB64(resp.Response.AttestationObject)
+	if err != nil {
+		return Credential{}, fmt.Errorf("%w: attestation object", ErrVerification)
+	}
+	v, rest, err := decodeCBOR(raw)
+	if err != nil || len(rest) != 0 {
+		return Credential{}, fmt.Errorf("%w: attestation object", ErrVerification)
+	}
+	att, _ := v.(map[any]any)
+	authData, _ := att["authData"].([]byte)
+
+	flags, signCount, rest, err := rp.parseAuthData(authData)
+	if err != nil {
+		return Credential{}, err
+	}
+	if flags&flagAttested == 0 || len(rest) < 18 {
+		return Credential{}, fmt.Errorf("%w: missing attested credential data", ErrVerification)
+	}
+	aaguid := append([]byte(nil), rest[:16]...)
+	idLen := int(binary.BigEndian.Uint16(rest[16:18]))
+	rest = rest[18:]
+	if idLen == 0 || idLen > 1023 || len(rest) < idLen {
+		return Credential{}, fmt.Errorf("%w: bad credential id", ErrVerification)
+	}
+	credID := append([]byte(nil), rest[:idLen]...)
+	rest = rest[idLen:]
+
+	if rawID, err := decodeB64(resp.RawID); err != nil || !bytes.Equal(rawID, credID) {
+		return Credential{}, fmt.Errorf("%w: credential id mismatch", ErrVerification)
+	}
+
+	// extensions, if any, follow the key; only the key itself is stored
+	_, after, err := parseCOSEKey(rest)
+	if err != nil {
+		return Credential{}, fmt.Errorf("%w: %v", ErrVerification, err)
+	}
+	coseKey := append([]byte(nil), rest[:len(rest)-len(after)]...)
+
+	return Credential{ID: credID, PublicKey: coseKey, SignCount: signCount, AAGUID: aaguid}, nil
+}
+
+// VerifyAssertion checks an authentication response for cred against the
+// challenge from RequestOptions and returns the authenticator's new
+// signature counter.
+func (rp RelyingParty) VerifyAssertion(resp AssertionResponse, challenge string, cred Credential) (uint32, error) {
+	if resp.Type != "public-key" {
+		return 0, fmt.Errorf("%w: unexpected credential type", ErrVerification)
+	}
+	clientData, err := rp.verifyClientData(resp.Response.ClientDataJSON, "webauthn.get", challenge)
+	if err != nil {
+		return 0, err
+	}
+
+	authData, err := decodeB64(resp.Response.AuthenticatorData)
+	if err != nil {
+		return 0, fmt.Errorf("%w: authenticator data", ErrVerification)
+	}
+	_, signCount, _, err := rp.parseAuthData(authData)
+	if err != nil {
+		return 0, err
+	}
+
+	sig, err := decodeB64(resp.Response.Signature)
+	if err != nil {
+		return 0, fmt.Errorf("%w: signature", ErrVerification)
+	}
+	key, _, err := parseCOSEKey(cred.PublicKey)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrVerification, err)
+	}
+	clientHash := sha256.Sum256(clientData)
+	if !key.verify(append(append([]byte(nil), authData...), clientHash[:]...), sig) {
+		return 0, fmt.Errorf("%w: bad signature", ErrVerification)
+	}
+
+	// a counter that doesn't move forward suggests a cloned authenticator;
+	// authenticators that don't count always report zero
+	if (signCount != 0 || cred.SignCount != 0) && signCount <= cred.SignCount {
+		return 0, fmt.Errorf("%w: signature counter went backwards", ErrVerification)
+	}
+	return signCount, nil
+}
+
+type clientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+// verifyClientData returns the raw client data, whose hash the authenticator
+// signs.
+func (rp RelyingParty) verifyClientData(encoded, typ, challenge string) ([]byte, error) {
+	raw, err := decodeB64(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("%w: client data", ErrVerification)
+	}
+	var cd clientData
+	if err := json.Unmarshal(raw, &cd); err != nil {
+		return nil, fmt.Errorf("%w: client data", ErrVerification)
+	}
+	switch {
+	case cd.Type != typ:
+		return nil, fmt.Errorf("%w: unexpected client data type %q", ErrVerification, cd.Type)
+	case challenge == "" || cd.Challenge != challenge:
+		return nil, fmt.Errorf("%w: challenge mismatch", ErrVerification)
+	case cd.CrossOrigin || !slices.Contains(rp.Origins, cd.Origin):
+		return nil, fmt.Errorf("%w: unexpected origin %q", ErrVerification, cd.Origin)
+	}
+	return raw, nil
+}
+
+// parseAuthData checks the fixed part of authenticator data and returns what
+// follows it.
+func (rp RelyingParty) parseAuthData(b []byte) (flags byte, signCount uint32, rest []byte, err error) {
+	if len(b) < 37 {
+		return 0, 0, nil, fmt.Errorf("%w: short authenticator data", ErrVerification)
+	}
+	rpIDHash := sha256.Sum256([]byte(rp.ID))
+	if !bytes.Equal(b[:32], rpIDHash[:]) {
+		return 0, 0, nil, fmt.Errorf("%w: relying party mismatch", ErrVerification)
+	}
+	flags = b[32]
+	if flags&flagUserPresent == 0 || flags&flagUserVerified == 0 {
+		return 0, 0, nil, fmt.Errorf("%w: user not verified", ErrVerification)
+	}
+	return flags, binary.BigEndian.Uint32(b[33:37]), b[37:], nil
+}
+
+// decodeB64 accepts base64url with or without padding.
+func decodeB64(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS user_identities (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  provider TEXT NOT NULL,
+  subject TEXT NOT NULL,
+  email TEXT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  UNIQUE(provider, subject),
+  UNIQUE(user_id, provider)
+);
+
+INSERT INTO user_identities(user_id, provider, subject, email)
+SELECT id, 'google', google_id, email
+FROM users
+WHERE google_id IS NOT NULL
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE users DROP COLUMN IF EXISTS google_id;
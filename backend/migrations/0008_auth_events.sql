@@ -0,0 +1,35 @@
+CREATE TABLE IF NOT EXISTS auth_events (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NULL REFERENCES users(id) ON DELETE SET NULL,
+  email TEXT NULL,
+  type TEXT NOT NULL,
+  reason TEXT NOT NULL DEFAULT '',
+  provider TEXT NOT NULL DEFAULT '',
+  ip TEXT NOT NULL DEFAULT '',
+  user_agent TEXT NOT NULL DEFAULT '',
+  request_id TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_auth_events_user ON auth_events(user_id, id DESC);
+CREATE INDEX IF NOT EXISTS idx_auth_events_email ON auth_events(email, id DESC);
+CREATE INDEX IF NOT EXISTS idx_auth_events_created ON auth_events(created_at);
+
+-- Events are append-only. The one permitted change strips the personal
+-- columns when the user deletes their account, leaving what happened and when.
+CREATE OR REPLACE FUNCTION auth_events_append_only() RETURNS trigger AS $$
+BEGIN
+  IF TG_OP = 'UPDATE'
+     AND NEW.user_id IS NULL AND NEW.email IS NULL AND NEW.ip = '' AND NEW.user_agent = ''
+     AND ROW(NEW.id, NEW.type, NEW.reason, NEW.provider, NEW.request_id, NEW.created_at)
+         IS NOT DISTINCT FROM ROW(OLD.id, OLD.type, OLD.reason, OLD.provider, OLD.request_id, OLD.created_at) THEN
+    RETURN NEW;
+  END IF;
+  RAISE EXCEPTION 'auth_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS auth_events_append_only ON auth_events;
+CREATE TRIGGER auth_events_append_only
+  BEFORE UPDATE OR DELETE ON auth_events
+  FOR EACH ROW EXECUTE FUNCTION auth_events_append_only();
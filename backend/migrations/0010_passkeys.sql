@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS passkeys (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  credential_id BYTEA NOT NULL UNIQUE,
+  public_key BYTEA NOT NULL, -- COSE_Key
+  sign_count BIGINT NOT NULL DEFAULT 0,
+  aaguid BYTEA NOT NULL,
+  transports TEXT[] NOT NULL DEFAULT '{}',
+  name TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  last_used_at TIMESTAMPTZ NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_passkeys_user ON passkeys(user_id);
@@ -0,0 +1,3 @@
+-- external_key is the supplier's identifier for a product; catalog imports
+-- upsert on it. Products created by hand have none.
+ALTER TABLE products ADD COLUMN IF NOT EXISTS external_key TEXT NULL UNIQUE;
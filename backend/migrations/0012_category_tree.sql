@@ -0,0 +1,45 @@
+ALTER TABLE categories
+  ADD COLUMN IF NOT EXISTS parent_id BIGINT NULL REFERENCES categories(id) ON DELETE RESTRICT,
+  ADD COLUMN IF NOT EXISTS slug TEXT NULL,
+  ADD COLUMN IF NOT EXISTS sort_order INT NOT NULL DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS description TEXT NOT NULL DEFAULT '';
+
+-- Slugs are derived from the name unless one is given; a taken slug gets the
+-- id appended.
+CREATE OR REPLACE FUNCTION category_default_slug() RETURNS trigger AS $$
+DECLARE
+  base TEXT;
+BEGIN
+  IF NEW.slug IS NULL OR NEW.slug = '' THEN
+    base := trim(both '-' from regexp_replace(lower(NEW.name), '[^a-z0-9]+', '-', 'g'));
+    IF base = '' THEN
+      base := 'category';
+    END IF;
+    NEW.slug := base;
+    IF EXISTS (SELECT 1 FROM categories WHERE slug = base AND id <> NEW.id) THEN
+      NEW.slug := base || '-' || NEW.id;
+    END IF;
+  END IF;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS category_default_slug ON categories;
+CREATE TRIGGER category_default_slug
+  BEFORE INSERT OR UPDATE ON categories
+  FOR EACH ROW EXECUTE FUNCTION category_default_slug();
+
+-- backfill existing rows one at a time through the trigger, oldest first
+DO $$
+DECLARE
+  cid BIGINT;
+BEGIN
+  FOR cid IN SELECT id FROM categories WHERE slug IS NULL ORDER BY id LOOP
+    UPDATE categories SET slug = NULL WHERE id = cid;
+  END LOOP;
+END;
+$$;
+
+ALTER TABLE categories ALTER COLUMN slug SET NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_categories_slug ON categories(slug);
+CREATE INDEX IF NOT EXISTS idx_categories_parent ON categories(parent_id, sort_order);
@@ -0,0 +1,18 @@
+-- Prices were stored as whole units of the shop currency (rupiah). Keep them
+-- in the currency's minor unit instead, next to an explicit ISO 4217 code.
+ALTER TABLE products
+  ADD COLUMN IF NOT EXISTS price_minor BIGINT NULL,
+  ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'IDR';
+
+-- IDR has two decimal places in ISO 4217
+UPDATE products SET price_minor = price * 100 WHERE price_minor IS NULL;
+
+ALTER TABLE products
+  ALTER COLUMN price_minor SET NOT NULL,
+  ADD CONSTRAINT products_price_minor_check CHECK (price_minor >= 0),
+  ADD CONSTRAINT products_currency_check CHECK (currency ~ '^[A-Z]{3}$');
+
+-- also drops idx_products_price and the old CHECK
+ALTER TABLE products DROP COLUMN IF EXISTS price;
+
+CREATE INDEX IF NOT EXISTS idx_products_price_minor ON products(price_minor);
@@ -0,0 +1,50 @@
+-- Explicit prices of a product in currencies other than its own.
+CREATE TABLE IF NOT EXISTS product_prices (
+  product_id BIGINT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+  currency CHAR(3) NOT NULL CHECK (currency ~ '^[A-Z]{3}$'),
+  amount_minor BIGINT NOT NULL CHECK (amount_minor >= 0),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (product_id, currency)
+);
+
+-- One unit of base buys rate units of quote. A pair is also used the other
+-- way round when only its inverse is listed.
+CREATE TABLE IF NOT EXISTS exchange_rates (
+  base CHAR(3) NOT NULL CHECK (base ~ '^[A-Z]{3}$'),
+  quote CHAR(3) NOT NULL CHECK (quote ~ '^[A-Z]{3}$'),
+  rate NUMERIC(24, 10) NOT NULL CHECK (rate > 0),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (base, quote),
+  CHECK (base <> quote)
+);
+
+-- Decimal places of a currency's minor unit; keep in sync with
+-- domain.currencyExponents.
+CREATE OR REPLACE FUNCTION currency_exponent(code CHAR(3)) RETURNS INT AS $$
+  SELECT CASE
+    WHEN code IN ('BIF', 'CLP', 'DJF', 'GNF', 'ISK', 'JPY', 'KMF', 'KRW',
+                  'PYG', 'RWF', 'UGX', 'UYI', 'VND', 'VUV', 'XAF', 'XOF', 'XPF') THEN 0
+    WHEN code IN ('BHD', 'IQD', 'JOD', 'KWD', 'LYD', 'OMR', 'TND') THEN 3
+    ELSE 2
+  END
+$$ LANGUAGE sql IMMUTABLE;
+
+-- A product's price in target minor units: its own price when the currency
+-- matches, else its price list entry, else its own price converted at the
+-- exchange rate. NULL when none of these exist.
+CREATE OR REPLACE FUNCTION product_price(pid BIGINT, own_minor BIGINT, own_currency CHAR(3), target CHAR(3))
+RETURNS BIGINT AS $$
+  SELECT CASE
+    WHEN own_currency = target THEN own_minor
+    ELSE COALESCE(
+      (SELECT amount_minor FROM product_prices WHERE product_id = pid AND currency = target),
+      (SELECT ROUND(own_minor * fx.rate * 10::NUMERIC ^ (currency_exponent(target) - currency_exponent(own_currency)))::BIGINT
+       FROM (
+         SELECT rate FROM exchange_rates WHERE base = own_currency AND quote = target
+         UNION ALL
+         SELECT 1 / rate FROM exchange_rates WHERE base = target AND quote = own_currency
+         LIMIT 1
+       ) fx)
+    )
+  END
+$$ LANGUAGE sql STABLE;
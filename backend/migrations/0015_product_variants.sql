@@ -0,0 +1,36 @@
+-- Variants of a product, e.g. {"color":"black","size":"M"}. Prices are in
+-- the product's currency.
+CREATE TABLE IF NOT EXISTS product_variants (
+  id BIGSERIAL PRIMARY KEY,
+  product_id BIGINT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+  sku TEXT NOT NULL UNIQUE,
+  options JSONB NOT NULL DEFAULT '{}'::jsonb,
+  price_minor BIGINT NOT NULL CHECK (price_minor >= 0),
+  in_stock BOOLEAN NOT NULL DEFAULT TRUE,
+  position INT NOT NULL DEFAULT 0,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  -- deferred so a replace can swap option values between two SKUs
+  CONSTRAINT product_variants_options_key UNIQUE (product_id, options) DEFERRABLE INITIALLY DEFERRED
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_variants_options ON product_variants USING GIN (options jsonb_path_ops);
+
+-- Products with variants keep the roll-up of them: price_minor is the lowest
+-- variant price, price_max_minor the highest and in_stock whether any variant
+-- is. price_max_minor is NULL for products without variants.
+ALTER TABLE products
+  ADD COLUMN IF NOT EXISTS price_max_minor BIGINT NULL;
+
+-- A price amount of a product in target minor units, converted at the same
+-- ratio as its base price so variants follow its price list entries.
+CREATE OR REPLACE FUNCTION product_price_scaled(pid BIGINT, base_minor BIGINT, amount_minor BIGINT, own_currency CHAR(3), target CHAR(3))
+RETURNS BIGINT AS $$
+  SELECT CASE
+    WHEN own_currency = target THEN amount_minor
+    WHEN amount_minor = base_minor THEN product_price(pid, base_minor, own_currency, target)
+    ELSE COALESCE(
+      ROUND(product_price(pid, base_minor, own_currency, target)::NUMERIC * amount_minor / NULLIF(base_minor, 0))::BIGINT,
+      product_price(pid, amount_minor, own_currency, target)
+    )
+  END
+$$ LANGUAGE sql STABLE;
@@ -0,0 +1,56 @@
+-- Places stock is kept. Stock written without a warehouse goes to 'main'.
+CREATE TABLE IF NOT EXISTS warehouses (
+  id BIGSERIAL PRIMARY KEY,
+  code TEXT NOT NULL UNIQUE,
+  name TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+INSERT INTO warehouses(code, name) VALUES ('main', 'Main warehouse') ON CONFLICT (code) DO NOTHING;
+
+-- Stock of a product, or of one of its variants, in one warehouse. Products
+-- and variants without rows are untracked and keep the in_stock they were
+-- given; tracked ones are in stock while on_hand - reserved is positive.
+CREATE TABLE IF NOT EXISTS inventory_levels (
+  id BIGSERIAL PRIMARY KEY,
+  product_id BIGINT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+  variant_id BIGINT NULL REFERENCES product_variants(id) ON DELETE CASCADE,
+  warehouse_id BIGINT NOT NULL REFERENCES warehouses(id),
+  on_hand INT NOT NULL DEFAULT 0 CHECK (on_hand >= 0),
+  reserved INT NOT NULL DEFAULT 0 CHECK (reserved >= 0),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  CONSTRAINT inventory_levels_reserved_check CHECK (reserved <= on_hand),
+  CONSTRAINT inventory_levels_item_key UNIQUE NULLS NOT DISTINCT (product_id, variant_id, warehouse_id)
+);
+
+-- Every change of on_hand made by an administrator, for auditing.
+CREATE TABLE IF NOT EXISTS inventory_adjustments (
+  id BIGSERIAL PRIMARY KEY,
+  product_id BIGINT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+  variant_id BIGINT NULL REFERENCES product_variants(id) ON DELETE CASCADE,
+  warehouse_id BIGINT NOT NULL REFERENCES warehouses(id),
+  delta INT NOT NULL,
+  on_hand_after INT NOT NULL,
+  reason TEXT NOT NULL,
+  actor_user_id BIGINT NULL REFERENCES users(id) ON DELETE SET NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_inventory_adjustments_product ON inventory_adjustments(product_id, id DESC);
+
+-- Quantities held back from sale, e.g. for an order being paid. Released
+-- reservations give their quantity back.
+CREATE TABLE IF NOT EXISTS inventory_reservations (
+  id BIGSERIAL PRIMARY KEY,
+  level_id BIGINT NOT NULL REFERENCES inventory_levels(id) ON DELETE CASCADE,
+  quantity INT NOT NULL CHECK (quantity > 0),
+  reference TEXT NOT NULL DEFAULT '',
+  status TEXT NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'released')),
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  released_at TIMESTAMPTZ NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_inventory_reservations_level ON inventory_reservations(level_id) WHERE status = 'active';
+
+ALTER TABLE products
+  ADD COLUMN IF NOT EXISTS low_stock_threshold INT NOT NULL DEFAULT 5 CHECK (low_stock_threshold >= 0);
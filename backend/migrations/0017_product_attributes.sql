@@ -0,0 +1,22 @@
+-- Typed attributes a category's products can have, e.g. ram_gb (number) or
+-- color (enum). Subcategories inherit their ancestors' attributes.
+CREATE TABLE IF NOT EXISTS category_attributes (
+  id BIGSERIAL PRIMARY KEY,
+  category_id BIGINT NOT NULL REFERENCES categories(id) ON DELETE CASCADE,
+  code TEXT NOT NULL,
+  label TEXT NOT NULL,
+  type TEXT NOT NULL CHECK (type IN ('string', 'number', 'boolean', 'enum')),
+  unit TEXT NOT NULL DEFAULT '',
+  -- the allowed values of an enum attribute, empty for the other types
+  enum_values TEXT[] NOT NULL DEFAULT '{}',
+  position INT NOT NULL DEFAULT 0,
+  CONSTRAINT category_attributes_code_key UNIQUE (category_id, code)
+);
+
+CREATE INDEX IF NOT EXISTS idx_category_attributes_code ON category_attributes(code);
+
+-- Attribute values by code, as JSON strings, numbers and booleans.
+ALTER TABLE products
+  ADD COLUMN IF NOT EXISTS attributes JSONB NOT NULL DEFAULT '{}'::jsonb;
+
+CREATE INDEX IF NOT EXISTS idx_products_attributes ON products USING GIN (attributes);
@@ -0,0 +1,76 @@
+CREATE TABLE IF NOT EXISTS brands (
+  id BIGSERIAL PRIMARY KEY,
+  name TEXT NOT NULL,
+  slug TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_brands_name ON brands(lower(name));
+CREATE UNIQUE INDEX IF NOT EXISTS idx_brands_slug ON brands(slug);
+
+-- Slugs are derived from the name unless one is given; a taken slug gets the
+-- id appended.
+CREATE OR REPLACE FUNCTION brand_default_slug() RETURNS trigger AS $$
+DECLARE
+  base TEXT;
+BEGIN
+  IF NEW.slug IS NULL OR NEW.slug = '' THEN
+    base := trim(both '-' from regexp_replace(lower(NEW.name), '[^a-z0-9]+', '-', 'g'));
+    IF base = '' THEN
+      base := 'brand';
+    END IF;
+    NEW.slug := base;
+    IF EXISTS (SELECT 1 FROM brands WHERE slug = base AND id <> NEW.id) THEN
+      NEW.slug := base || '-' || NEW.id;
+    END IF;
+  END IF;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS brand_default_slug ON brands;
+CREATE TRIGGER brand_default_slug
+  BEFORE INSERT OR UPDATE ON brands
+  FOR EACH ROW EXECUTE FUNCTION brand_default_slug();
+
+-- A brand in use cannot be deleted. brand_name copies the brand's name so
+-- the generated search_vector can include it.
+ALTER TABLE products
+  ADD COLUMN IF NOT EXISTS brand_id BIGINT NULL REFERENCES brands(id) ON DELETE RESTRICT,
+  ADD COLUMN IF NOT EXISTS brand_name TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_products_brand ON products(brand_id);
+
+CREATE OR REPLACE FUNCTION product_brand_name() RETURNS trigger AS $$
+BEGIN
+  NEW.brand_name := COALESCE((SELECT name FROM brands WHERE id = NEW.brand_id), '');
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS product_brand_name ON products;
+CREATE TRIGGER product_brand_name
+  BEFORE INSERT OR UPDATE OF brand_id ON products
+  FOR EACH ROW EXECUTE FUNCTION product_brand_name();
+
+CREATE OR REPLACE FUNCTION brand_rename_products() RETURNS trigger AS $$
+BEGIN
+  UPDATE products SET brand_name = NEW.name WHERE brand_id = NEW.id;
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS brand_rename_products ON brands;
+CREATE TRIGGER brand_rename_products
+  AFTER UPDATE OF name ON brands
+  FOR EACH ROW WHEN (OLD.name IS DISTINCT FROM NEW.name)
+  EXECUTE FUNCTION brand_rename_products();
+
+-- a generated column's expression cannot be altered, so it is rebuilt with
+-- the brand name added
+ALTER TABLE products DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE products ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+  to_tsvector('simple', coalesce(name,'') || ' ' || coalesce(description,'') || ' ' || coalesce(brand_name,''))
+) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_products_search_vector ON products USING GIN (search_vector);
@@ -0,0 +1,5 @@
+-- Wrong two-factor codes are counted; too many in a row lock the second
+-- factor for a while so a known password cannot be used to guess the code.
+ALTER TABLE user_totp
+  ADD COLUMN IF NOT EXISTS failed_attempts INT NOT NULL DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS locked_until TIMESTAMPTZ NULL;
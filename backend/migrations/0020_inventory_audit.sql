@@ -0,0 +1,19 @@
+-- Adjustments outlive the products and variants they were made to, so the
+-- audit trail is kept when those are deleted; sku records which variant a
+-- row was about once variant_id is gone.
+ALTER TABLE inventory_adjustments
+  ADD COLUMN IF NOT EXISTS sku TEXT NOT NULL DEFAULT '';
+
+UPDATE inventory_adjustments a
+SET sku = v.sku
+FROM product_variants v
+WHERE v.id = a.variant_id AND a.sku = '';
+
+ALTER TABLE inventory_adjustments
+  ALTER COLUMN product_id DROP NOT NULL,
+  DROP CONSTRAINT IF EXISTS inventory_adjustments_product_id_fkey,
+  DROP CONSTRAINT IF EXISTS inventory_adjustments_variant_id_fkey,
+  ADD CONSTRAINT inventory_adjustments_product_id_fkey
+    FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE SET NULL,
+  ADD CONSTRAINT inventory_adjustments_variant_id_fkey
+    FOREIGN KEY (variant_id) REFERENCES product_variants(id) ON DELETE SET NULL;
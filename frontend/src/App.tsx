@@ -20,11 +20,7 @@ export default function App() {
 
       <Route
         path="/products/:id"
-        element={
-          <ProtectedRoute>
-            <ProductDetailPage />
-          </ProtectedRoute>
-        }
+        element={<ProductDetailPage />}
       />
 
       <Route path="*" element={<Navigate to="/" replace />} />
@@ -4,7 +4,7 @@ import { Star, PackageCheck, PackageX } from "lucide-react";
 import type { ProductSummary } from "@/features/catalog/api";
 import { Button } from "@/components/ui/button";
 import { Badge } from "@/components/ui/badge";
-import { cn } from "@/lib/utils";
+import { cn, formatMoney } from "@/lib/utils";
 
 export function ProductCard({ p }: { p: ProductSummary }) {
   const nav = useNavigate();
@@ -52,7 +52,10 @@ export function ProductCard({ p }: { p: ProductSummary }) {
           <div className="line-clamp-2 text-sm font-medium leading-5">{p.name}</div>
         </div>
 
-        <div className="text-base font-semibold">Rp{(p.price)}</div>
+        <div className="text-base font-semibold">
+          {formatMoney(p.price)}
+          {p.priceMax && p.priceMax.amount !== p.price.amount ? ` – ${formatMoney(p.priceMax)}` : null}
+        </div>
 
         <div className="flex items-center justify-between text-xs text-muted-foreground">
           <div className="inline-flex items-center gap-1">
@@ -10,32 +10,71 @@ export type ProductCategory = {
     name: string 
 };
 
+export type Brand = {
+    id: number;
+    name: string;
+    slug: string;
+};
+
+// Prices are a decimal string in major units plus an ISO 4217 code, so
+// they never go through a float on the wire.
+export type Money = {
+    amount: string;
+    currency: string;
+};
+
 export type ProductSummary = {
     id: number;
     name: string;
-    price: number;
+    price: Money;
+    // highest variant price; equals price for products without variants
+    priceMax: Money;
     rating: number;
     inStock: boolean;
     createdAt: string;
     thumbnail?: string | null;
+    brand?: Brand;
     categories: ProductCategory[];
 };
 
+export type ProductVariant = {
+    id: number;
+    sku: string;
+    options: Record<string, string>;
+    price: Money;
+    inStock: boolean;
+};
+
+export type ProductAttribute = {
+    code: string;
+    label: string;
+    type: "string" | "number" | "boolean" | "enum";
+    unit?: string;
+    value: string | number | boolean;
+};
+
 export type ProductImage = { 
     url: string; 
     position: number 
 };
 
+// Anonymous visitors get a preview without price and stock when the
+// backend runs with PRODUCT_DETAIL_VISIBILITY=preview.
 export type ProductDetail = {
     id: number;
     name: string;
-    price: number;
+    price?: Money;
     description: string;
     rating: number;
-    inStock: boolean;
+    inStock?: boolean;
     createdAt: string;
     images: ProductImage[];
+    brand?: Brand;
     categories: ProductCategory[];
+    prices?: Money[];
+    variants?: ProductVariant[];
+    attributes?: ProductAttribute[];
+    preview?: boolean;
 };
 
 export type SearchResponse = {
@@ -44,11 +83,13 @@ export type SearchResponse = {
     pageSize: number;
     total: number;
     totalPages: number;
+    currency: string;
 };
 
 export type SearchParams = {
     q: string;
     categories: number[];
+    brands?: number[];
     minPrice: string; 
     maxPrice: string;
     inStock: "any" | "true" | "false";
@@ -56,6 +97,9 @@ export type SearchParams = {
     method: "asc" | "desc";
     page: number; 
     pageSize: number;
+    // prices, minPrice and maxPrice are in this currency; the backend
+    // defaults to the catalog currency
+    currency?: string;
 };
 
 export async function listCategories(): Promise<{ items: Category[] }> {
@@ -65,6 +109,13 @@ export async function listCategories(): Promise<{ items: Category[] }> {
     );
 }
 
+export async function listBrands(): Promise<{ items: Brand[] }> {
+    return http<{ items: Brand[] }>(
+        "/api/brands",
+        { method: "GET" }
+    );
+}
+
 export async function searchProducts(params: SearchParams): Promise<SearchResponse> {
     const sp = new URLSearchParams();
 
@@ -74,11 +125,15 @@ export async function searchProducts(params: SearchParams): Promise<SearchRespon
     for (const c of params.categories) {
         sp.append("category", String(c));
     }
+    for (const b of params.brands ?? []) {
+        sp.append("brand", String(b));
+    }
 
     if (params.minPrice.trim() !== "") sp.set("minPrice", params.minPrice.trim());
     if (params.maxPrice.trim() !== "") sp.set("maxPrice", params.maxPrice.trim());
 
     if (params.inStock !== "any") sp.set("inStock", params.inStock);
+    if (params.currency) sp.set("currency", params.currency);
 
     sp.set("sort", params.sort);
     sp.set("method", params.method);
@@ -91,9 +146,10 @@ export async function searchProducts(params: SearchParams): Promise<SearchRespon
     );
 }
 
-export async function getProductDetail(id: number): Promise<ProductDetail> {
+export async function getProductDetail(id: number, currency?: string): Promise<ProductDetail> {
+  const qs = currency ? `?currency=${encodeURIComponent(currency)}` : "";
   return http<ProductDetail>(
-    `/api/products/${id}`, 
+    `/api/products/${id}${qs}`, 
     { method: "GET" }
 );
 }
\ No newline at end of file
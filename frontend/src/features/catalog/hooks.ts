@@ -13,11 +13,21 @@ export function useCategories() {
     });
 }
 
+export function useBrands() {
+    return useQuery({
+        queryKey: ["brands"],
+        queryFn: api.listBrands,
+        staleTime: 5 * 60_000,
+        refetchOnWindowFocus: false
+    });
+}
+
 export function useProductSearch(params: api.SearchParams) {
     const key = [
         "productSearch",
         params.q,
         params.categories.slice().sort((a, b) => a - b).join(","),
+        (params.brands ?? []).slice().sort((a, b) => a - b).join(","),
         params.minPrice,
         params.maxPrice,
         params.inStock,
@@ -31,19 +31,68 @@ async function parseApiError(res: Response): Promise<ApiError> {
     return new ApiError(msg, code, res.status);
 }
 
-export async function http<T>(input: RequestInfo, init?: RequestInit): Promise<T> {
-    const res = await fetch(input, {
+const CSRF_HEADER = "X-CSRF-Token";
+let csrfToken: Promise<string> | null = null;
+
+// The backend rejects state-changing requests without the token from
+// /api/auth/csrf; it is fetched once and reused.
+function getCsrfToken(): Promise<string> {
+    if (!csrfToken) {
+        csrfToken = fetch("/api/auth/csrf", { credentials: "include" })
+            .then(async (res) => {
+                if (!res.ok) {
+                    throw await parseApiError(res);
+                }
+                return ((await res.json()) as { token: string }).token;
+            })
+            .catch((err) => {
+                csrfToken = null;
+                throw err;
+            });
+    }
+    return csrfToken;
+}
+
+function isSafeMethod(method?: string): boolean {
+    const m = (method ?? "GET").toUpperCase();
+    return m === "GET" || m === "HEAD" || m === "OPTIONS";
+}
+
+async function send(input: RequestInfo, init?: RequestInit): Promise<Response> {
+    const headers: Record<string, string> = {
+        "Content-Type": "application/json",
+        ...((init?.headers as Record<string, string>) ?? {})
+    };
+    if (!isSafeMethod(init?.method)) {
+        headers[CSRF_HEADER] = await getCsrfToken();
+    }
+    return fetch(input, {
         ...init,
         credentials: "include",
-        headers: {
-        "Content-Type": "application/json",
-        ...(init?.headers ?? {})
-        }
+        headers
     });
+}
+
+export async function http<T>(input: RequestInfo, init?: RequestInit): Promise<T> {
+    let res = await send(input, init);
 
     if (!res.ok){ 
-        throw await parseApiError(res);
+        let err = await parseApiError(res);
+        // the csrf cookie may have been cleared; get a new token and retry once
+        if (err.code === "CSRF_FAILED") {
+            csrfToken = null;
+            res = await send(input, init);
+            if (res.ok) {
+                return parse<T>(res);
+            }
+            err = await parseApiError(res);
+        }
+        throw err;
     }
+    return parse<T>(res);
+}
+
+async function parse<T>(res: Response): Promise<T> {
     const text = await res.text();
 
     if (!text) {
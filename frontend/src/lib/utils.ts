@@ -4,3 +4,19 @@ import { twMerge } from "tailwind-merge"
 export function cn(...inputs: ClassValue[]) {
   return twMerge(clsx(inputs))
 }
+
+// formatMoney renders an API price, e.g. {amount: "1250.50", currency: "IDR"}
+// as "Rp 1.250,50". Unknown currency codes fall back to "1250.50 XYZ".
+export function formatMoney(m: { amount: string; currency: string }) {
+  const digits = m.amount.split(".")[1]?.length ?? 0
+  try {
+    return new Intl.NumberFormat("id-ID", {
+      style: "currency",
+      currency: m.currency,
+      minimumFractionDigits: digits,
+      maximumFractionDigits: digits,
+    }).format(Number(m.amount))
+  } catch {
+    return `${m.amount} ${m.currency}`
+  }
+}
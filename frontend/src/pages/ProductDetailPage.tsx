@@ -4,6 +4,7 @@ import { toast } from "sonner";
 import { Star, PackageCheck, PackageX, ChevronLeft } from "lucide-react";
 
 import { ApiError } from "@/lib/http";
+import { cn, formatMoney } from "@/lib/utils";
 import { useProductDetail } from "@/features/catalog/hooks";
 
 import { Button } from "@/components/ui/button";
@@ -79,7 +80,7 @@ export function ProductDetailPage() {
 
     if (err instanceof ApiError && err.status === 401) {
       const next = encodeURIComponent(`/products/${id}?from=${encodeURIComponent(from)}`);
-      toast.error("Session expired. Please login again.");
+      toast.error("Please log in to view this product.");
       nav(`/login?next=${next}`, { replace: true });
       return null;
     }
@@ -191,6 +192,9 @@ export function ProductDetailPage() {
             <Card className="rounded-xl">
               <CardContent className="p-5 space-y-4">
                 <div className="space-y-1">
+                  {p.brand ? (
+                    <div className="text-xs uppercase tracking-wide text-muted-foreground">{p.brand.name}</div>
+                  ) : null}
                   <div className="text-lg font-semibold leading-tight">{p.name}</div>
 
                   <div className="flex flex-wrap items-center gap-2">
@@ -200,7 +204,7 @@ export function ProductDetailPage() {
                       <span className="text-muted-foreground">/ 5</span>
                     </div>
 
-                    {p.inStock ? (
+                    {p.preview ? null : p.inStock ? (
                       <Badge className="bg-emerald-600 hover:bg-emerald-600 text-white">
                         <PackageCheck className="mr-1 h-3.5 w-3.5" />
                         Stock available
@@ -229,9 +233,53 @@ export function ProductDetailPage() {
 
                 <div className="rounded-xl border bg-muted/30 p-4">
                   <div className="text-xs text-muted-foreground">Price</div>
-                  <div className="text-2xl font-bold">Rp{(p.price)}</div>
+                  {p.preview ? (
+                    <Button
+                      variant="link"
+                      className="h-auto p-0"
+                      onClick={() =>
+                        nav(`/login?next=${encodeURIComponent(`/products/${id}?from=${encodeURIComponent(from)}`)}`)
+                      }
+                    >
+                      Log in to see the price
+                    </Button>
+                  ) : (
+                    <div className="text-2xl font-bold">{p.price && formatMoney(p.price)}</div>
+                  )}
                 </div>
 
+                {!p.preview && p.variants?.length ? (
+                  <div className="space-y-1">
+                    <div className="text-xs text-muted-foreground">Variants</div>
+                    {p.variants.map((v) => (
+                      <div
+                        key={v.id}
+                        className="flex items-center justify-between rounded-lg border px-3 py-2 text-sm"
+                      >
+                        <span>{Object.values(v.options).join(" / ")}</span>
+                        <span className={cn("font-medium", !v.inStock && "text-muted-foreground line-through")}>
+                          {formatMoney(v.price)}
+                        </span>
+                      </div>
+                    ))}
+                  </div>
+                ) : null}
+
+                {p.attributes?.length ? (
+                  <div className="space-y-1">
+                    <div className="text-xs text-muted-foreground">Specifications</div>
+                    {p.attributes.map((a) => (
+                      <div key={a.code} className="flex items-center justify-between text-sm">
+                        <span className="text-muted-foreground">{a.label}</span>
+                        <span className="font-medium">
+                          {a.type === "boolean" ? (a.value ? "Yes" : "No") : String(a.value)}
+                          {a.unit ? ` ${a.unit}` : ""}
+                        </span>
+                      </div>
+                    ))}
+                  </div>
+                ) : null}
+
                 <div className="text-xs text-muted-foreground">
                   ID: <span className="font-medium text-foreground">{p.id}</span> - Date Posted:{" "}
                   <span className="font-medium text-foreground">
@@ -25,7 +25,7 @@ describe("ProductDetailPage", () => {
       data: {
         id: 1,
         name: "Nova Phone 0001",
-        price: 199.99,
+        price: { amount: "199.99", currency: "IDR" },
         description: "desc",
         rating: 4.5,
         inStock: true,